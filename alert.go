@@ -0,0 +1,211 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 告警规则，统计窗口内满足条件的日志数量达到阈值时向URL发送告警
+type AlertRule struct {
+	Name      string            `json:"name" yaml:"name"`           // 规则名称
+	Level     string            `json:"level" yaml:"level"`         // 日志的最低级别，为空时为error
+	Message   string            `json:"message" yaml:"message"`     // 日志消息包含的字符串，为空时不限制
+	Fields    map[string]string `json:"fields" yaml:"fields"`       // 日志字段的值，如component: db
+	Threshold int               `json:"threshold" yaml:"threshold"` // 统计窗口内的日志数量阈值，为0时为1
+	Window    int               `json:"window" yaml:"window"`       // 统计窗口，单位秒，为0时为60
+	Debounce  int               `json:"debounce" yaml:"debounce"`   // 两次告警的最小间隔，单位秒，为0时与Window相同
+	Samples   int               `json:"samples" yaml:"samples"`     // 告警中携带的最近日志条数，为0时为5
+	URL       string            `json:"url" yaml:"url"`             // 接收告警的地址
+	// 告警格式，三个选项
+	// json：默认格式，包含规则、数量和最近日志
+	// slack：Slack incoming webhook格式
+	// pagerduty：PagerDuty Events API v2格式，需要设置RoutingKey
+	Format     string `json:"format" yaml:"format"`
+	RoutingKey string `json:"routing_key" yaml:"routingKey"`
+}
+
+// 发送告警的http客户端
+var AlertClient = &http.Client{Timeout: 5 * time.Second}
+
+// 告警内容
+type alertEvent struct {
+	Rule    string                   `json:"rule"`
+	Count   int                      `json:"count"`
+	Window  string                   `json:"window"`
+	Summary string                   `json:"summary"`
+	Samples []map[string]interface{} `json:"samples"`
+}
+
+// 单条规则的统计状态
+type alerter struct {
+	sync.Mutex
+	entryMatcher
+	rule      AlertRule
+	threshold int
+	window    time.Duration
+	debounce  time.Duration
+	samples   int
+	hits      []time.Time // 统计窗口内的日志时间
+	recent    []map[string]interface{}
+	lastSent  time.Time
+	send      func(*AlertRule, alertEvent)
+}
+
+func newAlerter(r AlertRule) *alerter {
+	a := &alerter{
+		entryMatcher: newEntryMatcher(r.Level, r.Message, r.Fields, zapcore.ErrorLevel),
+		rule:         r,
+		threshold:    r.Threshold,
+		window:       time.Duration(r.Window) * time.Second,
+		debounce:     time.Duration(r.Debounce) * time.Second,
+		samples:      r.Samples,
+		send:         postAlert,
+	}
+	if a.threshold <= 0 {
+		a.threshold = 1
+	}
+	if a.window <= 0 {
+		a.window = time.Minute
+	}
+	if a.debounce <= 0 {
+		a.debounce = a.window
+	}
+	if a.samples <= 0 {
+		a.samples = 5
+	}
+	return a
+}
+
+// 记录一条满足条件的日志，达到阈值且距上次告警超过Debounce时发送告警
+func (a *alerter) record(ent zapcore.Entry, fields map[string]interface{}) {
+	a.Lock()
+	defer a.Unlock()
+	now := ent.Time
+	i := 0
+	for i < len(a.hits) && now.Sub(a.hits[i]) > a.window {
+		i++
+	}
+	a.hits = append(a.hits[i:], now)
+
+	sample := make(map[string]interface{}, len(fields)+3)
+	for k, v := range fields {
+		sample[k] = v
+	}
+	sample["ts"] = ent.Time.Format(time.RFC3339Nano)
+	sample["level"] = ent.Level.String()
+	sample["msg"] = ent.Message
+	if len(a.recent) == a.samples {
+		a.recent = a.recent[1:]
+	}
+	a.recent = append(a.recent, sample)
+
+	if len(a.hits) < a.threshold || (!a.lastSent.IsZero() && now.Sub(a.lastSent) < a.debounce) {
+		return
+	}
+	a.lastSent = now
+	event := alertEvent{
+		Rule:    a.rule.Name,
+		Count:   len(a.hits),
+		Window:  a.window.String(),
+		Summary: fmt.Sprintf("%s: %d entries in %s", a.rule.Name, len(a.hits), a.window),
+		Samples: append([]map[string]interface{}(nil), a.recent...),
+	}
+	go a.send(&a.rule, event)
+}
+
+// 按规则的格式发送告警，失败时输出到标准错误
+func postAlert(r *AlertRule, event alertEvent) {
+	var body interface{} = event
+	switch strings.ToLower(r.Format) {
+	case "slack":
+		samples, _ := json.MarshalIndent(event.Samples, "", "  ")
+		body = map[string]string{"text": event.Summary + "\n```" + string(samples) + "```"}
+	case "pagerduty":
+		host, _ := os.Hostname()
+		body = map[string]interface{}{
+			"routing_key":  r.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    r.Name,
+			"payload": map[string]interface{}{
+				"summary":        event.Summary,
+				"source":         host,
+				"severity":       "error",
+				"custom_details": event,
+			},
+		}
+	}
+	buf, err := json.Marshal(body)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = AlertClient.Post(r.URL, "application/json", bytes.NewReader(buf)); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("unexpected status %s", resp.Status)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "send alert %s: %v\n", r.Name, err)
+	}
+}
+
+// 按告警规则统计日志的core，不写入日志
+type alertCore struct {
+	alerters []*alerter
+	min      zapcore.Level
+	fields   []zapcore.Field
+}
+
+func newAlertCore(rules []AlertRule) *alertCore {
+	c := &alertCore{min: zapcore.FatalLevel}
+	for _, r := range rules {
+		if r.URL == "" {
+			continue
+		}
+		a := newAlerter(r)
+		if a.level < c.min {
+			c.min = a.level
+		}
+		c.alerters = append(c.alerters, a)
+	}
+	return c
+}
+
+func (c *alertCore) Enabled(l zapcore.Level) bool {
+	return len(c.alerters) > 0 && l >= c.min
+}
+
+func (c *alertCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *alertCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *alertCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	values := fieldMap(c.fields, fields)
+	for _, a := range c.alerters {
+		if a.match(ent, values) {
+			a.record(ent, values)
+		}
+	}
+	return nil
+}
+
+func (c *alertCore) Sync() error {
+	return nil
+}
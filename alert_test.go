@@ -0,0 +1,52 @@
+package logx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAlert(t *testing.T) {
+	bodies := make(chan map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	l := zap.New(newAlertCore([]AlertRule{
+		{Name: "db errors", Fields: map[string]string{"component": "db"}, Threshold: 2, Samples: 1, URL: srv.URL},
+		{Name: "slack", Message: "down", URL: srv.URL, Format: "slack"},
+	}))
+	db := l.With(zap.String("component", "db"))
+	db.Warn("slow query")
+	db.Error("query failed", zap.Int("attempt", 1))
+	l.Error("query failed", zap.String("component", "cache"))
+	db.Error("query failed", zap.Int("attempt", 2))
+	// 防抖期间不再告警
+	db.Error("query failed", zap.Int("attempt", 3))
+
+	body := <-bodies
+	assert.Equal(t, "db errors", body["rule"])
+	assert.Equal(t, float64(2), body["count"])
+	samples := body["samples"].([]interface{})
+	if assert.Equal(t, 1, len(samples)) {
+		assert.Equal(t, float64(2), samples[0].(map[string]interface{})["attempt"])
+	}
+
+	l.Error("service down")
+	body = <-bodies
+	assert.Contains(t, body["text"], "slack: 1 entries in 1m0s")
+
+	select {
+	case body = <-bodies:
+		t.Fatalf("unexpected alert %v", body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,130 @@
+package logx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"go.uber.org/zap/zapcore"
+)
+
+// 自定义appender类型的构造函数，options为配置中appender的options
+type AppenderFactory func(options map[string]interface{}) (rollingwriter.RollingWriter, error)
+
+var (
+	appendersMu sync.RWMutex
+	appenders   = make(map[string]AppenderFactory)
+
+	ErrUnknownAppender = errors.New("error unknown appender type")
+)
+
+// 注册自定义appender类型，注册后可以通过配置中appender的type选择
+func RegisterAppender(typ string, factory AppenderFactory) {
+	appendersMu.Lock()
+	defer appendersMu.Unlock()
+	typ = strings.TrimSpace(strings.ToLower(typ))
+	if factory == nil {
+		delete(appenders, typ)
+		return
+	}
+	appenders[typ] = factory
+}
+
+// 审计日志的最低级别，appender和运行时设置的更低级别不生效
+type auditLevel struct {
+	zapcore.LevelEnabler
+}
+
+func (l auditLevel) Enabled(level zapcore.Level) bool {
+	return level >= zapcore.InfoLevel && l.LevelEnabler.Enabled(level)
+}
+
+// 将只实现io.Writer的输出目标转换为Close不做任何操作的io.WriteCloser，用于Appender.Writer
+func NopCloser(w io.Writer) io.WriteCloser {
+	return nopCloser{w}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// 将appender的options转换为结构体，字段名与结构体的json tag一致
+func decodeOptions(options map[string]interface{}, v interface{}) error {
+	m := make(map[string]interface{}, len(options))
+	for k, e := range options {
+		m[k] = stringKeys(e) // yaml解析的嵌套map
+	}
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// 根据appender类型生成writer
+func newAppenderWriter(app Appender) (rollingwriter.RollingWriter, error) {
+	if app.Writer != nil {
+		return app.Writer, nil
+	}
+	typ := strings.TrimSpace(strings.ToLower(app.Type))
+	switch typ {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	case "audit":
+		if app.Rolling == nil {
+			return nil, rollingwriter.ErrInvalidArgument
+		}
+		c := *app.Rolling
+		c.WriterMode = "audit"
+		return rollingwriter.NewWriterFromConfig(&c)
+	case "failover":
+		return newFailoverWriter(app.Failover)
+	}
+	if typ == "" || typ == "rolling" {
+		if app.Rolling == nil {
+			return nil, rollingwriter.ErrInvalidArgument
+		}
+		if app.Router != nil {
+			return rollingwriter.NewRouterWriter(*app.Rolling, *app.Router)
+		}
+		return rollingwriter.NewWriterFromConfig(app.Rolling)
+	}
+	appendersMu.RLock()
+	factory, ok := appenders[typ]
+	appendersMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownAppender
+	}
+	return factory(app.Options)
+}
+
+// 并发生成所有appender的writer，生成失败的appender输出到标准输出，影子appender不输出
+func newAppenderWriters(apps []Appender) []rollingwriter.RollingWriter {
+	writers := make([]rollingwriter.RollingWriter, len(apps))
+	var wg sync.WaitGroup
+	for i := range apps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writer, err := newAppenderWriter(apps[i])
+			if apps[i].Shadow {
+				writer = newShadowWriter(writer, err)
+			} else if err != nil {
+				writer = os.Stdout
+			}
+			writers[i] = writer
+		}(i)
+	}
+	wg.Wait()
+	return writers
+}
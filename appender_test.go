@@ -0,0 +1,43 @@
+package logx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// 模拟lumberjack.Logger的输出目标
+type rotatingBuffer struct {
+	bytes.Buffer
+	rotated int
+	closed  bool
+}
+
+func (b *rotatingBuffer) Rotate() error {
+	b.rotated++
+	return nil
+}
+
+func (b *rotatingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestAppenderWriter(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	target := &rotatingBuffer{}
+	var plain bytes.Buffer
+	Init(&Config{Appenders: []Appender{
+		{Writer: target, Level: "info"},
+		{Writer: NopCloser(&plain), Level: "info"},
+	}})
+	Info("hello")
+	assert.Contains(t, target.String(), "hello")
+	assert.Contains(t, plain.String(), "hello")
+
+	_, err := controlRotate([]string{"0"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, target.rotated)
+}
@@ -0,0 +1,37 @@
+package logx
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAuditAppender(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	dir := t.TempDir()
+	rolling := rollingwriter.NewDefaultConfig()
+	rolling.LogPath = dir
+	rolling.FileName = "audit"
+	rolling.RollingPolicy = rollingwriter.WithoutRolling
+	Init(&Config{
+		Sampling:  &SamplingConfig{Initial: 1, Thereafter: 1000},
+		Appenders: []Appender{{Type: "audit", Level: "debug", Rolling: &rolling}},
+	})
+	writer := currentAppenders()[0].writer
+	defer writer.Close()
+	assert.IsType(t, &rollingwriter.AuditWriter{}, writer)
+
+	Debug("debug")
+	for i := 0; i < 3; i++ {
+		Info("transfer")
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(buf), "transfer"))
+	assert.NotContains(t, string(buf), "debug")
+}
@@ -0,0 +1,236 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Muskchen/logx"
+	"github.com/Muskchen/logx/rollingwriter"
+	"go.uber.org/zap"
+)
+
+// 一个基准场景，name为子基准的名称
+type benchCase struct {
+	name string
+	fn   func(*testing.B)
+}
+
+func runCases(b *testing.B, cases []benchCase) {
+	for _, c := range cases {
+		b.Run(c.name, c.fn)
+	}
+}
+
+// 参与比较的写入模式
+var _modes = []string{"none", "lock", "async", "buffer", "sharded"}
+
+func BenchmarkConcurrentWriters(b *testing.B) { runCases(b, concurrentCases()) }
+
+func BenchmarkMixedSizes(b *testing.B) { runCases(b, mixedCases()) }
+
+func BenchmarkRotationUnderLoad(b *testing.B) { runCases(b, rotationCases()) }
+
+func BenchmarkCompression(b *testing.B) { runCases(b, compressionCases()) }
+
+func BenchmarkLogger(b *testing.B) { runCases(b, loggerCases()) }
+
+func newWriter(b *testing.B, ops ...rollingwriter.Option) rollingwriter.RollingWriter {
+	ops = append([]rollingwriter.Option{rollingwriter.WithLogPath(b.TempDir()), rollingwriter.WithFileName("bench"),
+		rollingwriter.WithoutRollingPolicy()}, ops...)
+	w, err := rollingwriter.NewWriter(ops...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return w
+}
+
+// 各写入模式下16倍GOMAXPROCS个协程并发写入256字节的日志
+func concurrentCases() []benchCase {
+	line := []byte(strings.Repeat("x", 255) + "\n")
+	var cases []benchCase
+	for _, mode := range _modes {
+		mode := mode
+		cases = append(cases, benchCase{mode, func(b *testing.B) {
+			w := newWriter(b, rollingwriter.WithWriterMode(mode))
+			b.ReportAllocs()
+			b.SetBytes(int64(len(line)))
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w.Write(line)
+				}
+			})
+			b.StopTimer()
+			w.Close()
+		}})
+	}
+	return cases
+}
+
+// 按真实日志的分布生成的混合大小日志：多数为几百字节，少量为几KB到几十KB的堆栈或请求体
+func mixedEntries(n int) [][]byte {
+	r := rand.New(rand.NewSource(1))
+	entries := make([][]byte, n)
+	for i := range entries {
+		var size int
+		switch p := r.Intn(100); {
+		case p < 70:
+			size = 64 + r.Intn(256)
+		case p < 95:
+			size = 512 + r.Intn(2048)
+		case p < 99:
+			size = 4096 + r.Intn(12288)
+		default:
+			size = 32768 + r.Intn(32768)
+		}
+		entries[i] = []byte(strings.Repeat("x", size-1) + "\n")
+	}
+	return entries
+}
+
+// 各写入模式下并发写入混合大小的日志
+func mixedCases() []benchCase {
+	entries := mixedEntries(1024)
+	var total int
+	for _, e := range entries {
+		total += len(e)
+	}
+	var cases []benchCase
+	for _, mode := range _modes {
+		mode := mode
+		cases = append(cases, benchCase{mode, func(b *testing.B) {
+			w := newWriter(b, rollingwriter.WithWriterMode(mode))
+			b.ReportAllocs()
+			b.SetBytes(int64(total / len(entries)))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.Intn(len(entries))
+				for pb.Next() {
+					w.Write(entries[i%len(entries)])
+					i++
+				}
+			})
+			b.StopTimer()
+			w.Close()
+		}})
+	}
+	return cases
+}
+
+// 按1MB滚动时并发写入，测量滚动对写入的影响，compress时同时在后台压缩历史日志
+func rotationCases() []benchCase {
+	line := []byte(strings.Repeat("x", 511) + "\n")
+	var cases []benchCase
+	for _, mode := range []string{"lock", "async"} {
+		for _, compress := range []bool{false, true} {
+			mode, compress := mode, compress
+			name := mode
+			if compress {
+				name += "/compress"
+			}
+			cases = append(cases, benchCase{name, func(b *testing.B) {
+				ops := []rollingwriter.Option{rollingwriter.WithWriterMode(mode), rollingwriter.WithRollingVolumeSize("1mb"),
+					rollingwriter.WithMaxRemain(4), rollingwriter.WithRollingPolicy(rollingwriter.VolumeRolling)}
+				if compress {
+					ops = append(ops, rollingwriter.WithCompress())
+				}
+				w := newWriter(b, ops...)
+				b.ReportAllocs()
+				b.SetBytes(int64(len(line)))
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						w.Write(line)
+					}
+				})
+				b.StopTimer()
+				w.Close()
+			}})
+		}
+	}
+	return cases
+}
+
+// 压缩4MB的json历史日志文件
+func compressionCases() []benchCase {
+	line := `{"level":"info","ts":"2021-01-01T00:00:00.000+0800","msg":"request served","status":200}` + "\n"
+	content := strings.Repeat(line, 4<<20/len(line))
+	return []benchCase{{"gzip", func(b *testing.B) {
+		dir := b.TempDir()
+		w := newWriter(b).(*rollingwriter.LockedWriter)
+		b.SetBytes(int64(len(content)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			src := fmt.Sprintf("%s/archive-%d.log", dir, i)
+			f := createFile(b, src, content)
+			b.StartTimer()
+			if err := w.CompressFile(f, src+".gz"); err != nil {
+				b.Fatal(err)
+			}
+			b.StopTimer()
+			f.Close()
+			os.Remove(src)
+			os.Remove(src + ".gz")
+			b.StartTimer()
+		}
+		b.StopTimer()
+		w.Close()
+	}}}
+}
+
+func createFile(b *testing.B, name, content string) *os.File {
+	f, err := os.Create(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := io.WriteString(f, content); err != nil {
+		b.Fatal(err)
+	}
+	return f
+}
+
+// 通过logx记录带字段的json日志，包括编码和写入
+func loggerCases() []benchCase {
+	var cases []benchCase
+	for _, mode := range []string{"lock", "async"} {
+		mode := mode
+		cases = append(cases, benchCase{mode, func(b *testing.B) {
+			initQuiet(&logx.Config{
+				Type: "json",
+				Appenders: []logx.Appender{{
+					Rolling: &rollingwriter.Config{LogPath: b.TempDir(), FileName: "bench", WriterMode: mode, BatchSize: 64},
+				}},
+			})
+			l := logx.GetLogger()
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					l.Info("request served", zap.String("method", "GET"), zap.String("path", "/api/v1/orders"),
+						zap.Int("status", 200), zap.Int64("bytes", 5123), zap.Float64("latency_ms", 12.5))
+				}
+			})
+			b.StopTimer()
+			logx.Close()
+		}})
+	}
+	return cases
+}
+
+// 初始化logx，Init输出到标准输出的启动信息会打断go test -bench的结果行，初始化期间丢弃标准输出
+func initQuiet(cfg *logx.Config) {
+	stdout := os.Stdout
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	defer func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	}()
+	logx.Init(cfg)
+}
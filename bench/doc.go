@@ -0,0 +1,16 @@
+// bench包含rollingwriter和logx写入路径的基准测试，用于评估修改writer内部实现的性能影响
+//
+// 基准覆盖并发写入、混合大小的日志、负载下的日志滚动和历史日志压缩，输出可以直接用benchstat比较。
+// 基准结果与机器相关，仓库中不保存基线，修改前后在同一台机器上各运行一次：
+//
+//	go test ./bench -run '^$' -bench . -count 10 > old.txt
+//	# 修改writer
+//	go test ./bench -run '^$' -bench . -count 10 > new.txt
+//	benchstat old.txt new.txt
+//
+// 没有benchstat时可以手动执行回归检查，每个基准的ns/op超过基线中位数的(1+容忍度)倍时失败：
+//
+//	LOGX_BENCH_BASELINE=old.txt go test ./bench -run TestRegressionGate
+//
+// 回归检查不在go test ./...中执行，LOGX_BENCH_TOLERANCE指定容忍度，默认为0.2
+package bench
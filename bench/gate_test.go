@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// 基准输出中的一行，如BenchmarkMixedSizes/lock-8   	  500000	      2345 ns/op
+var _benchLine = regexp.MustCompile(`^(Benchmark\S+?)(?:-\d+)?\s+\d+\s+([\d.]+) ns/op`)
+
+// 读取go test -bench输出的基线，返回每个基准ns/op的中位数
+func loadBaseline(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	samples := make(map[string][]float64)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		m := _benchLine.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		samples[m[1]] = append(samples[m[1]], v)
+	}
+	medians := make(map[string]float64, len(samples))
+	for name, vs := range samples {
+		sort.Float64s(vs)
+		medians[name] = vs[len(vs)/2]
+	}
+	return medians, s.Err()
+}
+
+// 所有基准场景，名称与go test -bench的输出一致
+func allCases() map[string]func(*testing.B) {
+	all := make(map[string]func(*testing.B))
+	for top, cases := range map[string][]benchCase{
+		"BenchmarkConcurrentWriters": concurrentCases(),
+		"BenchmarkMixedSizes":        mixedCases(),
+		"BenchmarkRotationUnderLoad": rotationCases(),
+		"BenchmarkCompression":       compressionCases(),
+		"BenchmarkLogger":            loggerCases(),
+	} {
+		for _, c := range cases {
+			all[top+"/"+c.name] = c.fn
+		}
+	}
+	return all
+}
+
+// 性能回归检查，LOGX_BENCH_BASELINE指定同一台机器上生成的基线时执行，逐个运行基线中的基准并与基线比较
+func TestRegressionGate(t *testing.T) {
+	path := os.Getenv("LOGX_BENCH_BASELINE")
+	if path == "" {
+		t.Skip("set LOGX_BENCH_BASELINE to a baseline generated on this machine")
+	}
+	tolerance := 0.2
+	if s := os.Getenv("LOGX_BENCH_TOLERANCE"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			t.Fatal("invalid LOGX_BENCH_TOLERANCE", err)
+		}
+		tolerance = v
+	}
+	baseline, err := loadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := allCases()
+	var names []string
+	for name := range baseline {
+		if _, ok := cases[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := testing.Benchmark(cases[name])
+		if r.N == 0 {
+			t.Errorf("%s: benchmark failed", name)
+			continue
+		}
+		got := float64(r.NsPerOp())
+		limit := baseline[name] * (1 + tolerance)
+		if got > limit {
+			t.Errorf("%s: %.0f ns/op, baseline %.0f ns/op, limit %.0f ns/op", name, got, baseline[name], limit)
+			continue
+		}
+		t.Logf("%s: %.0f ns/op, baseline %.0f ns/op", name, got, baseline[name])
+	}
+}
+
+func TestLoadBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.txt")
+	ioutil.WriteFile(path, []byte(`goos: linux
+BenchmarkMixedSizes/lock-8   	 1000000	      1200 ns/op	1084.74 MB/s
+BenchmarkMixedSizes/lock-8   	 1000000	      1000 ns/op	1284.74 MB/s
+BenchmarkMixedSizes/lock-8   	2026/01/01 00:00:00 error in rotate log file
+ 1000000	      9999 ns/op
+BenchmarkMixedSizes/lock-8   	 1000000	      1100 ns/op	1184.74 MB/s
+BenchmarkLogger/async        	  566424	      1943 ns/op	     601 B/op	       4 allocs/op
+PASS
+`), 0644)
+	baseline, err := loadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"BenchmarkMixedSizes/lock": 1100, "BenchmarkLogger/async": 1943}
+	if !reflect.DeepEqual(want, baseline) {
+		t.Errorf("baseline %v, want %v", baseline, want)
+	}
+	// 基线中的名称与基准场景一致
+	cases := allCases()
+	for name := range want {
+		if _, ok := cases[name]; !ok {
+			t.Errorf("unknown benchmark %s", name)
+		}
+	}
+}
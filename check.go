@@ -0,0 +1,259 @@
+package logx
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"go.uber.org/zap"
+)
+
+// 配置检查的结果
+type Report struct {
+	File      string           `json:"file"`      // 配置文件路径
+	Config    *Config          `json:"config"`    // 合并include和模板后的配置
+	Appenders []AppenderReport `json:"appenders"` // 各appender的检查结果
+	Problems  []string         `json:"problems"`  // 全局配置的问题
+}
+
+// 单个appender的检查结果
+type AppenderReport struct {
+	Index    int      `json:"index"`
+	Type     string   `json:"type"`           // appender类型，为空时为rolling
+	Level    string   `json:"level"`          // 生效的日志级别
+	File     string   `json:"file,omitempty"` // 日志文件的绝对路径
+	Writable bool     `json:"writable"`       // 日志目录是否可写，目录不存在时检查能否创建
+	Problems []string `json:"problems"`       // 该appender的问题
+}
+
+// 配置没有任何问题
+func (r *Report) OK() bool {
+	if len(r.Problems) > 0 {
+		return false
+	}
+	for _, app := range r.Appenders {
+		if len(app.Problems) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 检查配置文件，不初始化logger也不打开日志文件，适合在CI或进程启动参数中使用
+// 按扩展名识别json和yaml类型，解析失败时返回错误，配置中的问题记录在Report中
+func CheckConfigFile(path string) (*Report, error) {
+	var typ string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		typ = "json"
+	case ".yaml", ".yml":
+		typ = "yaml"
+	default:
+		return nil, ErrConfigType
+	}
+	cfg, err := LoadConfigFile(path, typ)
+	if err != nil {
+		return nil, err
+	}
+	r := CheckConfig(cfg)
+	r.File = path
+	return r, nil
+}
+
+// 检查配置，解析日志路径，校验级别、写入模式和cron表达式，检查日志目录是否可写
+func CheckConfig(cfg *Config) *Report {
+	r := &Report{Config: cfg}
+	switch strings.TrimSpace(strings.ToLower(cfg.Type)) {
+	case "", "json", "console", "msgpack", "protobuf":
+	default:
+		r.Problems = append(r.Problems, fmt.Sprintf("unknown type %q", cfg.Type))
+	}
+	switch strings.TrimSpace(strings.ToLower(cfg.Color)) {
+	case "", "auto", "always", "never":
+	default:
+		r.Problems = append(r.Problems, fmt.Sprintf("unknown color %q", cfg.Color))
+	}
+	switch strings.TrimSpace(strings.ToLower(cfg.Multiline)) {
+	case "", "auto", "indent", "escape":
+	default:
+		r.Problems = append(r.Problems, fmt.Sprintf("unknown multiline mode %q", cfg.Multiline))
+	}
+	if cfg.Locale != "" && lookupLocale(cfg.Locale) == nil {
+		r.Problems = append(r.Problems, fmt.Sprintf("unknown locale %q", cfg.Locale))
+	}
+	levels := make([]string, 0, len(cfg.LevelLabels))
+	for level := range cfg.LevelLabels {
+		if !validLevel(level) {
+			levels = append(levels, level)
+		}
+	}
+	sort.Strings(levels)
+	for _, level := range levels {
+		r.Problems = append(r.Problems, fmt.Sprintf("level labels: invalid level %q", level))
+	}
+	switch cfg.EntrySizeMode {
+	case "", "truncate", "split":
+	default:
+		r.Problems = append(r.Problems, fmt.Sprintf("unknown entry size mode %q", cfg.EntrySizeMode))
+	}
+	if cfg.StacktraceLevel != "" && !validLevel(cfg.StacktraceLevel) {
+		r.Problems = append(r.Problems, fmt.Sprintf("invalid stacktrace level %q", cfg.StacktraceLevel))
+	}
+	for module, level := range cfg.Modules {
+		if !validLevel(level) {
+			r.Problems = append(r.Problems, fmt.Sprintf("module %s: invalid level %q", module, level))
+		}
+	}
+	if cfg.Sampling != nil {
+		r.Problems = append(r.Problems, checkSampling(cfg.Sampling)...)
+	}
+	names := make([]string, 0, len(cfg.ScrubProfiles))
+	for name := range cfg.ScrubProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := cfg.scrubProcessor(name); err != nil {
+			r.Problems = append(r.Problems, err.Error())
+		}
+	}
+	if len(cfg.Appenders) == 0 {
+		r.Problems = append(r.Problems, "no appenders")
+	}
+	for i, app := range cfg.withErrorFile().Appenders {
+		r.Appenders = append(r.Appenders, checkAppender(cfg, i, app))
+	}
+	return r
+}
+
+func checkAppender(cfg *Config, i int, app Appender) AppenderReport {
+	ar := AppenderReport{Index: i, Type: app.Type, Level: logLevel(app.Level).String()}
+	if app.Level != "" && !validLevel(app.Level) {
+		ar.Problems = append(ar.Problems, fmt.Sprintf("invalid level %q, using info", app.Level))
+	}
+	if app.TimeZone != "" {
+		if _, err := time.LoadLocation(app.TimeZone); err != nil {
+			ar.Problems = append(ar.Problems, fmt.Sprintf("invalid time zone %q", app.TimeZone))
+		}
+	}
+	for _, name := range app.Processors {
+		if len(lookupProcessors([]string{name})) == 0 {
+			ar.Problems = append(ar.Problems, fmt.Sprintf("unknown processor %q", name))
+		}
+	}
+	if app.Sampling != nil {
+		ar.Problems = append(ar.Problems, checkSampling(app.Sampling)...)
+	}
+	if _, ok := cfg.ScrubProfiles[app.ScrubProfile]; app.ScrubProfile != "" && !ok {
+		ar.Problems = append(ar.Problems, fmt.Sprintf("unknown scrub profile %q", app.ScrubProfile))
+	}
+	if app.Filter != nil {
+		if _, err := rollingwriter.NewFilterWriter(nil, *app.Filter); err != nil {
+			ar.Problems = append(ar.Problems, fmt.Sprintf("filter: %v", err))
+		}
+	}
+	if app.Writer != nil {
+		ar.Writable = true
+		return ar
+	}
+	typ := strings.TrimSpace(strings.ToLower(app.Type))
+	switch typ {
+	case "stdout", "stderr":
+		ar.Writable = true
+		return ar
+	case "failover":
+		return checkFailover(cfg, ar, app.Failover)
+	case "", "rolling", "audit":
+	default:
+		appendersMu.RLock()
+		_, ok := appenders[typ]
+		appendersMu.RUnlock()
+		if !ok {
+			ar.Problems = append(ar.Problems, fmt.Sprintf("unknown appender type %q", app.Type))
+		}
+		return ar
+	}
+	if app.Rolling == nil {
+		ar.Problems = append(ar.Problems, "missing rolling config")
+		return ar
+	}
+	c := *app.Rolling
+	if typ == "audit" {
+		c.WriterMode = "audit"
+	}
+	if err := c.Validate(); err != nil {
+		ar.Problems = append(ar.Problems, fmt.Sprintf("rolling: %v", err))
+	}
+	if c.LogPath == "" || c.FileName == "" {
+		return ar
+	}
+	file, err := filepath.Abs(rollingwriter.LogFilePath(&c))
+	if err != nil {
+		ar.Problems = append(ar.Problems, err.Error())
+		return ar
+	}
+	ar.File = file
+	if err := checkWritable(filepath.Dir(file)); err != nil {
+		ar.Problems = append(ar.Problems, fmt.Sprintf("log directory not writable: %v", err))
+	} else {
+		ar.Writable = true
+	}
+	return ar
+}
+
+// 检查故障转移的每个输出目标，任一目标可写即可写，File为第一个目标的日志文件
+func checkFailover(cfg *Config, ar AppenderReport, fc *FailoverConfig) AppenderReport {
+	if fc == nil || len(fc.Sinks) == 0 {
+		ar.Problems = append(ar.Problems, "missing failover sinks")
+		return ar
+	}
+	for j, sink := range fc.Sinks {
+		sr := checkAppender(cfg, j, sink)
+		if j == 0 {
+			ar.File = sr.File
+		}
+		ar.Writable = ar.Writable || sr.Writable
+		for _, p := range sr.Problems {
+			ar.Problems = append(ar.Problems, fmt.Sprintf("failover[%d]: %s", j, p))
+		}
+	}
+	return ar
+}
+
+// 级别名称是否有效
+func validLevel(level string) bool {
+	var l zap.AtomicLevel
+	return l.UnmarshalText([]byte(strings.TrimSpace(strings.ToLower(level)))) == nil
+}
+
+// 在dir或其最近的已存在的上级目录中创建临时文件，检查能否写入日志
+func checkWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	file, err := ioutil.TempFile(dir, ".logx-check-")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,46 @@
+package logx
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "logx.yaml")
+	assert.NoError(t, ioutil.WriteFile(name, []byte(`
+type: json
+appenders:
+  - level: debug
+    rolling: {logPath: `+dir+`/log, fileName: app, writerMode: lock, rollingPolicy: 1, rollingTimePattern: "0 0 * * *"}
+  - level: verbose
+    rolling: {logPath: `+dir+`/log, fileName: bad, writerMode: lock, rollingPolicy: 1, rollingTimePattern: "every day"}
+    filter: {exclude: [{pattern: "health("}]}
+  - type: kafka
+`), 0644))
+	r, err := CheckConfigFile(name)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, r.OK())
+	assert.Equal(t, name, r.File)
+	if assert.Equal(t, 3, len(r.Appenders)) {
+		assert.Empty(t, r.Appenders[0].Problems)
+		assert.True(t, r.Appenders[0].Writable)
+		assert.Equal(t, "debug", r.Appenders[0].Level)
+		assert.Equal(t, filepath.Join(dir, "log", "app.log"), r.Appenders[0].File)
+		assert.Equal(t, 3, len(r.Appenders[1].Problems))
+		assert.Contains(t, r.Appenders[1].Problems[1], "filter: error parsing regexp")
+		assert.Equal(t, "info", r.Appenders[1].Level)
+		assert.Equal(t, []string{`unknown appender type "kafka"`}, r.Appenders[2].Problems)
+	}
+	// 检查不创建日志目录
+	_, err = ioutil.ReadDir(filepath.Join(dir, "log"))
+	assert.Error(t, err)
+
+	_, err = CheckConfigFile(filepath.Join(dir, "logx.toml"))
+	assert.Equal(t, ErrConfigType, err)
+}
@@ -0,0 +1,288 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// clickhouse类型appender的参数，通过appender的options配置，日志需要使用json格式
+type ClickHouseConfig struct {
+	URL           string            `json:"url"`            // HTTP接口地址，如http://127.0.0.1:8123，不支持原生协议的tcp地址
+	Database      string            `json:"database"`       // 数据库，为空时为default
+	Table         string            `json:"table"`          // 写入的表
+	Username      string            `json:"username"`       // 用户名
+	Password      string            `json:"password"`       // 密码
+	Columns       map[string]string `json:"columns"`        // 列名到日志字段的映射，字段可以是ts这样的键或error.message这样的嵌套路径，为空时日志的字段按名称写入同名的列，忽略表中不存在的字段
+	AsyncInsert   bool              `json:"async_insert"`   // 是否使用服务端的异步插入，由ClickHouse合并小批量的插入
+	BatchSize     int               `json:"batch_size"`     // 每次插入的最大行数，为0时为1000
+	FlushInterval int               `json:"flush_interval"` // 行数不足BatchSize时的插入间隔，单位秒，为0时为1
+	Buffer        string            `json:"buffer"`         // 插入失败时缓存的日志大小上限，格式与RollingVolumeSize相同，为空时为8MB
+	Timeout       int               `json:"timeout"`        // 请求的超时时间，单位秒，为0时为10
+}
+
+var ErrClickHouseBufferFull = errors.New("error clickhouse buffer full")
+
+func init() {
+	RegisterAppender("clickhouse", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		var cfg ClickHouseConfig
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		return NewClickHouseWriter(cfg)
+	})
+}
+
+// 将日志按JSONEachRow格式批量插入ClickHouse表的writer，使用ClickHouse的HTTP接口
+// 写入时按Columns将日志转换为行，由后台协程每BatchSize行或每FlushInterval秒插入一次
+// 插入失败的行保留在缓存中，下一次插入时重试，缓存超过Buffer时写入返回ErrClickHouseBufferFull
+// 未使用原生TCP协议的clickhouse-go客户端，避免引入依赖并保持本模块的Go版本要求，批量插入通过HTTP接口完成
+type ClickHouseWriter struct {
+	mu      sync.Mutex
+	cfg     ClickHouseConfig
+	client  *http.Client
+	query   string   // INSERT语句
+	columns []string // 排序后的列名
+	limit   int
+	rows    [][]byte
+	size    int
+	signal  chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+func NewClickHouseWriter(cfg ClickHouseConfig) (*ClickHouseWriter, error) {
+	if cfg.URL == "" || cfg.Table == "" {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.Database == "" {
+		cfg.Database = "default"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+	limit := 8 << 20
+	if cfg.Buffer != "" {
+		limit = int(rollingwriter.ParseSize(cfg.Buffer))
+	}
+	columns := make([]string, 0, len(cfg.Columns))
+	for col := range cfg.Columns {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	query := "INSERT INTO " + quoteIdent(cfg.Database) + "." + quoteIdent(cfg.Table)
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = quoteIdent(col)
+		}
+		query += " (" + strings.Join(quoted, ", ") + ")"
+	}
+	w := &ClickHouseWriter{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		query:   query + " FORMAT JSONEachRow",
+		columns: columns,
+		limit:   limit,
+		signal:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+// ClickHouse的标识符
+func quoteIdent(name string) string {
+	return "`" + strings.Replace(name, "`", "\\`", -1) + "`"
+}
+
+func (w *ClickHouseWriter) Write(b []byte) (int, error) {
+	row, err := w.row(b)
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	if w.size+len(row) > w.limit {
+		return 0, ErrClickHouseBufferFull
+	}
+	w.rows = append(w.rows, row)
+	w.size += len(row)
+	if len(w.rows) >= w.cfg.BatchSize {
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// 插入缓存中的日志，失败时输出到标准错误
+func (w *ClickHouseWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+// 将日志转换为一行，不是json对象的日志作为message字段
+func (w *ClickHouseWriter) row(b []byte) ([]byte, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || b[0] != '{' {
+		b, _ = json.Marshal(map[string]string{"message": string(b)})
+	}
+	if len(w.columns) == 0 {
+		return append(append([]byte(nil), b...), '\n'), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	row := make(map[string]interface{}, len(w.columns))
+	for _, col := range w.columns {
+		if v, ok := lookupField(fields, w.cfg.Columns[col]); ok {
+			row[col] = v
+		}
+	}
+	buf, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+// 查找字段，先按完整的键查找，再按.分隔的路径查找嵌套的对象
+func lookupField(fields map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := fields[key]; ok {
+		return v, true
+	}
+	i := strings.IndexByte(key, '.')
+	if i < 0 {
+		return nil, false
+	}
+	nested, ok := fields[key[:i]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupField(nested, key[i+1:])
+}
+
+func (w *ClickHouseWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(time.Duration(w.cfg.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.signal:
+		case <-w.stop:
+			w.flush(true)
+			return
+		}
+		w.flush(false)
+	}
+}
+
+// 按BatchSize插入缓存中的日志，失败时将未插入的日志放回缓存，关闭时丢弃
+func (w *ClickHouseWriter) flush(closing bool) {
+	for {
+		w.mu.Lock()
+		n := len(w.rows)
+		if n > w.cfg.BatchSize {
+			n = w.cfg.BatchSize
+		}
+		batch := w.rows[:n:n]
+		w.rows = w.rows[n:]
+		for _, row := range batch {
+			w.size -= len(row)
+		}
+		w.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+		err := w.insert(batch)
+		if err == nil {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "clickhouse: insert: %v\n", err)
+		if closing {
+			fmt.Fprintf(os.Stderr, "clickhouse: %d entries dropped\n", len(batch))
+			continue
+		}
+		w.mu.Lock()
+		for _, row := range batch {
+			w.size += len(row)
+		}
+		w.rows = append(batch, w.rows...)
+		w.mu.Unlock()
+		return
+	}
+}
+
+func (w *ClickHouseWriter) insert(batch [][]byte) error {
+	params := url.Values{}
+	params.Set("database", w.cfg.Database)
+	params.Set("query", w.query)
+	if len(w.columns) == 0 {
+		params.Set("input_format_skip_unknown_fields", "1")
+	}
+	if w.cfg.AsyncInsert {
+		params.Set("async_insert", "1")
+		params.Set("wait_for_async_insert", "1")
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(w.cfg.URL, "/")+"/?"+params.Encode(), bytes.NewReader(bytes.Join(batch, nil)))
+	if err != nil {
+		return err
+	}
+	if w.cfg.Username != "" {
+		req.Header.Set("X-ClickHouse-User", w.cfg.Username)
+		req.Header.Set("X-ClickHouse-Key", w.cfg.Password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package logx
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClickHouseWriter(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		queries []string
+		rows    []string
+		fail    = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			fail = false
+			http.Error(w, "Code: 241. DB::Exception: Memory limit exceeded", http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, "logger", r.Header.Get("X-ClickHouse-User"))
+		assert.Equal(t, "1", r.URL.Query().Get("async_insert"))
+		body, _ := ioutil.ReadAll(r.Body)
+		queries = append(queries, r.URL.Query().Get("query"))
+		rows = append(rows, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+	}))
+	defer srv.Close()
+
+	w, err := NewClickHouseWriter(ClickHouseConfig{
+		URL:         srv.URL,
+		Table:       "logs",
+		Username:    "logger",
+		Columns:     map[string]string{"level": "level", "message": "msg", "error": "error.message"},
+		AsyncInsert: true,
+		BatchSize:   2,
+	})
+	assert.NoError(t, err)
+	w.Write([]byte(`{"level":"error","msg":"failed","error":{"message":"boom"},"extra":1}` + "\n"))
+	w.Write([]byte(`{"level":"info","msg":"ok"}` + "\n"))
+	// 第一次插入失败，下一次重试
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(rows) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "INSERT INTO `default`.`logs` (`error`, `level`, `message`) FORMAT JSONEachRow", queries[0])
+	assert.Equal(t, []string{
+		`{"error":"boom","level":"error","message":"failed"}`,
+		`{"level":"info","message":"ok"}`,
+	}, rows)
+}
+
+func TestClickHouseWriterURL(t *testing.T) {
+	_, err := NewClickHouseWriter(ClickHouseConfig{URL: "tcp://127.0.0.1:9000", Table: "logs"})
+	assert.Equal(t, rollingwriter.ErrInvalidArgument, err)
+}
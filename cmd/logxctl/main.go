@@ -0,0 +1,383 @@
+// logxctl 是logx日志的命令行工具，支持触发滚动、跨滚动tail、json日志格式化输出、按字段查询、查看压缩的历史日志、校验历史日志和检查配置文件
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Muskchen/logx"
+	"github.com/Muskchen/logx/control"
+	"github.com/Muskchen/logx/query"
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+const usage = `usage: logxctl <command> [flags] [args]
+
+commands:
+  rotate  -socket path            通过控制socket触发日志滚动
+  ctl     -socket path cmd [args] 发送控制命令：set-level level [appender...], rotate, flush, stats, config
+  tail    [-f] [-n lines] [-pretty] file
+                                  输出日志文件末尾n行，-f时跨滚动持续输出
+  pretty  [file...]               格式化输出json日志，未指定文件时读取标准输入
+  grep    [-level l] [-field k=v] [-since t] [-until t] [-contains s] file
+                                  查询当前及历史日志文件
+  cat     file...                 输出日志文件，自动解压压缩的历史日志
+  verify  file...                 使用.sha256校验文件校验历史日志
+  check   file                    检查logx配置文件，输出生效的配置和发现的问题
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "rotate":
+		err = ctl(os.Stdout, append([]string{"rotate"}, args...), true)
+	case "ctl":
+		err = ctl(os.Stdout, args, false)
+	case "tail":
+		err = tail(args)
+	case "pretty":
+		err = pretty(args)
+	case "grep":
+		err = grep(args)
+	case "cat":
+		err = cat(args)
+	case "verify":
+		err = verify(args)
+	case "check":
+		err = check(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logxctl:", err)
+		os.Exit(1)
+	}
+}
+
+// 发送控制命令并将结果输出到out，fixed为true时args[0]为固定的命令
+func ctl(out io.Writer, args []string, fixed bool) error {
+	socket, command, rest, err := parseCtl(args, fixed)
+	if err != nil {
+		return err
+	}
+	resp, err := control.Send(socket, command, rest...)
+	if err != nil {
+		return err
+	}
+	if len(resp.Result) > 0 {
+		fmt.Fprintln(out, string(resp.Result))
+	}
+	return nil
+}
+
+// 解析控制命令的参数，返回socket路径、命令和命令的参数
+func parseCtl(args []string, fixed bool) (socket, command string, rest []string, err error) {
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	fs.StringVar(&socket, "socket", "./logx.sock", "控制socket路径")
+	if fixed {
+		command, args = args[0], args[1:]
+	}
+	if err = fs.Parse(args); err != nil {
+		return "", "", nil, err
+	}
+	rest = fs.Args()
+	if !fixed {
+		if len(rest) == 0 {
+			return "", "", nil, fmt.Errorf("missing control command")
+		}
+		command, rest = rest[0], rest[1:]
+	}
+	return socket, command, rest, nil
+}
+
+// 输出日志文件末尾，follow时在文件被滚动（重命名或截断）后从新文件开头继续输出
+func tail(args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	follow := fs.Bool("f", false, "跨滚动持续输出")
+	lines := fs.Int("n", 10, "输出末尾的行数")
+	pp := fs.Bool("pretty", false, "格式化输出json日志")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("tail requires exactly one file")
+	}
+	name := fs.Arg(0)
+	out := func(line []byte) { os.Stdout.Write(line) }
+	if *pp {
+		p := newPrinter(os.Stdout)
+		out = p.print
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+	if err := lastLines(file, *lines, out); err != nil {
+		return err
+	}
+	if !*follow {
+		return nil
+	}
+
+	reader := bufio.NewReader(file)
+	var pending []byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		pending = append(pending, line...)
+		if err == nil {
+			out(pending)
+			pending = pending[:0]
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+		// 检查文件是否被滚动
+		cur, err1 := file.Stat()
+		info, err2 := os.Stat(name)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		offset, _ := file.Seek(0, io.SeekCurrent)
+		switch {
+		case !os.SameFile(cur, info):
+			// 重命名滚动：读完旧文件剩余内容后打开新文件
+			if rest, _ := ioutil.ReadAll(reader); len(rest) > 0 {
+				out(append(pending, rest...))
+				pending = pending[:0]
+			}
+			file.Close()
+			if file, err = os.Open(name); err != nil {
+				return err
+			}
+			reader.Reset(file)
+		case info.Size() < offset:
+			// copytruncate滚动：从文件开头继续读取
+			file.Seek(0, io.SeekStart)
+			reader.Reset(file)
+			pending = pending[:0]
+		}
+	}
+}
+
+// tail时每次从文件末尾向前读取的字节数
+const tailChunk = 32 * 1024
+
+// 输出文件的最后n行，并将文件偏移移动到文件末尾
+// 从文件末尾分块向前读取，只读取最后n行所在的数据
+func lastLines(file *os.File, n int, out func([]byte)) error {
+	end, err := file.Seek(0, io.SeekEnd)
+	if err != nil || n <= 0 {
+		return err
+	}
+	var buf []byte
+	for pos := end; pos > 0; {
+		size := int64(tailChunk)
+		if pos < size {
+			size = pos
+		}
+		pos -= size
+		b := make([]byte, int(size)+len(buf))
+		if _, err := file.ReadAt(b[:size], pos); err != nil {
+			return err
+		}
+		copy(b[size:], buf)
+		buf = b
+		if start := lineStart(buf, n); start >= 0 {
+			buf = buf[start:]
+			break
+		}
+	}
+	for len(buf) > 0 {
+		i := bytes.IndexByte(buf, '\n') + 1
+		if i == 0 {
+			i = len(buf)
+		}
+		out(buf[:i])
+		buf = buf[i:]
+	}
+	return nil
+}
+
+// 最后n行在data中的起始位置，data中不足n行时返回-1
+func lineStart(data []byte, n int) int {
+	data = bytes.TrimSuffix(data, []byte{'\n'})
+	for ; n > 0; n-- {
+		i := bytes.LastIndexByte(data, '\n')
+		if i < 0 {
+			return -1
+		}
+		if n == 1 {
+			return i + 1
+		}
+		data = data[:i]
+	}
+	return -1
+}
+
+// 格式化输出json日志
+func pretty(args []string) error {
+	p := newPrinter(os.Stdout)
+	if len(args) == 0 {
+		return p.copy(os.Stdin)
+	}
+	return cat(args, p.print)
+}
+
+// 查询日志
+func grep(args []string) error {
+	fs := flag.NewFlagSet("grep", flag.ExitOnError)
+	var levels, fields multiFlag
+	fs.Var(&levels, "level", "日志级别，可以指定多次")
+	fs.Var(&fields, "field", "字段条件key=value，可以指定多次")
+	since := fs.String("since", "", "起始时间，RFC3339格式")
+	until := fs.String("until", "", "结束时间，RFC3339格式")
+	contains := fs.String("contains", "", "日志消息包含的内容")
+	timeFormat := fs.String("time-format", "", "日志时间字段的格式")
+	dirLayout := fs.String("dir-layout", "", "历史日志文件所在日期目录的格式，如2006-01-02")
+	pp := fs.Bool("pretty", false, "格式化输出json日志")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("grep requires exactly one file")
+	}
+
+	filter := query.Filter{Levels: levels, Contains: *contains, TimeFormat: *timeFormat, DirLayout: *dirLayout}
+	if len(fields) > 0 {
+		filter.Fields = make(map[string]string, len(fields))
+		for _, f := range fields {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid field %q", f)
+			}
+			filter.Fields[kv[0]] = kv[1]
+		}
+	}
+	var err error
+	if *since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, *since); err != nil {
+			return err
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, *until); err != nil {
+			return err
+		}
+	}
+
+	it, err := query.Query(fs.Arg(0), filter)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	p := newPrinter(os.Stdout)
+	for it.Next() {
+		line := append(it.Entry().Raw, '\n')
+		if *pp {
+			p.print(line)
+		} else {
+			os.Stdout.Write(line)
+		}
+	}
+	return it.Err()
+}
+
+// 输出日志文件，自动解压压缩的历史日志，out不为空时逐行交给out处理
+func cat(files []string, out ...func([]byte)) error {
+	for _, name := range files {
+		rc, err := query.Open(name)
+		if err != nil {
+			return err
+		}
+		if len(out) == 0 {
+			_, err = io.Copy(os.Stdout, rc)
+		} else {
+			err = eachLine(rc, out[0])
+		}
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 校验历史日志文件，输出每个文件的结果，有文件校验失败时返回错误
+func verify(files []string) error {
+	failed := 0
+	for _, name := range files {
+		if err := rollingwriter.VerifyArchive(name); err != nil {
+			fmt.Printf("%s: FAILED (%v)\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: OK\n", name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d archives failed verification", failed, len(files))
+	}
+	return nil
+}
+
+// 检查logx配置文件，以json输出检查结果，有问题时返回错误
+func check(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("check requires one config file")
+	}
+	report, err := logx.CheckConfigFile(args[0])
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if !report.OK() {
+		return fmt.Errorf("%s has problems", args[0])
+	}
+	return nil
+}
+
+// 逐行读取r
+func eachLine(r io.Reader, out func([]byte)) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			out(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// 可以指定多次的命令行参数
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *multiFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
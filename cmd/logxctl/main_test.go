@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Muskchen/logx/control"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCtl(t *testing.T) {
+	for _, c := range []struct {
+		args    []string
+		fixed   bool
+		socket  string
+		command string
+		rest    []string
+		err     bool
+	}{
+		{args: []string{"stats"}, socket: "./logx.sock", command: "stats", rest: []string{}},
+		{args: []string{"-socket", "/tmp/app.sock", "set-level", "debug", "file"}, socket: "/tmp/app.sock", command: "set-level", rest: []string{"debug", "file"}},
+		{args: []string{"rotate", "-socket", "/tmp/app.sock"}, fixed: true, socket: "/tmp/app.sock", command: "rotate", rest: []string{}},
+		{args: []string{"rotate"}, fixed: true, socket: "./logx.sock", command: "rotate", rest: []string{}},
+		{args: []string{"-socket", "/tmp/app.sock"}, err: true},
+		{args: []string{"-unknown", "stats"}, err: true},
+	} {
+		socket, command, rest, err := parseCtl(c.args, c.fixed)
+		if c.err {
+			assert.Error(t, err, "%v", c.args)
+			continue
+		}
+		assert.NoError(t, err, "%v", c.args)
+		assert.Equal(t, c.socket, socket, "%v", c.args)
+		assert.Equal(t, c.command, command, "%v", c.args)
+		assert.Equal(t, c.rest, rest, "%v", c.args)
+	}
+}
+
+func TestCtl(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "logx.sock")
+	s, err := control.Listen(socket)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+	var rotated bool
+	s.Handle("rotate", func(args []string) (interface{}, error) {
+		rotated = true
+		return nil, nil
+	})
+	s.Handle("set-level", func(args []string) (interface{}, error) {
+		return args, nil
+	})
+
+	var out bytes.Buffer
+	assert.NoError(t, ctl(&out, []string{"-socket", socket, "set-level", "debug", "file"}, false))
+	assert.Equal(t, "[\"debug\",\"file\"]\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, ctl(&out, []string{"rotate", "-socket", socket}, true))
+	assert.True(t, rotated)
+
+	assert.Error(t, ctl(&out, []string{"-socket", socket, "missing"}, false))
+}
+
+func TestLastLines(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "tail.log")
+	var lines []string
+	for i := 0; i < 10000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d\n", i))
+	}
+	// 末尾没有换行的行也输出
+	lines = append(lines, "partial")
+	data := strings.Join(lines, "")
+	assert.Nil(t, ioutil.WriteFile(name, []byte(data), 0644))
+
+	// 8000行跨越多个读取块，20000行超过文件行数
+	for _, n := range []int{0, 1, 3, 8000, 20000} {
+		file, err := os.Open(name)
+		assert.Nil(t, err)
+		var got []string
+		assert.Nil(t, lastLines(file, n, func(line []byte) { got = append(got, string(line)) }))
+		want := lines
+		if n < len(lines) {
+			want = lines[len(lines)-n:]
+		}
+		if n == 0 {
+			want = nil
+		}
+		assert.Equal(t, want, got, "n=%d", n)
+		offset, _ := file.Seek(0, io.SeekCurrent)
+		assert.Equal(t, int64(len(data)), offset)
+		file.Close()
+	}
+}
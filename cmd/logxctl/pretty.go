@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 终端颜色
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorGray   = "\x1b[90m"
+)
+
+// json日志的格式化输出，非json日志原样输出
+type printer struct {
+	w     io.Writer
+	color bool
+}
+
+func newPrinter(w io.Writer) *printer {
+	p := &printer{w: w}
+	if f, ok := w.(*os.File); ok && os.Getenv("NO_COLOR") == "" {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			p.color = true
+		}
+	}
+	return p
+}
+
+// 逐行格式化输出r
+func (p *printer) copy(r io.Reader) error {
+	return eachLine(r, p.print)
+}
+
+// 格式化输出一行日志：时间 级别 调用位置 消息 其他字段
+func (p *printer) print(line []byte) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(line), &fields); err != nil {
+		p.w.Write(line)
+		return
+	}
+	level := fmt.Sprint(take(fields, "level"))
+	var buf bytes.Buffer
+	buf.WriteString(p.paint(colorGray, fmt.Sprint(take(fields, "ts"))))
+	buf.WriteByte(' ')
+	buf.WriteString(p.paint(levelColor(level), fmt.Sprintf("%-6s", level)))
+	if caller := take(fields, "file"); caller != nil {
+		buf.WriteString(p.paint(colorGray, fmt.Sprint(caller)))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(fmt.Sprint(take(fields, "msg")))
+	stack := take(fields, "stacktrace")
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, _ := json.Marshal(fields[k])
+		buf.WriteString(" " + p.paint(colorBlue, k) + "=" + string(v))
+	}
+	buf.WriteByte('\n')
+	if stack != nil {
+		buf.WriteString(p.paint(colorGray, "\t"+strings.Replace(fmt.Sprint(stack), "\n", "\n\t", -1)))
+		buf.WriteByte('\n')
+	}
+	p.w.Write(buf.Bytes())
+}
+
+func (p *printer) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// 取出字段并从map中删除
+func take(fields map[string]interface{}, key string) interface{} {
+	v, ok := fields[key]
+	if !ok {
+		return nil
+	}
+	delete(fields, key)
+	return v
+}
+
+func levelColor(level string) string {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return colorGray
+	case "INFO":
+		return colorGreen
+	case "WARN":
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
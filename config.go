@@ -0,0 +1,214 @@
+package logx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"gopkg.in/yaml.v2"
+)
+
+var ErrConfigType = rollingwriter.ErrConfigType
+
+// 从配置文件读取logx配置，支持json和yaml类型，其他类型返回ErrConfigType，未知的字段返回错误
+// 配置文件可以通过include包含其他同类型的配置文件，被包含的文件先合并，当前文件中的配置覆盖被包含的配置；
+// templates中定义appender模板，appender通过extends继承模板，只需配置与模板不同的字段，如fileName、level：
+//
+//	include: [base.yaml]
+//	templates:
+//	  rolling:
+//	    rolling: {logPath: ./log, writerMode: async, compress: true}
+//	appenders:
+//	  - extends: rolling
+//	    level: info
+//	    rolling: {fileName: app}
+func LoadConfigFile(path string, typ string) (*Config, error) {
+	raw, err := loadConfigMap(path, typ, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if err := expandTemplates(raw); err != nil {
+		return nil, err
+	}
+	delete(raw, "templates")
+
+	var buf []byte
+	if typ == "json" {
+		buf, err = json.Marshal(raw)
+	} else {
+		buf, err = yaml.Marshal(raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 未知的字段返回错误，避免拼写错误的配置被忽略
+	cfg := &Config{}
+	if err := rollingwriter.UnmarshalConfig(buf, typ, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// 读取配置文件并合并其包含的文件，visiting用于检测循环包含
+func loadConfigMap(path, typ string, visiting map[string]bool) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if visiting[abs] {
+		return nil, fmt.Errorf("%s: include cycle", path)
+	}
+	visiting[abs] = true
+	defer delete(visiting, abs)
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	switch typ {
+	case "json":
+		err = json.Unmarshal(buf, &raw)
+	case "yaml":
+		var v interface{}
+		if err = yaml.Unmarshal(buf, &v); err == nil && v != nil {
+			m, ok := stringKeys(v).(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: config must be a mapping", path)
+			}
+			raw = m
+		}
+	default:
+		return nil, ErrConfigType
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	includes, err := stringList(raw["include"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: include: %v", path, err)
+	}
+	delete(raw, "include")
+	merged := map[string]interface{}{}
+	for _, inc := range includes {
+		// 相对路径相对于当前配置文件所在的目录
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(path), inc)
+		}
+		base, err := loadConfigMap(inc, typ, visiting)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeMaps(merged, base)
+	}
+	return mergeMaps(merged, raw), nil
+}
+
+// 将appender中的extends替换为模板与appender配置合并后的结果，模板也可以通过extends继承其他模板
+func expandTemplates(raw map[string]interface{}) error {
+	templates, _ := raw["templates"].(map[string]interface{})
+	apps, _ := raw["appenders"].([]interface{})
+	for i, app := range apps {
+		m, ok := app.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		expanded, err := extend(m, templates, 0)
+		if err != nil {
+			return fmt.Errorf("appenders[%d]: %v", i, err)
+		}
+		apps[i] = expanded
+	}
+	return nil
+}
+
+// 模板继承的最大层数，超过时认为存在循环继承
+const _maxExtendDepth = 16
+
+func extend(m map[string]interface{}, templates map[string]interface{}, depth int) (map[string]interface{}, error) {
+	name, ok := m["extends"]
+	if !ok {
+		return m, nil
+	}
+	if depth >= _maxExtendDepth {
+		return nil, fmt.Errorf("template %v: extends too deep", name)
+	}
+	tmpl, ok := templates[fmt.Sprint(name)].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unknown template %v", name)
+	}
+	base, err := extend(tmpl, templates, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	own := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if k != "extends" {
+			own[k] = v
+		}
+	}
+	return mergeMaps(mergeMaps(map[string]interface{}{}, base), own), nil
+}
+
+// 将src深度合并到dst，map逐个字段合并，其他值由src覆盖，返回dst
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		sm, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dm, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dm = map[string]interface{}{}
+		} else {
+			dm = mergeMaps(map[string]interface{}{}, dm)
+		}
+		dst[k] = mergeMaps(dm, sm)
+	}
+	return dst
+}
+
+// 将yaml解析出的map[interface{}]interface{}转换为map[string]interface{}
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = stringKeys(e)
+		}
+	}
+	return v
+}
+
+// include可以是单个文件或文件列表
+func stringList(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid file %v", e)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("invalid value %v", v)
+}
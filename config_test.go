@@ -0,0 +1,93 @@
+package logx
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		name = filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+		return name
+	}
+	write("base.yaml", `
+type: json
+format: "2006-01-02"
+templates:
+  rolling:
+    level: info
+    rolling: {logPath: ./log, writerMode: async, compress: true, maxRemain: 7}
+  debug:
+    extends: rolling
+    level: debug
+`)
+	main := write("app.yaml", `
+include: base.yaml
+format: "15:04:05"
+appenders:
+  - extends: rolling
+    rolling: {fileName: app}
+  - extends: debug
+    rolling: {fileName: trace, compress: false}
+`)
+	cfg, err := LoadConfigFile(main, "yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "json", cfg.Type)
+	assert.Equal(t, "15:04:05", cfg.Format)
+	if assert.Equal(t, 2, len(cfg.Appenders)) {
+		app := cfg.Appenders[0]
+		assert.Equal(t, "info", app.Level)
+		assert.Equal(t, "app", app.Rolling.FileName)
+		assert.Equal(t, "async", app.Rolling.WriterMode)
+		assert.True(t, app.Rolling.Compress)
+		trace := cfg.Appenders[1]
+		assert.Equal(t, "debug", trace.Level)
+		assert.Equal(t, "trace", trace.Rolling.FileName)
+		assert.Equal(t, "./log", trace.Rolling.LogPath)
+		assert.Equal(t, 7, trace.Rolling.MaxRemain)
+		assert.False(t, trace.Rolling.Compress)
+	}
+
+	write("a.json", `{"include": ["b.json"], "type": "json"}`)
+	write("b.json", `{"include": "a.json"}`)
+	_, err = LoadConfigFile(filepath.Join(dir, "a.json"), "json")
+	assert.Error(t, err)
+
+	bad := write("bad.json", `{"appenders": [{"extends": "missing"}]}`)
+	_, err = LoadConfigFile(bad, "json")
+	assert.Error(t, err)
+	_, err = LoadConfigFile(bad, "toml")
+	assert.Equal(t, ErrConfigType, err)
+}
+
+func TestLoadConfigFileUnknownField(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.yaml")
+	ioutil.WriteFile(name, []byte(`
+type: json
+appenders:
+  - level: info
+    rolling: {logPth: ./log, fileName: app}
+`), 0644)
+	_, err := LoadConfigFile(name, "yaml")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `unknown field "logPth", did you mean "logPath"?`)
+	}
+
+	name = filepath.Join(dir, "app.json")
+	ioutil.WriteFile(name, []byte(`{"type": "json", "appendrs": []}`), 0644)
+	_, err = LoadConfigFile(name, "json")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `unknown field "appendrs", did you mean "appenders"?`)
+	}
+
+	_, err = LoadConfigFile(name, "toml")
+	assert.Equal(t, ErrConfigType, err)
+}
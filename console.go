@@ -0,0 +1,170 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// 终端颜色
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorGray   = "\x1b[90m"
+)
+
+// 判断console格式输出到w时是否彩色显示
+// auto或空：w为终端且未设置NO_COLOR环境变量时彩色显示，always：总是，never：从不
+func useColor(mode string, w io.Writer) bool {
+	switch strings.TrimSpace(strings.ToLower(mode)) {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+}
+
+// w是否为终端
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// 判断console格式输出到w时多行消息和栈信息的处理方式，返回indent、escape或空
+// auto：w为终端时缩进续行，其他writer转义换行
+func multilineMode(mode string, w io.Writer) string {
+	switch mode = strings.TrimSpace(strings.ToLower(mode)); mode {
+	case "auto":
+		if isTerminal(w) {
+			return "indent"
+		}
+		return "escape"
+	case "indent", "escape":
+		return mode
+	}
+	return ""
+}
+
+// 大写且补齐宽度的日志级别，列对齐，labels中有标签时使用标签
+func alignedLevelEncoder(labels map[zapcore.Level]string) zapcore.LevelEncoder {
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(padLevel(l, labels))
+	}
+}
+
+// 彩色、大写且补齐宽度的日志级别
+func colorLevelEncoder(labels map[zapcore.Level]string) zapcore.LevelEncoder {
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(levelColor(l) + padLevel(l, labels) + colorReset)
+	}
+}
+
+func padLevel(l zapcore.Level, labels map[zapcore.Level]string) string {
+	s, ok := labels[l]
+	if !ok {
+		s = l.CapitalString()
+	}
+	if w := displayWidth(s); w < 6 {
+		s += strings.Repeat(" ", 6-w)
+	}
+	return s
+}
+
+func levelColor(l zapcore.Level) string {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return colorGray
+	case l == zapcore.InfoLevel:
+		return colorGreen
+	case l == zapcore.WarnLevel:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// 将console格式日志末尾的json字段以缩进的多行格式输出
+type prettyEncoder struct {
+	zapcore.Encoder
+}
+
+func (e *prettyEncoder) Clone() zapcore.Encoder {
+	return &prettyEncoder{e.Encoder.Clone()}
+}
+
+func (e *prettyEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	line := buf.Bytes()
+	i := bytes.LastIndex(line, []byte("\t{"))
+	if i < 0 {
+		return buf, nil
+	}
+	end := len(bytes.TrimRight(line, "\n"))
+	var out bytes.Buffer
+	if end <= i+1 || json.Indent(&out, line[i+1:end], "\t", "  ") != nil {
+		return buf, nil
+	}
+	pretty := _bufferPool.Get()
+	pretty.Write(line[:i])
+	pretty.AppendString("\n\t")
+	pretty.Write(out.Bytes())
+	pretty.Write(line[end:])
+	buf.Free()
+	return pretty, nil
+}
+
+// 处理console格式中多行的消息和栈信息，indent模式下续行以制表符缩进，与下一条日志区分；
+// escape模式下换行转义为\n和\r，保证每条日志只占一行，便于按行处理的工具解析
+type multilineEncoder struct {
+	zapcore.Encoder
+	escape bool
+}
+
+func (e *multilineEncoder) Clone() zapcore.Encoder {
+	return &multilineEncoder{Encoder: e.Encoder.Clone(), escape: e.escape}
+}
+
+func (e *multilineEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	line := buf.Bytes()
+	end := len(bytes.TrimRight(line, "\r\n"))
+	if bytes.IndexAny(line[:end], "\r\n") < 0 {
+		return buf, nil
+	}
+	out := _bufferPool.Get()
+	for _, c := range line[:end] {
+		switch {
+		case e.escape && c == '\n':
+			out.AppendString(`\n`)
+		case e.escape && c == '\r':
+			out.AppendString(`\r`)
+		case c == '\n':
+			out.AppendString("\n\t")
+		default:
+			out.AppendByte(c)
+		}
+	}
+	out.Write(line[end:])
+	buf.Free()
+	return out, nil
+}
+
+var _bufferPool = buffer.NewPool()
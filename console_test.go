@@ -0,0 +1,61 @@
+package logx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConsoleEncoder(t *testing.T) {
+	config := newEncoderConfig("15:04:05")
+	config.CallerKey = ""
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Date(2020, 1, 1, 8, 0, 0, 0, time.UTC), Message: "hello"}
+	fields := []zapcore.Field{zap.String("user", "bob")}
+	var out bytes.Buffer
+
+	enc := consoleEncoder(&Config{Type: "console"}, config, nil, &out)
+	buf, err := enc.EncodeEntry(ent, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "08:00:00\tINFO  \thello\t{\"user\": \"bob\"}\n", buf.String())
+
+	enc = consoleEncoder(&Config{Type: "console", Color: "always", Pretty: true}, config, nil, &out)
+	buf, err = enc.EncodeEntry(ent, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "08:00:00\t"+colorGreen+"INFO  "+colorReset+"\thello\n\t{\n\t  \"user\": \"bob\"\n\t}\n", buf.String())
+
+	json := zapcore.NewJSONEncoder(config)
+	assert.Equal(t, json, consoleEncoder(&Config{Type: "json", Color: "always"}, config, json, &out))
+	assert.False(t, useColor("auto", &out))
+}
+
+func TestMultilineEncoder(t *testing.T) {
+	config := newEncoderConfig("15:04:05")
+	config.CallerKey = ""
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Date(2020, 1, 1, 8, 0, 0, 0, time.UTC),
+		Message: "query failed:\nSELECT 1", Stack: "main.main\n\t/app/main.go:10"}
+	fields := []zapcore.Field{zap.String("sql", "a\nb")}
+	var out bytes.Buffer
+
+	enc := consoleEncoder(&Config{Type: "console", Multiline: "indent"}, config, nil, &out)
+	buf, err := enc.EncodeEntry(ent, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "08:00:00\tERROR \tquery failed:\n\tSELECT 1\t{\"sql\": \"a\\nb\"}\n\tmain.main\n\t\t/app/main.go:10\n", buf.String())
+
+	// 输出到非终端时转义
+	enc = consoleEncoder(&Config{Type: "console", Multiline: "auto"}, config, nil, &out)
+	buf, err = enc.EncodeEntry(ent, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "08:00:00\tERROR \tquery failed:\\nSELECT 1\t{\"sql\": \"a\\nb\"}\\nmain.main\\n\t/app/main.go:10\n", buf.String())
+
+	ent.Message, ent.Stack = "single", ""
+	buf, err = enc.EncodeEntry(ent, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "08:00:00\tERROR \tsingle\n", buf.String())
+
+	r := CheckConfig(&Config{Multiline: "fold", Appenders: []Appender{{Type: "stdout"}}})
+	assert.Equal(t, []string{`unknown multiline mode "fold"`}, r.Problems)
+}
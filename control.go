@@ -0,0 +1,206 @@
+package logx
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/Muskchen/logx/control"
+	"github.com/Muskchen/logx/rollingwriter"
+	"go.uber.org/zap"
+)
+
+// Init生成的appender运行时状态
+type appenderState struct {
+	typ    string
+	writer rollingwriter.RollingWriter
+	level  zap.AtomicLevel
+}
+
+var (
+	stateMu        sync.RWMutex
+	appenderStates []*appenderState
+	controlServer  *control.Server
+	initConfig     Config // Init使用的配置
+)
+
+// 更新当前的appender
+func setAppenders(apps []*appenderState) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	appenderStates = apps
+}
+
+// 记录Init使用的配置
+func setConfig(cfg *Config) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	initConfig = *cfg
+}
+
+// 当前生效的配置，appender的级别和模块级别包括运行时的修改，rolling为writer填充默认值后的配置
+func DumpConfig() Config {
+	stateMu.RLock()
+	cfg := initConfig
+	apps := appenderStates
+	stateMu.RUnlock()
+	cfg.Appenders = append([]Appender(nil), cfg.Appenders...)
+	for i := range cfg.Appenders {
+		if i >= len(apps) {
+			break
+		}
+		cfg.Appenders[i].Level = apps[i].level.String()
+		if w, ok := apps[i].writer.(interface{ Config() rollingwriter.Config }); ok {
+			c := w.Config()
+			cfg.Appenders[i].Rolling = &c
+		}
+	}
+	if levels := ModuleLevels(); len(levels) > 0 {
+		cfg.Modules = levels
+	}
+	return cfg
+}
+
+// 当前的appender
+func currentAppenders() []*appenderState {
+	stateMu.RLock()
+	defer stateMu.RUnlock()
+	return appenderStates
+}
+
+// 启动控制socket
+func startControl(socket string) error {
+	stopControl()
+	s, err := control.Listen(socket)
+	if err != nil {
+		return err
+	}
+	s.Handle("set-level", controlSetLevel)
+	s.Handle("module-level", controlModuleLevel)
+	s.Handle("rotate", controlRotate)
+	s.Handle("flush", controlFlush)
+	s.Handle("stats", controlStats)
+	s.Handle("config", controlConfig)
+	stateMu.Lock()
+	controlServer = s
+	stateMu.Unlock()
+	return nil
+}
+
+// 关闭控制socket
+func stopControl() {
+	stateMu.Lock()
+	s := controlServer
+	controlServer = nil
+	stateMu.Unlock()
+	if s != nil {
+		s.Close()
+	}
+}
+
+// 按参数选择appender，参数为appender的序号，为空时选择全部
+func selectAppenders(args []string) ([]*appenderState, error) {
+	apps := currentAppenders()
+	if len(args) == 0 {
+		return apps, nil
+	}
+	selected := make([]*appenderState, 0, len(args))
+	for _, arg := range args {
+		i, err := strconv.Atoi(arg)
+		if err != nil || i < 0 || i >= len(apps) {
+			return nil, errors.New("invalid appender index " + arg)
+		}
+		selected = append(selected, apps[i])
+	}
+	return selected, nil
+}
+
+// set-level <level> [appender...]：修改日志级别
+func controlSetLevel(args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing level")
+	}
+	var level zap.AtomicLevel
+	if err := level.UnmarshalText([]byte(args[0])); err != nil {
+		return nil, err
+	}
+	apps, err := selectAppenders(args[1:])
+	if err != nil {
+		return nil, err
+	}
+	for _, app := range apps {
+		app.level.SetLevel(level.Level())
+	}
+	return nil, nil
+}
+
+// module-level [module [level]]：查看、设置或删除（level为空时）模块的日志级别
+func controlModuleLevel(args []string) (interface{}, error) {
+	switch len(args) {
+	case 0:
+		return ModuleLevels(), nil
+	case 1:
+		RemoveModuleLevel(args[0])
+		return nil, nil
+	default:
+		return nil, SetModuleLevel(args[0], args[1])
+	}
+}
+
+// rotate [appender...]：立即滚动日志文件
+func controlRotate(args []string) (interface{}, error) {
+	apps, err := selectAppenders(args)
+	if err != nil {
+		return nil, err
+	}
+	rotated := 0
+	for _, app := range apps {
+		switch r := app.writer.(type) {
+		case interface{ Rotate() }:
+			r.Rotate()
+		// lumberjack.Logger等第三方滚动库
+		case interface{ Rotate() error }:
+			if err := r.Rotate(); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		rotated++
+	}
+	return map[string]int{"rotated": rotated}, nil
+}
+
+// flush：将缓存的日志写入文件
+func controlFlush(args []string) (interface{}, error) {
+	if logger == nil {
+		return nil, nil
+	}
+	return nil, logger.Sync()
+}
+
+// stats：返回各appender的状态
+func controlStats(args []string) (interface{}, error) {
+	apps := currentAppenders()
+	stats := make([]map[string]interface{}, 0, len(apps))
+	for i, app := range apps {
+		stat := map[string]interface{}{
+			"index": i,
+			"type":  app.typ,
+			"level": app.level.String(),
+		}
+		if w, ok := app.writer.(interface{ Stats() rollingwriter.Stats }); ok {
+			stat["writer"] = w.Stats()
+		}
+		if w, ok := app.writer.(*shadowWriter); ok {
+			stat["shadow"] = w.Stats()
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// config：返回当前生效的配置
+func controlConfig(args []string) (interface{}, error) {
+	return DumpConfig(), nil
+}
@@ -0,0 +1,55 @@
+// control 定义logx控制socket的协议，每个连接发送一行json请求并接收一行json响应
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+)
+
+// 客户端连接控制socket的超时时间
+var DialTimeout = 3 * time.Second
+
+// 控制请求
+type Request struct {
+	Command string   `json:"command"`        // 命令，例如rotate, set-level, flush, stats
+	Args    []string `json:"args,omitempty"` // 命令参数
+}
+
+// 控制响应
+type Response struct {
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result,omitempty"` // 命令的返回结果
+	Error  string          `json:"error,omitempty"`  // 命令失败的原因
+}
+
+// 向unix socket发送控制命令并等待响应，命令执行失败时返回响应中的错误
+func Send(socket string, command string, args ...string) (*Response, error) {
+	conn, err := net.DialTimeout("unix", socket, DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	buf, err := json.Marshal(Request{Command: command, Args: args})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(buf, '\n')); err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
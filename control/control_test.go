@@ -0,0 +1,60 @@
+package control
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControl(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "logx.sock")
+	s, err := Listen(socket)
+	if err != nil {
+		t.Fatal("error in listen", err)
+	}
+	defer s.Close()
+	s.Handle("echo", func(args []string) (interface{}, error) {
+		return args, nil
+	})
+	s.Handle("fail", func(args []string) (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+
+	resp, err := Send(socket, "echo", "a", "b")
+	assert.Nil(t, err)
+	assert.Equal(t, `["a","b"]`, string(resp.Result))
+
+	_, err = Send(socket, "fail")
+	assert.Equal(t, "failed", err.Error())
+
+	_, err = Send(socket, "missing")
+	assert.NotNil(t, err)
+}
+
+func TestListenExisting(t *testing.T) {
+	dir := t.TempDir()
+	// 残留的socket文件会被替换
+	socket := filepath.Join(dir, "logx.sock")
+	l, err := net.Listen("unix", socket)
+	if !assert.Nil(t, err) {
+		return
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	s, err := Listen(socket)
+	if assert.Nil(t, err) {
+		s.Close()
+	}
+
+	// 不是socket的文件不会被删除
+	name := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("log\n"), 0644))
+	_, err = Listen(name)
+	assert.NotNil(t, err)
+	buf, _ := ioutil.ReadFile(name)
+	assert.Equal(t, "log\n", string(buf))
+}
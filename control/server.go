@@ -0,0 +1,119 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// 控制命令的处理函数，返回值会编码为json作为响应的result
+type HandlerFunc func(args []string) (interface{}, error)
+
+// 控制socket服务
+type Server struct {
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	listener net.Listener
+	socket   string
+	wg       sync.WaitGroup
+}
+
+// 在unix socket上启动控制服务，socket文件已存在时会先删除，路径上是其他类型的文件时返回错误
+func Listen(socket string) (*Server, error) {
+	if fi, err := os.Lstat(socket); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	// 控制socket只允许当前用户访问
+	if err := os.Chmod(socket, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	s := &Server{handlers: make(map[string]HandlerFunc), listener: l, socket: socket}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// 注册控制命令
+func (s *Server) Handle(command string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = fn
+}
+
+// 关闭控制服务并删除socket文件
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	os.Remove(s.socket)
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// 处理一个连接：读取一行请求，执行命令后写入一行响应
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	var resp Response
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		resp = s.dispatch(line)
+	} else {
+		resp.Error = err.Error()
+	}
+	buf, _ := json.Marshal(resp)
+	conn.Write(append(buf, '\n'))
+}
+
+func (s *Server) dispatch(line []byte) (resp Response) {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	s.mu.RLock()
+	fn, ok := s.handlers[req.Command]
+	s.mu.RUnlock()
+	if !ok {
+		resp.Error = fmt.Sprintf("unknown command %q", req.Command)
+		return resp
+	}
+	result, err := fn(req.Args)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	if result != nil {
+		buf, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Result = buf
+	}
+	resp.OK = true
+	return resp
+}
@@ -0,0 +1,31 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDumpConfig(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	rolling := rollingwriter.NewDefaultConfig()
+	rolling.LogPath = t.TempDir()
+	rolling.RotationStrategy = ""
+	cfg := &Config{Appenders: []Appender{{Level: "info", Rolling: &rolling}}}
+	Init(cfg)
+	defer currentAppenders()[0].writer.Close()
+
+	_, err := controlSetLevel([]string{"debug"})
+	assert.NoError(t, err)
+	dump := DumpConfig()
+	if assert.Equal(t, 1, len(dump.Appenders)) {
+		assert.Equal(t, "debug", dump.Appenders[0].Level)
+		assert.Equal(t, "rename", dump.Appenders[0].Rolling.RotationStrategy)
+		assert.Equal(t, rollingwriter.DefualtFileMode, dump.Appenders[0].Rolling.FileMode)
+	}
+	// 不修改Init使用的配置
+	assert.Equal(t, "info", cfg.Appenders[0].Level)
+	assert.Equal(t, "", rolling.RotationStrategy)
+}
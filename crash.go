@@ -0,0 +1,113 @@
+package logx
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	crashMu  sync.Mutex
+	crashDir string
+	rings    []*ring // Init生成的所有环形缓存，写入崩溃文件时使用
+)
+
+// 更新崩溃文件目录和环形缓存
+func setCrash(dir string, rs []*ring) {
+	crashMu.Lock()
+	defer crashMu.Unlock()
+	crashDir, rings = dir, rs
+}
+
+// 写入崩溃文件，包含崩溃原因、构建信息、环形缓存中的日志和所有协程的栈，返回崩溃文件路径
+func writeCrash(reason string) (string, error) {
+	crashMu.Lock()
+	defer crashMu.Unlock()
+	if crashDir == "" {
+		return "", nil
+	}
+	if err := os.MkdirAll(crashDir, 0700); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "time: %s\npid: %d\nreason: %s\n", now.Format(time.RFC3339Nano), os.Getpid(), reason)
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&buf, "\n== build info ==\n%s %s\n", bi.Path, bi.Main.Version)
+		for _, dep := range bi.Deps {
+			fmt.Fprintf(&buf, "dep %s %s\n", dep.Path, dep.Version)
+		}
+	}
+	buf.WriteString("\n== recent entries ==\n")
+	for _, r := range rings {
+		for _, entry := range r.snapshot() {
+			buf.Write(entry)
+		}
+	}
+	buf.WriteString("\n== goroutines ==\n")
+	stack := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(stack, true)
+		if n < len(stack) {
+			stack = stack[:n]
+			break
+		}
+		stack = make([]byte, len(stack)*2)
+	}
+	buf.Write(stack)
+
+	name := filepath.Join(crashDir, fmt.Sprintf("crash-%s-%d.log", now.Format("20060102150405.000000000"), os.Getpid()))
+	return name, ioutil.WriteFile(name, buf.Bytes(), 0600)
+}
+
+// 在Panic和Fatal级别的日志写入后生成崩溃文件
+type crashCore struct {
+	fields []zapcore.Field
+}
+
+func (c *crashCore) Enabled(l zapcore.Level) bool {
+	return l >= zapcore.PanicLevel
+}
+
+func (c *crashCore) With(fields []zapcore.Field) zapcore.Core {
+	return &crashCore{fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *crashCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *crashCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		f.AddTo(enc)
+	}
+	_, err := writeCrash(fmt.Sprintf("%s %s %v", ent.Level.CapitalString(), ent.Message, enc.Fields))
+	return err
+}
+
+func (c *crashCore) Sync() error {
+	return nil
+}
+
+// 捕获当前协程的panic，记录错误日志并写入崩溃文件后重新panic，需要通过defer调用
+//
+//	defer logx.RecoverAndLog()
+func RecoverAndLog() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	logPanic(r, panicOptions{repanic: true})
+}
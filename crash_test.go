@@ -0,0 +1,35 @@
+package logx
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCrashCore(t *testing.T) {
+	dir := t.TempDir()
+	enc := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	rc := newRingCore(enc, zapcore.AddSync(ioutil.Discard), &RingConfig{Size: 10}, zapcore.DebugLevel)
+	setCrash(dir, []*ring{rc.(*ringCore).ring})
+	defer setCrash("", nil)
+
+	logger := zap.New(zapcore.NewTee(&crashCore{}, rc))
+	logger.Info("before crash")
+	assert.Panics(t, func() {
+		logger.With(zap.String("k", "v")).Panic("boom")
+	})
+
+	files, _ := filepath.Glob(filepath.Join(dir, "crash-*.log"))
+	if assert.Equal(t, 1, len(files)) {
+		buf, _ := ioutil.ReadFile(files[0])
+		content := string(buf)
+		assert.True(t, strings.Contains(content, "reason: PANIC boom map[k:v]"))
+		assert.True(t, strings.Contains(content, "before crash"))
+		assert.True(t, strings.Contains(content, "goroutine "))
+	}
+}
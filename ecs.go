@@ -0,0 +1,57 @@
+package logx
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ECS输出使用的版本
+const ECSVersion = "1.6.0"
+
+func init() {
+	RegisterProcessor("ecs", ecsProcessor)
+}
+
+// 是否使用ECS（Elastic Common Schema）字段名输出
+func (c *Config) ecs() bool {
+	return strings.TrimSpace(strings.ToLower(c.Schema)) == "ecs"
+}
+
+// 将字段名替换为ECS字段名，未设置Format时时间使用ISO8601格式
+func ecsEncoderConfig(config zapcore.EncoderConfig, format string) zapcore.EncoderConfig {
+	config.TimeKey = "@timestamp"
+	config.LevelKey = "log.level"
+	config.MessageKey = "message"
+	config.StacktraceKey = "error.stack_trace"
+	config.CallerKey = "log.origin.file.name"
+	config.NameKey = "log.logger"
+	config.EncodeLevel = zapcore.LowercaseLevelEncoder
+	if format == "" {
+		config.EncodeTime = func(t time.Time, en zapcore.PrimitiveArrayEncoder) {
+			en.AppendString(t.Format("2006-01-02T15:04:05.000Z07:00"))
+		}
+	}
+	return config
+}
+
+// ECS要求的固定字段
+func ecsFields() []zap.Field {
+	hostname, _ := os.Hostname()
+	return []zap.Field{zap.String("ecs.version", ECSVersion), zap.String("host.name", hostname)}
+}
+
+// 将zap.Error生成的error字段改为ECS的error.message
+func ecsProcessor(e *Entry) {
+	for i := range e.Fields {
+		f := &e.Fields[i]
+		if f.Key == "error" && f.Type == zapcore.ErrorType {
+			if err, ok := f.Interface.(error); ok {
+				*f = zap.String("error.message", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package logx
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestECS(t *testing.T) {
+	cfg := &Config{Schema: "ECS", StacktraceLevel: "error"}
+	enc := zapcore.NewJSONEncoder(ecsEncoderConfig(newEncoderConfig(""), ""))
+	obs, logs := observer.New(zapcore.InfoLevel)
+	l := newLogger(&processorCore{Core: obs, processors: lookupProcessors([]string{"ecs"})}, cfg)
+	l.Error("failed", zap.Error(errors.New("boom")))
+
+	entry := logs.All()[0]
+	buf, err := enc.EncodeEntry(entry.Entry, entry.Context)
+	assert.NoError(t, err)
+	var out map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	for _, key := range []string{"@timestamp", "log.origin.file.name", "error.stack_trace", "host.name"} {
+		assert.Contains(t, out, key)
+	}
+	assert.Equal(t, "error", out["log.level"])
+	assert.Equal(t, "failed", out["message"])
+	assert.Equal(t, "boom", out["error.message"])
+	assert.Equal(t, ECSVersion, out["ecs.version"])
+}
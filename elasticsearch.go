@@ -0,0 +1,505 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// elasticsearch类型appender的参数，通过appender的options配置，兼容OpenSearch，日志需要使用json格式
+type ElasticsearchConfig struct {
+	URLs          []string               `json:"urls"`           // 集群节点地址，如http://127.0.0.1:9200，请求失败时依次尝试下一个节点
+	Username      string                 `json:"username"`       // 用户名，不为空时使用Basic认证
+	Password      string                 `json:"password"`       // 密码
+	APIKey        string                 `json:"api_key"`        // base64编码的API key，优先于用户名和密码
+	Index         string                 `json:"index"`          // 索引名称前缀，为空时为logx
+	IndexLayout   string                 `json:"index_layout"`   // 索引名称中的日期格式，按写入时的UTC时间生成Index-日期的索引名称，为空时为2006.01.02，为-时不按时间分索引
+	Template      map[string]interface{} `json:"template"`       // 索引模板，启动后通过_index_template创建，未设置index_patterns时为Index-*
+	BatchSize     int                    `json:"batch_size"`     // 每次_bulk请求的最大日志条数，为0时为500
+	FlushInterval int                    `json:"flush_interval"` // 日志条数不足BatchSize时的发送间隔，单位秒，为0时为1
+	MaxRetries    int                    `json:"max_retries"`    // 请求失败或被限流时的重试次数，为0时为5
+	RetryBackoff  int                    `json:"retry_backoff"`  // 第一次重试前的等待时间，单位毫秒，之后每次翻倍，为0时为100
+	MaxBackoff    int                    `json:"max_backoff"`    // 重试等待时间的上限，单位秒，为0时为30
+	Buffer        string                 `json:"buffer"`         // 内存中缓存的日志大小上限，格式与RollingVolumeSize相同，为空时为8MB
+	SpillDir      string                 `json:"spill_dir"`      // 重试失败或缓存已满时保存日志的目录，集群恢复后按顺序重发，为空时不保存
+	SpillSize     string                 `json:"spill_size"`     // SpillDir中保存的日志大小上限，超过时丢弃新的日志，为空时为1GB
+	Timeout       int                    `json:"timeout"`        // 请求的超时时间，单位秒，为0时为10
+}
+
+var ErrElasticsearchBufferFull = errors.New("error elasticsearch buffer full")
+
+// 保存到SpillDir的文件的扩展名
+const _spillSuffix = ".ndjson"
+
+func init() {
+	RegisterAppender("elasticsearch", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		var cfg ElasticsearchConfig
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		return NewElasticsearchWriter(cfg)
+	})
+}
+
+// 通过_bulk接口批量写入Elasticsearch或OpenSearch的writer，写入只进入缓存，由后台协程每BatchSize条或每FlushInterval秒发送
+// 请求失败、返回429或5xx时按指数退避重试，重试失败的日志保存到SpillDir，集群恢复后先于新的日志重发
+// 文档被拒绝（如mapping冲突）时不重试，错误输出到标准错误
+type ElasticsearchWriter struct {
+	mu         sync.Mutex
+	cfg        ElasticsearchConfig
+	client     *http.Client
+	limit      int
+	spillLimit int64
+	node       int      // 当前使用的节点
+	entries    [][]byte // 待发送的文档，每个文档为action和source两行
+	size       int
+	templated  bool
+	signal     chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+	closed     bool
+}
+
+func NewElasticsearchWriter(cfg ElasticsearchConfig) (*ElasticsearchWriter, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	for i, u := range cfg.URLs {
+		cfg.URLs[i] = strings.TrimRight(u, "/")
+	}
+	if cfg.Index == "" {
+		cfg.Index = "logx"
+	}
+	if cfg.IndexLayout == "" {
+		cfg.IndexLayout = "2006.01.02"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 1
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 100
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+	limit := 8 << 20
+	if cfg.Buffer != "" {
+		limit = int(rollingwriter.ParseSize(cfg.Buffer))
+	}
+	spillLimit := int64(1 << 30)
+	if cfg.SpillSize != "" {
+		spillLimit = rollingwriter.ParseSize(cfg.SpillSize)
+	}
+	if cfg.SpillDir != "" {
+		if err := os.MkdirAll(cfg.SpillDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	w := &ElasticsearchWriter{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		limit:      limit,
+		spillLimit: spillLimit,
+		templated:  cfg.Template == nil,
+		signal:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+func (w *ElasticsearchWriter) Write(b []byte) (int, error) {
+	doc := w.document(b, time.Now())
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	if w.size+len(doc) > w.limit {
+		if w.cfg.SpillDir == "" {
+			return 0, ErrElasticsearchBufferFull
+		}
+		// 缓存已满时说明集群长时间不可用，将缓存保存到磁盘
+		if err := w.spill(w.entries); err != nil {
+			return 0, err
+		}
+		w.entries, w.size = nil, 0
+	}
+	w.entries = append(w.entries, doc)
+	w.size += len(doc)
+	if len(w.entries) >= w.cfg.BatchSize {
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// 发送缓存中的日志，发送失败的日志保存到SpillDir
+func (w *ElasticsearchWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+// 写入时的索引名称
+func (w *ElasticsearchWriter) index(t time.Time) string {
+	if w.cfg.IndexLayout == "-" {
+		return w.cfg.Index
+	}
+	return w.cfg.Index + "-" + t.UTC().Format(w.cfg.IndexLayout)
+}
+
+// 生成_bulk请求中的一个文档，不是json对象的日志作为message字段
+func (w *ElasticsearchWriter) document(b []byte, t time.Time) []byte {
+	b = bytes.TrimSpace(b)
+	action, _ := json.Marshal(map[string]interface{}{"create": map[string]string{"_index": w.index(t)}})
+	doc := make([]byte, 0, len(action)+len(b)+16)
+	doc = append(append(doc, action...), '\n')
+	if len(b) > 0 && b[0] == '{' {
+		doc = append(doc, b...)
+	} else {
+		msg, _ := json.Marshal(map[string]string{"message": string(b)})
+		doc = append(doc, msg...)
+	}
+	return append(doc, '\n')
+}
+
+func (w *ElasticsearchWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(time.Duration(w.cfg.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.signal:
+		case <-w.stop:
+			w.flush(true)
+			return
+		}
+		w.flush(false)
+	}
+}
+
+// 先重发SpillDir中的日志，再按BatchSize发送缓存中的日志，失败时将未发送的日志保存到SpillDir
+// 没有配置SpillDir时未发送的日志放回缓存，等待下一次发送；关闭时只尝试一次
+func (w *ElasticsearchWriter) flush(closing bool) {
+	down := w.replay(closing) != nil
+	for {
+		w.mu.Lock()
+		n := len(w.entries)
+		if n > w.cfg.BatchSize {
+			n = w.cfg.BatchSize
+		}
+		batch := w.entries[:n:n]
+		w.entries = w.entries[n:]
+		for _, doc := range batch {
+			w.size -= len(doc)
+		}
+		w.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+		if !down {
+			batch, down = w.send(batch, closing)
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		if w.cfg.SpillDir == "" {
+			if !closing {
+				w.requeue(batch)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "elasticsearch: %d entries dropped\n", len(batch))
+			continue
+		}
+		w.mu.Lock()
+		err := w.spill(batch)
+		w.mu.Unlock()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "elasticsearch: spill: %v\n", err)
+		}
+	}
+}
+
+// 将未发送的日志放回缓存的开头
+func (w *ElasticsearchWriter) requeue(batch [][]byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, doc := range batch {
+		w.size += len(doc)
+	}
+	w.entries = append(batch, w.entries...)
+}
+
+// 发送一批日志，返回没有写入成功且可以重试的日志，集群不可用时down为true
+func (w *ElasticsearchWriter) send(batch [][]byte, closing bool) (rest [][]byte, down bool) {
+	if !w.putTemplate() {
+		return batch, true
+	}
+	backoff := time.Duration(w.cfg.RetryBackoff) * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		var err error
+		batch, err = w.bulk(batch)
+		if len(batch) == 0 {
+			return nil, false
+		}
+		if attempt >= w.cfg.MaxRetries || closing {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "elasticsearch: bulk: %v\n", err)
+			}
+			return batch, err != nil
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.stop:
+			closing = true
+		}
+		if backoff *= 2; backoff > time.Duration(w.cfg.MaxBackoff)*time.Second {
+			backoff = time.Duration(w.cfg.MaxBackoff) * time.Second
+		}
+	}
+}
+
+// 通过_bulk写入，返回需要重试的日志，请求失败时返回全部日志和错误
+func (w *ElasticsearchWriter) bulk(batch [][]byte) ([][]byte, error) {
+	body := bytes.Join(batch, nil)
+	resp, err := w.request(http.MethodPost, "/_bulk", "application/x-ndjson", body)
+	if err != nil {
+		return batch, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return batch, fmt.Errorf("status %s", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "elasticsearch: bulk: %s: %s\n", resp.Status, bytes.TrimSpace(msg))
+		return nil, nil
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || !result.Errors {
+		return nil, nil
+	}
+	var retry [][]byte
+	for i, item := range result.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, r := range item {
+			switch {
+			case r.Status == http.StatusTooManyRequests || r.Status >= 500:
+				retry = append(retry, batch[i])
+			case r.Status >= 300:
+				fmt.Fprintf(os.Stderr, "elasticsearch: document rejected: %s\n", r.Error)
+			}
+		}
+	}
+	return retry, nil
+}
+
+// 创建索引模板，成功或没有配置模板时返回true
+func (w *ElasticsearchWriter) putTemplate() bool {
+	if w.templated {
+		return true
+	}
+	tmpl := make(map[string]interface{}, len(w.cfg.Template)+1)
+	for k, v := range w.cfg.Template {
+		tmpl[k] = v
+	}
+	if _, ok := tmpl["index_patterns"]; !ok {
+		tmpl["index_patterns"] = []string{w.cfg.Index + "-*"}
+	}
+	body, err := json.Marshal(tmpl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "elasticsearch: index template: %v\n", err)
+		w.templated = true
+		return true
+	}
+	resp, err := w.request(http.MethodPut, "/_index_template/"+w.cfg.Index, "application/json", body)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return false
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "elasticsearch: index template: %s: %s\n", resp.Status, bytes.TrimSpace(msg))
+	}
+	w.templated = true
+	return true
+}
+
+// 向当前节点发送请求，连接失败时切换到下一个节点
+func (w *ElasticsearchWriter) request(method, path, contentType string, body []byte) (*http.Response, error) {
+	var err error
+	for i := 0; i < len(w.cfg.URLs); i++ {
+		var req *http.Request
+		req, err = http.NewRequest(method, w.cfg.URLs[w.node]+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		if w.cfg.APIKey != "" {
+			req.Header.Set("Authorization", "ApiKey "+w.cfg.APIKey)
+		} else if w.cfg.Username != "" {
+			req.SetBasicAuth(w.cfg.Username, w.cfg.Password)
+		}
+		var resp *http.Response
+		if resp, err = w.client.Do(req); err == nil {
+			return resp, nil
+		}
+		w.node = (w.node + 1) % len(w.cfg.URLs)
+	}
+	return nil, err
+}
+
+// 将日志保存到SpillDir中的新文件，调用时需要持有mu
+func (w *ElasticsearchWriter) spill(batch [][]byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	if w.cfg.SpillDir == "" {
+		return ErrElasticsearchBufferFull
+	}
+	body := bytes.Join(batch, nil)
+	if spillSize(w.cfg.SpillDir)+int64(len(body)) > w.spillLimit {
+		fmt.Fprintf(os.Stderr, "elasticsearch: spill dir full, %d entries dropped\n", len(batch))
+		return nil
+	}
+	name := filepath.Join(w.cfg.SpillDir, strconv.FormatInt(time.Now().UnixNano(), 10)+_spillSuffix)
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, body, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// 按保存顺序重发SpillDir中的日志，集群不可用时返回错误，剩余的文件等待下一次重发
+func (w *ElasticsearchWriter) replay(closing bool) error {
+	if w.cfg.SpillDir == "" {
+		return nil
+	}
+	for _, name := range spillFiles(w.cfg.SpillDir) {
+		batch, err := readSpill(name)
+		if err != nil {
+			// 文件末尾不完整的文档丢弃，其余的日志照常重发
+			fmt.Fprintf(os.Stderr, "elasticsearch: replay %s: %v\n", name, err)
+		}
+		for len(batch) > 0 {
+			n := len(batch)
+			if n > w.cfg.BatchSize {
+				n = w.cfg.BatchSize
+			}
+			rest, down := w.send(batch[:n], closing)
+			if len(rest) > 0 {
+				// 将未发送的日志写回文件，保证下一次从这里继续
+				body := bytes.Join(append(rest, batch[n:]...), nil)
+				if err := ioutil.WriteFile(name, body, 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "elasticsearch: spill: %v\n", err)
+				}
+				if down {
+					return errors.New("elasticsearch unavailable")
+				}
+				break
+			}
+			batch = batch[n:]
+		}
+		if len(batch) == 0 {
+			os.Remove(name)
+		}
+	}
+	return nil
+}
+
+// SpillDir中待重发的文件，按保存时间排序
+func spillFiles(dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), _spillSuffix) {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func spillSize(dir string) int64 {
+	var size int64
+	for _, name := range spillFiles(dir) {
+		if info, err := os.Stat(name); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// 读取保存的日志，每两行为一个文档
+func readSpill(name string) ([][]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var batch [][]byte
+	r := bufio.NewReader(f)
+	for {
+		action, err := r.ReadBytes('\n')
+		if err != nil {
+			if len(bytes.TrimSpace(action)) > 0 {
+				return batch, fmt.Errorf("truncated document")
+			}
+			return batch, nil
+		}
+		source, err := r.ReadBytes('\n')
+		if err != nil {
+			return batch, fmt.Errorf("truncated document")
+		}
+		batch = append(batch, append(action, source...))
+	}
+}
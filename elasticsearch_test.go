@@ -0,0 +1,116 @@
+package logx
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 记录_bulk写入的文档，fail大于0时返回503并减一
+type fakeES struct {
+	mu       sync.Mutex
+	docs     []string
+	indexes  []string
+	template string
+	fail     int32
+}
+
+func (s *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	if strings.HasPrefix(r.URL.Path, "/_index_template/") {
+		s.mu.Lock()
+		s.template = string(body)
+		s.mu.Unlock()
+		return
+	}
+	if atomic.AddInt32(&s.fail, -1) >= 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	sc := bufio.NewScanner(strings.NewReader(string(body)))
+	var items []string
+	for sc.Scan() {
+		var action struct {
+			Create struct {
+				Index string `json:"_index"`
+			} `json:"create"`
+		}
+		json.Unmarshal(sc.Bytes(), &action)
+		sc.Scan()
+		s.mu.Lock()
+		s.indexes = append(s.indexes, action.Create.Index)
+		s.docs = append(s.docs, sc.Text())
+		s.mu.Unlock()
+		items = append(items, `{"create":{"status":201}}`)
+	}
+	w.Write([]byte(`{"errors":false,"items":[` + strings.Join(items, ",") + `]}`))
+}
+
+func (s *fakeES) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.docs)
+}
+
+func TestElasticsearchWriter(t *testing.T) {
+	es := &fakeES{fail: 2}
+	srv := httptest.NewServer(es)
+	defer srv.Close()
+
+	w, err := NewElasticsearchWriter(ElasticsearchConfig{
+		URLs:         []string{"http://127.0.0.1:1", srv.URL},
+		Index:        "app",
+		Template:     map[string]interface{}{"template": map[string]interface{}{}},
+		BatchSize:    2,
+		RetryBackoff: 1,
+	})
+	assert.NoError(t, err)
+	w.Write([]byte(`{"msg":"a"}` + "\n"))
+	w.Write([]byte("plain\n"))
+	assert.Eventually(t, func() bool { return es.count() == 2 }, 5*time.Second, 10*time.Millisecond)
+	w.Write([]byte(`{"msg":"c"}` + "\n"))
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []string{`{"msg":"a"}`, `{"message":"plain"}`, `{"msg":"c"}`}, es.docs)
+	assert.Equal(t, "app-"+time.Now().UTC().Format("2006.01.02"), es.indexes[0])
+	assert.Contains(t, es.template, `"index_patterns":["app-*"]`)
+}
+
+func TestElasticsearchSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logx-es")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// 集群不可用时保存到磁盘
+	es := &fakeES{fail: 1 << 20}
+	srv := httptest.NewServer(es)
+	defer srv.Close()
+	w, err := NewElasticsearchWriter(ElasticsearchConfig{
+		URLs: []string{srv.URL}, IndexLayout: "-", MaxRetries: 1, RetryBackoff: 1, SpillDir: dir,
+	})
+	assert.NoError(t, err)
+	w.Write([]byte(`{"msg":"a"}`))
+	w.Write([]byte(`{"msg":"b"}`))
+	assert.NoError(t, w.Close())
+	assert.Len(t, spillFiles(dir), 1)
+
+	// 集群恢复后先重发保存的日志
+	atomic.StoreInt32(&es.fail, 0)
+	w, err = NewElasticsearchWriter(ElasticsearchConfig{URLs: []string{srv.URL}, IndexLayout: "-", SpillDir: dir})
+	assert.NoError(t, err)
+	w.Write([]byte(`{"msg":"c"}`))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, []string{`{"msg":"a"}`, `{"msg":"b"}`, `{"msg":"c"}`}, es.docs)
+	assert.Equal(t, []string{"logx", "logx", "logx"}, es.indexes)
+	assert.Empty(t, spillFiles(dir))
+}
@@ -0,0 +1,199 @@
+package logx
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 超长日志携带的字段
+const (
+	TruncatedKey = "truncated" // 被截断的日志在截断前消息和字符串字段的总长度
+	PartKey      = "part"      // 拆分的日志的序号，从1开始
+	PartsKey     = "parts"     // 拆分的日志的总条数
+)
+
+// 限制单条日志的大小，在编码前处理，避免意外输出的超大日志占用缓存并影响下游的解析
+// 只计算消息、字符串、[]byte和Stringer字段的长度，With添加的字段和其他类型的字段不计入
+// truncate模式按比例截断最长的部分并添加truncated字段，split模式将消息拆分为多条日志，字段截断到上限的一半以内
+type entrySizeCore struct {
+	zapcore.Core
+	max   int
+	split bool
+}
+
+func newEntrySizeCore(core zapcore.Core, cfg *Config) zapcore.Core {
+	max := int(rollingwriter.ParseSize(cfg.MaxEntrySize))
+	if cfg.MaxEntrySize == "" || max <= 0 {
+		return core
+	}
+	return &entrySizeCore{Core: core, max: max, split: cfg.EntrySizeMode == "split"}
+}
+
+func (c *entrySizeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &entrySizeCore{Core: c.Core.With(fields), max: c.max, split: c.split}
+}
+
+func (c *entrySizeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	downstream := c.Core.Check(ent, nil)
+	if downstream == nil {
+		return ce
+	}
+	e := &sizedEntry{core: c, downstream: downstream}
+	e.outer = ce.AddCore(ent, e)
+	return e.outer
+}
+
+// 一条需要检查大小的日志，写入时截断或拆分后交给下游的core
+type sizedEntry struct {
+	zapcore.Core
+	core       *entrySizeCore
+	outer      *zapcore.CheckedEntry
+	downstream *zapcore.CheckedEntry
+}
+
+func (e *sizedEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e.downstream.ErrorOutput = e.outer.ErrorOutput
+	fields = stringifyFields(fields)
+	size := len(ent.Message) + fieldsSize(fields)
+	if size <= e.core.max {
+		e.downstream.Write(fields...)
+		return nil
+	}
+	if !e.core.split || len(ent.Message) == 0 {
+		e.downstream.Entry.Message, fields = truncateEntry(ent.Message, fields, e.core.max)
+		e.downstream.Write(append(fields, zap.Int(TruncatedKey, size))...)
+		return nil
+	}
+	// 字段最多占用上限的一半，剩余的空间用于拆分消息
+	if fs := fieldsSize(fields); fs > e.core.max/2 {
+		_, fields = truncateEntry("", fields, e.core.max/2)
+		fields = append(fields, zap.Int(TruncatedKey, size))
+	}
+	chunk := e.core.max - fieldsSize(fields)
+	parts := splitString(ent.Message, chunk)
+	for i, part := range parts {
+		partFields := append(fields[:len(fields):len(fields)], zap.Int(PartKey, i+1), zap.Int(PartsKey, len(parts)))
+		if i == 0 {
+			e.downstream.Entry.Message = part
+			e.downstream.Write(partFields...)
+			continue
+		}
+		ent.Message = part
+		if ce := e.core.Core.Check(ent, nil); ce != nil {
+			ce.ErrorOutput = e.outer.ErrorOutput
+			ce.Write(partFields...)
+		}
+	}
+	return nil
+}
+
+// 将Stringer字段转为字符串字段，使其长度可以计算和截断
+func stringifyFields(fields []zapcore.Field) []zapcore.Field {
+	copied := false
+	for i, f := range fields {
+		if f.Type != zapcore.StringerType {
+			continue
+		}
+		// 不修改调用方的切片
+		if !copied {
+			fields = append([]zapcore.Field(nil), fields...)
+			copied = true
+		}
+		fields[i] = zap.String(f.Key, fmt.Sprint(f.Interface))
+	}
+	return fields
+}
+
+// 可以截断的字段值的长度，其他类型的字段为0
+func fieldSize(f zapcore.Field) int {
+	switch f.Type {
+	case zapcore.StringType:
+		return len(f.String)
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		b, _ := f.Interface.([]byte)
+		return len(b)
+	}
+	return 0
+}
+
+func fieldsSize(fields []zapcore.Field) int {
+	n := 0
+	for _, f := range fields {
+		n += fieldSize(f)
+	}
+	return n
+}
+
+// 按相同的上限截断消息和字段，使总长度不超过max，较短的部分保持不变
+func truncateEntry(msg string, fields []zapcore.Field, max int) (string, []zapcore.Field) {
+	sizes := make([]int, 0, len(fields)+1)
+	sizes = append(sizes, len(msg))
+	for _, f := range fields {
+		sizes = append(sizes, fieldSize(f))
+	}
+	limit := waterLevel(sizes, max)
+	msg = truncateString(msg, limit)
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		if fieldSize(f) > limit {
+			switch f.Type {
+			case zapcore.StringType:
+				f.String = truncateString(f.String, limit)
+			default:
+				f.Interface = f.Interface.([]byte)[:limit]
+			}
+		}
+		out[i] = f
+	}
+	return msg, out
+}
+
+// 计算最大的上限limit，使所有长度截断到limit后的总和不超过max
+func waterLevel(sizes []int, max int) int {
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+	remain := max
+	for i, size := range sorted {
+		// 剩余的len(sorted)-i个都不短于size
+		n := len(sorted) - i
+		if size*n > remain {
+			return remain / n
+		}
+		remain -= size
+	}
+	return max
+}
+
+// 截断到不超过n字节，不拆分UTF-8字符
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// 按不超过n字节拆分字符串，不拆分UTF-8字符
+func splitString(s string, n int) []string {
+	if n <= 0 {
+		n = 1
+	}
+	var parts []string
+	for len(s) > n {
+		part := truncateString(s, n)
+		if part == "" {
+			_, size := utf8.DecodeRuneInString(s)
+			part = s[:size]
+		}
+		parts = append(parts, part)
+		s = s[len(part):]
+	}
+	return append(parts, s)
+}
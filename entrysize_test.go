@@ -0,0 +1,72 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestEntrySizeTruncate(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	var buf bytes.Buffer
+	Init(&Config{Type: "json", MaxEntrySize: "1kb", Appenders: []Appender{{Writer: NopCloser(&buf)}}})
+	Info("small", zap.String("k", "v"))
+	Info(strings.Repeat("m", 2000), zap.String("short", "abc"), zap.String("dump", strings.Repeat("d", 5000)), zap.Int("n", 1))
+
+	records := decodeLines(t, &buf)
+	if !assert.Len(t, records, 2) {
+		return
+	}
+	assert.NotContains(t, records[0], TruncatedKey)
+	r := records[1]
+	assert.Equal(t, float64(7003), r[TruncatedKey])
+	assert.Equal(t, "abc", r["short"])
+	assert.Equal(t, float64(1), r["n"])
+	assert.Len(t, r["msg"], 510)
+	assert.Len(t, r["dump"], 510)
+}
+
+func TestEntrySizeSplit(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	var buf bytes.Buffer
+	Init(&Config{Type: "json", MaxEntrySize: "1kb", EntrySizeMode: "split", Sequence: true,
+		Appenders: []Appender{{Writer: NopCloser(&buf)}}})
+	Warn(strings.Repeat("中", 1000), zap.String("k", "v"))
+
+	records := decodeLines(t, &buf)
+	// 1024-36(run_id)-1(k)=987字节，每段329个汉字
+	if !assert.Len(t, records, 4) {
+		return
+	}
+	var msg string
+	for i, r := range records {
+		assert.Equal(t, float64(i+1), r[PartKey])
+		assert.Equal(t, float64(4), r[PartsKey])
+		assert.Equal(t, "v", r["k"])
+		assert.Equal(t, "WARN", r["level"])
+		assert.Equal(t, records[0][SequenceKey], r[SequenceKey])
+		msg += r["msg"].(string)
+	}
+	assert.Equal(t, strings.Repeat("中", 1000), msg)
+}
+
+func TestWaterLevel(t *testing.T) {
+	assert.Equal(t, 100, waterLevel([]int{10, 20}, 100))
+	assert.Equal(t, 45, waterLevel([]int{10, 500, 1000}, 100))
+	assert.Equal(t, "ab", truncateString("ab中", 4))
+	assert.Equal(t, []string{"中", "中", "a"}, splitString("中中a", 2))
+}
@@ -0,0 +1,126 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 展开的错误链的最大深度，避免Unwrap循环
+const maxErrorDepth = 32
+
+// 可以为日志提供额外字段的error，如错误码、请求ID，展开错误时输出到fields中
+type Fielder interface {
+	LogFields() []zapcore.Field
+}
+
+func init() {
+	RegisterProcessor("errors", errorsProcessor)
+}
+
+// 将zap.Error生成的字段展开为结构化的错误，便于日志系统解析和聚合
+func errorsProcessor(e *Entry) {
+	for i := range e.Fields {
+		f := &e.Fields[i]
+		if f.Type != zapcore.ErrorType {
+			continue
+		}
+		if err, ok := f.Interface.(error); ok {
+			*f = ErrorObject(f.Key, err)
+		}
+	}
+}
+
+// 将err展开为对象字段：message、type，Fielder提供的fields，pkg/errors的stack，
+// 没有stack时%+v与message不同则输出errorVerbose，errors.Unwrap得到的错误链输出到causes
+func ErrorObject(key string, err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Object(key, errorObject{err: err, chain: true})
+}
+
+type errorObject struct {
+	err   error
+	chain bool // 是否输出causes，错误链中的错误不再嵌套
+}
+
+func (o errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	// 指针类型的nil错误，调用方法可能panic，与zap.Error一样输出<nil>
+	if isNilPointer(o.err) {
+		enc.AddString("message", "<nil>")
+		enc.AddString("type", reflect.TypeOf(o.err).String())
+		return nil
+	}
+	msg := o.err.Error()
+	enc.AddString("message", msg)
+	enc.AddString("type", reflect.TypeOf(o.err).String())
+	if f, ok := o.err.(Fielder); ok {
+		if err := enc.AddObject("fields", fieldsObject(f.LogFields())); err != nil {
+			return err
+		}
+	}
+	if stack := errorStack(o.err); stack != "" {
+		enc.AddString("stack", stack)
+	} else if _, ok := o.err.(fmt.Formatter); ok {
+		if verbose := fmt.Sprintf("%+v", o.err); verbose != msg {
+			enc.AddString("errorVerbose", verbose)
+		}
+	}
+	if !o.chain {
+		return nil
+	}
+	var causes causesArray
+	for err := errors.Unwrap(o.err); err != nil && len(causes) < maxErrorDepth; err = errors.Unwrap(err) {
+		causes = append(causes, errorObject{err: err})
+	}
+	if len(causes) > 0 {
+		return enc.AddArray("causes", causes)
+	}
+	return nil
+}
+
+type causesArray []errorObject
+
+func (a causesArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, o := range a {
+		if err := enc.AppendObject(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fieldsObject []zapcore.Field
+
+func (fs fieldsObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range fs {
+		f.AddTo(enc)
+	}
+	return nil
+}
+
+// pkg/errors的错误通过StackTrace方法返回栈，按%+v格式化，不依赖pkg/errors
+func errorStack(err error) string {
+	if isNilPointer(err) {
+		return ""
+	}
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return ""
+	}
+	st := m.Call(nil)[0]
+	if st.Kind() != reflect.Slice || st.Len() == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%+v", st.Interface()), "\n")
+}
+
+func isNilPointer(err error) bool {
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,82 @@
+package logx
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+func (e *codeError) LogFields() []zapcore.Field { return []zapcore.Field{zap.Int("code", e.code)} }
+
+// 模拟pkg/errors的栈
+type stackFrame string
+
+func (f stackFrame) Format(s fmt.State, verb rune) { fmt.Fprintf(s, "%s\n\tmain.go:1", string(f)) }
+
+type stackTrace []stackFrame
+
+func (st stackTrace) Format(s fmt.State, verb rune) {
+	for _, f := range st {
+		fmt.Fprintf(s, "\n%+v", f)
+	}
+}
+
+type stackError struct{ error }
+
+func (e stackError) StackTrace() stackTrace { return stackTrace{"main.main"} }
+
+func (e stackError) Unwrap() error { return e.error }
+
+// %+v输出详细信息的错误
+type verboseError struct{}
+
+func (e verboseError) Error() string { return "verbose" }
+
+func (e verboseError) Format(s fmt.State, verb rune) { fmt.Fprint(s, "verbose\ndetail") }
+
+// 方法使用接收者的字段，nil指针调用时panic
+type ptrStackError struct{ frames stackTrace }
+
+func (e *ptrStackError) Error() string { return string(e.frames[0]) }
+
+func (e *ptrStackError) StackTrace() stackTrace { return e.frames }
+
+func TestErrorsProcessor(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(&processorCore{Core: core, processors: lookupProcessors([]string{"errors"})})
+	err := fmt.Errorf("pay order: %w", stackError{&codeError{402}})
+	l.Error("failed", zap.Error(err), zap.Int("n", 1))
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, int64(1), fields["n"])
+	assert.Equal(t, map[string]interface{}{
+		"message": "pay order: code 402",
+		"type":    "*fmt.wrapError",
+		"causes": []interface{}{
+			map[string]interface{}{"message": "code 402", "type": "logx.stackError", "stack": "main.main\n\tmain.go:1"},
+			map[string]interface{}{"message": "code 402", "type": "*logx.codeError", "fields": map[string]interface{}{"code": int64(402)}},
+		},
+	}, fields["error"])
+
+	l.Info("ok", ErrorObject("cause", nil))
+	assert.Empty(t, logs.All()[1].ContextMap())
+
+	l.Error("failed", zap.Error(verboseError{}))
+	assert.Equal(t, map[string]interface{}{
+		"message": "verbose", "type": "logx.verboseError", "errorVerbose": "verbose\ndetail",
+	}, logs.All()[2].ContextMap()["error"])
+
+	var nilErr *ptrStackError
+	l.Error("failed", ErrorObject("error", nilErr))
+	assert.Equal(t, map[string]interface{}{
+		"message": "<nil>", "type": "*logx.ptrStackError",
+	}, logs.All()[3].ContextMap()["error"])
+}
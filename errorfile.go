@@ -0,0 +1,34 @@
+package logx
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// 返回添加了ErrorFile对应appender的配置副本，没有配置ErrorFile时返回cfg
+// 错误日志文件沿用第一个rolling appender的滚动策略和滚动时间、大小，其他配置使用默认值，延迟到第一条错误日志时创建
+// 不沿用历史文件模式、保留策略、事件回调和头信息，避免与主日志位于同一目录时清理主日志的历史文件或重复触发回调
+func (cfg *Config) withErrorFile() *Config {
+	if cfg.ErrorFile == "" {
+		return cfg
+	}
+	rolling := rollingwriter.NewDefaultConfig()
+	for _, app := range cfg.Appenders {
+		typ := strings.TrimSpace(strings.ToLower(app.Type))
+		if app.Writer == nil && app.Rolling != nil && (typ == "" || typ == "rolling") {
+			rolling.TimeTagFormat = app.Rolling.TimeTagFormat
+			rolling.RollingPolicy = app.Rolling.RollingPolicy
+			rolling.RollingTimePattern = app.Rolling.RollingTimePattern
+			rolling.RollingVolumeSize = app.Rolling.RollingVolumeSize
+			break
+		}
+	}
+	rolling.LogPath = filepath.Dir(cfg.ErrorFile)
+	rolling.FileName = strings.TrimSuffix(filepath.Base(cfg.ErrorFile), ".log")
+	rolling.LazyOpen = true
+	c := *cfg
+	c.Appenders = append(append([]Appender(nil), cfg.Appenders...), Appender{Level: "error", Rolling: &rolling})
+	return &c
+}
@@ -0,0 +1,85 @@
+package logx
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestErrorFile(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	dir := t.TempDir()
+	var all bytes.Buffer
+	Init(&Config{
+		ErrorFile: filepath.Join(dir, "error.log"),
+		Appenders: []Appender{{Writer: NopCloser(&all), Level: "debug"}},
+	})
+	apps := currentAppenders()
+	if !assert.Len(t, apps, 2) {
+		return
+	}
+	defer apps[1].writer.Close()
+
+	Info("normal")
+	Error("failed")
+	assert.Contains(t, all.String(), "normal")
+	assert.Contains(t, all.String(), "failed")
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "error.log"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(buf), "normal")
+	assert.Contains(t, string(buf), "failed")
+}
+
+func TestErrorFileSameDir(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	dir := t.TempDir()
+	archives := []string{filepath.Join(dir, "app-1.log.gz"), filepath.Join(dir, "app-2.log.gz")}
+	for _, name := range archives {
+		assert.NoError(t, ioutil.WriteFile(name, []byte("archive\n"), 0644))
+	}
+	rolling := rollingwriter.NewDefaultConfig()
+	rolling.LogPath = dir
+	rolling.FileName = "app"
+	rolling.RollingPolicy = rollingwriter.VolumeRolling
+	rolling.RollingVolumeSize = "1mb"
+	rolling.MaxRemain = 2
+	rolling.ArchivePatterns = []string{"app-*.log.gz"}
+	rolling.Header = "header\n"
+	rolling.OnEvent = func(rollingwriter.Event) {}
+	cfg := &Config{
+		ErrorFile: filepath.Join(dir, "error.log"),
+		Appenders: []Appender{{Level: "debug", Rolling: &rolling}},
+	}
+
+	// 只沿用滚动策略和滚动大小
+	errRolling := cfg.withErrorFile().Appenders[1].Rolling
+	assert.Equal(t, rollingwriter.VolumeRolling, errRolling.RollingPolicy)
+	assert.Equal(t, "1mb", errRolling.RollingVolumeSize)
+	assert.Empty(t, errRolling.ArchivePatterns)
+	assert.Equal(t, -1, errRolling.MaxRemain)
+	assert.Empty(t, errRolling.Header)
+	assert.Nil(t, errRolling.OnEvent)
+
+	Init(cfg)
+	apps := currentAppenders()
+	if !assert.Len(t, apps, 2) {
+		return
+	}
+	defer apps[0].writer.Close()
+	defer apps[1].writer.Close()
+	Error("failed")
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "error.log"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(buf), "header")
+	assert.Contains(t, string(buf), "failed")
+	for _, name := range archives {
+		_, err := os.Stat(name)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,142 @@
+package logx
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 错误突增时临时提升日志级别的配置
+type EscalationConfig struct {
+	Threshold int    `json:"threshold" yaml:"threshold"` // 统计窗口内同一模块的错误日志数量达到该值时提升级别
+	Window    int    `json:"window" yaml:"window"`       // 统计窗口，单位秒，为0时为60
+	Level     string `json:"level" yaml:"level"`         // 提升后的日志级别，为空时为debug
+	Duration  int    `json:"duration" yaml:"duration"`   // 提升的持续时间，单位秒，为0时为300
+}
+
+// 统计各模块的错误日志，模块为logger名称，未命名时为调用方的包路径
+type escalator struct {
+	sync.Mutex
+	threshold int
+	window    time.Duration
+	level     zapcore.Level
+	duration  time.Duration
+	scopes    map[string]*escalation
+}
+
+// 单个模块的错误统计和提升状态
+type escalation struct {
+	start     time.Time // 当前统计窗口的开始时间
+	count     int
+	escalated bool
+	prev      zapcore.Level // 提升前模块设置的级别
+	hasPrev   bool
+}
+
+func newEscalator(c *EscalationConfig) *escalator {
+	e := &escalator{
+		threshold: c.Threshold,
+		window:    time.Duration(c.Window) * time.Second,
+		level:     zapcore.DebugLevel,
+		duration:  time.Duration(c.Duration) * time.Second,
+		scopes:    make(map[string]*escalation),
+	}
+	if e.threshold <= 0 {
+		e.threshold = 1
+	}
+	if e.window <= 0 {
+		e.window = time.Minute
+	}
+	if c.Level != "" {
+		var l zap.AtomicLevel
+		if err := l.UnmarshalText([]byte(c.Level)); err == nil {
+			e.level = l.Level()
+		}
+	}
+	if e.duration <= 0 {
+		e.duration = 5 * time.Minute
+	}
+	return e
+}
+
+// 记录一条错误日志，达到阈值时提升模块级别，持续时间后恢复
+func (e *escalator) record(scope string, now time.Time) {
+	if scope == "" {
+		return
+	}
+	e.Lock()
+	defer e.Unlock()
+	s, ok := e.scopes[scope]
+	if !ok {
+		s = &escalation{start: now}
+		e.scopes[scope] = s
+	}
+	if s.escalated {
+		return
+	}
+	if now.Sub(s.start) > e.window {
+		s.start, s.count = now, 0
+	}
+	s.count++
+	if s.count < e.threshold {
+		return
+	}
+	s.escalated = true
+	s.prev, s.hasPrev = modules.get(scope)
+	modules.set(scope, e.level)
+	time.AfterFunc(e.duration, func() { e.revert(scope) })
+}
+
+// 恢复模块提升前的级别，期间级别被修改过时保留修改后的级别
+func (e *escalator) revert(scope string) {
+	e.Lock()
+	defer e.Unlock()
+	s := e.scopes[scope]
+	delete(e.scopes, scope)
+	if l, ok := modules.get(scope); !ok || l != e.level {
+		return
+	}
+	if s.hasPrev {
+		modules.set(scope, s.prev)
+	} else {
+		RemoveModuleLevel(scope)
+	}
+}
+
+// 统计错误日志的core，不写入日志
+type escalationCore struct {
+	e *escalator
+}
+
+func (c *escalationCore) Enabled(l zapcore.Level) bool {
+	return l >= zapcore.ErrorLevel
+}
+
+func (c *escalationCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *escalationCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *escalationCore) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	scope := ent.LoggerName
+	if scope == "" && ent.Caller.Defined {
+		if fn := runtime.FuncForPC(ent.Caller.PC); fn != nil {
+			scope = packageName(fn.Name())
+		}
+	}
+	c.e.record(scope, ent.Time)
+	return nil
+}
+
+func (c *escalationCore) Sync() error {
+	return nil
+}
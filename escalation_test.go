@@ -0,0 +1,48 @@
+package logx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestEscalation(t *testing.T) {
+	defer setModuleLevels(nil)
+	obs, logs := observer.New(zapcore.DebugLevel)
+	e := newEscalator(&EscalationConfig{Threshold: 2, Window: 60})
+	e.duration = 50 * time.Millisecond
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	root := zap.New(zapcore.NewTee(&moduleCore{Core: obs, level: level}, &escalationCore{e}))
+	l := root.Named("db")
+
+	l.Debug("before")
+	l.Error("first")
+	l.Debug("below threshold")
+	l.Error("second")
+	l.Debug("escalated")
+	root.Named("other").Debug("other scope")
+	assert.Equal(t, []string{"first", "second", "escalated"}, messages(logs))
+	assert.Equal(t, map[string]string{"db": "debug"}, ModuleLevels())
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, ModuleLevels())
+	logs.TakeAll()
+	l.Debug("reverted")
+	assert.Equal(t, 0, logs.Len())
+}
+
+func TestEscalationKeepsPrevious(t *testing.T) {
+	defer setModuleLevels(nil)
+	assert.NoError(t, SetModuleLevel("db", "warn"))
+	e := newEscalator(&EscalationConfig{Threshold: 1})
+	e.duration = time.Millisecond
+	e.record("db", time.Now())
+	_, ok := modules.get("db")
+	assert.True(t, ok)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, map[string]string{"db": "warn"}, ModuleLevels())
+}
@@ -0,0 +1,72 @@
+package logx
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// 故障转移appender的配置，日志写入可用的最靠前的输出目标，当前目标写入失败时切换到下一个，之前的目标恢复后切回
+type FailoverConfig struct {
+	// 按优先级排列的输出目标，如主日志文件、其他磁盘上的日志文件、stderr，只使用Writer、Type、Options、Rolling和Router
+	Sinks []Appender `json:"sinks" yaml:"sinks"`
+	// 失败的输出目标重新尝试的间隔，单位毫秒，默认为1000
+	RetryInterval int `json:"retry_interval" yaml:"retryInterval"`
+}
+
+// 生成故障转移appender的writer，创建失败的输出目标在重新尝试时再次创建
+func newFailoverWriter(cfg *FailoverConfig) (rollingwriter.RollingWriter, error) {
+	if cfg == nil || len(cfg.Sinks) == 0 {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	sinks := make([]rollingwriter.RollingWriter, len(cfg.Sinks))
+	for i, app := range cfg.Sinks {
+		sink := &failoverSink{app: app}
+		sink.w, sink.err = newAppenderWriter(app)
+		sinks[i] = sink
+	}
+	return rollingwriter.NewFailoverWriter(time.Duration(cfg.RetryInterval)*time.Millisecond, sinks...)
+}
+
+// 故障转移的输出目标，创建失败时写入返回创建的错误，并在下一次写入时重新创建
+type failoverSink struct {
+	mu  sync.Mutex
+	app Appender
+	w   rollingwriter.RollingWriter
+	err error
+}
+
+func (s *failoverSink) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	if s.w == nil {
+		if s.w, s.err = newAppenderWriter(s.app); s.err != nil {
+			s.mu.Unlock()
+			return 0, s.err
+		}
+	}
+	w := s.w
+	s.mu.Unlock()
+	return w.Write(b)
+}
+
+// 输出目标是否可用，如设置了写入超时的rolling目标在写入卡住时不可用
+func (s *failoverSink) Healthy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h, ok := s.w.(interface{ Healthy() bool }); ok {
+		return h.Healthy()
+	}
+	return true
+}
+
+// 关闭输出目标，不关闭标准输出和标准错误
+func (s *failoverSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil || s.w == os.Stdout || s.w == os.Stderr {
+		return nil
+	}
+	return s.w.Close()
+}
@@ -0,0 +1,59 @@
+package logx
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailoverAppender(t *testing.T) {
+	dir := t.TempDir()
+	// 主日志目录被同名文件占用，创建失败
+	primaryDir := filepath.Join(dir, "primary")
+	ioutil.WriteFile(primaryDir, nil, 0644)
+	secondaryDir := filepath.Join(dir, "secondary")
+	writers := newAppenderWriters([]Appender{{
+		Type: "failover",
+		Failover: &FailoverConfig{
+			Sinks: []Appender{
+				{Rolling: &rollingwriter.Config{LogPath: primaryDir, FileName: "app", WriterMode: "lock"}},
+				{Rolling: &rollingwriter.Config{LogPath: secondaryDir, FileName: "app", WriterMode: "lock"}},
+				{Type: "stderr"},
+			},
+			RetryInterval: 20,
+		},
+	}})
+	fw, ok := writers[0].(*rollingwriter.FailoverWriter)
+	if !assert.True(t, ok) {
+		return
+	}
+	_, err := fw.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fw.Active())
+	buf, _ := ioutil.ReadFile(filepath.Join(secondaryDir, "app.log"))
+	assert.Equal(t, "first\n", string(buf))
+
+	// 主日志目录恢复后切回
+	os.Remove(primaryDir)
+	time.Sleep(30 * time.Millisecond)
+	fw.Write([]byte("second\n"))
+	assert.Equal(t, 0, fw.Active())
+	buf, _ = ioutil.ReadFile(filepath.Join(primaryDir, "app.log"))
+	assert.Equal(t, "second\n", string(buf))
+	assert.NoError(t, fw.Close())
+}
+
+func TestCheckFailover(t *testing.T) {
+	r := CheckConfig(&Config{Appenders: []Appender{
+		{Type: "failover", Failover: &FailoverConfig{Sinks: []Appender{{Type: "rolling"}, {Type: "stderr"}}}},
+		{Type: "failover"},
+	}})
+	assert.Equal(t, []string{"failover[0]: missing rolling config"}, r.Appenders[0].Problems)
+	assert.True(t, r.Appenders[0].Writable)
+	assert.Equal(t, []string{"missing failover sinks"}, r.Appenders[1].Problems)
+}
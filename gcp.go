@@ -0,0 +1,95 @@
+package logx
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Schema为gcp时的配置，日志按Google Cloud Logging的结构化格式输出到标准输出或文件，由GKE/GCE的日志代理采集，
+// 也可以通过gcp类型的appender直接写入Cloud Logging
+type GCPConfig struct {
+	ProjectID string            `json:"project_id" yaml:"projectID"` // 项目ID，设置后Ctx添加的trace关联到Cloud Trace
+	Labels    map[string]string `json:"labels" yaml:"labels"`        // 所有日志携带的资源标签
+}
+
+const (
+	gcpLabelsKey         = "logging.googleapis.com/labels"
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanKey           = "logging.googleapis.com/spanId"
+	gcpSampledKey        = "logging.googleapis.com/trace_sampled"
+)
+
+func init() {
+	RegisterProcessor("gcp", gcpProcessor)
+}
+
+// 是否使用Google Cloud Logging的字段名输出
+func (c *Config) gcp() bool {
+	return strings.TrimSpace(strings.ToLower(c.Schema)) == "gcp"
+}
+
+// zap级别对应的Cloud Logging severity
+func GCPSeverity(l zapcore.Level) string {
+	switch l {
+	case zapcore.DebugLevel:
+		return "DEBUG"
+	case zapcore.InfoLevel:
+		return "INFO"
+	case zapcore.WarnLevel:
+		return "WARNING"
+	case zapcore.ErrorLevel:
+		return "ERROR"
+	case zapcore.DPanicLevel:
+		return "CRITICAL"
+	case zapcore.PanicLevel:
+		return "ALERT"
+	case zapcore.FatalLevel:
+		return "EMERGENCY"
+	}
+	return "DEFAULT"
+}
+
+// 将字段名替换为Cloud Logging的字段名，调用位置由gcp处理器写入sourceLocation，未设置Format时时间使用RFC3339
+func gcpEncoderConfig(config zapcore.EncoderConfig, format string) zapcore.EncoderConfig {
+	config.TimeKey = "timestamp"
+	config.LevelKey = "severity"
+	config.MessageKey = "message"
+	config.StacktraceKey = "stack_trace"
+	config.CallerKey = ""
+	config.NameKey = "logger"
+	config.EncodeLevel = func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(GCPSeverity(l))
+	}
+	if format == "" {
+		config.EncodeTime = func(t time.Time, en zapcore.PrimitiveArrayEncoder) {
+			en.AppendString(t.Format(time.RFC3339Nano))
+		}
+	}
+	return config
+}
+
+// 资源标签字段
+func gcpFields(c *GCPConfig) []zap.Field {
+	if c == nil || len(c.Labels) == 0 {
+		return nil
+	}
+	return []zap.Field{zap.Any(gcpLabelsKey, c.Labels)}
+}
+
+// 将调用位置写入sourceLocation
+func gcpProcessor(e *Entry) {
+	caller := e.Caller
+	if !caller.Defined {
+		return
+	}
+	e.Add(zap.Object(gcpSourceLocationKey, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("file", caller.File)
+		enc.AddInt("line", caller.Line)
+		enc.AddString("function", caller.Function)
+		return nil
+	})))
+}
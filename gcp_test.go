@@ -0,0 +1,62 @@
+package logx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGCP(t *testing.T) {
+	cfg := &Config{Schema: "gcp", GCP: &GCPConfig{Labels: map[string]string{"env": "prod"}}}
+	enc := zapcore.NewJSONEncoder(gcpEncoderConfig(newEncoderConfig(""), ""))
+	obs, logs := observer.New(zapcore.InfoLevel)
+	l := newLogger(&processorCore{Core: obs, processors: lookupProcessors([]string{"gcp"})}, cfg)
+	l.Warn("slow")
+
+	entry := logs.All()[0]
+	buf, err := enc.EncodeEntry(entry.Entry, entry.Context)
+	assert.NoError(t, err)
+	var out map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, "WARNING", out["severity"])
+	assert.Equal(t, "slow", out["message"])
+	assert.Contains(t, out, "timestamp")
+	assert.Equal(t, map[string]interface{}{"env": "prod"}, out[gcpLabelsKey])
+	loc, ok := out[gcpSourceLocationKey].(map[string]interface{})
+	if assert.True(t, ok) {
+		assert.Contains(t, loc["file"], "gcp_test.go")
+		assert.NotZero(t, loc["line"])
+	}
+}
+
+func TestGCPSeverity(t *testing.T) {
+	assert.Equal(t, "ERROR", GCPSeverity(zapcore.ErrorLevel))
+	assert.Equal(t, "CRITICAL", GCPSeverity(zapcore.DPanicLevel))
+	assert.Equal(t, "EMERGENCY", GCPSeverity(zapcore.FatalLevel))
+}
+
+func TestGCPCtx(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	defer setTrace(&Config{})
+	obs, logs := observer.New(zapcore.InfoLevel)
+	setLogger(zap.New(obs))
+	setTrace(&Config{Schema: "gcp", GCP: &GCPConfig{ProjectID: "demo"}})
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	Ctx(trace.ContextWithSpanContext(context.Background(), sc)).Info("info")
+	assert.Equal(t, map[string]interface{}{
+		gcpTraceKey:   "projects/demo/traces/01000000000000000000000000000000",
+		gcpSpanKey:    "0200000000000000",
+		gcpSampledKey: true,
+	}, logs.All()[0].ContextMap())
+}
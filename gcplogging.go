@@ -0,0 +1,341 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"go.uber.org/zap/zapcore"
+)
+
+// gcp类型appender的参数，通过appender的options配置
+type GCPLoggingConfig struct {
+	ProjectID      string            `json:"project_id"`      // 项目ID，必填
+	LogID          string            `json:"log_id"`          // 日志名称，为空时为logx
+	ResourceType   string            `json:"resource_type"`   // 监控资源类型，如gce_instance、k8s_container，为空时为global
+	ResourceLabels map[string]string `json:"resource_labels"` // 监控资源的标签，如project_id、zone
+	Labels         map[string]string `json:"labels"`          // 所有日志携带的标签，日志中的logging.googleapis.com/labels覆盖同名标签
+	AccessToken    string            `json:"access_token"`    // OAuth2访问令牌，为空时从GCE/GKE/Cloud Run的元数据服务获取服务账号的令牌
+	Endpoint       string            `json:"endpoint"`        // API地址，为空时为https://logging.googleapis.com
+	BatchSize      int               `json:"batch_size"`      // 每次entries:write请求的最大日志条数，为0时为500
+	FlushInterval  int               `json:"flush_interval"`  // 日志条数不足BatchSize时的发送间隔，单位秒，为0时为1
+	MaxRetries     int               `json:"max_retries"`     // 请求失败或被限流时的重试次数，为0时为3
+	Buffer         string            `json:"buffer"`          // 内存中缓存的日志大小上限，格式与RollingVolumeSize相同，为空时为8MB
+	Timeout        int               `json:"timeout"`         // 请求的超时时间，单位秒，为0时为10
+}
+
+var ErrGCPBufferFull = errors.New("error gcp logging buffer full")
+
+// 元数据服务中默认服务账号的令牌地址
+var GCPMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+func init() {
+	RegisterAppender("gcp", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		var cfg GCPLoggingConfig
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		return NewGCPLoggingWriter(cfg)
+	})
+}
+
+// 通过Cloud Logging API的entries:write接口直接写入日志的writer，不依赖日志代理采集
+// 未使用官方的cloud.google.com/go/logging客户端，避免引入gRPC等依赖并保持本模块的Go版本要求，通过REST接口实现相同的批量写入
+// 日志需要使用json格式，level或severity字段转换为severity，Schema为gcp时的trace、spanId、sourceLocation和labels字段提升为日志条目的字段，
+// 不是json对象的日志作为textPayload；写入只进入缓存，由后台协程每BatchSize条或每FlushInterval秒发送，Sync时立即发送
+type GCPLoggingWriter struct {
+	mu      sync.Mutex
+	sendMu  sync.Mutex // 保证后台发送和Sync按顺序发送
+	tokenMu sync.Mutex // 获取令牌时不阻塞写入
+	cfg     GCPLoggingConfig
+	client  *http.Client
+	limit   int
+	entries []json.RawMessage
+	size    int
+	token   string
+	expiry  time.Time
+	signal  chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+func NewGCPLoggingWriter(cfg GCPLoggingConfig) (*GCPLoggingWriter, error) {
+	if cfg.ProjectID == "" {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.LogID == "" {
+		cfg.LogID = "logx"
+	}
+	if cfg.ResourceType == "" {
+		cfg.ResourceType = "global"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://logging.googleapis.com"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 1
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+	limit := 8 << 20
+	if cfg.Buffer != "" {
+		limit = int(rollingwriter.ParseSize(cfg.Buffer))
+	}
+	w := &GCPLoggingWriter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		limit:  limit,
+		token:  cfg.AccessToken,
+		signal: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+func (w *GCPLoggingWriter) Write(b []byte) (int, error) {
+	entry := gcpEntry(b, time.Now())
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	if w.size+len(entry) > w.limit {
+		return 0, ErrGCPBufferFull
+	}
+	w.entries = append(w.entries, entry)
+	w.size += len(entry)
+	if len(w.entries) >= w.cfg.BatchSize {
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// 立即发送缓存中的日志，返回发送失败的错误
+func (w *GCPLoggingWriter) Sync() error {
+	return w.flush()
+}
+
+// 发送缓存中的日志后停止后台协程
+func (w *GCPLoggingWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return w.flush()
+}
+
+func (w *GCPLoggingWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(time.Duration(w.cfg.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.signal:
+		case <-w.stop:
+			return
+		}
+		if err := w.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "gcp logging: %v\n", err)
+		}
+	}
+}
+
+// 按BatchSize发送缓存中的日志，重试失败的日志丢弃并返回错误
+func (w *GCPLoggingWriter) flush() error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	var errs []string
+	for {
+		w.mu.Lock()
+		n := len(w.entries)
+		if n > w.cfg.BatchSize {
+			n = w.cfg.BatchSize
+		}
+		batch := w.entries[:n:n]
+		w.entries = w.entries[n:]
+		for _, e := range batch {
+			w.size -= len(e)
+		}
+		w.mu.Unlock()
+		if len(batch) == 0 {
+			break
+		}
+		if err := w.send(batch); err != nil {
+			errs = append(errs, fmt.Sprintf("%d entries dropped: %v", len(batch), err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// 发送一批日志，请求失败、返回429或5xx时按指数退避重试
+func (w *GCPLoggingWriter) send(batch []json.RawMessage) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"logName":  "projects/" + w.cfg.ProjectID + "/logs/" + url.PathEscape(w.cfg.LogID),
+		"resource": map[string]interface{}{"type": w.cfg.ResourceType, "labels": w.cfg.ResourceLabels},
+		"labels":   w.cfg.Labels,
+		"entries":  batch,
+	})
+	if err != nil {
+		return err
+	}
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = w.write(body)
+		if err == nil || !retry || attempt >= w.cfg.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// 调用entries:write，返回的retry表示错误可以重试
+func (w *GCPLoggingWriter) write(body []byte) (retry bool, err error) {
+	token, err := w.accessToken()
+	if err != nil {
+		return true, err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.cfg.Endpoint+"/v2/entries:write", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return false, nil
+	}
+	msg, _ := ioutil.ReadAll(resp.Body)
+	err = fmt.Errorf("entries:write: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusUnauthorized && w.cfg.AccessToken == "" {
+		// 令牌失效时重新获取
+		w.tokenMu.Lock()
+		w.token = ""
+		w.tokenMu.Unlock()
+		return true, err
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// 配置的令牌或元数据服务中未过期的令牌
+func (w *GCPLoggingWriter) accessToken() (string, error) {
+	w.tokenMu.Lock()
+	defer w.tokenMu.Unlock()
+	if w.token != "" && (w.cfg.AccessToken != "" || time.Now().Before(w.expiry)) {
+		return w.token, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, GCPMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("metadata token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata token: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("metadata token: %v", err)
+	}
+	// 提前一分钟刷新
+	w.token, w.expiry = token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn-60)*time.Second)
+	return w.token, nil
+}
+
+// 将一行日志转换为LogEntry，json对象中的级别、时间和Cloud Logging的特殊字段提升为LogEntry的字段
+func gcpEntry(b []byte, now time.Time) json.RawMessage {
+	b = bytes.TrimSpace(b)
+	entry := map[string]interface{}{"timestamp": now.UTC().Format(time.RFC3339Nano), "severity": "DEFAULT"}
+	var payload map[string]interface{}
+	if len(b) == 0 || b[0] != '{' || json.Unmarshal(b, &payload) != nil {
+		entry["textPayload"] = string(b)
+		buf, _ := json.Marshal(entry)
+		return buf
+	}
+	for _, key := range []string{"severity", "level"} {
+		if s, ok := payload[key].(string); ok {
+			entry["severity"] = gcpSeverityText(s)
+			delete(payload, key)
+			break
+		}
+	}
+	if s, ok := payload["timestamp"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			entry["timestamp"] = t.UTC().Format(time.RFC3339Nano)
+			delete(payload, "timestamp")
+		}
+	}
+	for key, field := range map[string]string{gcpTraceKey: "trace", gcpSpanKey: "spanId", gcpSampledKey: "traceSampled",
+		gcpSourceLocationKey: "sourceLocation", gcpLabelsKey: "labels"} {
+		if v, ok := payload[key]; ok {
+			entry[field] = v
+			delete(payload, key)
+		}
+	}
+	// sourceLocation的line在API中为字符串
+	if loc, ok := entry["sourceLocation"].(map[string]interface{}); ok {
+		if line, ok := loc["line"].(float64); ok {
+			loc["line"] = fmt.Sprint(int64(line))
+		}
+	}
+	entry["jsonPayload"] = payload
+	buf, _ := json.Marshal(entry)
+	return buf
+}
+
+// 将日志中的级别转换为severity，已经是severity时保持不变
+func gcpSeverityText(s string) string {
+	switch upper := strings.ToUpper(s); upper {
+	case "DEFAULT", "DEBUG", "INFO", "NOTICE", "WARNING", "ERROR", "CRITICAL", "ALERT", "EMERGENCY":
+		return upper
+	}
+	var l zapcore.Level
+	if l.UnmarshalText([]byte(strings.ToLower(strings.TrimSpace(s)))) != nil {
+		return "DEFAULT"
+	}
+	return GCPSeverity(l)
+}
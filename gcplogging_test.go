@@ -0,0 +1,101 @@
+package logx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGCPLoggingWriter(t *testing.T) {
+	var mu sync.Mutex
+	var requests []map[string]interface{}
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			w.Write([]byte(`{"access_token":"meta-token","expires_in":3600}`))
+		case "/v2/entries:write":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			requests = append(requests, body)
+			auth = append(auth, r.Header.Get("Authorization"))
+			mu.Unlock()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	defer func(u string) { GCPMetadataTokenURL = u }(GCPMetadataTokenURL)
+	GCPMetadataTokenURL = srv.URL + "/token"
+
+	w, err := NewGCPLoggingWriter(GCPLoggingConfig{ProjectID: "demo", ResourceType: "k8s_container",
+		ResourceLabels: map[string]string{"cluster_name": "prod"}, Labels: map[string]string{"app": "api"},
+		Endpoint: srv.URL, FlushInterval: 60})
+	if !assert.NoError(t, err) {
+		return
+	}
+	enc := zapcore.NewJSONEncoder(gcpEncoderConfig(newEncoderConfig(""), ""))
+	buf, _ := enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "slow"},
+		[]zapcore.Field{zap.String(gcpTraceKey, "projects/demo/traces/abc"), zap.Int("ms", 900)})
+	w.Write(buf.Bytes())
+	w.Write([]byte(`{"level":"error","msg":"failed"}` + "\n"))
+	w.Write([]byte("plain text\n"))
+	assert.NoError(t, w.Sync())
+
+	mu.Lock()
+	if assert.Equal(t, 1, len(requests)) {
+		body := requests[0]
+		assert.Equal(t, "Bearer meta-token", auth[0])
+		assert.Equal(t, "projects/demo/logs/logx", body["logName"])
+		assert.Equal(t, map[string]interface{}{"type": "k8s_container", "labels": map[string]interface{}{"cluster_name": "prod"}}, body["resource"])
+		assert.Equal(t, map[string]interface{}{"app": "api"}, body["labels"])
+		entries := body["entries"].([]interface{})
+		first := entries[0].(map[string]interface{})
+		assert.Equal(t, "WARNING", first["severity"])
+		assert.Equal(t, "projects/demo/traces/abc", first["trace"])
+		assert.Equal(t, map[string]interface{}{"message": "slow", "ms": float64(900)}, first["jsonPayload"])
+		assert.Equal(t, "ERROR", entries[1].(map[string]interface{})["severity"])
+		assert.Equal(t, "plain text", entries[2].(map[string]interface{})["textPayload"])
+	}
+	mu.Unlock()
+
+	// 关闭时发送剩余的日志
+	w.Write([]byte(`{"severity":"INFO","message":"bye"}`))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 2, len(requests))
+	_, err = w.Write([]byte("late"))
+	assert.Error(t, err)
+}
+
+func TestGCPLoggingWriterRetry(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	w, err := NewGCPLoggingWriter(GCPLoggingConfig{ProjectID: "demo", AccessToken: "t", Endpoint: srv.URL})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	w.Write([]byte(`{"msg":"x"}`))
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, 2, calls)
+
+	_, err = NewGCPLoggingWriter(GCPLoggingConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package logx
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	hookMu sync.Mutex
+	hooks  atomic.Value // []func(zapcore.Entry) error
+)
+
+// 添加日志钩子，每条写入的日志都会调用，无需重新Init，可用于统计错误或转发告警
+func AddHook(hook func(zapcore.Entry) error) {
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	current, _ := hooks.Load().([]func(zapcore.Entry) error)
+	next := make([]func(zapcore.Entry) error, len(current), len(current)+1)
+	copy(next, current)
+	hooks.Store(append(next, hook))
+}
+
+// 删除所有日志钩子
+func ResetHooks() {
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	hooks.Store([]func(zapcore.Entry) error(nil))
+}
+
+// 在日志写入后调用已添加的钩子
+type hookCore struct {
+	zapcore.Core
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{c.Core.With(fields)}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	downstream := c.Core.Check(ent, ce)
+	if downstream != nil {
+		if hs, _ := hooks.Load().([]func(zapcore.Entry) error); len(hs) > 0 {
+			return downstream.AddCore(ent, c)
+		}
+	}
+	return downstream
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	var err error
+	hs, _ := hooks.Load().([]func(zapcore.Entry) error)
+	for _, hook := range hs {
+		err = multierr.Append(err, hook(ent))
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package logx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAddHook(t *testing.T) {
+	defer ResetHooks()
+	obs, logs := observer.New(zapcore.InfoLevel)
+	var errorsSeen int
+	l := newLogger(&hookCore{obs}, &Config{}, zap.Fields(zap.String("app", "test")))
+
+	l.Error("before hook")
+	AddHook(func(ent zapcore.Entry) error {
+		if ent.Level >= zapcore.ErrorLevel {
+			errorsSeen++
+		}
+		return nil
+	})
+	AddHook(func(zapcore.Entry) error { return errors.New("hook failed") })
+	l.Debug("filtered")
+	l.Info("info")
+	l.Error("error")
+
+	assert.Equal(t, 1, errorsSeen)
+	assert.Equal(t, 3, logs.Len())
+	assert.Equal(t, "test", logs.All()[0].ContextMap()["app"])
+}
@@ -0,0 +1,93 @@
+package logx
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 容器内service account信息的目录
+var ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var (
+	k8sOnce   sync.Once
+	k8sFields []zapcore.Field
+)
+
+func init() {
+	RegisterProcessor("k8s", K8sProcessor())
+}
+
+// 为每条日志添加kubernetes元数据的处理器，首次使用时读取
+// 从downward API注入的环境变量POD_NAME、POD_NAMESPACE（或NAMESPACE）、NODE_NAME、POD_IP，
+// 及service account的namespace和名称中读取，不存在的字段不添加
+func K8sProcessor() Processor {
+	return func(e *Entry) {
+		k8sOnce.Do(func() { k8sFields = loadK8sFields() })
+		e.Add(k8sFields...)
+	}
+}
+
+func loadK8sFields() []zapcore.Field {
+	namespace := firstEnv("POD_NAMESPACE", "NAMESPACE")
+	serviceAccount := ""
+	if buf, err := ioutil.ReadFile(filepath.Join(ServiceAccountDir, "namespace")); err == nil && namespace == "" {
+		namespace = strings.TrimSpace(string(buf))
+	}
+	if buf, err := ioutil.ReadFile(filepath.Join(ServiceAccountDir, "token")); err == nil {
+		serviceAccount = tokenServiceAccount(string(buf))
+	}
+	var fields []zapcore.Field
+	for _, kv := range [][2]string{
+		{"k8s.pod", os.Getenv("POD_NAME")},
+		{"k8s.namespace", namespace},
+		{"k8s.node", os.Getenv("NODE_NAME")},
+		{"k8s.pod_ip", os.Getenv("POD_IP")},
+		{"k8s.service_account", serviceAccount},
+	} {
+		if kv[1] != "" {
+			fields = append(fields, zap.String(kv[0], kv[1]))
+		}
+	}
+	return fields
+}
+
+// 第一个不为空的环境变量
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// 从service account token的sub中取出名称，形如system:serviceaccount:<namespace>:<name>，不校验签名
+func tokenServiceAccount(token string) string {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	if json.Unmarshal(payload, &claims) != nil {
+		return ""
+	}
+	sub := strings.Split(claims.Sub, ":")
+	if len(sub) != 4 || sub[1] != "serviceaccount" {
+		return ""
+	}
+	return sub[3]
+}
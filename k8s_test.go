@@ -0,0 +1,36 @@
+package logx
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestK8sFields(t *testing.T) {
+	dir := t.TempDir()
+	defer func(old string) { ServiceAccountDir = old }(ServiceAccountDir)
+	ServiceAccountDir = dir
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"system:serviceaccount:prod:api"}`))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("e30."+payload+".sig"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "namespace"), []byte("prod\n"), 0600))
+	os.Setenv("POD_NAME", "api-7d9f")
+	os.Setenv("NODE_NAME", "node-1")
+	defer os.Unsetenv("POD_NAME")
+	defer os.Unsetenv("NODE_NAME")
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range loadK8sFields() {
+		f.AddTo(enc)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"k8s.pod":             "api-7d9f",
+		"k8s.namespace":       "prod",
+		"k8s.node":            "node-1",
+		"k8s.service_account": "api",
+	}, enc.Fields)
+}
@@ -0,0 +1,139 @@
+package logx
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 日志时间中的月份、星期、上下午名称和日志级别标签的本地化
+type Locale struct {
+	Months      [12]string        // 对应时间格式中的January
+	ShortMonths [12]string        // 对应Jan
+	Days        [7]string         // 对应Monday，从星期日开始
+	ShortDays   [7]string         // 对应Mon，从星期日开始
+	AM, PM      string            // 对应PM和pm
+	Levels      map[string]string // 日志级别的标签，如info: 信息
+}
+
+var (
+	localeMu sync.Mutex
+	locales  = map[string]*Locale{
+		"zh": {
+			Months:      [12]string{"一月", "二月", "三月", "四月", "五月", "六月", "七月", "八月", "九月", "十月", "十一月", "十二月"},
+			ShortMonths: [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+			Days:        [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+			ShortDays:   [7]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+			AM:          "上午",
+			PM:          "下午",
+			Levels: map[string]string{"debug": "调试", "info": "信息", "warn": "警告", "error": "错误",
+				"dpanic": "严重", "panic": "恐慌", "fatal": "致命"},
+		},
+	}
+)
+
+// 注册语言，name为语言代码，如ja、zh-TW，l为nil时删除
+func RegisterLocale(name string, l *Locale) {
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	name = strings.ToLower(name)
+	if l == nil {
+		delete(locales, name)
+		return
+	}
+	locales[name] = l
+}
+
+// 按语言代码查找，如zh-CN、zh_CN未注册时使用zh，不存在时返回nil
+func lookupLocale(name string) *Locale {
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	name = strings.ToLower(strings.TrimSpace(name))
+	if l, ok := locales[name]; ok {
+		return l
+	}
+	if i := strings.IndexAny(name, "-_"); i > 0 {
+		return locales[name[:i]]
+	}
+	return nil
+}
+
+// 将按Go时间格式输出的英文名称替换为本地名称，完整名称在前，保证优先匹配
+func (l *Locale) replacer() *strings.Replacer {
+	var pairs []string
+	for m := time.January; m <= time.December; m++ {
+		pairs = append(pairs, m.String(), l.Months[m-1])
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		pairs = append(pairs, d.String(), l.Days[d])
+	}
+	for m := time.January; m <= time.December; m++ {
+		pairs = append(pairs, m.String()[:3], l.ShortMonths[m-1])
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		pairs = append(pairs, d.String()[:3], l.ShortDays[d])
+	}
+	return strings.NewReplacer(append(pairs, "AM", l.AM, "PM", l.PM, "am", l.AM, "pm", l.PM)...)
+}
+
+// 日志级别的标签，Locale的标签在前，LevelLabels覆盖，Schema为ecs或gcp时使用规范的级别名称，返回nil
+func (c *Config) levelLabels() map[zapcore.Level]string {
+	if c.ecs() || c.gcp() {
+		return nil
+	}
+	var labels map[zapcore.Level]string
+	add := func(m map[string]string) {
+		for level, label := range m {
+			// logLevel不支持dpanic
+			var l zapcore.Level
+			if l.UnmarshalText([]byte(strings.TrimSpace(strings.ToLower(level)))) != nil {
+				continue
+			}
+			if labels == nil {
+				labels = make(map[zapcore.Level]string)
+			}
+			labels[l] = label
+		}
+	}
+	if l := lookupLocale(c.Locale); l != nil {
+		add(l.Levels)
+	}
+	add(c.LevelLabels)
+	return labels
+}
+
+// 按Locale和LevelLabels本地化时间和日志级别，只替换Format中的月份、星期和上下午名称
+func localeEncoderConfig(config zapcore.EncoderConfig, cfg *Config) zapcore.EncoderConfig {
+	if l := lookupLocale(cfg.Locale); l != nil && cfg.Format != "" {
+		r, format := l.replacer(), cfg.Format
+		config.EncodeTime = func(t time.Time, en zapcore.PrimitiveArrayEncoder) {
+			en.AppendString(r.Replace(t.Format(format)))
+		}
+	}
+	if labels := cfg.levelLabels(); labels != nil {
+		next := config.EncodeLevel
+		config.EncodeLevel = func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+			if s, ok := labels[l]; ok {
+				enc.AppendString(s)
+				return
+			}
+			next(l, enc)
+		}
+	}
+	return config
+}
+
+// 终端中的显示宽度，中日韩等宽字符按2计算
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x2E80 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,42 @@
+package logx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLocale(t *testing.T) {
+	cfg := &Config{Type: "console", Format: "Mon Jan 2 03:04PM", Locale: "zh-CN", LevelLabels: map[string]string{"warn": "注意"}}
+	config := localeEncoderConfig(newEncoderConfig(cfg.Format), cfg)
+	config.CallerKey = ""
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Date(2021, 3, 1, 15, 4, 0, 0, time.UTC), Message: "hello"}
+
+	buf, err := zapcore.NewJSONEncoder(config).EncodeEntry(ent, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"信息","ts":"周一 3月 1 03:04下午","msg":"hello"}`+"\n", buf.String())
+
+	// console格式按显示宽度对齐
+	var out bytes.Buffer
+	buf, err = consoleEncoder(cfg, config, nil, &out).EncodeEntry(ent, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "周一 3月 1 03:04下午\t信息  \thello\n", buf.String())
+	ent.Level = zapcore.WarnLevel
+	buf, _ = consoleEncoder(cfg, config, nil, &out).EncodeEntry(ent, nil)
+	assert.Equal(t, "周一 3月 1 03:04下午\t注意  \thello\n", buf.String())
+
+	// 规范的字段名使用规范的级别
+	cfg.Schema = "ecs"
+	assert.Nil(t, cfg.levelLabels())
+
+	ja := &Locale{Levels: map[string]string{"info": "情報"}}
+	RegisterLocale("JA", ja)
+	assert.Equal(t, ja, lookupLocale("ja_JP"))
+	RegisterLocale("ja", nil)
+	assert.Nil(t, lookupLocale("ja"))
+	r := CheckConfig(&Config{Locale: "ja", LevelLabels: map[string]string{"verbose": "v"}, Appenders: []Appender{{Type: "stdout"}}})
+	assert.Equal(t, []string{`unknown locale "ja"`, `level labels: invalid level "verbose"`}, r.Problems)
+}
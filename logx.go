@@ -2,8 +2,10 @@ package logx
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,65 +18,325 @@ import (
 type Config struct {
 	// 时间格式
 	Format string `json:"format" yaml:"format"`
-	// 日志格式，json和console
+	// 日志格式，json、console、msgpack和protobuf，后两种为带长度前缀的二进制格式，通过reader包读取
 	Type string `json:"type" yaml:"type"`
-	// 是否开通栈追踪，开启后error及以下级别打印栈信息
-	Stacktrace  bool `json:"stacktrace" yaml:"stacktrace"`
+	// 输出的字段名规范，为空时使用默认字段名，ecs为Elastic Common Schema，gcp为Google Cloud Logging的结构化日志
+	Schema string `json:"schema" yaml:"schema"`
+	// Schema为gcp时的项目和资源标签
+	GCP *GCPConfig `json:"gcp" yaml:"gcp"`
+	// console格式的彩色显示，auto：输出到终端时彩色显示，默认方式；always：总是；never：从不
+	Color string `json:"color" yaml:"color"`
+	// console格式下是否将字段以缩进的多行json显示
+	Pretty bool `json:"pretty" yaml:"pretty"`
+	// console格式下多行消息和栈信息的处理方式，为空时原样输出；indent：续行缩进；
+	// escape：换行转义为\n，每条日志一行；auto：输出到终端时缩进，输出到文件等其他writer时转义
+	// json格式总是转义换行，不受影响
+	Multiline string `json:"multiline" yaml:"multiline"`
+	// 日志时间和级别的语言，如zh、zh-CN，替换Format中的月份、星期和上下午名称，并使用该语言的级别标签，
+	// 内置zh，其他语言通过RegisterLocale注册
+	Locale string `json:"locale" yaml:"locale"`
+	// 日志级别的标签，如{"info": "信息"}，优先于Locale，Schema为ecs或gcp时不生效
+	LevelLabels map[string]string `json:"level_labels" yaml:"levelLabels"`
+	// 是否开通栈追踪，开启后error及以上级别打印栈信息，development模式下为warn及以上级别
+	Stacktrace bool `json:"stacktrace" yaml:"stacktrace"`
+	// 打印栈信息的最低级别，warn、error、panic等，设置后开启栈追踪
+	StacktraceLevel string `json:"stacktrace_level" yaml:"stacktraceLevel"`
+	// 是否关闭栈追踪，优先于Stacktrace和StacktraceLevel
+	DisableStacktrace bool `json:"disable_stacktrace" yaml:"disableStacktrace"`
+	// 开发模式，DPanic级别的日志会panic
 	Development bool `json:"development" yaml:"development"`
+	// 日志采样，每秒内相同级别和消息的日志超过Initial条后每Thereafter条记录一条，Levels限定采样的级别
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling"`
+	// 是否为所有appender添加kubernetes元数据字段，等同于在每个appender的processors中添加k8s
+	Kubernetes bool `json:"kubernetes" yaml:"kubernetes"`
+	// 是否将通过Ctx记录的error及以上级别日志添加为OpenTelemetry span事件
+	TraceEvents bool `json:"trace_events" yaml:"traceEvents"`
+	// 日志字段的版本，大于0时所有日志携带schema_version字段，字段名变化时递增，并通过RegisterMigration注册旧版本的转换
+	SchemaVersion int `json:"schema_version" yaml:"schemaVersion"`
+	// 是否为每条日志添加进程内递增的seq字段和本次运行的run_id字段，用于发现丢失的日志和恢复顺序
+	Sequence bool `json:"sequence" yaml:"sequence"`
+	// 单条日志消息和字符串字段的总长度上限，格式与rollingwriter的RollingVolumeSize相同，如64kb，为空时不限制
+	MaxEntrySize string `json:"max_entry_size" yaml:"maxEntrySize"`
+	// 超过MaxEntrySize时的处理，truncate：截断并添加truncated字段，split：将消息拆分为多条带part、parts字段的日志，为空时为truncate
+	EntrySizeMode string `json:"entry_size_mode" yaml:"entrySizeMode"`
+	// 所有日志都携带的字段
+	Fields map[string]interface{} `json:"fields" yaml:"fields"`
+	// 命名的脱敏方案，appender通过scrubProfile选择，如external删除内网IP和用户ID，未选择的appender输出完整内容
+	ScrubProfiles map[string][]ScrubRule `json:"scrub_profiles" yaml:"scrubProfiles"`
+	// 日志时间的来源，为nil时使用本机时间，可以使用rollingwriter.Clock的实现，如测试中的假时钟
+	Clock Clock `json:"-" yaml:"-"`
+	// 包装Init生成的core，用于添加自定义的core
+	WrapCore func(zapcore.Core) zapcore.Core `json:"-" yaml:"-"`
+	// 调用信息跳过的栈帧数，通过自定义函数封装logger时设置为封装的层数
+	CallerSkip int `json:"caller_skip" yaml:"callerSkip"`
+	// 是否关闭调用信息
+	DisableCaller bool `json:"disable_caller" yaml:"disableCaller"`
 	// 日志文件及级别配置
-	Appenders []appender `json:"appenders" yaml:"appenders"`
+	Appenders []Appender `json:"appenders" yaml:"appenders"`
+	// 按logger名称或包路径覆盖appender的日志级别，如"github.com/acme/db": "debug"
+	Modules map[string]string `json:"modules" yaml:"modules"`
+	// 根据日志生成计数器的规则，通过Metrics或MetricsHandler读取
+	Metrics []MetricRule `json:"metrics" yaml:"metrics"`
+	// 告警规则，满足条件的日志达到阈值时向webhook发送告警
+	Alerts []AlertRule `json:"alerts" yaml:"alerts"`
+	// 模块错误日志突增时临时提升该模块的日志级别
+	Escalation *EscalationConfig `json:"escalation" yaml:"escalation"`
+	// 实时日志流，配置后可以通过StreamHandler在浏览器中查看最近的和新的日志
+	Stream *StreamConfig `json:"stream" yaml:"stream"`
+	// 控制socket路径，不为空时在该unix socket上接收set-level, rotate, flush, stats, config命令
+	ControlSocket string `json:"control_socket" yaml:"controlSocket"`
+	// 错误日志文件路径，不为空时error及以上级别的日志在写入各appender的同时写入该文件，如./log/error.log
+	ErrorFile string `json:"error_file" yaml:"errorFile"`
+	// 崩溃文件目录，不为空时在Panic、Fatal日志和RecoverAndLog、CapturePanic捕获panic时写入崩溃文件
+	CrashDir string `json:"crash_dir" yaml:"crashDir"`
 }
 
-type appender struct {
+// appender配置，指定日志的输出目标、级别和过滤规则
+type Appender struct {
+	// 直接使用的输出目标，如lumberjack.Logger，设置后忽略Type和Rolling
+	Writer io.WriteCloser `json:"-" yaml:"-"`
+	// appender类型，为空时为rolling，stdout和stderr输出到标准输出和标准错误，
+	// audit为审计日志，每条日志同步落盘、不丢弃、级别不低于info，failover按Failover中的顺序故障转移，
+	// 其他类型需通过RegisterAppender注册
+	Type string `json:"type" yaml:"type"`
+	// 自定义appender类型的参数
+	Options map[string]interface{} `json:"options" yaml:"options"`
 	// 日志级别
 	Level string `json:"level" yaml:"level"`
 	// writer信息
 	Rolling *rollingwriter.Config `json:"rolling" yaml:"rolling"`
+	// 按字段值将日志写入不同文件，Rolling为每个文件的配置，FileName作为文件名前缀
+	Router *rollingwriter.RouterConfig `json:"router" yaml:"router"`
+	// 故障转移，Type为failover时按顺序使用的输出目标
+	Failover *FailoverConfig `json:"failover" yaml:"failover"`
+	// 日志过滤规则
+	Filter *rollingwriter.FilterConfig `json:"filter" yaml:"filter"`
+	// 内存环形缓存，配置后缓存appender级别启用的日志，只在出现错误日志时写入
+	Ring *RingConfig `json:"ring" yaml:"ring"`
+	// 是否在该appender中省略栈信息
+	DisableStacktrace bool `json:"disable_stacktrace" yaml:"disableStacktrace"`
+	// 该appender使用的处理器名称，在共用的处理器之后执行，需通过RegisterProcessor注册
+	Processors []string `json:"processors" yaml:"processors"`
+	// 该appender使用的脱敏方案名称，在所有处理器之后执行，方案在Config.ScrubProfiles中定义
+	ScrubProfile string `json:"scrub_profile" yaml:"scrubProfile"`
+	// 影子appender，用于试用新的输出目标，日志异步写入，写入失败、阻塞或创建失败都不影响应用和其他appender
+	Shadow bool `json:"shadow" yaml:"shadow"`
+	// 日志时间使用的时区，如UTC、Asia/Shanghai，为空时使用本机时区
+	TimeZone string `json:"time_zone" yaml:"timeZone"`
+	// 该appender的日志采样，在全局采样之后生效，审计日志不采样
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling"`
 }
 
-var logger *zap.Logger
+// 未调用Init前使用不输出的logger，避免包初始化时panic
+var logger = zap.NewNop()
 
+// 包级别日志函数使用的logger，多跳过一层栈帧，保证调用信息指向调用方
 var (
-	Debug   = logger.Debug
-	Debugf  = logger.Sugar().Debugf
-	Info    = logger.Info
-	Infof   = logger.Sugar().Infof
-	Warn    = logger.Warn
-	Warnf   = logger.Sugar().Warnf
-	Error   = logger.Error
-	Errorf  = logger.Sugar().Errorf
-	DPanic  = logger.DPanic
-	DPanicf = logger.Sugar().DPanicf
-	Panic   = logger.Panic
-	Panicf  = logger.Sugar().Panicf
-	Fatal   = logger.Fatal
-	Fatalf  = logger.Sugar().Fatalf
+	helper  = logger
+	shelper = logger.Sugar()
 )
 
-func Init(cfg *Config) {
+// 替换包级别的logger
+func setLogger(l *zap.Logger) {
+	logger = l
+	helper = l.WithOptions(zap.AddCallerSkip(1))
+	shelper = helper.Sugar()
+}
+
+func Debug(msg string, fields ...zap.Field) { helper.Debug(msg, fields...) }
+
+func Debugf(template string, args ...interface{}) { shelper.Debugf(template, args...) }
+
+func Info(msg string, fields ...zap.Field) { helper.Info(msg, fields...) }
+
+func Infof(template string, args ...interface{}) { shelper.Infof(template, args...) }
+
+func Warn(msg string, fields ...zap.Field) { helper.Warn(msg, fields...) }
+
+func Warnf(template string, args ...interface{}) { shelper.Warnf(template, args...) }
+
+func Error(msg string, fields ...zap.Field) { helper.Error(msg, fields...) }
+
+func Errorf(template string, args ...interface{}) { shelper.Errorf(template, args...) }
+
+func DPanic(msg string, fields ...zap.Field) { helper.DPanic(msg, fields...) }
+
+func DPanicf(template string, args ...interface{}) { shelper.DPanicf(template, args...) }
+
+func Panic(msg string, fields ...zap.Field) { helper.Panic(msg, fields...) }
+
+func Panicf(template string, args ...interface{}) { shelper.Panicf(template, args...) }
+
+func Fatal(msg string, fields ...zap.Field) { helper.Fatal(msg, fields...) }
+
+func Fatalf(template string, args ...interface{}) { shelper.Fatalf(template, args...) }
+
+// 根据配置初始化logger，opts为额外的zap.Option，在配置生成的选项之后应用
+func Init(cfg *Config, opts ...zap.Option) {
+	cfg = cfg.withErrorFile()
 	hostname, pwd := runner()
 	fmt.Printf("HostName: %s, Workerspace: %s\n", hostname, pwd)
 	config := newEncoderConfig(cfg.Format)
+	if cfg.ecs() {
+		config = ecsEncoderConfig(config, cfg.Format)
+	}
+	if cfg.gcp() {
+		config = gcpEncoderConfig(config, cfg.Format)
+	}
+	config = localeEncoderConfig(config, cfg)
 	encoder := encoder(cfg.Type, config)
-	var Logs []zapcore.Core
-	for _, app := range cfg.Appenders {
-		writer, err := rollingwriter.NewWriterFromConfig(app.Rolling)
-		if err != nil {
-			writer = os.Stdout
+	var Logs, audits []zapcore.Core
+	var apps []*appenderState
+	var rs []*ring
+	writers := newAppenderWriters(cfg.Appenders)
+	for i, app := range cfg.Appenders {
+		writer := writers[i]
+		state := &appenderState{typ: app.Type, writer: writer, level: zap.NewAtomicLevelAt(logLevel(app.Level))}
+		apps = append(apps, state)
+		if app.Filter != nil {
+			fw, err := rollingwriter.NewFilterWriter(writer, *app.Filter)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "appender %d: filter: %v\n", i, err)
+			} else {
+				writer = fw
+			}
+		}
+		var core zapcore.Core
+		appConfig, appEncoder := appenderEncoder(cfg, app, config, encoder)
+		audit := strings.TrimSpace(strings.ToLower(app.Type)) == "audit"
+		if audit {
+			// 审计日志不使用环形缓存、模块级别和采样
+			core = zapcore.NewCore(consoleEncoder(cfg, appConfig, appEncoder, writer), zapcore.AddSync(writer), auditLevel{state.level})
+		} else if app.Ring != nil {
+			rc := newRingCore(consoleEncoder(cfg, appConfig, appEncoder, writer).Clone(), zapcore.AddSync(writer), app.Ring, state.level)
+			rs = append(rs, rc.(*ringCore).ring)
+			core = &moduleCore{Core: rc, level: state.level}
+		} else {
+			core = &moduleCore{Core: zapcore.NewCore(consoleEncoder(cfg, appConfig, appEncoder, writer), zapcore.AddSync(writer), state.level), level: state.level}
+		}
+		if app.DisableStacktrace {
+			core = &noStackCore{core}
+		}
+		if app.Sampling != nil && !audit {
+			core = newSampler(core, app.Sampling)
+		}
+		names := app.Processors
+		if cfg.Kubernetes {
+			names = append([]string{"k8s"}, names...)
+		}
+		if cfg.ecs() {
+			names = append(names, "ecs")
+		}
+		if cfg.gcp() {
+			names = append(names, "gcp")
+		}
+		ps := lookupProcessors(names)
+		if app.ScrubProfile != "" {
+			scrub, err := cfg.scrubProcessor(app.ScrubProfile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "appender %d: %v\n", i, err)
+			} else {
+				ps = append(ps, scrub)
+			}
+		}
+		core = &processorCore{Core: core, processors: ps}
+		if audit {
+			audits = append(audits, core)
+			continue
 		}
-		level := logLevel(app.Level)
-		core := zapcore.NewCore(encoder, zapcore.AddSync(writer), level)
 		Logs = append(Logs, core)
 	}
 
+	if cfg.CrashDir != "" {
+		// 放在最前面，保证在环形缓存被输出清空前写入崩溃文件
+		Logs = append([]zapcore.Core{&crashCore{}}, Logs...)
+	}
+	if len(cfg.Metrics) > 0 {
+		Logs = append(Logs, newMetricsCore(cfg.Metrics))
+	}
+	if len(cfg.Alerts) > 0 {
+		Logs = append(Logs, newAlertCore(cfg.Alerts))
+	}
+	if cfg.Escalation != nil {
+		Logs = append(Logs, &escalationCore{newEscalator(cfg.Escalation)})
+	}
+	var hub *streamHub
+	if cfg.Stream != nil {
+		hub = newStream(cfg.Stream)
+		Logs = append(Logs, newStreamCore(config, cfg.Stream, hub))
+	}
+	setStream(hub)
+	setCrash(cfg.CrashDir, rs)
+	setModuleLevels(cfg.Modules)
+	setTrace(cfg)
 	core := zapcore.NewTee(Logs...)
-	logger = zap.New(core, zap.AddCaller())
-	if cfg.Stacktrace {
-		logger = logger.WithOptions(zap.AddStacktrace(zapcore.ErrorLevel))
+	if cfg.Sampling != nil {
+		core = newSampler(core, cfg.Sampling)
+	}
+	if len(audits) > 0 {
+		core = zapcore.NewTee(append([]zapcore.Core{core}, audits...)...)
+	}
+	// 在序号之内，拆分后的多条日志使用同一序号
+	core = newEntrySizeCore(core, cfg)
+	if cfg.Sequence {
+		core = &sequenceCore{core}
+	}
+	if cfg.Clock != nil {
+		core = &clockCore{Core: core, clock: cfg.Clock}
+	}
+	setLogger(newLogger(&hookCore{core}, cfg, opts...))
+	setAppenders(apps)
+	setConfig(cfg)
+	if cfg.ControlSocket != "" {
+		if err := startControl(cfg.ControlSocket); err != nil {
+			fmt.Fprintf(os.Stderr, "start control socket %s: %v\n", cfg.ControlSocket, err)
+		}
+	}
+}
+
+// 根据配置创建logger
+func newLogger(core zapcore.Core, cfg *Config, extra ...zap.Option) *zap.Logger {
+	var opts []zap.Option
+	if !cfg.DisableCaller {
+		opts = append(opts, zap.AddCaller(), zap.AddCallerSkip(cfg.CallerSkip))
+	}
+	switch {
+	case cfg.DisableStacktrace:
+	case cfg.StacktraceLevel != "":
+		opts = append(opts, zap.AddStacktrace(logLevel(cfg.StacktraceLevel)))
+	case cfg.Stacktrace && cfg.Development:
+		opts = append(opts, zap.AddStacktrace(zapcore.WarnLevel))
+	case cfg.Stacktrace:
+		opts = append(opts, zap.AddStacktrace(zapcore.ErrorLevel))
 	}
 	if cfg.Development {
-		logger.WithOptions(zap.Development())
+		opts = append(opts, zap.Development())
+	}
+	if cfg.ecs() {
+		opts = append(opts, zap.Fields(ecsFields()...))
+	}
+	if cfg.gcp() {
+		opts = append(opts, zap.Fields(gcpFields(cfg.GCP)...))
+	}
+	if cfg.SchemaVersion > 0 {
+		opts = append(opts, zap.Fields(zap.Int(SchemaVersionKey, cfg.SchemaVersion)))
+	}
+	if len(cfg.Fields) > 0 {
+		keys := make([]string, 0, len(cfg.Fields))
+		for k := range cfg.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fields := make([]zap.Field, 0, len(keys))
+		for _, k := range keys {
+			fields = append(fields, zap.Any(k, cfg.Fields[k]))
+		}
+		opts = append(opts, zap.Fields(fields...))
+	}
+	if cfg.WrapCore != nil {
+		opts = append(opts, zap.WrapCore(cfg.WrapCore))
 	}
+	return zap.New(core, append(opts, extra...)...)
 }
 
 func GetLogger() *zap.Logger {
@@ -86,6 +348,7 @@ func GetSLogger() *zap.SugaredLogger {
 }
 
 func Close() {
+	stopControl()
 	if err := logger.Sync(); err != nil {
 		logger.Error("closed err", zap.Error(err))
 	}
@@ -117,11 +380,35 @@ func encoder(typ string, config zapcore.EncoderConfig) (encoder zapcore.Encoder)
 		return zapcore.NewJSONEncoder(config)
 	case "console":
 		return zapcore.NewConsoleEncoder(config)
+	case "msgpack":
+		return newMsgpackEncoder(config)
+	case "protobuf":
+		return newProtobufEncoder(config)
 	default:
 		return zapcore.NewJSONEncoder(config)
 	}
 }
 
+// console格式下按输出的writer生成对齐、彩色或多行显示的encoder，其他格式返回enc
+func consoleEncoder(cfg *Config, config zapcore.EncoderConfig, enc zapcore.Encoder, w io.Writer) zapcore.Encoder {
+	if strings.TrimSpace(strings.ToLower(cfg.Type)) != "console" {
+		return enc
+	}
+	labels := cfg.levelLabels()
+	config.EncodeLevel = alignedLevelEncoder(labels)
+	if useColor(cfg.Color, w) {
+		config.EncodeLevel = colorLevelEncoder(labels)
+	}
+	enc = zapcore.NewConsoleEncoder(config)
+	if mode := multilineMode(cfg.Multiline, w); mode != "" {
+		enc = &multilineEncoder{Encoder: enc, escape: mode == "escape"}
+	}
+	if cfg.Pretty {
+		enc = &prettyEncoder{enc}
+	}
+	return enc
+}
+
 // 日志级别
 func logLevel(level string) zapcore.Level {
 	level = strings.TrimSpace(strings.ToLower(level))
@@ -143,6 +430,27 @@ func logLevel(level string) zapcore.Level {
 	}
 }
 
+// 写入时去掉栈信息的core
+type noStackCore struct {
+	zapcore.Core
+}
+
+func (c *noStackCore) With(fields []zapcore.Field) zapcore.Core {
+	return &noStackCore{c.Core.With(fields)}
+}
+
+func (c *noStackCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *noStackCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Stack = ""
+	return c.Core.Write(ent, fields)
+}
+
 func runner() (hostname, pwd string) {
 	hostname, _ = os.Hostname()
 	path, _ := filepath.Abs(os.Args[0])
@@ -0,0 +1,75 @@
+package logx
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func wrapped(msg string) { GetLogger().Info(msg) }
+
+func TestCaller(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	tests := []struct {
+		cfg    Config
+		log    func()
+		caller bool
+	}{
+		{Config{}, func() { Info("helper") }, true},
+		{Config{}, func() { Infof("%s", "sugar") }, true},
+		{Config{CallerSkip: 1}, func() { wrapped("wrapped") }, true},
+		{Config{DisableCaller: true}, func() { Info("disabled") }, false},
+	}
+	for _, tt := range tests {
+		core, logs := observer.New(zapcore.DebugLevel)
+		setLogger(newLogger(core, &tt.cfg))
+		tt.log()
+		entry := logs.All()[0]
+		assert.Equal(t, tt.caller, entry.Caller.Defined, entry.Message)
+		if tt.caller {
+			assert.Equal(t, "logx_test.go", filepath.Base(entry.Caller.File), entry.Message)
+			// 跳过封装函数所在的行
+			assert.NotEqual(t, 13, entry.Caller.Line, entry.Message)
+		}
+	}
+}
+
+func TestStacktraceLevel(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	core, logs := observer.New(zapcore.DebugLevel)
+	setLogger(newLogger(core, &Config{Stacktrace: true, StacktraceLevel: "warn"}))
+	Info("info")
+	Warn("warn")
+	entries := logs.All()
+	assert.Empty(t, entries[0].Stack)
+	assert.NotEmpty(t, entries[1].Stack)
+
+	core, logs = observer.New(zapcore.DebugLevel)
+	setLogger(newLogger(&noStackCore{core}, &Config{Stacktrace: true}))
+	Error("error")
+	assert.Empty(t, logs.All()[0].Stack)
+}
+
+func TestNewLoggerOptions(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := newLogger(core, &Config{Development: true, Stacktrace: true, Fields: map[string]interface{}{"app": "logx"}})
+	assert.Panics(t, func() { l.DPanic("dpanic") })
+	l.Warn("warn")
+	entries := logs.All()
+	assert.Equal(t, "logx", entries[0].ContextMap()["app"])
+	assert.NotEmpty(t, entries[1].Stack)
+
+	core, logs = observer.New(zapcore.DebugLevel)
+	wrapped := false
+	l = newLogger(core, &Config{Stacktrace: true, DisableStacktrace: true, WrapCore: func(c zapcore.Core) zapcore.Core {
+		wrapped = true
+		return c
+	}})
+	assert.NotPanics(t, func() { l.DPanic("dpanic") })
+	assert.Empty(t, logs.All()[0].Stack)
+	assert.True(t, wrapped)
+}
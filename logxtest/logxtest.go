@@ -0,0 +1,95 @@
+// logxtest提供单元测试中使用的logger和日志文件工具
+package logxtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// 测试工具使用的testing.TB方法，*testing.T和*testing.B都实现了该接口
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+	TempDir() string
+}
+
+// 在内存中记录所有日志的logger
+type TestLogger struct {
+	*zap.Logger
+	t    TB
+	logs *observer.ObservedLogs
+}
+
+// 创建记录所有级别日志的logger
+func NewTestLogger(t TB) *TestLogger {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return &TestLogger{Logger: zap.New(core), t: t, logs: logs}
+}
+
+// 已记录的日志
+func (l *TestLogger) Entries() []observer.LoggedEntry {
+	return l.logs.All()
+}
+
+// 清空已记录的日志
+func (l *TestLogger) Reset() {
+	l.logs.TakeAll()
+}
+
+// 判断是否记录了指定级别、消息包含msgContains且包含所有fields的日志，不存在时测试失败
+// fields的值与日志字段的值按fmt格式化后比较
+func (l *TestLogger) AssertLogged(level zapcore.Level, msgContains string, fields map[string]interface{}) bool {
+	l.t.Helper()
+	if l.find(level, msgContains, fields) {
+		return true
+	}
+	l.t.Errorf("no %s entry contains %q with fields %v, logged:\n%s", level.CapitalString(), msgContains, fields, l.dump())
+	return false
+}
+
+// 判断没有记录符合条件的日志，存在时测试失败
+func (l *TestLogger) AssertNotLogged(level zapcore.Level, msgContains string, fields map[string]interface{}) bool {
+	l.t.Helper()
+	if !l.find(level, msgContains, fields) {
+		return true
+	}
+	l.t.Errorf("unexpected %s entry contains %q with fields %v, logged:\n%s", level.CapitalString(), msgContains, fields, l.dump())
+	return false
+}
+
+func (l *TestLogger) find(level zapcore.Level, msgContains string, fields map[string]interface{}) bool {
+	for _, entry := range l.logs.All() {
+		if entry.Level == level && strings.Contains(entry.Message, msgContains) && matchFields(entry.ContextMap(), fields) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchFields(got, want map[string]interface{}) bool {
+	for k, v := range want {
+		gv, ok := got[k]
+		if !ok {
+			return false
+		}
+		if !reflect.DeepEqual(gv, v) && fmt.Sprint(gv) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *TestLogger) dump() string {
+	var b strings.Builder
+	for _, entry := range l.logs.All() {
+		fmt.Fprintf(&b, "  %s %s %v\n", entry.Level.CapitalString(), entry.Message, entry.ContextMap())
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package logxtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAssertLogged(t *testing.T) {
+	logger := NewTestLogger(t)
+	logger.Info("user login", zap.String("user", "bob"), zap.Int("attempt", 2))
+
+	assert.True(t, logger.AssertLogged(zapcore.InfoLevel, "login", map[string]interface{}{"user": "bob", "attempt": 2}))
+	assert.True(t, logger.AssertNotLogged(zapcore.ErrorLevel, "login", nil))
+
+	rec := &recorder{TB: t}
+	other := &TestLogger{Logger: logger.Logger, t: rec, logs: logger.logs}
+	assert.False(t, other.AssertLogged(zapcore.InfoLevel, "login", map[string]interface{}{"user": "alice"}))
+	assert.False(t, other.AssertNotLogged(zapcore.InfoLevel, "login", nil))
+	if assert.Len(t, rec.errors, 2) {
+		assert.Contains(t, rec.errors[0], `no INFO entry contains "login"`)
+		assert.Contains(t, rec.errors[1], `unexpected INFO entry contains "login"`)
+	}
+
+	logger.Reset()
+	assert.Empty(t, logger.Entries())
+}
+
+// 记录Errorf的错误而不使测试失败
+type recorder struct {
+	TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRollingWriter(t *testing.T) {
+	w := NewRollingWriter(t)
+	_, err := w.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	w.Rotate()
+	_, err = w.Write([]byte("second\n"))
+	assert.NoError(t, err)
+
+	archives := w.Archives()
+	if assert.Equal(t, 1, len(archives)) {
+		assert.Equal(t, "first\n", w.ReadFile(archives[0]))
+	}
+	assert.Equal(t, "second\n", w.ReadFile(w.Current()))
+}
+
+func TestRollingWriterFakeClock(t *testing.T) {
+	w := NewRollingWriter(t, rollingwriter.WithRollingTimePattern("0 * * * *"))
+	_, err := w.Write([]byte("first\n"))
+	assert.NoError(t, err)
+
+	w.Clock.WaitTimers(1)
+	w.Clock.Advance(30 * time.Minute)
+	assert.Empty(t, w.Archives())
+	w.Clock.Advance(30 * time.Minute)
+	w.WaitArchives(1)
+
+	archives := w.Archives()
+	assert.Equal(t, filepath.Join(w.Dir(), "test.log.20200101000000.000000000"), archives[0])
+	assert.Equal(t, "first\n", w.ReadFile(archives[0]))
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+	clock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, time.Unix(1, 0), <-ticker.C())
+	assert.Equal(t, time.Unix(1, 500*int64(time.Millisecond)), clock.Now())
+
+	timer := clock.NewTimer(time.Second)
+	assert.True(t, timer.Stop())
+	clock.Advance(time.Minute)
+	select {
+	case <-timer.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+}
@@ -0,0 +1,113 @@
+package logxtest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// 等待滚动完成的最长时间
+var RotateTimeout = 5 * time.Second
+
+// 写入测试临时目录的RollingWriter，测试结束时自动关闭
+type RollingWriter struct {
+	rollingwriter.RollingWriter
+	Config rollingwriter.Config
+	Clock  *FakeClock // 未通过opts设置时钟时使用的假时钟，起始时间为2020-01-01 00:00:00
+	t      TB
+}
+
+// 在t.TempDir中创建RollingWriter，默认不自动滚动、同步写入、使用假时钟，历史文件名精确到纳秒
+// 通过opts修改配置，LogPath固定为临时目录
+func NewRollingWriter(t TB, opts ...rollingwriter.Option) *RollingWriter {
+	t.Helper()
+	clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local))
+	cfg := rollingwriter.NewDefaultConfig()
+	cfg.Clock = clock
+	cfg.TimeTagFormat = "20060102150405.000000000"
+	cfg.FileName = "test"
+	cfg.RollingPolicy = rollingwriter.WithoutRolling
+	cfg.WriterMode = "none"
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.LogPath = t.TempDir()
+	w, err := rollingwriter.NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatalf("create rolling writer: %v", err)
+	}
+	rw := &RollingWriter{RollingWriter: w, Config: cfg, t: t}
+	if cfg.Clock == rollingwriter.Clock(clock) {
+		rw.Clock = clock
+	}
+	t.Cleanup(func() { _ = rw.Close() })
+	return rw
+}
+
+// 日志目录
+func (w *RollingWriter) Dir() string {
+	return w.Config.LogPath
+}
+
+// 当前日志文件路径
+func (w *RollingWriter) Current() string {
+	return rollingwriter.LogFilePath(&w.Config)
+}
+
+// 立即执行一次滚动，等待历史文件生成后返回，使用假时钟时先推进1秒避免历史文件重名
+func (w *RollingWriter) Rotate() {
+	w.t.Helper()
+	r, ok := w.RollingWriter.(interface{ Rotate() })
+	if !ok {
+		w.t.Fatalf("writer %T does not support Rotate", w.RollingWriter)
+	}
+	if w.Clock != nil {
+		w.Clock.Advance(time.Second)
+	}
+	n := len(w.Archives())
+	r.Rotate()
+	w.WaitArchives(n + 1)
+}
+
+// 等待历史文件数量不少于n，超时后测试失败
+func (w *RollingWriter) WaitArchives(n int) {
+	w.t.Helper()
+	deadline := time.Now().Add(RotateTimeout)
+	for len(w.Archives()) < n {
+		if time.Now().After(deadline) {
+			w.t.Fatalf("want %d archives, got %v", n, w.Archives())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 按名称排序的历史日志文件
+func (w *RollingWriter) Archives() []string {
+	prefix := w.Config.FileName + ".log."
+	infos, _ := ioutil.ReadDir(w.Dir())
+	var files []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".tmp") ||
+			strings.HasSuffix(name, ".index") || strings.HasSuffix(name, ".state") || strings.HasSuffix(name, ".partial") {
+			continue
+		}
+		files = append(files, filepath.Join(w.Dir(), name))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// 读取日志文件内容，读取失败时测试失败
+func (w *RollingWriter) ReadFile(name string) string {
+	w.t.Helper()
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		w.t.Fatalf("read %s: %v", name, err)
+	}
+	return string(buf)
+}
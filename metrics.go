@@ -0,0 +1,186 @@
+package logx
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 根据日志生成计数器的规则，日志满足所有条件时计数器加一
+type MetricRule struct {
+	Name    string            `json:"name" yaml:"name"`       // 计数器名称，如payment_failed_total
+	Help    string            `json:"help" yaml:"help"`       // 计数器说明
+	Level   string            `json:"level" yaml:"level"`     // 日志的最低级别，为空时不限制
+	Message string            `json:"message" yaml:"message"` // 日志消息包含的字符串，为空时不限制
+	Fields  map[string]string `json:"fields" yaml:"fields"`   // 日志字段的值，按fmt格式化后比较
+}
+
+var (
+	metricsMu sync.RWMutex
+	counters  = make(map[string]*counter)
+)
+
+type counter struct {
+	help  string
+	value uint64
+}
+
+// 当前所有计数器的值
+func Metrics() map[string]uint64 {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	values := make(map[string]uint64, len(counters))
+	for name, c := range counters {
+		values[name] = atomic.LoadUint64(&c.value)
+	}
+	return values
+}
+
+// 删除所有计数器，之后Init的规则重新从0计数
+func ResetMetrics() {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	counters = make(map[string]*counter)
+}
+
+// 以Prometheus文本格式输出所有计数器的http.Handler
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metricsMu.RLock()
+		names := make([]string, 0, len(counters))
+		for name := range counters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var b strings.Builder
+		for _, name := range names {
+			c := counters[name]
+			if c.help != "" {
+				fmt.Fprintf(&b, "# HELP %s %s\n", name, c.help)
+			}
+			fmt.Fprintf(&b, "# TYPE %s counter\n%s %d\n", name, name, atomic.LoadUint64(&c.value))
+		}
+		metricsMu.RUnlock()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(b.String()))
+	})
+}
+
+// 注册规则中的计数器，已存在的计数器保留原值
+func registerCounter(name, help string) *counter {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	c, ok := counters[name]
+	if !ok {
+		c = &counter{help: help}
+		counters[name] = c
+	}
+	return c
+}
+
+// 日志的匹配条件，计数和告警规则共用
+type entryMatcher struct {
+	level   zapcore.Level
+	message string
+	fields  map[string]string
+}
+
+func newEntryMatcher(level, message string, fields map[string]string, def zapcore.Level) entryMatcher {
+	m := entryMatcher{level: def, message: message, fields: fields}
+	if level != "" {
+		m.level = logLevel(level)
+	}
+	return m
+}
+
+func (r *entryMatcher) match(ent zapcore.Entry, fields map[string]interface{}) bool {
+	if ent.Level < r.level || !strings.Contains(ent.Message, r.message) {
+		return false
+	}
+	for k, want := range r.fields {
+		v, ok := fields[k]
+		if !ok || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// 合并core的字段和日志的字段
+func fieldMap(with, fields []zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range with {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+// 编译后的规则
+type metricRule struct {
+	entryMatcher
+	counter *counter
+}
+
+// 按规则统计日志的core，不写入日志
+type metricsCore struct {
+	rules  []*metricRule
+	min    zapcore.Level
+	fields []zapcore.Field
+}
+
+func newMetricsCore(rules []MetricRule) *metricsCore {
+	c := &metricsCore{min: zapcore.FatalLevel}
+	for _, r := range rules {
+		if r.Name == "" {
+			continue
+		}
+		m := newEntryMatcher(r.Level, r.Message, r.Fields, zapcore.DebugLevel)
+		if m.level < c.min {
+			c.min = m.level
+		}
+		c.rules = append(c.rules, &metricRule{entryMatcher: m, counter: registerCounter(r.Name, r.Help)})
+	}
+	return c
+}
+
+func (c *metricsCore) Enabled(l zapcore.Level) bool {
+	return len(c.rules) > 0 && l >= c.min
+}
+
+func (c *metricsCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *metricsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *metricsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var values map[string]interface{}
+	for _, r := range c.rules {
+		if len(r.fields) > 0 && values == nil {
+			values = fieldMap(c.fields, fields)
+		}
+		if r.match(ent, values) {
+			atomic.AddUint64(&r.counter.value, 1)
+		}
+	}
+	return nil
+}
+
+func (c *metricsCore) Sync() error {
+	return nil
+}
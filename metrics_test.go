@@ -0,0 +1,29 @@
+package logx
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestMetrics(t *testing.T) {
+	defer ResetMetrics()
+	l := zap.New(newMetricsCore([]MetricRule{
+		{Name: "test_payment_failed_total", Help: "failed payments", Level: "error", Message: "payment failed"},
+		{Name: "test_card_declined_total", Message: "payment failed", Fields: map[string]string{"reason": "declined"}},
+	}))
+	l.Warn("payment failed")
+	l.Error("payment failed", zap.String("reason", "timeout"))
+	l.With(zap.String("reason", "declined")).Error("payment failed")
+	l.Error("other")
+
+	metrics := Metrics()
+	assert.Equal(t, uint64(2), metrics["test_payment_failed_total"])
+	assert.Equal(t, uint64(1), metrics["test_card_declined_total"])
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), "# HELP test_payment_failed_total failed payments\n# TYPE test_payment_failed_total counter\ntest_payment_failed_total 2\n")
+}
@@ -0,0 +1,102 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// 记录日志字段版本的字段名
+const SchemaVersionKey = "schema_version"
+
+// 将一条日志从版本from升级到from+1，如重命名字段，可以直接修改并返回record
+type Migration func(record map[string]interface{}) map[string]interface{}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = make(map[int]Migration)
+)
+
+// 注册从版本from升级到from+1的转换函数，fn为nil时删除
+func RegisterMigration(from int, fn Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	if fn == nil {
+		delete(migrations, from)
+		return
+	}
+	migrations[from] = fn
+}
+
+// 将一条日志逐个版本升级到target版本，没有schema_version字段的日志为版本0
+// 日志版本高于target时不处理，缺少中间版本的转换函数时返回错误
+func Migrate(record map[string]interface{}, target int) (map[string]interface{}, error) {
+	version, err := schemaVersion(record)
+	if err != nil {
+		return nil, err
+	}
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	for ; version < target; version++ {
+		fn, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", version)
+		}
+		record = fn(record)
+		record[SchemaVersionKey] = version + 1
+	}
+	return record, nil
+}
+
+func schemaVersion(record map[string]interface{}) (int, error) {
+	switch v := record[SchemaVersionKey].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err
+	}
+	return 0, fmt.Errorf("invalid schema version %v", record[SchemaVersionKey])
+}
+
+// 将json日志文件中的日志升级到target版本后写入dst，用于转换旧的日志文件
+// 不是json对象的行原样写入，转换后的字段按名称排序，转换失败时返回出错的行号
+func MigrateFile(dst io.Writer, src io.Reader, target int) error {
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	w := bufio.NewWriter(dst)
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Bytes()
+		var record map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&record); err != nil || record == nil {
+			w.Write(raw)
+			w.WriteByte('\n')
+			continue
+		}
+		record, err := Migrate(record, target)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		buf, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		w.Write(buf)
+		w.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
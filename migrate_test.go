@@ -0,0 +1,46 @@
+package logx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMigrate(t *testing.T) {
+	RegisterMigration(0, func(r map[string]interface{}) map[string]interface{} {
+		r["message"] = r["msg"]
+		delete(r, "msg")
+		return r
+	})
+	RegisterMigration(1, func(r map[string]interface{}) map[string]interface{} {
+		r["severity"] = r["level"]
+		delete(r, "level")
+		return r
+	})
+	defer RegisterMigration(0, nil)
+	defer RegisterMigration(1, nil)
+
+	src := strings.NewReader(`{"level":"INFO","msg":"a"}
+not json
+{"schema_version":1,"level":"WARN","message":"b","n":12345678901234}
+`)
+	var dst bytes.Buffer
+	assert.NoError(t, MigrateFile(&dst, src, 2))
+	assert.Equal(t, `{"message":"a","schema_version":2,"severity":"INFO"}
+not json
+{"message":"b","n":12345678901234,"schema_version":2,"severity":"WARN"}
+`, dst.String())
+
+	_, err := Migrate(map[string]interface{}{}, 3)
+	assert.Error(t, err)
+	err = MigrateFile(&dst, strings.NewReader(`{"schema_version":"x"}`), 1)
+	assert.EqualError(t, err, "line 1: invalid schema version x")
+
+	obs, logs := observer.New(zapcore.InfoLevel)
+	newLogger(obs, &Config{SchemaVersion: 2}).Info("hi")
+	assert.Equal(t, int64(2), logs.All()[0].ContextMap()[SchemaVersionKey])
+}
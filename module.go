@@ -0,0 +1,176 @@
+package logx
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 按logger名称或调用方包名覆盖appender的日志级别
+type moduleLevels struct {
+	sync.RWMutex
+	levels map[string]zapcore.Level
+	min    zapcore.Level // 所有覆盖级别中的最低级别
+}
+
+var modules = &moduleLevels{}
+
+// 设置模块的日志级别，module为logger名称或包路径，匹配自身及其子模块
+//
+//	logx.SetModuleLevel("github.com/acme/db", "debug")
+func SetModuleLevel(module, level string) error {
+	var l zap.AtomicLevel
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	modules.set(module, l.Level())
+	return nil
+}
+
+// 删除模块的日志级别，恢复使用appender的级别
+func RemoveModuleLevel(module string) {
+	modules.Lock()
+	defer modules.Unlock()
+	delete(modules.levels, module)
+	modules.update()
+}
+
+// 当前设置的模块日志级别
+func ModuleLevels() map[string]string {
+	modules.RLock()
+	defer modules.RUnlock()
+	levels := make(map[string]string, len(modules.levels))
+	for module, l := range modules.levels {
+		levels[module] = l.String()
+	}
+	return levels
+}
+
+// 替换全部模块日志级别，级别无法解析的模块忽略
+func setModuleLevels(levels map[string]string) {
+	modules.Lock()
+	modules.levels = nil
+	modules.update()
+	modules.Unlock()
+	for module, level := range levels {
+		_ = SetModuleLevel(module, level)
+	}
+}
+
+func (m *moduleLevels) set(module string, l zapcore.Level) {
+	m.Lock()
+	defer m.Unlock()
+	if m.levels == nil {
+		m.levels = make(map[string]zapcore.Level)
+	}
+	m.levels[module] = l
+	m.update()
+}
+
+func (m *moduleLevels) get(module string) (zapcore.Level, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	l, ok := m.levels[module]
+	return l, ok
+}
+
+// 需要持有写锁
+func (m *moduleLevels) update() {
+	m.min = zapcore.FatalLevel
+	for _, l := range m.levels {
+		if l < m.min {
+			m.min = l
+		}
+	}
+}
+
+// 是否有模块可能输出该级别的日志
+func (m *moduleLevels) enabled(l zapcore.Level) (enabled, active bool) {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.levels) > 0 && l >= m.min, len(m.levels) > 0
+}
+
+// 查找日志对应的模块级别，logger名称优先，其次按调用方的包路径，多个模块匹配时使用最长的
+func (m *moduleLevels) lookup(ent zapcore.Entry) (zapcore.Level, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	if len(m.levels) == 0 {
+		return 0, false
+	}
+	if l, ok := m.match(ent.LoggerName); ok {
+		return l, true
+	}
+	if ent.Caller.Defined {
+		if fn := runtime.FuncForPC(ent.Caller.PC); fn != nil {
+			return m.match(packageName(fn.Name()))
+		}
+	}
+	return 0, false
+}
+
+func (m *moduleLevels) match(name string) (level zapcore.Level, ok bool) {
+	if name == "" {
+		return 0, false
+	}
+	longest := -1
+	for module, l := range m.levels {
+		if len(module) > longest && (name == module || strings.HasPrefix(name, module+"/") || strings.HasPrefix(name, module+".")) {
+			level, ok, longest = l, true, len(module)
+		}
+	}
+	return level, ok
+}
+
+// 从函数全名中取出包路径，如github.com/acme/db.(*Conn).Query中的github.com/acme/db
+func packageName(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
+
+// appender的core，模块设置了级别时按模块级别过滤，否则使用appender的级别
+type moduleCore struct {
+	zapcore.Core
+	level zap.AtomicLevel
+}
+
+func (c *moduleCore) Enabled(l zapcore.Level) bool {
+	if c.level.Enabled(l) {
+		return true
+	}
+	enabled, _ := modules.enabled(l)
+	return enabled
+}
+
+func (c *moduleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *moduleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	enabled, active := modules.enabled(ent.Level)
+	if !active {
+		return c.Core.Check(ent, ce)
+	}
+	if enabled || c.level.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// 调用信息在Check之后才会填充，因此在写入时按模块级别过滤
+func (c *moduleCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	level := c.level.Level()
+	if l, ok := modules.lookup(ent); ok {
+		level = l
+	}
+	if ent.Level < level {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -0,0 +1,49 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestModuleLevel(t *testing.T) {
+	defer setModuleLevels(nil)
+	obs, logs := observer.New(zapcore.DebugLevel)
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	l := zap.New(&moduleCore{Core: obs, level: level}, zap.AddCaller())
+
+	l.Debug("default")
+	assert.Equal(t, 0, logs.Len())
+
+	assert.NoError(t, SetModuleLevel("github.com/Muskchen/logx", "debug"))
+	assert.NoError(t, SetModuleLevel("db", "error"))
+	assert.Error(t, SetModuleLevel("db", "verbose"))
+	l.Debug("package")
+	l.Named("db").Warn("named")
+	l.Named("db").Named("pool").Error("child")
+	l.Named("dbx").Debug("prefix only")
+	assert.Equal(t, []string{"package", "child", "prefix only"}, messages(logs))
+	assert.Equal(t, map[string]string{"github.com/Muskchen/logx": "debug", "db": "error"}, ModuleLevels())
+
+	RemoveModuleLevel("github.com/Muskchen/logx")
+	logs.TakeAll()
+	l.Debug("removed")
+	assert.Equal(t, 0, logs.Len())
+}
+
+func TestPackageName(t *testing.T) {
+	assert.Equal(t, "github.com/acme/db", packageName("github.com/acme/db.(*Conn).Query"))
+	assert.Equal(t, "github.com/acme/db", packageName("github.com/acme/db.Open.func1"))
+	assert.Equal(t, "main", packageName("main.main"))
+}
+
+func messages(logs *observer.ObservedLogs) []string {
+	var msgs []string
+	for _, entry := range logs.All() {
+		msgs = append(msgs, entry.Message)
+	}
+	return msgs
+}
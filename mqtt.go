@@ -0,0 +1,343 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// mqtt类型appender的参数，通过appender的options配置
+type MQTTConfig struct {
+	Broker             string `json:"broker"`               // broker地址，如tcp://127.0.0.1:1883，tls://、ssl://或mqtts://时使用TLS
+	ClientID           string `json:"client_id"`            // 客户端标识，为空时为logx-主机名
+	Username           string `json:"username"`             // 用户名
+	Password           string `json:"password"`             // 密码
+	Topic              string `json:"topic"`                // 发布日志的主题
+	QoS                int    `json:"qos"`                  // 0或1，为1时等待broker确认
+	Retained           bool   `json:"retained"`             // 是否为保留消息
+	KeepAlive          int    `json:"keep_alive"`           // 心跳间隔，单位秒，为0时为60
+	Timeout            int    `json:"timeout"`              // 连接、发送和等待确认的超时时间，单位秒，为0时为5
+	CAFile             string `json:"ca_file"`              // 校验broker证书的CA文件
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // 不校验broker证书
+
+	// 本地文件的配置，broker不可用时日志写入该文件，为空时返回发送失败的错误
+	Fallback *rollingwriter.Config `json:"fallback"`
+}
+
+var (
+	ErrMQTTConnect    = errors.New("error mqtt connect")
+	errMQTTReconnect  = errors.New("error mqtt not connected")
+	errMQTTAckTimeout = errors.New("error mqtt puback timeout")
+)
+
+func init() {
+	RegisterAppender("mqtt", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		def := rollingwriter.NewDefaultConfig()
+		cfg := MQTTConfig{Fallback: &def}
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		if options["fallback"] == nil {
+			cfg.Fallback = nil
+		}
+		return NewMQTTWriter(cfg)
+	})
+}
+
+// 将每条日志作为一条MQTT消息发布的writer，实现了MQTT 3.1.1中发布所需的部分，并发安全
+// 连接断开后在下一次写入时重连，两次连接至少间隔1秒，期间的日志写入Fallback
+type MQTTWriter struct {
+	mu       sync.Mutex
+	cfg      MQTTConfig
+	tls      *tls.Config
+	conn     net.Conn
+	acks     chan uint16   // 当前连接收到的PUBACK
+	broken   chan struct{} // 当前连接断开时关闭
+	packetID uint16
+	lastDial time.Time
+	fallback rollingwriter.RollingWriter
+	stop     chan struct{}
+	closed   bool
+}
+
+func NewMQTTWriter(cfg MQTTConfig) (*MQTTWriter, error) {
+	u, err := url.Parse(cfg.Broker)
+	if err != nil || u.Host == "" || cfg.Topic == "" || cfg.QoS < 0 || cfg.QoS > 1 {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.ClientID == "" {
+		hostname, _ := runner()
+		cfg.ClientID = "logx-" + hostname
+	}
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = 60
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5
+	}
+	w := &MQTTWriter{cfg: cfg, stop: make(chan struct{})}
+	switch u.Scheme {
+	case "tls", "ssl", "mqtts":
+		w.tls = &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: cfg.InsecureSkipVerify}
+		if cfg.CAFile != "" {
+			pem, err := ioutil.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			w.tls.RootCAs = x509.NewCertPool()
+			w.tls.RootCAs.AppendCertsFromPEM(pem)
+		}
+	}
+	if cfg.Fallback != nil {
+		// 只在broker不可用时创建本地文件
+		fc := *cfg.Fallback
+		fc.LazyOpen = true
+		if w.fallback, err = rollingwriter.NewWriterFromConfig(&fc); err != nil {
+			return nil, err
+		}
+	}
+	go w.keepAlive()
+	return w, nil
+}
+
+func (w *MQTTWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	err := w.publish(bytes.TrimRight(b, "\n"))
+	if err == nil {
+		return len(b), nil
+	}
+	if w.fallback == nil {
+		return 0, err
+	}
+	return w.fallback.Write(b)
+}
+
+// 断开与broker的连接，关闭本地文件
+func (w *MQTTWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	close(w.stop)
+	if w.conn != nil {
+		w.conn.Write([]byte{0xe0, 0})
+		w.disconnect()
+	}
+	if w.fallback != nil {
+		return w.fallback.Close()
+	}
+	return nil
+}
+
+func (w *MQTTWriter) timeout() time.Duration {
+	return time.Duration(w.cfg.Timeout) * time.Second
+}
+
+func (w *MQTTWriter) publish(payload []byte) error {
+	if err := w.connect(); err != nil {
+		return err
+	}
+	c := w.cfg
+	header := byte(0x30) | byte(c.QoS)<<1
+	if c.Retained {
+		header |= 1
+	}
+	body := appendMQTTString(nil, c.Topic)
+	if c.QoS > 0 {
+		w.packetID++
+		if w.packetID == 0 {
+			w.packetID = 1
+		}
+		body = append(body, byte(w.packetID>>8), byte(w.packetID))
+	}
+	body = append(body, payload...)
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout()))
+	if _, err := w.conn.Write(mqttPacket(header, body)); err != nil {
+		w.disconnect()
+		return err
+	}
+	if c.QoS == 0 {
+		return nil
+	}
+	timer := time.NewTimer(w.timeout())
+	defer timer.Stop()
+	for {
+		select {
+		case id := <-w.acks:
+			if id == w.packetID {
+				return nil
+			}
+		case <-w.broken:
+			w.disconnect()
+			return io.ErrUnexpectedEOF
+		case <-timer.C:
+			w.disconnect()
+			return errMQTTAckTimeout
+		}
+	}
+}
+
+// 没有连接或连接已断开时连接broker
+func (w *MQTTWriter) connect() error {
+	if w.conn != nil {
+		select {
+		case <-w.broken:
+			w.disconnect()
+		default:
+			return nil
+		}
+	}
+	if time.Since(w.lastDial) < time.Second {
+		return errMQTTReconnect
+	}
+	w.lastDial = time.Now()
+	u, _ := url.Parse(w.cfg.Broker)
+	dialer := &net.Dialer{Timeout: w.timeout()}
+	var conn net.Conn
+	var err error
+	if w.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", u.Host, w.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", u.Host)
+	}
+	if err != nil {
+		return err
+	}
+
+	c := w.cfg
+	flags := byte(0x02) // clean session
+	if c.Username != "" {
+		flags |= 0x80
+	}
+	if c.Password != "" {
+		flags |= 0x40
+	}
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags, byte(c.KeepAlive>>8), byte(c.KeepAlive))
+	body = appendMQTTString(body, c.ClientID)
+	if c.Username != "" {
+		body = appendMQTTString(body, c.Username)
+	}
+	if c.Password != "" {
+		body = appendMQTTString(body, c.Password)
+	}
+	conn.SetDeadline(time.Now().Add(w.timeout()))
+	r := bufio.NewReader(conn)
+	if _, err := conn.Write(mqttPacket(0x10, body)); err != nil {
+		conn.Close()
+		return err
+	}
+	typ, ack, err := readMQTTPacket(r)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if typ != 2 || len(ack) < 2 || ack[1] != 0 {
+		conn.Close()
+		return fmt.Errorf("%w: %v", ErrMQTTConnect, ack)
+	}
+	conn.SetDeadline(time.Time{})
+	w.conn = conn
+	w.acks = make(chan uint16, 16)
+	w.broken = make(chan struct{})
+	go readMQTT(r, w.acks, w.broken)
+	return nil
+}
+
+func (w *MQTTWriter) disconnect() {
+	w.conn.Close()
+	w.conn = nil
+}
+
+// 按KeepAlive的一半发送心跳
+func (w *MQTTWriter) keepAlive() {
+	ticker := time.NewTicker(time.Duration(w.cfg.KeepAlive) * time.Second / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.conn != nil {
+				w.conn.SetWriteDeadline(time.Now().Add(w.timeout()))
+				if _, err := w.conn.Write([]byte{0xc0, 0}); err != nil {
+					w.disconnect()
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// 读取连接上的所有报文，将PUBACK交给等待确认的写入，连接断开时关闭broken
+func readMQTT(r *bufio.Reader, acks chan<- uint16, broken chan struct{}) {
+	defer close(broken)
+	for {
+		typ, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+		if typ == 4 && len(body) >= 2 {
+			select {
+			case acks <- uint16(body[0])<<8 | uint16(body[1]):
+			default:
+			}
+		}
+	}
+}
+
+func mqttPacket(header byte, body []byte) []byte {
+	b := appendVarint([]byte{header}, uint64(len(body)))
+	return append(b, body...)
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+// 读取一个报文，返回报文类型和剩余部分
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var n, shift uint
+	for i := 0; ; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		if i == 4 {
+			return 0, nil, ErrMQTTConnect
+		}
+		n |= uint(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
@@ -0,0 +1,75 @@
+package logx
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMQTTWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	published := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if typ, body, err := readMQTTPacket(r); err != nil || typ != 1 || string(body[2:6]) != "MQTT" {
+			return
+		}
+		conn.Write([]byte{0x20, 2, 0, 0})
+		for {
+			typ, body, err := readMQTTPacket(r)
+			if err != nil || typ != 3 {
+				return
+			}
+			n := int(body[0])<<8 | int(body[1])
+			// 主题之后是packet id和消息
+			conn.Write([]byte{0x40, 2, body[2+n], body[3+n]})
+			published <- string(body[4+n:])
+		}
+	}()
+
+	dir := t.TempDir()
+	w, err := newAppenderWriter(Appender{Type: "mqtt", Options: map[string]interface{}{
+		"broker": "tcp://" + ln.Addr().String(), "topic": "logs/app", "qos": 1,
+		"fallback": map[string]interface{}{"log_path": dir, "file_name": "mqtt"},
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	_, err = w.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	select {
+	case msg := <-published:
+		assert.Equal(t, "first", msg)
+	case <-time.After(time.Second):
+		t.Fatal("message not published")
+	}
+	_, err = ioutil.ReadFile(filepath.Join(dir, "mqtt.log"))
+	assert.Error(t, err)
+
+	// broker不可用时写入本地文件
+	ln.Close()
+	w.(*MQTTWriter).mu.Lock()
+	w.(*MQTTWriter).conn.Close()
+	w.(*MQTTWriter).mu.Unlock()
+	_, err = w.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	buf, _ := ioutil.ReadFile(filepath.Join(dir, "mqtt.log"))
+	assert.Equal(t, "second\n", string(buf))
+
+	_, err = NewMQTTWriter(MQTTConfig{Broker: "tcp://127.0.0.1:1883"})
+	assert.Error(t, err)
+}
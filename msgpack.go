@@ -0,0 +1,250 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var _msgpackPool = buffer.NewPool()
+
+// msgpack格式的encoder，每条日志为一个msgpack map，前面是4字节大端序的长度，不包含换行符
+// 时间使用msgpack timestamp扩展类型，time.Duration为纳秒数，通过reader包读取
+type msgpackEncoder struct {
+	*zapcore.MapObjectEncoder
+	config zapcore.EncoderConfig
+}
+
+func newMsgpackEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return &msgpackEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), config: config}
+}
+
+func (enc *msgpackEncoder) Clone() zapcore.Encoder {
+	clone := zapcore.NewMapObjectEncoder()
+	for k, v := range enc.Fields {
+		clone.Fields[k] = v
+	}
+	return &msgpackEncoder{MapObjectEncoder: clone, config: enc.config}
+}
+
+func (enc *msgpackEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.Clone().(*msgpackEncoder)
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	c := enc.config
+	entry := make(map[string]interface{}, 6)
+	if c.TimeKey != "" {
+		entry[c.TimeKey] = ent.Time
+	}
+	if c.LevelKey != "" {
+		entry[c.LevelKey] = ent.Level.CapitalString()
+	}
+	if c.NameKey != "" && ent.LoggerName != "" {
+		entry[c.NameKey] = ent.LoggerName
+	}
+	if c.CallerKey != "" && ent.Caller.Defined {
+		entry[c.CallerKey] = ent.Caller.TrimmedPath()
+	}
+	if c.MessageKey != "" {
+		entry[c.MessageKey] = ent.Message
+	}
+	if c.StacktraceKey != "" && ent.Stack != "" {
+		entry[c.StacktraceKey] = ent.Stack
+	}
+
+	// 日志本身的字段在前，与json格式的字段顺序一致
+	b := make([]byte, 4, 256)
+	b = appendMapHeader(b, len(entry)+len(final.Fields))
+	for _, key := range []string{c.TimeKey, c.LevelKey, c.NameKey, c.CallerKey, c.MessageKey, c.StacktraceKey} {
+		if v, ok := entry[key]; ok {
+			b = appendMsgpack(appendMsgpack(b, key), v)
+			delete(entry, key)
+		}
+	}
+	for k, v := range final.Fields {
+		b = appendMsgpack(appendMsgpack(b, k), v)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	buf := _msgpackPool.Get()
+	buf.Write(b)
+	return buf, nil
+}
+
+// 按msgpack格式追加v，MapObjectEncoder之外的类型通过json转换
+func appendMsgpack(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case uintptr:
+		return appendUint(b, uint64(v))
+	case float32:
+		b = append(b, 0xca)
+		return appendUint32(b, math.Float32bits(v))
+	case float64:
+		b = append(b, 0xcb)
+		return appendUint64(b, math.Float64bits(v))
+	case complex64, complex128:
+		return appendMsgpack(b, fmt.Sprint(v))
+	case string:
+		return appendString(b, v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return appendInt(b, n)
+		}
+		f, _ := v.Float64()
+		return appendMsgpack(b, f)
+	case []byte:
+		n := len(v)
+		switch {
+		case n <= math.MaxUint8:
+			b = append(b, 0xc4, byte(n))
+		case n <= math.MaxUint16:
+			b = append(b, 0xc5, byte(n>>8), byte(n))
+		default:
+			b = append(b, 0xc6)
+			b = appendUint32(b, uint32(n))
+		}
+		return append(b, v...)
+	case time.Time:
+		// timestamp 96：4字节纳秒和8字节秒
+		b = append(b, 0xc7, 12, 0xff)
+		b = appendUint32(b, uint32(v.Nanosecond()))
+		return appendUint64(b, uint64(v.Unix()))
+	case time.Duration:
+		return appendInt(b, int64(v))
+	case []interface{}:
+		n := len(v)
+		switch {
+		case n < 16:
+			b = append(b, 0x90|byte(n))
+		case n <= math.MaxUint16:
+			b = append(b, 0xdc, byte(n>>8), byte(n))
+		default:
+			b = append(b, 0xdd)
+			b = appendUint32(b, uint32(n))
+		}
+		for _, e := range v {
+			b = appendMsgpack(b, e)
+		}
+		return b
+	case map[string]interface{}:
+		b = appendMapHeader(b, len(v))
+		for k, e := range v {
+			b = appendMsgpack(appendMsgpack(b, k), e)
+		}
+		return b
+	}
+	// 其他类型，如AddReflected添加的结构体，转换为json对应的基础类型
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return appendString(b, fmt.Sprint(v))
+	}
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return appendString(b, string(buf))
+	}
+	return appendMsgpack(b, generic)
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	}
+	b = append(b, 0xdf)
+	return appendUint32(b, uint32(n))
+}
+
+func appendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb)
+		b = appendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return append(b, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		b = append(b, 0xd2)
+		return appendUint32(b, uint32(v))
+	}
+	b = append(b, 0xd3)
+	return appendUint64(b, uint64(v))
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 128:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return append(b, 0xcd, byte(v>>8), byte(v))
+	case v <= math.MaxUint32:
+		b = append(b, 0xce)
+		return appendUint32(b, uint32(v))
+	}
+	b = append(b, 0xcf)
+	return appendUint64(b, v)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(appendUint32(b, uint32(v>>32)), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,54 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Muskchen/logx/reader"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMsgpackEncoder(t *testing.T) {
+	enc := encoder("msgpack", newEncoderConfig(""))
+	enc.AddString("service", "api")
+	now := time.Unix(1600000000, 123)
+	var data bytes.Buffer
+	for i := 0; i < 2; i++ {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Time: now, Message: "slow"}, []zapcore.Field{
+			zap.Int("n", -300), zap.Uint64("big", 1<<40), zap.Float64("f", 1.5), zap.Duration("d", time.Second),
+			zap.Error(errors.New("boom")), zap.Strings("tags", []string{"a", "b"}), zap.Binary("raw", []byte{1, 2}),
+			zap.Reflect("obj", struct{ A int }{A: 1}), zap.Namespace("ns"), zap.Bool("ok", true),
+		})
+		assert.NoError(t, err)
+		data.Write(buf.Bytes())
+		buf.Free()
+	}
+
+	r := reader.NewReader(&data)
+	for i := 0; i < 2; i++ {
+		rec, err := r.Next()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, now.Equal(rec["ts"].(time.Time)))
+		assert.Equal(t, "WARN", rec["level"])
+		assert.Equal(t, "slow", rec["msg"])
+		assert.Equal(t, "api", rec["service"])
+		assert.Equal(t, int64(-300), rec["n"])
+		assert.Equal(t, int64(1<<40), rec["big"])
+		assert.Equal(t, 1.5, rec["f"])
+		assert.Equal(t, int64(time.Second), rec["d"])
+		assert.Equal(t, "boom", rec["error"])
+		assert.Equal(t, []interface{}{"a", "b"}, rec["tags"])
+		assert.Equal(t, []byte{1, 2}, rec["raw"])
+		assert.Equal(t, map[string]interface{}{"A": int64(1)}, rec["obj"])
+		assert.Equal(t, map[string]interface{}{"ok": true}, rec["ns"])
+	}
+	_, err := r.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,352 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// nats类型appender的参数，通过appender的options配置
+type NATSConfig struct {
+	URL             string `json:"url"`              // 服务地址，如nats://127.0.0.1:4222，可以包含用户名和密码
+	Subject         string `json:"subject"`          // 发布日志的subject，JetStream时为stream监听的subject
+	JetStream       bool   `json:"jetstream"`        // 是否等待JetStream的确认，未确认的日志在重连后重发
+	Token           string `json:"token"`            // 认证token
+	Name            string `json:"name"`             // 连接名称，为空时为logx
+	Timeout         int    `json:"timeout"`          // 连接和关闭时等待确认的超时时间，单位秒，为0时为5
+	ReconnectBuffer string `json:"reconnect_buffer"` // 断开期间缓存的日志大小上限，格式与RollingVolumeSize相同，为空时为8MB
+}
+
+var ErrNATSBufferFull = errors.New("error nats reconnect buffer full")
+
+func init() {
+	RegisterAppender("nats", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		var cfg NATSConfig
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		return NewNATSWriter(cfg)
+	})
+}
+
+// 将每条日志发布到NATS的writer，实现了发布所需的NATS客户端协议，写入只进入缓存，由后台协程发送
+// 连接断开期间日志保留在缓存中，每秒重连一次，缓存超过ReconnectBuffer时写入返回ErrNATSBufferFull
+// 开启JetStream时每条日志带回复subject发布，收到确认前保留，连接断开后重发，确认中的错误输出到标准错误
+type NATSWriter struct {
+	mu      sync.Mutex
+	cfg     NATSConfig
+	limit   int
+	queue   [][]byte          // 待发送的日志
+	size    int               // 待发送日志的总大小
+	pending map[uint64][]byte // 等待JetStream确认的日志
+	seq     uint64
+	inbox   string
+	signal  chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+func NewNATSWriter(cfg NATSConfig) (*NATSWriter, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" || cfg.Subject == "" {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.Name == "" {
+		cfg.Name = "logx"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5
+	}
+	limit := 8 << 20
+	if cfg.ReconnectBuffer != "" {
+		limit = int(rollingwriter.ParseSize(cfg.ReconnectBuffer))
+	}
+	w := &NATSWriter{
+		cfg:     cfg,
+		limit:   limit,
+		pending: make(map[uint64][]byte),
+		inbox:   "_INBOX.logx." + strconv.FormatInt(time.Now().UnixNano(), 36),
+		signal:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+func (w *NATSWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	if w.size+len(b) > w.limit {
+		return 0, ErrNATSBufferFull
+	}
+	w.queue = append(w.queue, append([]byte(nil), bytes.TrimRight(b, "\n")...))
+	w.size += len(b)
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+	return len(b), nil
+}
+
+// 发送缓存中的日志，JetStream时等待确认，最多等待Timeout后断开连接
+func (w *NATSWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+func (w *NATSWriter) timeout() time.Duration {
+	return time.Duration(w.cfg.Timeout) * time.Second
+}
+
+// 连接并发送日志，连接断开后每秒重连，关闭时退出
+func (w *NATSWriter) loop() {
+	defer close(w.done)
+	for {
+		conn, r, err := w.dial()
+		if err == nil {
+			if w.serve(conn, r) {
+				return
+			}
+		}
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (w *NATSWriter) dial() (net.Conn, *bufio.Reader, error) {
+	u, _ := url.Parse(w.cfg.URL)
+	conn, err := net.DialTimeout("tcp", u.Host, w.timeout())
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(w.timeout()))
+	r := bufio.NewReader(conn)
+	// 服务端先发送INFO
+	if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, nil, fmt.Errorf("nats: unexpected %q: %v", line, err)
+	}
+	opts := map[string]interface{}{
+		"verbose": false, "pedantic": false, "name": w.cfg.Name, "lang": "go", "version": "logx", "protocol": 1,
+	}
+	if u.User != nil {
+		opts["user"] = u.User.Username()
+		opts["pass"], _ = u.User.Password()
+	}
+	if w.cfg.Token != "" {
+		opts["auth_token"] = w.cfg.Token
+	}
+	buf, _ := json.Marshal(opts)
+	cmd := "CONNECT " + string(buf) + "\r\nPING\r\n"
+	if w.cfg.JetStream {
+		cmd += "SUB " + w.inbox + ".* 1\r\n"
+	}
+	if _, err := io.WriteString(conn, cmd); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	// PONG表示CONNECT成功，认证失败时为-ERR
+	if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "PONG") {
+		conn.Close()
+		return nil, nil, fmt.Errorf("nats: connect %q: %v", strings.TrimSpace(line), err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// 在连接上发送日志直到连接断开或关闭，关闭时返回true
+func (w *NATSWriter) serve(conn net.Conn, r *bufio.Reader) bool {
+	defer conn.Close()
+	var wmu sync.Mutex // 保护发送日志和回复PONG
+	bw := bufio.NewWriter(conn)
+	broken := make(chan struct{})
+	go w.read(r, func(s string) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		bw.WriteString(s)
+		bw.Flush()
+	}, broken)
+
+	stopping := false
+	for {
+		w.mu.Lock()
+		batch := w.queue
+		w.queue, w.size = nil, 0
+		start := w.seq
+		w.mu.Unlock()
+
+		wmu.Lock()
+		for i, b := range batch {
+			if w.cfg.JetStream {
+				w.mu.Lock()
+				w.pending[start+uint64(i)+1] = b
+				w.mu.Unlock()
+				fmt.Fprintf(bw, "PUB %s %s.%d %d\r\n", w.cfg.Subject, w.inbox, start+uint64(i)+1, len(b))
+			} else {
+				fmt.Fprintf(bw, "PUB %s %d\r\n", w.cfg.Subject, len(b))
+			}
+			bw.Write(b)
+			bw.WriteString("\r\n")
+		}
+		err := bw.Flush()
+		wmu.Unlock()
+		w.mu.Lock()
+		w.seq += uint64(len(batch))
+		w.mu.Unlock()
+		if err != nil {
+			if !w.cfg.JetStream {
+				w.requeue(batch)
+			}
+			w.requeuePending()
+			return stopping
+		}
+		if stopping {
+			w.waitAcks(broken)
+			return true
+		}
+
+		select {
+		case <-w.signal:
+		case <-broken:
+			w.requeuePending()
+			return false
+		case <-w.stop:
+			stopping = true
+		}
+	}
+}
+
+// 关闭时等待JetStream确认，超时后未确认的日志丢弃
+func (w *NATSWriter) waitAcks(broken chan struct{}) {
+	deadline := time.Now().Add(w.timeout())
+	for {
+		w.mu.Lock()
+		n := len(w.pending)
+		w.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			fmt.Fprintf(os.Stderr, "nats: %d entries not acknowledged\n", n)
+			return
+		}
+		select {
+		case <-broken:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// 将未发送的日志放回缓存的开头
+func (w *NATSWriter) requeue(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, b := range batch {
+		w.size += len(b)
+	}
+	w.queue = append(batch, w.queue...)
+}
+
+// 将未确认的日志按发布顺序放回缓存重发
+func (w *NATSWriter) requeuePending() {
+	w.mu.Lock()
+	seqs := make([]uint64, 0, len(w.pending))
+	for seq := range w.pending {
+		seqs = append(seqs, seq)
+	}
+	w.mu.Unlock()
+	if len(seqs) == 0 {
+		return
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	batch := make([][]byte, 0, len(seqs))
+	w.mu.Lock()
+	for _, seq := range seqs {
+		batch = append(batch, w.pending[seq])
+		delete(w.pending, seq)
+	}
+	w.mu.Unlock()
+	w.requeue(batch)
+}
+
+// 读取服务端消息：回复PING，处理JetStream确认，连接断开时关闭broken
+func (w *NATSWriter) read(r *bufio.Reader, write func(string), broken chan struct{}) {
+	defer close(broken)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			write("PONG\r\n")
+		case strings.HasPrefix(line, "-ERR"):
+			fmt.Fprintf(os.Stderr, "nats: %s\n", line)
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply] <size>
+			args := strings.Fields(line)
+			size, err := strconv.Atoi(args[len(args)-1])
+			if err != nil {
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			w.ack(args[1], payload[:size])
+		}
+	}
+}
+
+// 处理JetStream的确认，确认中有错误时输出到标准错误，不再重发
+func (w *NATSWriter) ack(subject string, payload []byte) {
+	seq, err := strconv.ParseUint(strings.TrimPrefix(subject, w.inbox+"."), 10, 64)
+	if err != nil {
+		return
+	}
+	var ack struct {
+		Error *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(payload, &ack) == nil && ack.Error != nil {
+		fmt.Fprintf(os.Stderr, "nats: jetstream publish: %s\n", ack.Error.Description)
+	}
+	w.mu.Lock()
+	delete(w.pending, seq)
+	w.mu.Unlock()
+}
@@ -0,0 +1,87 @@
+package logx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 只处理CONNECT、PING和PUB的NATS服务端，JetStream时回复确认
+func fakeNATS(t *testing.T, ln net.Listener, published chan<- string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			io.WriteString(conn, "INFO {\"server_id\":\"test\"}\r\n")
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				args := strings.Fields(line)
+				switch args[0] {
+				case "PING":
+					io.WriteString(conn, "PONG\r\n")
+				case "PUB":
+					size, _ := strconv.Atoi(args[len(args)-1])
+					payload := make([]byte, size+2)
+					io.ReadFull(r, payload)
+					published <- string(payload[:size])
+					if len(args) == 4 {
+						ack := `{"stream":"LOGS","seq":1}`
+						fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", args[2], len(ack), ack)
+					}
+				}
+			}
+		}(conn)
+	}
+}
+
+func TestNATSWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	published := make(chan string, 10)
+	go fakeNATS(t, ln, published)
+
+	for _, js := range []bool{false, true} {
+		w, err := newAppenderWriter(Appender{Type: "nats", Options: map[string]interface{}{
+			"url": "nats://" + ln.Addr().String(), "subject": "logs.app", "jetstream": js,
+		}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = w.Write([]byte("{\"msg\":\"hello\"}\n"))
+		assert.NoError(t, err)
+		select {
+		case msg := <-published:
+			assert.Equal(t, `{"msg":"hello"}`, msg)
+		case <-time.After(2 * time.Second):
+			t.Fatal("message not published")
+		}
+		assert.NoError(t, w.Close())
+		assert.Empty(t, w.(*NATSWriter).pending)
+	}
+
+	// 缓存超过上限时返回错误
+	w, err := NewNATSWriter(NATSConfig{URL: "nats://127.0.0.1:1", Subject: "logs", ReconnectBuffer: "1kb"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	_, err = w.Write(make([]byte, 2048))
+	assert.Equal(t, ErrNATSBufferFull, err)
+}
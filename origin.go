@@ -0,0 +1,77 @@
+package logx
+
+import (
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 栈信息中第一个用户代码栈帧的字段名，值包含file（目录/文件:行号）和function
+const OriginKey = "error.origin"
+
+// 查找用户代码栈帧时跳过的函数前缀，测试文件中的函数总是视为用户代码
+var OriginSkipPrefixes = []string{"runtime.", "go.uber.org/zap", "github.com/Muskchen/logx."}
+
+func init() {
+	RegisterProcessor("origin", originProcessor)
+}
+
+// 解析栈信息，将第一个用户代码栈帧添加为error.origin字段，便于在日志系统中按出错位置聚合
+// 没有栈信息或找不到用户代码栈帧时不添加
+func originProcessor(e *Entry) {
+	if e.Stack == "" {
+		return
+	}
+	if fn, file, line, ok := originFrame(e.Stack); ok {
+		e.Add(zap.Object(OriginKey, origin{function: fn, caller: zapcore.EntryCaller{Defined: true, File: file, Line: line}}))
+	}
+}
+
+// 栈信息每个栈帧占两行，第一行为函数名，第二行为制表符开头的文件:行号
+func originFrame(stack string) (fn, file string, line int, ok bool) {
+	lines := strings.Split(stack, "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		fn = lines[i]
+		loc := strings.TrimSpace(lines[i+1])
+		// 可能带有+0x偏移
+		if j := strings.LastIndexByte(loc, ' '); j >= 0 {
+			loc = loc[:j]
+		}
+		j := strings.LastIndexByte(loc, ':')
+		if j < 0 {
+			continue
+		}
+		file = loc[:j]
+		if skipOrigin(fn) && !strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		n, err := strconv.Atoi(loc[j+1:])
+		if err != nil {
+			continue
+		}
+		return fn, file, n, true
+	}
+	return "", "", 0, false
+}
+
+func skipOrigin(fn string) bool {
+	for _, prefix := range OriginSkipPrefixes {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type origin struct {
+	function string
+	caller   zapcore.EntryCaller
+}
+
+func (o origin) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", o.caller.TrimmedPath())
+	enc.AddString("function", o.function)
+	return nil
+}
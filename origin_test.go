@@ -0,0 +1,36 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestOriginFrame(t *testing.T) {
+	stack := "github.com/Muskchen/logx.Error\n\t/src/logx/logx.go:148\n" +
+		"example.com/app/order.(*Service).Pay\n\t/src/app/order/service.go:42 +0x1d\n" +
+		"main.main\n\t/src/app/main.go:10"
+	fn, file, line, ok := originFrame(stack)
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/app/order.(*Service).Pay", fn)
+	assert.Equal(t, "/src/app/order/service.go", file)
+	assert.Equal(t, 42, line)
+
+	_, _, _, ok = originFrame("runtime.goexit\n\t/usr/go/src/runtime/asm_amd64.s:1374")
+	assert.False(t, ok)
+}
+
+func TestOriginProcessor(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(&processorCore{Core: core, processors: lookupProcessors([]string{"origin"})}, zap.AddStacktrace(zapcore.ErrorLevel))
+	l.Info("no stack")
+	l.Error("failed")
+
+	assert.Nil(t, logs.All()[0].ContextMap()[OriginKey])
+	origin := logs.All()[1].ContextMap()[OriginKey].(map[string]interface{})
+	assert.Equal(t, "github.com/Muskchen/logx.TestOriginProcessor", origin["function"])
+	assert.Contains(t, origin["file"], "origin_test.go:")
+}
@@ -0,0 +1,70 @@
+package logx
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// CapturePanic和Go的选项
+type PanicOption func(*panicOptions)
+
+type panicOptions struct {
+	repanic bool
+	fields  []zap.Field
+}
+
+// 记录日志后重新panic，通常用于无法继续运行的情况，进程仍按原有方式崩溃
+func Repanic() PanicOption {
+	return func(o *panicOptions) {
+		o.repanic = true
+	}
+}
+
+// panic日志携带的字段，如任务名称
+func PanicFields(fields ...zap.Field) PanicOption {
+	return func(o *panicOptions) {
+		o.fields = append(o.fields, fields...)
+	}
+}
+
+// 捕获当前协程的panic，以error级别记录panic值和栈到所有appender，配置了CrashDir时写入崩溃文件
+// 默认不再panic，需要通过defer直接调用
+//
+//	defer logx.CapturePanic(logx.PanicFields(zap.String("job", name)))
+func CapturePanic(opts ...PanicOption) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	var o panicOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	logPanic(r, o)
+}
+
+// 启动协程执行fn，fn中的panic按CapturePanic记录，不会导致进程崩溃，除非使用Repanic
+func Go(fn func(), opts ...PanicOption) {
+	go func() {
+		defer CapturePanic(opts...)
+		fn()
+	}()
+}
+
+// 记录panic日志，重新panic前同步日志，保证进程退出前日志已写入
+func logPanic(r interface{}, o panicOptions) {
+	name, err := writeCrash(fmt.Sprintf("panic: %v", r))
+	fields := append(o.fields[:len(o.fields):len(o.fields)], zap.Any("panic", r), zap.Stack("stacktrace"))
+	if name != "" {
+		fields = append(fields, zap.String("crash_file", name))
+	}
+	if err != nil {
+		fields = append(fields, zap.NamedError("crash_error", err))
+	}
+	logger.Error("panic recovered", fields...)
+	if o.repanic {
+		_ = logger.Sync()
+		panic(r)
+	}
+}
@@ -0,0 +1,56 @@
+package logx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func observePanics(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	old := logger
+	setLogger(zap.New(core))
+	t.Cleanup(func() { setLogger(old) })
+	return logs
+}
+
+func TestCapturePanic(t *testing.T) {
+	logs := observePanics(t)
+	func() {
+		defer CapturePanic(PanicFields(zap.String("job", "sync")))
+		panic("boom")
+	}()
+	assert.Panics(t, func() {
+		defer CapturePanic(Repanic())
+		panic(errors.New("fatal"))
+	})
+
+	entries := logs.All()
+	if assert.Equal(t, 2, len(entries)) {
+		assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "boom", fields["panic"])
+		assert.Equal(t, "sync", fields["job"])
+		assert.True(t, strings.Contains(fields["stacktrace"].(string), "TestCapturePanic"))
+		assert.Equal(t, "fatal", entries[1].ContextMap()["panic"])
+	}
+}
+
+func TestGo(t *testing.T) {
+	logs := observePanics(t)
+	done := make(chan struct{})
+	Go(func() {
+		defer close(done)
+		panic("in goroutine")
+	})
+	<-done
+	// close在CapturePanic之前执行，等待日志写入
+	assert.Eventually(t, func() bool { return logs.Len() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, "in goroutine", logs.All()[0].ContextMap()["panic"])
+}
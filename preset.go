@@ -0,0 +1,59 @@
+package logx
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Muskchen/logx/rollingwriter"
+	"go.uber.org/zap"
+)
+
+// 日志采样配置
+type SamplingConfig struct {
+	Initial    int `json:"initial" yaml:"initial"`       // 每秒内相同级别和消息的日志完整记录的条数
+	Thereafter int `json:"thereafter" yaml:"thereafter"` // 超过Initial条后每Thereafter条记录一条
+	// 采样的日志级别，如[debug]只采样debug日志，warn及以上的日志总是记录，为空时所有级别都采样
+	Levels []string `json:"levels" yaml:"levels"`
+}
+
+// 开发环境的默认配置：console格式、终端彩色显示、debug级别、输出到标准输出
+func NewDevelopmentConfig() *Config {
+	return &Config{
+		Format:      "2006-01-02 15:04:05.000",
+		Type:        "console",
+		Color:       "auto",
+		Stacktrace:  true,
+		Development: true,
+		Appenders:   []Appender{{Type: "stdout", Level: "debug"}},
+	}
+}
+
+// 生产环境的默认配置：json格式、info级别、每天滚动、保留30个历史文件、日志采样
+// path为日志文件路径，如./log/app.log
+func NewProductionConfig(path string) *Config {
+	rolling := rollingwriter.NewDefaultConfig()
+	rolling.LogPath = filepath.Dir(path)
+	rolling.FileName = strings.TrimSuffix(filepath.Base(path), ".log")
+	rolling.MaxRemain = 30
+	rolling.Compress = true
+	rolling.WriterMode = "async"
+	return &Config{
+		Format:     "2006-01-02T15:04:05.000Z07:00",
+		Type:       "json",
+		Stacktrace: true,
+		Sampling:   &SamplingConfig{Initial: 100, Thereafter: 100},
+		Appenders:  []Appender{{Level: "info", Rolling: &rolling}},
+	}
+}
+
+// 使用开发环境的默认配置初始化并返回logger
+func NewDevelopment(opts ...zap.Option) *zap.Logger {
+	Init(NewDevelopmentConfig(), opts...)
+	return GetLogger()
+}
+
+// 使用生产环境的默认配置初始化并返回写入path的logger
+func NewProduction(path string, opts ...zap.Option) *zap.Logger {
+	Init(NewProductionConfig(path), opts...)
+	return GetLogger()
+}
@@ -0,0 +1,23 @@
+package logx
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestPresets(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	cfg := NewProductionConfig("/var/log/app/app.log")
+	rolling := cfg.Appenders[0].Rolling
+	assert.Equal(t, "/var/log/app", rolling.LogPath)
+	assert.Equal(t, "app", rolling.FileName)
+	assert.Equal(t, "json", cfg.Type)
+	assert.NotNil(t, cfg.Sampling)
+
+	l := NewDevelopment()
+	assert.True(t, l.Core().Enabled(zap.DebugLevel))
+	assert.Equal(t, os.Stdout, currentAppenders()[0].writer)
+}
@@ -0,0 +1,131 @@
+package logx
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 处理器处理的日志，修改后的内容用于编码写入
+// 通过With添加的字段已完成编码，不在Fields中
+type Entry struct {
+	zapcore.Entry
+	Fields []zapcore.Field
+}
+
+// 日志处理器，在编码前修改日志，如注入字段、重命名字段、转换字段值
+type Processor func(*Entry)
+
+var (
+	processorMu sync.Mutex
+	processors  atomic.Value // []Processor，所有appender共用
+	named       = make(map[string]Processor)
+)
+
+// 添加所有appender共用的处理器，按添加顺序执行，无需重新Init
+func AddProcessor(p Processor) {
+	processorMu.Lock()
+	defer processorMu.Unlock()
+	current, _ := processors.Load().([]Processor)
+	next := make([]Processor, len(current), len(current)+1)
+	copy(next, current)
+	processors.Store(append(next, p))
+}
+
+// 删除所有共用的处理器
+func ResetProcessors() {
+	processorMu.Lock()
+	defer processorMu.Unlock()
+	processors.Store([]Processor(nil))
+}
+
+// 注册命名的处理器，注册后可以在appender的processors中使用，p为nil时删除
+func RegisterProcessor(name string, p Processor) {
+	processorMu.Lock()
+	defer processorMu.Unlock()
+	if p == nil {
+		delete(named, name)
+		return
+	}
+	named[name] = p
+}
+
+// 按名称查找处理器，未注册的名称忽略
+func lookupProcessors(names []string) []Processor {
+	processorMu.Lock()
+	defer processorMu.Unlock()
+	var ps []Processor
+	for _, name := range names {
+		if p, ok := named[name]; ok {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// 添加字段
+func (e *Entry) Add(fields ...zapcore.Field) {
+	e.Fields = append(e.Fields, fields...)
+}
+
+// 查找字段，不存在时返回nil
+func (e *Entry) Field(key string) *zapcore.Field {
+	for i := range e.Fields {
+		if e.Fields[i].Key == key {
+			return &e.Fields[i]
+		}
+	}
+	return nil
+}
+
+// 重命名字段
+func (e *Entry) Rename(old, new string) {
+	if f := e.Field(old); f != nil {
+		f.Key = new
+	}
+}
+
+// 删除字段
+func (e *Entry) Delete(key string) {
+	fields := e.Fields[:0]
+	for _, f := range e.Fields {
+		if f.Key != key {
+			fields = append(fields, f)
+		}
+	}
+	e.Fields = fields
+}
+
+// 写入前执行共用和appender的处理器
+type processorCore struct {
+	zapcore.Core
+	processors []Processor
+}
+
+func (c *processorCore) With(fields []zapcore.Field) zapcore.Core {
+	return &processorCore{Core: c.Core.With(fields), processors: c.processors}
+}
+
+func (c *processorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *processorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	shared, _ := processors.Load().([]Processor)
+	if len(shared) == 0 && len(c.processors) == 0 {
+		return c.Core.Write(ent, fields)
+	}
+	// 复制字段，避免影响其他appender
+	e := &Entry{Entry: ent, Fields: append([]zapcore.Field(nil), fields...)}
+	for _, p := range shared {
+		p(e)
+	}
+	for _, p := range c.processors {
+		p(e)
+	}
+	return c.Core.Write(e.Entry, e.Fields)
+}
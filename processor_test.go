@@ -0,0 +1,35 @@
+package logx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestProcessors(t *testing.T) {
+	defer ResetProcessors()
+	defer RegisterProcessor("upper", nil)
+	AddProcessor(func(e *Entry) { e.Add(zap.String("git_sha", "abc123")) })
+	RegisterProcessor("upper", func(e *Entry) {
+		e.Rename("usr", "user")
+		if f := e.Field("user"); f != nil {
+			f.String = strings.ToUpper(f.String)
+		}
+		e.Delete("password")
+	})
+
+	plain, plainLogs := observer.New(zapcore.InfoLevel)
+	custom, customLogs := observer.New(zapcore.InfoLevel)
+	l := zap.New(zapcore.NewTee(
+		&processorCore{Core: plain},
+		&processorCore{Core: custom, processors: lookupProcessors([]string{"upper", "missing"})},
+	))
+	l.Info("login", zap.String("usr", "bob"), zap.String("password", "secret"))
+
+	assert.Equal(t, map[string]interface{}{"git_sha": "abc123", "usr": "bob", "password": "secret"}, plainLogs.All()[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{"git_sha": "abc123", "user": "BOB"}, customLogs.All()[0].ContextMap())
+}
@@ -0,0 +1,152 @@
+package logx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// protobuf格式的encoder，每条日志为proto/record.proto中的Record，前面是varint编码的长度
+// Record的字段名固定，EncoderConfig中的key只决定是否输出对应的字段，trace_id和span_id字段写入Record的同名字段
+type protobufEncoder struct {
+	*zapcore.MapObjectEncoder
+	config zapcore.EncoderConfig
+}
+
+func newProtobufEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return &protobufEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), config: config}
+}
+
+func (enc *protobufEncoder) Clone() zapcore.Encoder {
+	clone := zapcore.NewMapObjectEncoder()
+	for k, v := range enc.Fields {
+		clone.Fields[k] = v
+	}
+	return &protobufEncoder{MapObjectEncoder: clone, config: enc.config}
+}
+
+func (enc *protobufEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.Clone().(*protobufEncoder)
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	c := enc.config
+	var b []byte
+	if c.TimeKey != "" {
+		b = appendProtoVarint(b, 1, uint64(ent.Time.UnixNano()))
+	}
+	if c.LevelKey != "" {
+		b = appendProtoBytes(b, 2, ent.Level.CapitalString())
+	}
+	if c.NameKey != "" && ent.LoggerName != "" {
+		b = appendProtoBytes(b, 3, ent.LoggerName)
+	}
+	if c.CallerKey != "" && ent.Caller.Defined {
+		b = appendProtoBytes(b, 4, ent.Caller.TrimmedPath())
+	}
+	if c.MessageKey != "" {
+		b = appendProtoBytes(b, 5, ent.Message)
+	}
+	if c.StacktraceKey != "" && ent.Stack != "" {
+		b = appendProtoBytes(b, 6, ent.Stack)
+	}
+	for i, key := range []string{"trace_id", "span_id"} {
+		if id, ok := final.Fields[key].(string); ok {
+			b = appendProtoBytes(b, 7+i, id)
+			delete(final.Fields, key)
+		}
+	}
+	for k, v := range final.Fields {
+		// map的每一项为{1: key, 2: value}
+		entry := appendProtoBytes(nil, 1, k)
+		entry = appendProtoBytes(entry, 2, string(appendProtoValue(nil, v)))
+		b = appendProtoBytes(b, 9, string(entry))
+	}
+	buf := _msgpackPool.Get()
+	buf.Write(appendVarint(nil, uint64(len(b))))
+	buf.Write(b)
+	return buf, nil
+}
+
+// 按Value消息编码字段值
+func appendProtoValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendProtoBytes(b, 1, v)
+	case int:
+		return appendProtoVarint(b, 2, uint64(v))
+	case int8:
+		return appendProtoVarint(b, 2, uint64(v))
+	case int16:
+		return appendProtoVarint(b, 2, uint64(v))
+	case int32:
+		return appendProtoVarint(b, 2, uint64(v))
+	case int64:
+		return appendProtoVarint(b, 2, uint64(v))
+	case time.Duration:
+		return appendProtoVarint(b, 2, uint64(v))
+	case uint:
+		return appendProtoVarint(b, 3, uint64(v))
+	case uint8:
+		return appendProtoVarint(b, 3, uint64(v))
+	case uint16:
+		return appendProtoVarint(b, 3, uint64(v))
+	case uint32:
+		return appendProtoVarint(b, 3, uint64(v))
+	case uint64:
+		return appendProtoVarint(b, 3, v)
+	case uintptr:
+		return appendProtoVarint(b, 3, uint64(v))
+	case float32:
+		return appendProtoFixed64(b, 4, math.Float64bits(float64(v)))
+	case float64:
+		return appendProtoFixed64(b, 4, math.Float64bits(v))
+	case bool:
+		if v {
+			return appendProtoVarint(b, 5, 1)
+		}
+		return appendProtoVarint(b, 5, 0)
+	case []byte:
+		return appendProtoBytes(b, 6, string(v))
+	case time.Time:
+		return appendProtoVarint(b, 8, uint64(v.UnixNano()))
+	case complex64, complex128:
+		return appendProtoBytes(b, 1, fmt.Sprint(v))
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return appendProtoBytes(b, 1, fmt.Sprint(v))
+	}
+	return appendProtoBytes(b, 7, string(buf))
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3)
+	return appendVarint(b, v)
+}
+
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3|1)
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+func appendProtoBytes(b []byte, field int, s string) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
@@ -0,0 +1,52 @@
+package logx
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Muskchen/logx/reader"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestProtobufEncoder(t *testing.T) {
+	enc := encoder("protobuf", newEncoderConfig(""))
+	enc.AddString("trace_id", "abc")
+	now := time.Unix(1600000000, 123)
+	var data bytes.Buffer
+	for i := 0; i < 2; i++ {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Time: now, Message: "failed", LoggerName: "db"}, []zapcore.Field{
+			zap.Int("n", -3), zap.Uint("u", 7), zap.Float64("f", 0.5), zap.Bool("ok", false), zap.Duration("d", time.Millisecond),
+			zap.Time("at", now), zap.Strings("tags", []string{"a"}), zap.Binary("raw", []byte{1}),
+		})
+		assert.NoError(t, err)
+		data.Write(buf.Bytes())
+		buf.Free()
+	}
+
+	r := reader.NewProtoReader(&data)
+	for i := 0; i < 2; i++ {
+		rec, err := r.Next()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, now.Equal(rec["ts"].(time.Time)))
+		assert.Equal(t, "ERROR", rec["level"])
+		assert.Equal(t, "failed", rec["msg"])
+		assert.Equal(t, "abc", rec["trace_id"])
+		assert.NotContains(t, rec, "logger") // 默认配置没有NameKey
+		assert.Equal(t, int64(-3), rec["n"])
+		assert.Equal(t, uint64(7), rec["u"])
+		assert.Equal(t, 0.5, rec["f"])
+		assert.Equal(t, false, rec["ok"])
+		assert.Equal(t, int64(time.Millisecond), rec["d"])
+		assert.True(t, now.Equal(rec["at"].(time.Time)))
+		assert.Equal(t, []interface{}{"a"}, rec["tags"])
+		assert.Equal(t, []byte{1}, rec["raw"])
+	}
+	_, err := r.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,309 @@
+// query 提供对当前日志文件和历史日志文件的查询，支持按级别、时间范围和字段过滤json日志
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// 日志过滤条件，条件为空时不过滤
+type Filter struct {
+	Levels   []string          // 日志级别，不区分大小写
+	Since    time.Time         // 起始时间（包含）
+	Until    time.Time         // 结束时间（不包含）
+	Fields   map[string]string // 字段值需要完全相等
+	Contains string            // 日志消息包含的子字符串
+
+	TimeKey    string // 时间字段名，默认为ts
+	LevelKey   string // 级别字段名，默认为level
+	MessageKey string // 消息字段名，默认为msg
+	TimeFormat string // 时间字段的格式，为空时依次尝试RFC3339和"2006-01-02 15:04:05"
+	DirLayout  string // 历史日志文件按日期分目录时的目录格式，与writer的DirLayout相同
+}
+
+// 查询到的一条日志
+type Entry struct {
+	File    string                 // 日志所在的文件
+	Raw     []byte                 // 原始日志，不包含换行符
+	Fields  map[string]interface{} // 解析后的json字段，非json日志为nil
+	Time    time.Time              // 日志时间
+	Level   string                 // 日志级别
+	Message string                 // 日志消息
+}
+
+// 压缩文件的解压函数
+type Decoder func(r io.Reader) (io.Reader, error)
+
+var (
+	decodersMu sync.RWMutex
+	// 按文件头魔数识别的解压函数，默认支持gzip
+	decoders = map[string]Decoder{
+		"\x1f\x8b": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+)
+
+// 注册压缩文件的解压函数，magic为文件头魔数，例如zstd为"\x28\xb5\x2f\xfd"
+func RegisterDecoder(magic string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[magic] = decoder
+}
+
+// 日志迭代器，按历史日志文件从旧到新、最后是当前日志文件的顺序返回日志
+type Iterator struct {
+	filter Filter
+	files  []string
+	levels map[string]bool
+	file   io.ReadCloser
+	reader *bufio.Reader
+	name   string
+	entry  Entry
+	err    error
+}
+
+// 查询path对应的当前日志文件及其所有历史日志文件（包括压缩文件）
+func Query(path string, filter Filter) (*Iterator, error) {
+	files, err := logFiles(path, filter.DirLayout)
+	if err != nil {
+		return nil, err
+	}
+	if filter.TimeKey == "" {
+		filter.TimeKey = "ts"
+	}
+	if filter.LevelKey == "" {
+		filter.LevelKey = "level"
+	}
+	if filter.MessageKey == "" {
+		filter.MessageKey = "msg"
+	}
+	it := &Iterator{filter: filter, files: files}
+	if len(filter.Levels) > 0 {
+		it.levels = make(map[string]bool, len(filter.Levels))
+		for _, l := range filter.Levels {
+			it.levels[strings.ToUpper(l)] = true
+		}
+	}
+	return it, nil
+}
+
+// 打开日志文件，按文件头魔数选择解压函数，返回解压后的内容
+func Open(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(file)
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	for magic, decoder := range decoders {
+		if head, _ := br.Peek(len(magic)); string(head) == magic {
+			r, err := decoder(br)
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("decode %s: %v", name, err)
+			}
+			return readCloser{Reader: r, Closer: file}, nil
+		}
+	}
+	return readCloser{Reader: br, Closer: file}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// 查找当前日志文件和历史日志文件，历史日志文件按修改时间排序
+// layout不为空时同时查找日志目录下按layout命名的日期目录
+func logFiles(path, layout string) ([]string, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type archive struct {
+		name    string
+		modTime time.Time
+	}
+	var archives []archive
+	add := func(dir string, infos []os.FileInfo) {
+		for _, fi := range infos {
+			// 跳过滚动索引、滚动状态、清理记录、不完整日志、压缩临时文件、溢出文件和校验文件
+			if fi.IsDir() || !strings.HasPrefix(fi.Name(), base+".") || rollingwriter.IsArtifact(fi.Name()) {
+				continue
+			}
+			archives = append(archives, archive{filepath.Join(dir, fi.Name()), fi.ModTime()})
+		}
+	}
+	add(dir, infos)
+	if layout != "" {
+		for _, fi := range infos {
+			if !fi.IsDir() {
+				continue
+			}
+			if _, err := time.Parse(layout, fi.Name()); err != nil {
+				continue
+			}
+			sub := filepath.Join(dir, fi.Name())
+			subInfos, err := ioutil.ReadDir(sub)
+			if err != nil {
+				return nil, err
+			}
+			add(sub, subInfos)
+		}
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		return archives[i].modTime.Before(archives[j].modTime)
+	})
+	files := make([]string, 0, len(archives)+1)
+	for _, a := range archives {
+		files = append(files, a.name)
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// 读取下一条符合条件的日志，没有更多日志或出错时返回false
+func (it *Iterator) Next() bool {
+	for it.err == nil {
+		if it.reader == nil {
+			if len(it.files) == 0 {
+				return false
+			}
+			it.err = it.open(it.files[0])
+			it.files = it.files[1:]
+			continue
+		}
+		line, err := it.reader.ReadBytes('\n')
+		if len(line) > 0 && it.match(bytes.TrimRight(line, "\r\n")) {
+			return true
+		}
+		if err != nil {
+			it.closeFile()
+			if err != io.EOF {
+				it.err = err
+			}
+		}
+	}
+	return false
+}
+
+// 当前日志
+func (it *Iterator) Entry() Entry {
+	return it.entry
+}
+
+// 迭代过程中的错误
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// 关闭正在读取的文件
+func (it *Iterator) Close() error {
+	it.files = nil
+	return it.closeFile()
+}
+
+// 打开日志文件
+func (it *Iterator) open(name string) error {
+	rc, err := Open(name)
+	if err != nil {
+		// 读取期间被删除的历史日志文件
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	it.file, it.name = rc, name
+	it.reader = bufio.NewReader(rc)
+	return nil
+}
+
+func (it *Iterator) closeFile() error {
+	it.reader = nil
+	if it.file == nil {
+		return nil
+	}
+	err := it.file.Close()
+	it.file = nil
+	return err
+}
+
+// 解析日志并判断是否符合过滤条件
+func (it *Iterator) match(raw []byte) bool {
+	f := &it.filter
+	entry := Entry{File: it.name, Raw: append([]byte(nil), raw...)}
+	if err := json.Unmarshal(raw, &entry.Fields); err != nil {
+		entry.Fields = nil
+	}
+	structured := it.levels != nil || !f.Since.IsZero() || !f.Until.IsZero() || len(f.Fields) > 0
+	if entry.Fields == nil {
+		// 非json日志只在没有字段条件时返回
+		if structured || (f.Contains != "" && !bytes.Contains(raw, []byte(f.Contains))) {
+			return false
+		}
+		it.entry = entry
+		return true
+	}
+
+	entry.Level, _ = entry.Fields[f.LevelKey].(string)
+	entry.Message, _ = entry.Fields[f.MessageKey].(string)
+	entry.Time = it.parseTime(entry.Fields[f.TimeKey])
+	if it.levels != nil && !it.levels[strings.ToUpper(entry.Level)] {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	for k, v := range f.Fields {
+		if fv, ok := entry.Fields[k]; !ok || fmt.Sprint(fv) != v {
+			return false
+		}
+	}
+	if f.Contains != "" && !strings.Contains(entry.Message, f.Contains) {
+		return false
+	}
+	it.entry = entry
+	return true
+}
+
+// 解析时间字段，支持格式化的时间字符串和以秒为单位的时间戳
+func (it *Iterator) parseTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case string:
+		formats := []string{time.RFC3339Nano, "2006-01-02 15:04:05"}
+		if it.filter.TimeFormat != "" {
+			formats = []string{it.filter.TimeFormat}
+		}
+		for _, format := range formats {
+			if ts, err := time.ParseInLocation(format, t, time.Local); err == nil {
+				return ts
+			}
+		}
+	case float64:
+		sec := int64(t)
+		return time.Unix(sec, int64((t-float64(sec))*1e9))
+	}
+	return time.Time{}
+}
@@ -0,0 +1,85 @@
+package query
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "app.log")
+
+	// 压缩的历史日志文件
+	gz, _ := os.Create(filepath.Join(dir, "app.log.gz.202401010000"))
+	zw := gzip.NewWriter(gz)
+	zw.Write([]byte(`{"level":"ERROR","ts":"2024-01-01 00:00:01","msg":"old error","component":"db"}` + "\n"))
+	zw.Close()
+	gz.Close()
+	os.Chtimes(gz.Name(), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+
+	ioutil.WriteFile(active, []byte(`{"level":"INFO","ts":"2024-01-02 00:00:01","msg":"hello"}
+{"level":"ERROR","ts":"2024-01-02 00:00:02","msg":"new error","component":"db"}
+not json
+`), 0644)
+	ioutil.WriteFile(active+".index", []byte(`{"level":"ERROR"}`+"\n"), 0644)
+
+	collect := func(f Filter) []string {
+		it, err := Query(active, f)
+		assert.Nil(t, err)
+		defer it.Close()
+		var msgs []string
+		for it.Next() {
+			msgs = append(msgs, string(it.Entry().Raw))
+		}
+		assert.Nil(t, it.Err())
+		return msgs
+	}
+
+	assert.Equal(t, 4, len(collect(Filter{})))
+
+	msgs := collect(Filter{Levels: []string{"error"}, Fields: map[string]string{"component": "db"}})
+	assert.Equal(t, 2, len(msgs))
+
+	since, _ := time.ParseInLocation("2006-01-02", "2024-01-02", time.Local)
+	it, _ := Query(active, Filter{Levels: []string{"error"}, Since: since})
+	assert.True(t, it.Next())
+	assert.Equal(t, "new error", it.Entry().Message)
+	assert.Equal(t, active, it.Entry().File)
+	assert.False(t, it.Next())
+	it.Close()
+}
+
+func TestQueryArchives(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "app.log")
+	day := filepath.Join(dir, "2024-01-01")
+	os.Mkdir(day, 0755)
+	old := filepath.Join(day, "app.log.202401010000")
+	ioutil.WriteFile(old, []byte(`{"msg":"old"}`+"\n"), 0644)
+	os.Chtimes(old, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	ioutil.WriteFile(active, []byte(`{"msg":"new"}`+"\n"), 0644)
+	// 附属文件不是日志
+	for _, suffix := range []string{".spill", ".sha256", ".partial"} {
+		ioutil.WriteFile(active+suffix, []byte(`{"msg":"artifact"}`+"\n"), 0644)
+	}
+
+	collect := func(f Filter) []string {
+		it, err := Query(active, f)
+		assert.Nil(t, err)
+		defer it.Close()
+		var msgs []string
+		for it.Next() {
+			msgs = append(msgs, it.Entry().Message)
+		}
+		assert.Nil(t, it.Err())
+		return msgs
+	}
+	assert.Equal(t, []string{"new"}, collect(Filter{}))
+	assert.Equal(t, []string{"old", "new"}, collect(Filter{DirLayout: "2006-01-02"}))
+}
@@ -0,0 +1,137 @@
+package reader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+)
+
+// Record中固定字段的名称，与proto/record.proto中的字段编号对应
+var protoKeys = map[uint64]string{2: "level", 3: "logger", 4: "caller", 5: "msg", 6: "stacktrace", 7: "trace_id", 8: "span_id"}
+
+// 顺序读取protobuf格式的日志，每条日志为varint长度前缀加proto/record.proto中的Record
+// 返回的Record中时间为ts，其他固定字段使用proto中的字段名，fields中的字段与其平级
+type ProtoReader struct {
+	r *bufio.Reader
+}
+
+func NewProtoReader(r io.Reader) *ProtoReader {
+	return &ProtoReader{r: bufio.NewReader(r)}
+}
+
+// 读取下一条日志，没有更多日志时返回io.EOF，最后一条日志不完整时返回io.ErrUnexpectedEOF
+func (r *ProtoReader) Next() (Record, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(MaxRecordSize) {
+		return nil, ErrRecordTooLarge
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	rec := Record{}
+	err = eachField(buf, func(field, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			rec["ts"] = time.Unix(0, int64(v))
+		case 9:
+			var key string
+			var value interface{}
+			err := eachField(b, func(field, _ uint64, b []byte) (err error) {
+				if field == 1 {
+					key = string(b)
+				} else if field == 2 {
+					value, err = protoValue(b)
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			rec[key] = value
+		default:
+			if key, ok := protoKeys[field]; ok {
+				rec[key] = string(b)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// 解码Value消息
+func protoValue(buf []byte) (value interface{}, err error) {
+	err = eachField(buf, func(field, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			value = string(b)
+		case 2:
+			value = int64(v)
+		case 3:
+			value = v
+		case 4:
+			value = math.Float64frombits(v)
+		case 5:
+			value = v != 0
+		case 6:
+			value = append([]byte(nil), b...)
+		case 7:
+			return json.Unmarshal(b, &value)
+		case 8:
+			value = time.Unix(0, int64(v))
+		}
+		return nil
+	})
+	return value, err
+}
+
+// 依次处理消息中的字段，varint和fixed64字段的值为v，length-delimited字段的值为b
+func eachField(buf []byte, fn func(field, v uint64, b []byte) error) error {
+	for len(buf) > 0 {
+		tag, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return ErrInvalidRecord
+		}
+		buf = buf[n:]
+		var v uint64
+		var b []byte
+		switch tag & 7 {
+		case 0:
+			if v, n = binary.Uvarint(buf); n <= 0 {
+				return ErrInvalidRecord
+			}
+			buf = buf[n:]
+		case 1:
+			if len(buf) < 8 {
+				return ErrInvalidRecord
+			}
+			v = binary.LittleEndian.Uint64(buf)
+			buf = buf[8:]
+		case 2:
+			l, n := binary.Uvarint(buf)
+			if n <= 0 || l > uint64(len(buf)-n) {
+				return ErrInvalidRecord
+			}
+			b = buf[n : n+int(l)]
+			buf = buf[n+int(l):]
+		default:
+			return ErrInvalidRecord
+		}
+		if err := fn(tag>>3, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
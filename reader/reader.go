@@ -0,0 +1,233 @@
+// reader 读取logx msgpack和protobuf格式的日志文件，每条日志为长度前缀加一个编码后的记录
+package reader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var (
+	ErrRecordTooLarge = errors.New("error record too large")
+	ErrInvalidRecord  = errors.New("error invalid record")
+)
+
+// 单条日志的最大长度，超过时认为文件已损坏
+var MaxRecordSize = 64 << 20
+
+// 一条日志，时间字段为time.Time，整数为int64，超过int64范围的无符号整数为uint64，浮点数为float64
+type Record map[string]interface{}
+
+// 顺序读取msgpack格式的日志
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// 读取下一条日志，没有更多日志时返回io.EOF，最后一条日志不完整时返回io.ErrUnexpectedEOF
+func (r *Reader) Next() (Record, error) {
+	var head [4]byte
+	if _, err := io.ReadFull(r.r, head[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(head[:])
+	if uint64(n) > uint64(MaxRecordSize) {
+		return nil, ErrRecordTooLarge
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	d := decoder{buf: buf}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok || d.off != len(buf) {
+		return nil, ErrInvalidRecord
+	}
+	return Record(m), nil
+}
+
+// 解码一条日志的msgpack数据
+type decoder struct {
+	buf []byte
+	off int
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || d.off+n > len(d.buf) {
+		return nil, ErrInvalidRecord
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+// 读取n字节的大端序无符号整数
+func (d *decoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *decoder) value() (interface{}, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapValue(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xc7:
+		return d.ext()
+	case 0xca:
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.uint(1 << (c - 0xcc))
+		if v <= math.MaxInt64 {
+			return int64(v), err
+		}
+		return v, err
+	case 0xd0:
+		v, err := d.uint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.uint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.uint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.uint(8)
+		return int64(v), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n))
+	}
+	return nil, fmt.Errorf("%w: unsupported type 0x%x", ErrInvalidRecord, c)
+}
+
+func (d *decoder) str(n int) (string, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *decoder) array(n int) ([]interface{}, error) {
+	if n > len(d.buf)-d.off {
+		return nil, ErrInvalidRecord
+	}
+	a := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+func (d *decoder) mapValue(n int) (map[string]interface{}, error) {
+	if n > len(d.buf)-d.off {
+		return nil, ErrInvalidRecord
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, ErrInvalidRecord
+		}
+		if m[key], err = d.value(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// 只支持timestamp 96扩展类型
+func (d *decoder) ext() (interface{}, error) {
+	b, err := d.next(2)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] != 12 || int8(b[1]) != -1 {
+		return nil, fmt.Errorf("%w: unsupported ext type %d", ErrInvalidRecord, int8(b[1]))
+	}
+	nsec, err := d.uint(4)
+	if err != nil {
+		return nil, err
+	}
+	sec, err := d.uint(8)
+	if err != nil {
+		return nil, err
+	}
+	return time.Unix(int64(sec), int64(nsec)), nil
+}
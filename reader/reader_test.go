@@ -0,0 +1,31 @@
+package reader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader(t *testing.T) {
+	// {"msg": "hi", "n": -1, "ok": true}
+	body := []byte{0x83, 0xa3, 'm', 's', 'g', 0xa2, 'h', 'i', 0xa1, 'n', 0xff, 0xa2, 'o', 'k', 0xc3}
+	frame := append([]byte{0, 0, 0, byte(len(body))}, body...)
+	data := append(append([]byte{}, frame...), frame[:6]...)
+
+	r := NewReader(bytes.NewReader(data))
+	rec, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{"msg": "hi", "n": int64(-1), "ok": true}, rec)
+	_, err = r.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = NewReader(bytes.NewReader(nil)).Next()
+	assert.Equal(t, io.EOF, err)
+	_, err = NewReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff})).Next()
+	assert.Equal(t, ErrRecordTooLarge, err)
+	// 不是map
+	_, err = NewReader(bytes.NewReader([]byte{0, 0, 0, 1, 0x01})).Next()
+	assert.Equal(t, ErrInvalidRecord, err)
+}
@@ -0,0 +1,200 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// redis类型appender的参数，通过appender的options配置
+type RedisConfig struct {
+	Addr     string `json:"addr"`     // 服务地址，如127.0.0.1:6379
+	Username string `json:"username"` // ACL用户名，为空时只使用密码认证
+	Password string `json:"password"` // 密码
+	DB       int    `json:"db"`       // 数据库编号
+	Stream   string `json:"stream"`   // 写入的stream
+	Field    string `json:"field"`    // 日志所在的字段名，为空时为log
+	MaxLen   int64  `json:"max_len"`  // stream保留的大约条数，写入时通过MAXLEN ~裁剪，为0时不裁剪
+	Timeout  int    `json:"timeout"`  // 连接和写入的超时时间，单位秒，为0时为5
+}
+
+var errRedisReconnect = errors.New("error redis not connected")
+
+func init() {
+	RegisterAppender("redis", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		var cfg RedisConfig
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		return NewRedisWriter(cfg)
+	})
+}
+
+// 通过XADD将每条日志写入Redis stream的writer，多个消费者可以通过消费组分别读取，并发安全
+// 连接断开后在下一次写入时重连，两次连接至少间隔1秒，期间的写入返回错误
+type RedisWriter struct {
+	mu       sync.Mutex
+	cfg      RedisConfig
+	conn     net.Conn
+	r        *bufio.Reader
+	lastDial time.Time
+	closed   bool
+}
+
+func NewRedisWriter(cfg RedisConfig) (*RedisWriter, error) {
+	if cfg.Addr == "" || cfg.Stream == "" || cfg.MaxLen < 0 {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.Field == "" {
+		cfg.Field = "log"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5
+	}
+	return &RedisWriter{cfg: cfg}, nil
+}
+
+func (w *RedisWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	args := []string{"XADD", w.cfg.Stream}
+	if w.cfg.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.FormatInt(w.cfg.MaxLen, 10))
+	}
+	args = append(args, "*", w.cfg.Field, string(bytes.TrimRight(b, "\n")))
+	if _, err := w.do(args...); err != nil {
+		if _, ok := err.(redisError); !ok {
+			w.disconnect()
+		}
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// 断开与Redis的连接
+func (w *RedisWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	if w.conn != nil {
+		w.do("QUIT")
+		w.disconnect()
+	}
+	return nil
+}
+
+// 连接并认证，选择数据库
+func (w *RedisWriter) connect() error {
+	if w.conn != nil {
+		return nil
+	}
+	if time.Since(w.lastDial) < time.Second {
+		return errRedisReconnect
+	}
+	w.lastDial = time.Now()
+	conn, err := net.DialTimeout("tcp", w.cfg.Addr, time.Duration(w.cfg.Timeout)*time.Second)
+	if err != nil {
+		return err
+	}
+	w.conn, w.r = conn, bufio.NewReader(conn)
+	if w.cfg.Password != "" {
+		args := []string{"AUTH", w.cfg.Password}
+		if w.cfg.Username != "" {
+			args = []string{"AUTH", w.cfg.Username, w.cfg.Password}
+		}
+		if _, err := w.do(args...); err != nil {
+			w.disconnect()
+			return err
+		}
+	}
+	if w.cfg.DB != 0 {
+		if _, err := w.do("SELECT", strconv.Itoa(w.cfg.DB)); err != nil {
+			w.disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *RedisWriter) disconnect() {
+	w.conn.Close()
+	w.conn, w.r = nil, nil
+}
+
+// Redis返回的错误，不需要重连
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// 发送命令并读取回复
+func (w *RedisWriter) do(args ...string) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	w.conn.SetDeadline(time.Now().Add(time.Duration(w.cfg.Timeout) * time.Second))
+	if _, err := w.conn.Write(buf.Bytes()); err != nil {
+		return "", err
+	}
+	return readRedisReply(w.r)
+}
+
+// 读取一个回复，数组回复只读取不返回内容
+func readRedisReply(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 3 {
+		return "", fmt.Errorf("redis: invalid reply %q", line)
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return "", err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return "", err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i < n; i++ {
+			if _, err := readRedisReply(r); err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("redis: invalid reply %q", line)
+}
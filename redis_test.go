@@ -0,0 +1,105 @@
+package logx
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 读取客户端发送的命令
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	commands := make(chan []string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			args, err := readRedisCommand(r)
+			if err != nil {
+				return
+			}
+			commands <- args
+			switch args[0] {
+			case "XADD":
+				if args[len(args)-1] == "bad" {
+					io.WriteString(conn, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n")
+					continue
+				}
+				io.WriteString(conn, "$15\r\n1700000000000-0\r\n")
+			default:
+				io.WriteString(conn, "+OK\r\n")
+			}
+		}
+	}()
+
+	w, err := newAppenderWriter(Appender{Type: "redis", Options: map[string]interface{}{
+		"addr": ln.Addr().String(), "password": "secret", "db": 2, "stream": "logs", "max_len": 1000,
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = w.Write([]byte(`{"msg":"hello"}` + "\n"))
+	assert.NoError(t, err)
+	// 服务端返回的错误不断开连接
+	_, err = w.Write([]byte("bad"))
+	assert.Error(t, err)
+	_, err = w.Write([]byte("ok"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	expected := [][]string{
+		{"AUTH", "secret"},
+		{"SELECT", "2"},
+		{"XADD", "logs", "MAXLEN", "~", "1000", "*", "log", `{"msg":"hello"}`},
+		{"XADD", "logs", "MAXLEN", "~", "1000", "*", "log", "bad"},
+		{"XADD", "logs", "MAXLEN", "~", "1000", "*", "log", "ok"},
+		{"QUIT"},
+	}
+	for _, cmd := range expected {
+		select {
+		case args := <-commands:
+			assert.Equal(t, cmd, args)
+		case <-time.After(time.Second):
+			t.Fatalf("command %v not received", cmd)
+		}
+	}
+
+	_, err = NewRedisWriter(RedisConfig{Addr: "127.0.0.1:6379"})
+	assert.Error(t, err)
+}
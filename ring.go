@@ -0,0 +1,145 @@
+package logx
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 内存环形缓存配置，缓存最近的日志，出现错误日志时将缓存的日志一起写入
+type RingConfig struct {
+	// 缓存的日志条数
+	Size int `json:"size" yaml:"size"`
+	// 触发写入的日志级别，默认为error
+	Level string `json:"level" yaml:"level"`
+}
+
+// 环形缓存，被同一appender派生出的所有core共享
+type ring struct {
+	sync.Mutex
+	entries [][]byte
+	next    int // 下一条日志写入的位置
+	full    bool
+}
+
+// 按写入顺序遍历缓存的日志后清空缓存
+func (r *ring) drain(fn func([]byte)) {
+	if r.full {
+		for _, b := range r.entries[r.next:] {
+			fn(b)
+		}
+	}
+	for _, b := range r.entries[:r.next] {
+		fn(b)
+	}
+	r.next, r.full = 0, false
+}
+
+// 按写入顺序复制缓存的日志
+func (r *ring) snapshot() [][]byte {
+	r.Lock()
+	defer r.Unlock()
+	entries := make([][]byte, 0, len(r.entries))
+	if r.full {
+		for _, b := range r.entries[r.next:] {
+			entries = append(entries, append([]byte(nil), b...))
+		}
+	}
+	for _, b := range r.entries[:r.next] {
+		entries = append(entries, append([]byte(nil), b...))
+	}
+	return entries
+}
+
+// 缓存appender级别启用的日志，出现不低于触发级别的日志时写入缓存的日志和该日志
+type ringCore struct {
+	enc     zapcore.Encoder
+	out     zapcore.WriteSyncer
+	level   zapcore.LevelEnabler
+	trigger zapcore.Level
+	ring    *ring
+}
+
+func newRingCore(enc zapcore.Encoder, out zapcore.WriteSyncer, c *RingConfig, level zapcore.LevelEnabler) zapcore.Core {
+	size := c.Size
+	if size <= 0 {
+		size = 100
+	}
+	trigger := zapcore.ErrorLevel
+	if c.Level != "" {
+		trigger = logLevel(c.Level)
+	}
+	return &ringCore{
+		enc:     enc,
+		out:     out,
+		level:   level,
+		trigger: trigger,
+		ring:    &ring{entries: make([][]byte, size)},
+	}
+}
+
+func (c *ringCore) Enabled(l zapcore.Level) bool {
+	return c.level.Enabled(l)
+}
+
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	c.ring.Lock()
+	defer c.ring.Unlock()
+	if ent.Level < c.trigger {
+		r := c.ring
+		r.entries[r.next] = append(r.entries[r.next][:0], buf.Bytes()...)
+		r.next++
+		if r.next == len(r.entries) {
+			r.next, r.full = 0, true
+		}
+		return nil
+	}
+	c.ring.drain(func(b []byte) {
+		if _, e := c.out.Write(b); e != nil && err == nil {
+			err = e
+		}
+	})
+	if _, e := c.out.Write(buf.Bytes()); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+func (c *ringCore) Sync() error {
+	return c.out.Sync()
+}
+
+// 将环形缓存中的日志立即写入，用于进程退出前保留现场
+func (c *ringCore) Dump() error {
+	c.ring.Lock()
+	defer c.ring.Unlock()
+	var err error
+	c.ring.drain(func(b []byte) {
+		if _, e := c.out.Write(b); e != nil && err == nil {
+			err = e
+		}
+	})
+	return err
+}
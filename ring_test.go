@@ -0,0 +1,53 @@
+package logx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRingCore(t *testing.T) {
+	var buf bytes.Buffer
+	enc := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	core := newRingCore(enc, zapcore.AddSync(&buf), &RingConfig{Size: 2}, zapcore.DebugLevel)
+	logger := zap.New(core)
+
+	logger.Debug("one")
+	logger.Info("two")
+	logger.With(zap.String("k", "v")).Debug("three")
+	assert.Equal(t, 0, buf.Len())
+
+	logger.Error("boom")
+	assert.Equal(t, []string{"two", `three	{"k": "v"}`, "boom"}, strings.Split(strings.TrimSpace(buf.String()), "\n"))
+
+	buf.Reset()
+	logger.Warn("four")
+	assert.Nil(t, core.(*ringCore).Dump())
+	assert.Equal(t, "four\n", buf.String())
+}
+
+func TestRingLevel(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	var buf bytes.Buffer
+	Init(&Config{Appenders: []Appender{{Writer: NopCloser(&buf), Level: "warn", Ring: &RingConfig{Size: 10}}}})
+
+	// 低于appender级别的日志不进入缓存
+	Debug("debug")
+	Warn("warn")
+	Error("error")
+	assert.NotContains(t, buf.String(), "debug")
+	assert.Contains(t, buf.String(), "warn")
+	assert.Contains(t, buf.String(), "error")
+
+	// 运行时修改的级别同样生效
+	buf.Reset()
+	_, err := controlSetLevel([]string{"debug"})
+	assert.NoError(t, err)
+	Debug("debug")
+	Error("error")
+	assert.Contains(t, buf.String(), "debug")
+}
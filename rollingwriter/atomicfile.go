@@ -0,0 +1,52 @@
+package rollingwriter
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// 当前写入的日志文件，滚动和重建时原子性的替换，各写入模式共享
+// 所有读取都通过Load，替换后仍在写入的协程持有旧文件，旧文件由替换方负责关闭
+type atomicFile struct {
+	v  atomic.Value
+	mu sync.Mutex // 保证Swap的读取和替换是一个整体
+}
+
+func newAtomicFile(f *os.File) *atomicFile {
+	a := &atomicFile{}
+	a.v.Store(f)
+	return a
+}
+
+func (a *atomicFile) Load() *os.File {
+	return a.v.Load().(*os.File)
+}
+
+// 替换为f，返回被替换的文件
+func (a *atomicFile) Swap(f *os.File) *os.File {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	old := a.Load()
+	a.v.Store(f)
+	return old
+}
+
+// 当前日志文件路径，按日期分目录时滚动后会改变
+type atomicPath struct {
+	v atomic.Value
+}
+
+func newAtomicPath(name string) *atomicPath {
+	p := &atomicPath{}
+	p.v.Store(name)
+	return p
+}
+
+func (p *atomicPath) Load() string {
+	return p.v.Load().(string)
+}
+
+func (p *atomicPath) Store(name string) {
+	p.v.Store(name)
+}
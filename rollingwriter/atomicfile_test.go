@@ -0,0 +1,81 @@
+package rollingwriter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 并发写入的同时按大小和手动触发滚动，配合go test -race检查替换日志文件时的数据竞争，
+// 并检查所有日志都完整的写入了日志文件或历史日志文件
+func TestConcurrentWriteRotate(t *testing.T) {
+	for _, mode := range []string{"none", "lock", "async", "buffer", "sharded"} {
+		t.Run(mode, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := NewDefaultConfig()
+			cfg.LogPath = dir
+			cfg.FileName = "app"
+			cfg.WriterMode = mode
+			cfg.RollingPolicy = VolumeRolling
+			cfg.RollingVolumeSize = "16kb"
+			cfg.BufferWriterThreshold = 256
+			w, err := NewWriterFromConfig(&cfg)
+			if !assert.NoError(t, err) {
+				return
+			}
+			const goroutines, lines = 8, 500
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < lines; i++ {
+						fmt.Fprintf(w, "goroutine %d line %d\n", g, i)
+					}
+				}(g)
+			}
+			stop := make(chan struct{})
+			rotated := make(chan struct{})
+			go func() {
+				defer close(rotated)
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						w.(interface{ Rotate() }).Rotate()
+					}
+				}
+			}()
+			wg.Wait()
+			close(stop)
+			<-rotated
+			assert.NoError(t, w.Close())
+
+			files, _ := filepath.Glob(filepath.Join(dir, "app.log*"))
+			seen := make(map[string]bool)
+			for _, name := range files {
+				if strings.HasSuffix(name, ".state") {
+					continue
+				}
+				buf, err := ioutil.ReadFile(name)
+				if !assert.NoError(t, err) {
+					return
+				}
+				for _, line := range strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n") {
+					if line == "" {
+						continue
+					}
+					assert.Regexp(t, `^goroutine \d+ line \d+$`, line)
+					seen[line] = true
+				}
+			}
+			assert.Len(t, seen, goroutines*lines)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package rollingwriter
+
+// 当WriterMode为audit时使用的结构，并发安全
+// 以追加方式写入，每条日志写入后fsync，成功落盘后才返回，不丢弃日志，不支持copytruncate
+type AuditWriter struct {
+	LockedWriter
+}
+
+func init() {
+	RegisterWriterMode("audit", func(c Config, w Writer) (RollingWriter, error) {
+		// copytruncate在复制和截断之间写入的日志会丢失
+		if c.RotationStrategy == "copytruncate" {
+			return nil, ErrInvalidArgument
+		}
+		return &AuditWriter{LockedWriter{Writer: w}}, nil
+	})
+}
+
+// 写入并同步到磁盘
+func (w *AuditWriter) Write(b []byte) (n int, err error) {
+	w.Lock()
+	defer w.Unlock()
+	if err := w.rolling(); err != nil {
+		return 0, err
+	}
+	if n, err = w.current().Write(b); err != nil {
+		return w.written(b, n, err)
+	}
+	return w.written(b, n, w.current().Sync())
+}
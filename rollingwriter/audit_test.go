@@ -0,0 +1,31 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditWriter(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewWriter(WithLogPath(dir), WithoutRollingPolicy(), WithCopyTruncate(), func(c *Config) { c.WriterMode = "audit" })
+	assert.Equal(t, ErrInvalidArgument, err)
+
+	w, err := NewWriter(WithLogPath(dir), WithoutRollingPolicy(), func(c *Config) { c.WriterMode = "audit" })
+	if !assert.NoError(t, err) {
+		return
+	}
+	aw := w.(*AuditWriter)
+	_, err = aw.Write([]byte("transfer 100\n"))
+	assert.NoError(t, err)
+	aw.rotate(dir + "/log.log.1")
+	_, err = aw.Write([]byte("transfer 200\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	buf, _ := ioutil.ReadFile(dir + "/log.log.1")
+	assert.Equal(t, "transfer 100\n", string(buf))
+	buf, _ = ioutil.ReadFile(dir + "/log.log")
+	assert.Equal(t, "transfer 200\n", string(buf))
+}
@@ -0,0 +1,92 @@
+package rollingwriter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"runtime/debug"
+	"text/template"
+	"time"
+)
+
+// 写入日志文件头尾信息时可以使用的模板变量
+type BannerInfo struct {
+	Time       string // 写入时间
+	Hostname   string // 主机名
+	Pid        int    // 进程号
+	Version    string // 主模块版本
+	ConfigHash string // 配置的sha256摘要
+	FileName   string // 日志文件路径
+}
+
+// 日志文件的头尾信息模板
+type banner struct {
+	header *template.Template
+	footer *template.Template
+	info   BannerInfo
+	clock  Clock
+}
+
+// 解析配置中的头尾信息模板，都未配置时返回nil
+func newBanner(c *Config) (*banner, error) {
+	if c.Header == "" && c.Footer == "" {
+		return nil, nil
+	}
+	b := &banner{clock: c.clock()}
+	var err error
+	if c.Header != "" {
+		if b.header, err = template.New("header").Parse(c.Header); err != nil {
+			return nil, err
+		}
+	}
+	if c.Footer != "" {
+		if b.footer, err = template.New("footer").Parse(c.Footer); err != nil {
+			return nil, err
+		}
+	}
+	b.info.Hostname, _ = os.Hostname()
+	b.info.Pid = os.Getpid()
+	b.info.FileName = LogFilePath(c)
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		b.info.Version = bi.Main.Version
+	}
+	if buf, err := json.Marshal(c); err == nil {
+		sum := sha256.Sum256(buf)
+		b.info.ConfigHash = hex.EncodeToString(sum[:8])
+	}
+	return b, nil
+}
+
+// 渲染模板并写入文件，内容末尾没有换行符时补写换行符
+func (b *banner) write(file *os.File, tmpl *template.Template) error {
+	if b == nil || tmpl == nil {
+		return nil
+	}
+	info := b.info
+	info.Time = b.clock.Now().Format(time.RFC3339)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, info); err != nil {
+		return err
+	}
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	_, err := file.Write(buf.Bytes())
+	return err
+}
+
+// 在新的日志文件开头写入头信息
+func (w *Writer) writeHeader(file *os.File) {
+	if w.banner != nil {
+		w.handleError("write header", w.banner.write(file, w.banner.header))
+	}
+}
+
+// 在滚动前的日志文件末尾写入尾信息
+func (w *Writer) writeFooter(file *os.File) {
+	if w.banner != nil {
+		w.handleError("write footer", w.banner.write(file, w.banner.footer))
+	}
+}
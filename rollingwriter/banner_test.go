@@ -0,0 +1,39 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanner(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.Header = "# start pid={{.Pid}}"
+	cfg.Footer = "# end\n"
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w.Write([]byte("line\n"))
+	archive := path.Join(cfg.LogPath, "unittest.log.1")
+	assert.Nil(t, w.(*Writer).Reopen(archive))
+	w.Write([]byte("next\n"))
+	w.Close()
+
+	header := "# start pid=" + strconv.Itoa(os.Getpid()) + "\n"
+	buf, _ := ioutil.ReadFile(archive)
+	assert.Equal(t, header+"line\n# end\n", string(buf))
+	buf, _ = ioutil.ReadFile(LogFilePath(&cfg))
+	assert.Equal(t, header+"next\n", string(buf))
+
+	cfg.Header = "{{.Missing"
+	_, err = NewWriterFromConfig(&cfg)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,95 @@
+package rollingwriter
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// 分级缓存池的各级缓存大小，从256B开始每级扩大4倍，最大为1MB
+var _poolTiers = [...]int{0x100, 0x400, 0x1000, 0x4000, 0x10000, 0x40000, 0x100000}
+
+// 按数据大小分级的缓存池，缓存对象为*[]byte，Get和Put都不会产生内存分配
+type bufferPool struct {
+	gets   uint64 // 原子操作的字段放在开头，保证32位平台上8字节对齐
+	misses uint64 // 缓存池中没有可用缓存或超过上限时新分配的次数
+	max    int    // 使用缓存池的最大缓存大小，为0时使用BufferSize
+	pools  [len(_poolTiers)]sync.Pool
+}
+
+// 不属于某个writer时使用的缓存池
+var _asyncBufferPool = newBufferPool(0)
+
+func newBufferPool(max int) *bufferPool {
+	p := &bufferPool{max: max}
+	for i := range _poolTiers {
+		size := _poolTiers[i]
+		p.pools[i].New = func() interface{} {
+			atomic.AddUint64(&p.misses, 1)
+			b := make([]byte, 0, size)
+			return &b
+		}
+	}
+	return p
+}
+
+// 使用缓存池的最大缓存大小
+func (p *bufferPool) limit() int {
+	if p.max > 0 {
+		return p.max
+	}
+	return BufferSize
+}
+
+// 获取容量不小于size的缓存，超过最大级别或上限的缓存直接分配
+func (p *bufferPool) Get(size int) *[]byte {
+	atomic.AddUint64(&p.gets, 1)
+	if size <= p.limit() {
+		for i, tier := range _poolTiers {
+			if size <= tier {
+				return p.pools[i].Get().(*[]byte)
+			}
+		}
+	}
+	atomic.AddUint64(&p.misses, 1)
+	b := make([]byte, 0, size)
+	return &b
+}
+
+// 命中和未命中缓存池的次数
+func (p *bufferPool) stats() (hits, misses uint64) {
+	gets, misses := atomic.LoadUint64(&p.gets), atomic.LoadUint64(&p.misses)
+	if misses > gets {
+		return 0, misses
+	}
+	return gets - misses, misses
+}
+
+// async模式的队列长度
+func (c *Config) queueSize() int {
+	if c.QueueSize > 0 {
+		return c.QueueSize
+	}
+	return QueueSize
+}
+
+// 归还缓存，只有容量与某一级别完全一致且不超过上限的缓存才会放回缓存池
+func (p *bufferPool) Put(b *[]byte) {
+	c := cap(*b)
+	if c > p.limit() {
+		return
+	}
+	for i, tier := range _poolTiers {
+		if c == tier {
+			*b = (*b)[:0]
+			p.pools[i].Put(b)
+			return
+		}
+	}
+}
+
+// 获取缓存并写入b中的数据
+func (p *bufferPool) Copy(b []byte) *[]byte {
+	buf := p.Get(len(b))
+	*buf = append((*buf)[:0], b...)
+	return buf
+}
@@ -0,0 +1,58 @@
+package rollingwriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferPool(t *testing.T) {
+	p := newBufferPool(0)
+	assert.Equal(t, 0x100, cap(*p.Get(1)))
+	assert.Equal(t, 0x1000, cap(*p.Get(0x1000)))
+	assert.Equal(t, 0x4000, cap(*p.Get(0x1001)))
+	assert.Equal(t, BufferSize+1, cap(*p.Get(BufferSize + 1)))
+
+	buf := p.Copy([]byte("foo"))
+	assert.Equal(t, "foo", string(*buf))
+	p.Put(buf)
+	assert.Equal(t, 0, len(*buf))
+}
+
+func TestBufferPoolLimit(t *testing.T) {
+	p := newBufferPool(0x1000)
+	buf := p.Get(0x1000)
+	p.Put(buf)
+	p.Get(0x1000)
+	// 超过上限的缓存直接分配，也不放回缓存池
+	big := p.Get(0x1001)
+	assert.Equal(t, 0x1001, cap(*big))
+	p.Put(p.Get(0x4000))
+	hits, misses := p.stats()
+	assert.Equal(t, uint64(4), hits+misses)
+	// 第一次Get新分配，超过上限的两次直接分配，第二次Get可能命中
+	assert.GreaterOrEqual(t, misses, uint64(3))
+}
+
+func TestWriterBufferPoolConfig(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "async"
+	cfg.BatchSize = 0
+	WithBufferPool(0x400, 8)(&cfg)
+	rw, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w := rw.(*AsynchronousWriter)
+	assert.Equal(t, 8, cap(w.queue))
+	assert.Equal(t, 0x400, w.pool.limit())
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("pooled line\n"))
+	}
+	assert.NoError(t, w.Close())
+	stats := w.Stats()
+	assert.Equal(t, uint64(100), stats.PoolHits+stats.PoolMisses)
+	assert.True(t, stats.PeakQueued >= 1 && stats.PeakQueued <= 8, stats.PeakQueued)
+}
@@ -0,0 +1,68 @@
+package rollingwriter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// 校验文件的后缀，内容与sha256sum的输出格式一致，可以使用sha256sum -c校验
+const ChecksumSuffix = ".sha256"
+
+var ErrChecksumMismatch = errors.New("error checksum mismatch")
+
+// 计算文件的sha256摘要
+func fileChecksum(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 为滚动和压缩完成的历史日志文件写入校验文件
+func (w *Writer) writeChecksum(archive string) error {
+	sum, err := fileChecksum(archive)
+	if err != nil {
+		return err
+	}
+	file, err := w.cf.openFile(archive+ChecksumSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write([]byte(sum + "  " + path.Base(archive) + "\n")); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// 使用校验文件校验历史日志文件，内容不一致时返回ErrChecksumMismatch
+func VerifyArchive(name string) error {
+	buf, err := ioutil.ReadFile(name + ChecksumSuffix)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(buf))
+	if len(fields) == 0 {
+		return ErrChecksumMismatch
+	}
+	sum, err := fileChecksum(name)
+	if err != nil {
+		return err
+	}
+	if sum != fields[0] {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
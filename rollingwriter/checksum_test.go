@@ -0,0 +1,43 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	cfg.Checksum = true
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	archive := path.Join(cfg.LogPath, "unittest.log.1")
+	assert.Nil(t, w.(*Writer).Reopen(archive))
+	// 等待后台写入校验文件
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(archive + ChecksumSuffix); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	buf, err := ioutil.ReadFile(archive + ChecksumSuffix)
+	assert.Nil(t, err)
+	assert.Equal(t, "b640e840b19d378660b32fb51ae18d67dccb4a8596a29e7bd72c1b2ae5928f41  unittest.log.1\n", string(buf))
+	assert.Nil(t, VerifyArchive(archive))
+
+	assert.Nil(t, ioutil.WriteFile(archive, []byte("forged\n"), 0644))
+	assert.Equal(t, ErrChecksumMismatch, VerifyArchive(archive))
+}
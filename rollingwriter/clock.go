@@ -0,0 +1,82 @@
+package rollingwriter
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 日志滚动使用的时钟，包括滚动时间、大小检查、文件监测和cron调度，测试中可以注入假时钟
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+}
+
+// 周期计时器
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// 单次计时器
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// 使用time包的时钟
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// 配置的时钟，未配置时使用RealClock
+func (c *Config) clock() Clock {
+	if c.Clock == nil {
+		return RealClock
+	}
+	return c.Clock
+}
+
+// 按cron表达式调度，每到触发时间调用fn，stop关闭时退出
+func schedule(clock Clock, sched cron.Schedule, fn func(), stop chan int) {
+	for {
+		now := clock.Now()
+		timer := clock.NewTimer(sched.Next(now).Sub(now))
+		select {
+		case <-timer.C():
+			fn()
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
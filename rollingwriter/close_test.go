@@ -0,0 +1,157 @@
+package rollingwriter
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCloseWriter(t *testing.T, mode string) (RollingWriter, *Config) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "app"
+	cfg.WriterMode = mode
+	cfg.RollingPolicy = WithoutRolling
+	cfg.BufferWriterThreshold = 256
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, &cfg
+}
+
+// 各写入模式关闭时写入所有日志，重复关闭和关闭后的写入返回ErrClosed
+func TestCloseSemantics(t *testing.T) {
+	for _, mode := range []string{"none", "lock", "async", "buffer", "sharded", "audit", "gzip"} {
+		t.Run(mode, func(t *testing.T) {
+			w, cfg := newCloseWriter(t, mode)
+			for i := 0; i < 100; i++ {
+				fmt.Fprintf(w, "line %d\n", i)
+			}
+			assert.NoError(t, w.Close())
+			assert.Equal(t, ErrClosed, w.Close())
+			_, err := w.Write([]byte("after close\n"))
+			assert.Equal(t, ErrClosed, err)
+			if mode == "gzip" {
+				return
+			}
+			buf, _ := ioutil.ReadFile(LogFilePath(cfg))
+			assert.Equal(t, 100, strings.Count(string(buf), "\n"))
+			assert.NotContains(t, string(buf), "after close")
+		})
+	}
+}
+
+// 与Close同时进行的写入要么写入日志文件，要么返回ErrClosed
+func TestCloseConcurrentWrites(t *testing.T) {
+	for _, mode := range []string{"lock", "async", "buffer", "sharded", "audit"} {
+		t.Run(mode, func(t *testing.T) {
+			w, cfg := newCloseWriter(t, mode)
+			var written int64
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; ; i++ {
+						if _, err := fmt.Fprintf(w, "goroutine %d line %d\n", g, i); err != nil {
+							assert.Equal(t, ErrClosed, err)
+							return
+						}
+						atomic.AddInt64(&written, 1)
+					}
+				}(g)
+			}
+			time.Sleep(20 * time.Millisecond)
+			assert.NoError(t, w.Close())
+			wg.Wait()
+			buf, _ := ioutil.ReadFile(LogFilePath(cfg))
+			assert.Equal(t, atomic.LoadInt64(&written), int64(strings.Count(string(buf), "\n")))
+		})
+	}
+}
+
+// 与Close同时进行的ReadFrom返回的字节数与写入日志文件和统计的字节数一致
+func TestCloseConcurrentReadFrom(t *testing.T) {
+	for _, mode := range []string{"lock", "async", "buffer", "sharded", "audit"} {
+		t.Run(mode, func(t *testing.T) {
+			w, cfg := newCloseWriter(t, mode)
+			rf := w.(io.ReaderFrom)
+			var written int64
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; ; i++ {
+						n, err := rf.ReadFrom(strings.NewReader(fmt.Sprintf("goroutine %d line %d\n", g, i)))
+						atomic.AddInt64(&written, n)
+						if err != nil {
+							assert.Equal(t, ErrClosed, err)
+							return
+						}
+					}
+				}(g)
+			}
+			time.Sleep(20 * time.Millisecond)
+			assert.NoError(t, w.Close())
+			wg.Wait()
+			buf, _ := ioutil.ReadFile(LogFilePath(cfg))
+			assert.Equal(t, atomic.LoadInt64(&written), int64(len(buf)))
+			assert.Equal(t, uint64(len(buf)), w.(interface{ Stats() Stats }).Stats().BytesWritten)
+		})
+	}
+}
+
+// 在Read中等待期间writer被关闭，读取的数据不计入返回值
+type closingReader struct {
+	started, release chan struct{}
+}
+
+func (r *closingReader) Read(b []byte) (int, error) {
+	close(r.started)
+	<-r.release
+	return copy(b, "late line\n"), io.EOF
+}
+
+func TestAsyncReadFromDuringClose(t *testing.T) {
+	w, cfg := newCloseWriter(t, "async")
+	r := &closingReader{started: make(chan struct{}), release: make(chan struct{})}
+	type result struct {
+		n   int64
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		n, err := w.(io.ReaderFrom).ReadFrom(r)
+		done <- result{n, err}
+	}()
+	<-r.started
+	assert.NoError(t, w.Close())
+	close(r.release)
+	res := <-done
+	assert.Equal(t, int64(0), res.n)
+	assert.Equal(t, ErrClosed, res.err)
+	buf, _ := ioutil.ReadFile(LogFilePath(cfg))
+	assert.Empty(t, buf)
+	assert.Equal(t, uint64(0), w.(interface{ Stats() Stats }).Stats().BytesWritten)
+}
+
+func TestManagerCloseTwice(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "app"
+	m, err := NewManager(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	m.Close()
+	assert.NotPanics(t, m.Close)
+}
@@ -0,0 +1,130 @@
+package rollingwriter
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 历史日志文件名称中的时间，压缩的历史日志文件去掉压缩扩展名后解析，忽略避免重名的序号
+// 依次尝试FileName.log.之后的部分和最后一个扩展名（旧的压缩命名方式FileName.log.gz.时间）
+func (c *Config) archiveTime(name string) (time.Time, bool) {
+	name = c.trimCompressExt(name)
+	tags := []string{strings.TrimPrefix(path.Ext(name), ".")}
+	if base := path.Base(name); strings.HasPrefix(base, c.FileName+".log.") {
+		tags = append([]string{strings.TrimPrefix(base, c.FileName+".log.")}, tags...)
+	}
+	for _, tag := range tags {
+		if t, err := time.Parse(c.TimeTagFormat, tag); err == nil {
+			return t, true
+		}
+		if i := strings.LastIndex(tag, "."); i > 0 && isDigits(tag[i+1:]) {
+			if t, err := time.Parse(c.TimeTagFormat, tag[:i]); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// 删除压缩中断时残留的.gz.tmp等临时文件
+func (c *Config) removeCompressTemp() {
+	suffix := ".*" + c.compressExt() + ".tmp"
+	patterns := []string{path.Join(c.LogPath, c.FileName+".log"+suffix)}
+	if c.DirLayout != "" {
+		patterns = append(patterns, path.Join(c.LogPath, "*", c.FileName+".log"+suffix))
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, name := range matches {
+			if err := os.Remove(name); err != nil {
+				c.handleError("remove compress tempfile", err)
+			}
+		}
+	}
+}
+
+// 允许压缩的时间段，为一天中的偏移，start大于end时跨越零点
+type compressWindow struct {
+	start, end time.Duration
+}
+
+// 解析HH:MM-HH:MM格式的压缩时间段，为空时返回nil
+func parseCompressWindow(s string) (*compressWindow, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return nil, ErrInvalidArgument
+	}
+	var offsets [2]time.Duration
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return nil, ErrInvalidArgument
+		}
+		offsets[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if offsets[0] == offsets[1] {
+		return nil, ErrInvalidArgument
+	}
+	return &compressWindow{start: offsets[0], end: offsets[1]}, nil
+}
+
+// 不早于t且位于时间段内的最早时间
+func (cw *compressWindow) next(t time.Time) time.Time {
+	if cw == nil {
+		return t
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	d := t.Sub(midnight)
+	if cw.start < cw.end {
+		switch {
+		case d < cw.start:
+			return midnight.Add(cw.start)
+		case d < cw.end:
+			return t
+		default:
+			return midnight.AddDate(0, 0, 1).Add(cw.start)
+		}
+	}
+	if d >= cw.start || d < cw.end {
+		return t
+	}
+	return midnight.Add(cw.start)
+}
+
+// 等待到允许压缩的时间，writer关闭时返回false
+// 压缩在滚动后延迟CompressAfter秒，并且只在CompressWindow时间段内执行，磁盘剩余空间低于低水位时立即执行
+func (w *Writer) waitCompress(rotated time.Time) bool {
+	window, _ := parseCompressWindow(w.cf.CompressWindow)
+	at := window.next(rotated.Add(time.Duration(w.cf.CompressAfter) * time.Second))
+	clock := w.cf.clock()
+	wait := at.Sub(clock.Now())
+	if wait <= 0 {
+		return true
+	}
+	timer := clock.NewTimer(wait)
+	select {
+	case <-timer.C():
+		return true
+	case <-w.diskLow():
+		timer.Stop()
+		return true
+	case <-w.closing:
+		timer.Stop()
+		return false
+	}
+}
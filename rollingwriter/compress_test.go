@@ -0,0 +1,115 @@
+package rollingwriter
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressWindow(t *testing.T) {
+	for _, s := range []string{"02:00", "2-5", "25:00-03:00", "02:00-02:00"} {
+		_, err := parseCompressWindow(s)
+		assert.Equal(t, ErrInvalidArgument, err, s)
+	}
+
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 5, day, hour, min, 0, 0, time.UTC)
+	}
+	night, err := parseCompressWindow("02:00-05:00")
+	assert.Nil(t, err)
+	assert.Equal(t, at(1, 2, 0), night.next(at(1, 1, 30)))
+	assert.Equal(t, at(1, 3, 15), night.next(at(1, 3, 15)))
+	assert.Equal(t, at(2, 2, 0), night.next(at(1, 5, 0)))
+
+	wrap, err := parseCompressWindow("22:00-04:00")
+	assert.Nil(t, err)
+	assert.Equal(t, at(1, 23, 0), wrap.next(at(1, 23, 0)))
+	assert.Equal(t, at(1, 1, 0), wrap.next(at(1, 1, 0)))
+	assert.Equal(t, at(1, 22, 0), wrap.next(at(1, 12, 0)))
+
+	var none *compressWindow
+	assert.Equal(t, at(1, 12, 0), none.next(at(1, 12, 0)))
+}
+
+func TestCompressFile(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	rw, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w := rw.(*Writer)
+	defer w.Close()
+	w.Write([]byte("compressed\n"))
+	cmpname := path.Join(cfg.LogPath, "unittest.log.1.gz")
+
+	// 读取失败时删除临时文件，不生成压缩文件
+	wronly, err := os.OpenFile(LogFilePath(&cfg), os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	assert.NotNil(t, w.CompressFile(wronly, cmpname))
+	wronly.Close()
+	assertNoFile(t, cmpname)
+	assertNoFile(t, cmpname+".tmp")
+
+	// 重命名失败时删除临时文件
+	assert.Nil(t, os.Mkdir(cmpname, 0700))
+	ioutil.WriteFile(path.Join(cmpname, "keep"), nil, 0644)
+	assert.NotNil(t, w.CompressFile(w.current(), cmpname))
+	assertNoFile(t, cmpname+".tmp")
+	assert.Nil(t, os.RemoveAll(cmpname))
+
+	assert.Nil(t, w.CompressFile(w.current(), cmpname))
+	assertNoFile(t, cmpname+".tmp")
+	file, err := os.Open(cmpname)
+	assert.Nil(t, err)
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	assert.Nil(t, err)
+	buf, _ := ioutil.ReadAll(r)
+	assert.Equal(t, "compressed\n", string(buf))
+}
+
+func TestCompressAfterRotate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	cfg.Compress = true
+	// 上次压缩中断时残留的临时文件
+	ioutil.WriteFile(path.Join(cfg.LogPath, "unittest.log.0.gz.tmp"), []byte("partial"), 0644)
+	rw, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w := rw.(*Writer)
+	defer w.Close()
+	assertNoFile(t, path.Join(cfg.LogPath, "unittest.log.0.gz.tmp"))
+
+	w.Write([]byte("first\n"))
+	archive := path.Join(cfg.LogPath, "unittest.log.1")
+	assert.Nil(t, w.Reopen(archive))
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(archive); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertNoFile(t, archive)
+	_, err = os.Stat(archive + ".gz")
+	assert.Nil(t, err)
+}
+
+func assertNoFile(t *testing.T, name string) {
+	t.Helper()
+	_, err := os.Stat(name)
+	assert.True(t, os.IsNotExist(err), name)
+}
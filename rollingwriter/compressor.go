@@ -0,0 +1,135 @@
+package rollingwriter
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 历史日志文件的压缩方式，Compress将src压缩后写入dst，Ext为压缩文件在历史日志文件名称后添加的扩展名，如.gz
+// writer调用Compress时dst为临时文件，返回nil后重命名为历史日志文件名称加Ext并删除src
+type Compressor interface {
+	Compress(src, dst string) error
+	Ext() string
+}
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[string]Compressor{"gzip": gzipCompressor{}}
+)
+
+// 注册压缩方式，注册后可以通过配置中的Compressor选择，如xz、zstd等外部格式
+// 内置的gzip不能被覆盖
+func RegisterCompressor(name string, c Compressor) {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+	if name == "gzip" {
+		return
+	}
+	if c == nil {
+		delete(compressors, name)
+		return
+	}
+	compressors[name] = c
+}
+
+// 查找压缩方式，name为空时为gzip
+func lookupCompressor(name string) (Compressor, bool) {
+	if name == "" {
+		name = "gzip"
+	}
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	c, ok := compressors[name]
+	return c, ok
+}
+
+// 配置的压缩方式，未注册时使用gzip
+func (c *Config) compressor() Compressor {
+	if comp, ok := lookupCompressor(c.Compressor); ok {
+		return comp
+	}
+	return gzipCompressor{}
+}
+
+// 压缩的历史日志文件的扩展名
+func (c *Config) compressExt() string {
+	return c.compressor().Ext()
+}
+
+// 去掉历史日志文件名称中的压缩扩展名，同时识别gzip的.gz，切换压缩方式前的文件仍能识别
+func (c *Config) trimCompressExt(name string) string {
+	if ext := c.compressExt(); strings.HasSuffix(name, ext) {
+		return strings.TrimSuffix(name, ext)
+	}
+	return strings.TrimSuffix(name, ".gz")
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Ext() string {
+	return ".gz"
+}
+
+func (gzipCompressor) Compress(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := out.Close(); err == nil {
+			err = e
+		}
+	}()
+	gw := gzip.NewWriter(out)
+	if _, err = io.Copy(gw, in); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// 使用配置的压缩方式压缩历史日志文件file，成功时删除file并返回压缩文件名称
+// gzip直接读取oldfile，其他压缩方式读取file，先写入临时文件并同步到磁盘，成功后原子性的重命名
+func (w *Writer) compressArchive(file string, oldfile *os.File) (string, error) {
+	comp := w.cf.compressor()
+	cmpname := file + comp.Ext()
+	if _, ok := comp.(gzipCompressor); ok {
+		if err := w.CompressFile(oldfile, cmpname); err != nil {
+			return file, err
+		}
+	} else {
+		tmpname := cmpname + ".tmp"
+		if err := comp.Compress(file, tmpname); err != nil {
+			os.Remove(tmpname)
+			return file, err
+		}
+		if err := syncFile(tmpname); err != nil {
+			os.Remove(tmpname)
+			return file, err
+		}
+		if err := os.Rename(tmpname, cmpname); err != nil {
+			os.Remove(tmpname)
+			return file, err
+		}
+	}
+	if err := os.Remove(file); err != nil {
+		w.handleError("remove compressed log file", err)
+	}
+	return cmpname, nil
+}
+
+func syncFile(name string) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
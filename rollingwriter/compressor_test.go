@@ -0,0 +1,62 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 在内容前加标记的压缩方式
+type markCompressor struct{}
+
+func (markCompressor) Ext() string { return ".mark" }
+
+func (markCompressor) Compress(src, dst string) error {
+	buf, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, append([]byte("MARK:"), buf...), 0644)
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	RegisterCompressor("mark", markCompressor{})
+	defer RegisterCompressor("mark", nil)
+	// 内置的gzip不能被覆盖
+	RegisterCompressor("gzip", markCompressor{})
+	comp, _ := lookupCompressor("gzip")
+	assert.Equal(t, ".gz", comp.Ext())
+
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	WithCompressor("mark")(&cfg)
+	rw, err := NewWriterFromConfig(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	w := rw.(*Writer)
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	archive := path.Join(cfg.LogPath, "unittest.log.1")
+	assert.Nil(t, w.Reopen(archive))
+	assert.Eventually(t, func() bool { return !exists(archive) }, time.Second, 10*time.Millisecond)
+	buf, err := ioutil.ReadFile(archive + ".mark")
+	assert.NoError(t, err)
+	assert.Equal(t, "MARK:first\n", string(buf))
+	assertNoFile(t, archive+".mark.tmp")
+
+	// 压缩后的同名文件视为已存在
+	assert.Equal(t, archive+".1", cfg.uniqueArchive(archive))
+	_, ok := cfg.archiveTime(path.Join(cfg.LogPath, "unittest.log."+time.Now().Format(cfg.TimeTagFormat)+".mark"))
+	assert.True(t, ok)
+
+	cfg.Compressor = "missing"
+	assert.Equal(t, ErrInvalidArgument, cfg.Validate())
+}
@@ -0,0 +1,44 @@
+package rollingwriter
+
+import (
+	"context"
+)
+
+// writer关闭时关闭的channel，用于结束跟随context的协程
+type closeNotifier interface {
+	closingCh() <-chan struct{}
+}
+
+func (w *Writer) closingCh() <-chan struct{} {
+	return w.closing
+}
+
+// 生成跟随context生命周期的RollingWriter，context取消时停止滚动管理协程，
+// 处理完async模式队列中的日志并关闭日志文件，适合交给服务的生命周期框架管理
+// writer先被Close时不再等待context
+func NewWriterContext(ctx context.Context, c *Config) (RollingWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w, err := NewWriterFromConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return w, nil
+	}
+	var closing <-chan struct{}
+	if n, ok := w.(closeNotifier); ok {
+		closing = n.closingCh()
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := w.Close(); err != nil && err != ErrClosed {
+				c.handleError("close writer on context done", err)
+			}
+		case <-closing:
+		}
+	}()
+	return w, nil
+}
@@ -0,0 +1,44 @@
+package rollingwriter
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWriterContext(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := NewDefaultConfig()
+	cfg.LogPath = dir
+	cfg.WriterMode = "async"
+	cfg.RollingPolicy = WithoutRolling
+	w, err := NewWriterContext(ctx, &cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 100; i++ {
+		_, err = w.Write([]byte("line\n"))
+		assert.NoError(t, err)
+	}
+	cancel()
+	assert.Eventually(t, func() bool {
+		return w.Close() == ErrClosed
+	}, time.Second, 10*time.Millisecond)
+	buf, _ := ioutil.ReadFile(dir + "/log.log")
+	assert.Equal(t, 500, len(buf))
+
+	_, err = NewWriterContext(ctx, &cfg)
+	assert.Equal(t, context.Canceled, err)
+
+	// 先关闭writer时跟随context的协程退出
+	cfg.FileName = "lazy"
+	cfg.LazyOpen = true
+	w, err = NewWriterContext(context.Background(), &cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+}
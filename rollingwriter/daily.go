@@ -0,0 +1,55 @@
+package rollingwriter
+
+import (
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// 日志文件所在的目录，t为日志文件的开始时间，配置了DirLayout时为LogPath下按日期命名的子目录
+func (c *Config) logDir(t time.Time) string {
+	if c.DirLayout == "" {
+		return c.LogPath
+	}
+	return path.Join(c.LogPath, t.Format(c.DirLayout))
+}
+
+// 判断目录名称是否为按DirLayout命名的日期目录
+func (c *Config) isDailyDir(name string) bool {
+	if c.DirLayout == "" {
+		return false
+	}
+	_, err := time.Parse(c.DirLayout, name)
+	return err == nil
+}
+
+// 校验按日期分目录的配置，日期格式只能生成一级目录
+// copytruncate不移动当前日志文件，不能与按日期分目录同时使用
+func (c *Config) checkDirLayout() error {
+	if c.DirLayout == "" {
+		return nil
+	}
+	if strings.Contains(c.DirLayout, "/") || c.RotationStrategy == "copytruncate" {
+		return ErrInvalidArgument
+	}
+	return nil
+}
+
+// 删除历史日志文件后清理空的日期目录，目录不为空时不做处理
+func (c *Config) removeEmptyDir(dir string) {
+	if dir == c.LogPath || !c.isDailyDir(path.Base(dir)) {
+		return
+	}
+	_ = os.Remove(dir)
+}
+
+// 原子性的获取当前日志文件路径，按日期分目录时滚动后会改变
+func (w *Writer) path() string {
+	return w.absPath.Load()
+}
+
+// 原子性的更新当前日志文件路径
+func (w *Writer) setPath(name string) {
+	w.absPath.Store(name)
+}
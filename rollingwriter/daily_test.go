@@ -0,0 +1,68 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 只修改当前时间的时钟
+type stubClock struct {
+	realClock
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *stubClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// 修改当前时间，后台协程可能同时读取
+func (c *stubClock) set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func TestDirLayout(t *testing.T) {
+	clock := &stubClock{now: time.Date(2024, 5, 1, 23, 0, 0, 0, time.Local)}
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "app"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	cfg.DirLayout = "2006-01-02"
+	cfg.Clock = clock
+
+	cfg.RotationStrategy = "copytruncate"
+	_, err := NewWriterFromConfig(&cfg)
+	assert.Equal(t, ErrInvalidArgument, err)
+	cfg.RotationStrategy = "rename"
+
+	rw, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w := rw.(*Writer)
+	defer w.Close()
+	assert.Equal(t, path.Join(cfg.LogPath, "2024-05-01", "app.log"), w.path())
+	w.Write([]byte("first\n"))
+
+	clock.set(clock.Now().Add(2 * time.Hour))
+	archive := w.m.(*manager).GenLogFileName(&cfg)
+	assert.Equal(t, path.Join(cfg.LogPath, "2024-05-01", "app.log.202405012300"), archive)
+	assert.Nil(t, w.Reopen(archive))
+	assert.Equal(t, path.Join(cfg.LogPath, "2024-05-02", "app.log"), w.path())
+	w.Write([]byte("second\n"))
+
+	buf, _ := ioutil.ReadFile(archive)
+	assert.Equal(t, "first\n", string(buf))
+	buf, _ = ioutil.ReadFile(path.Join(cfg.LogPath, "2024-05-02", "app.log"))
+	assert.Equal(t, "second\n", string(buf))
+}
@@ -0,0 +1,224 @@
+package rollingwriter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 写入日志文件超过WriteTimeout时返回的错误
+var ErrWriteTimeout = errors.New("error write timeout")
+
+// 写入超时的事件类型
+const (
+	EventDegraded  = "degraded"  // 写入超时，之后的日志写入降级输出，直到卡住的写入完成
+	EventRecovered = "recovered" // 卡住的写入已完成，恢复写入日志文件
+)
+
+// deadlineWriter中写入协程的状态
+const (
+	deadlineIdle    int32 = iota // 空闲
+	deadlineBusy                 // 正在写入，调用方等待结果
+	deadlineStalled              // 写入超时，调用方已返回
+)
+
+// 为写入设置超时的writer，避免NFS、FUSE等挂载点无响应时应用的协程一直阻塞
+// 写入在后台协程中执行，超时后该条及之后的日志写入降级输出（默认为标准错误），
+// 直到卡住的写入完成后自动恢复，期间Healthy返回false
+type deadlineWriter struct {
+	timeouts uint64 // 原子操作的字段放在开头，保证32位平台上8字节对齐
+	state    int32
+	RollingWriter
+	cf      *Config
+	timeout time.Duration
+	sink    io.Writer
+	pool    *bufferPool
+	reqs    chan deadlineReq
+	mu      sync.Mutex // 保证同一时间只有一个等待结果的写入
+	closed  bool
+}
+
+type deadlineReq struct {
+	buf  *[]byte
+	done chan deadlineResult
+}
+
+type deadlineResult struct {
+	n   int
+	err error
+}
+
+// 配置了WriteTimeout时为writer设置写入超时
+func (c *Config) withDeadline(w RollingWriter) RollingWriter {
+	if c.WriteTimeout <= 0 {
+		return w
+	}
+	sink := c.DegradedWriter
+	if sink == nil {
+		sink = os.Stderr
+	}
+	d := &deadlineWriter{
+		RollingWriter: w,
+		cf:            c,
+		timeout:       time.Duration(c.WriteTimeout) * time.Millisecond,
+		sink:          sink,
+		pool:          newBufferPool(c.BufferSize),
+		reqs:          make(chan deadlineReq),
+	}
+	go d.loop()
+	return d
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	// 下层writer可能在超时后仍在使用数据，写入副本
+	return w.submit(w.pool.Copy(b))
+}
+
+func (w *deadlineWriter) WriteString(s string) (int, error) {
+	buf := w.pool.Get(len(s))
+	*buf = append((*buf)[:0], s...)
+	return w.submit(buf)
+}
+
+// 按行分块读取，每块与Write一样受WriteTimeout限制
+func (w *deadlineWriter) ReadFrom(r io.Reader) (int64, error) {
+	return readLines(w.pool, r, w.submit)
+}
+
+// 在后台写入buf并等待结果，写入完成后归还buf
+// 超时后卡住的写入仍在读取buf，buf只用于降级输出，不再归还缓存池
+func (w *deadlineWriter) submit(buf *[]byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		w.pool.Put(buf)
+		return 0, ErrClosed
+	}
+	// 上一次超时的写入还没有完成，直接降级输出
+	if atomic.LoadInt32(&w.state) == deadlineStalled {
+		n, err := w.degrade(*buf)
+		w.pool.Put(buf)
+		return n, err
+	}
+	req := deadlineReq{buf: buf, done: make(chan deadlineResult, 1)}
+	atomic.StoreInt32(&w.state, deadlineBusy)
+	w.reqs <- req
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-req.done:
+		w.pool.Put(buf)
+		return r.n, r.err
+	case <-timer.C:
+	}
+	// 超时与写入完成同时发生时以写入结果为准
+	if !atomic.CompareAndSwapInt32(&w.state, deadlineBusy, deadlineStalled) {
+		r := <-req.done
+		w.pool.Put(buf)
+		return r.n, r.err
+	}
+	atomic.AddUint64(&w.timeouts, 1)
+	w.cf.handleError("write", ErrWriteTimeout)
+	w.emit(EventDegraded, ErrWriteTimeout)
+	return w.degrade(*buf)
+}
+
+// 降级输出，返回ErrWriteTimeout让调用方知道日志没有写入日志文件
+func (w *deadlineWriter) degrade(b []byte) (int, error) {
+	w.sink.Write(b)
+	return len(b), ErrWriteTimeout
+}
+
+// 在后台执行实际的写入
+func (w *deadlineWriter) loop() {
+	for req := range w.reqs {
+		n, err := w.RollingWriter.Write(*req.buf)
+		if atomic.CompareAndSwapInt32(&w.state, deadlineBusy, deadlineIdle) {
+			req.done <- deadlineResult{n, err}
+			continue
+		}
+		// 超时的写入最终完成，调用方已经返回
+		if err != nil {
+			w.cf.handleError("write", err)
+		}
+		w.emit(EventRecovered, nil)
+		atomic.StoreInt32(&w.state, deadlineIdle)
+	}
+}
+
+func (w *deadlineWriter) emit(typ string, err error) {
+	if w.cf.OnEvent == nil {
+		return
+	}
+	w.cf.OnEvent(Event{Type: typ, Time: w.cf.clock().Now(), File: LogFilePath(w.cf), Err: err})
+}
+
+// 是否正常写入日志文件，写入超时后直到卡住的写入完成前为false
+func (w *deadlineWriter) Healthy() bool {
+	if atomic.LoadInt32(&w.state) == deadlineStalled {
+		return false
+	}
+	if h, ok := w.RollingWriter.(interface{ Healthy() bool }); ok {
+		return h.Healthy()
+	}
+	return true
+}
+
+// 关闭下层writer，关闭同样受WriteTimeout限制，超时后在后台继续关闭
+func (w *deadlineWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	close(w.reqs)
+	w.mu.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		done <- w.RollingWriter.Close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(w.timeout):
+		return ErrWriteTimeout
+	}
+}
+
+// 交接下层writer的日志文件
+func (w *deadlineWriter) Handover() (*os.File, string, error) {
+	if h, ok := w.RollingWriter.(handoverer); ok {
+		return h.Handover()
+	}
+	return nil, "", ErrInvalidArgument
+}
+
+// 下层writer的运行统计，包括写入超时的次数和当前是否降级
+func (w *deadlineWriter) Stats() Stats {
+	var s Stats
+	if st, ok := w.RollingWriter.(interface{ Stats() Stats }); ok {
+		s = st.Stats()
+	}
+	s.WriteTimeouts = atomic.LoadUint64(&w.timeouts)
+	s.Degraded = atomic.LoadInt32(&w.state) == deadlineStalled
+	return s
+}
+
+// 立即执行一次日志滚动
+func (w *deadlineWriter) Rotate() {
+	if r, ok := w.RollingWriter.(interface{ Rotate() }); ok {
+		r.Rotate()
+	}
+}
+
+// writer实际生效的配置，包括默认值
+func (w *deadlineWriter) Config() Config {
+	if c, ok := w.RollingWriter.(interface{ Config() Config }); ok {
+		return c.Config()
+	}
+	return w.cf.resolved()
+}
@@ -0,0 +1,136 @@
+package rollingwriter
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 写入时阻塞直到release被关闭的writer，模拟无响应的挂载点
+type stallWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	stall   bool
+	release chan struct{}
+}
+
+func (w *stallWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	stall := w.stall
+	w.mu.Unlock()
+	if stall {
+		<-w.release
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *stallWriter) Close() error {
+	return nil
+}
+
+func (w *stallWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestWriteTimeout(t *testing.T) {
+	var sink bytes.Buffer
+	var events []string
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	WithWriteTimeout(50*time.Millisecond, &sink)(&cfg)
+	cfg.OnEvent = func(e Event) {
+		events = append(events, e.Type)
+	}
+	inner := &stallWriter{release: make(chan struct{})}
+	w := cfg.withDeadline(inner).(*deadlineWriter)
+
+	n, err := w.Write([]byte("ok\n"))
+	assert.Equal(t, 3, n)
+	assert.NoError(t, err)
+	assert.True(t, w.Healthy())
+
+	inner.mu.Lock()
+	inner.stall = true
+	inner.mu.Unlock()
+	start := time.Now()
+	n, err = w.Write([]byte("stalled\n"))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, 8, n)
+	assert.Equal(t, ErrWriteTimeout, err)
+	assert.False(t, w.Healthy())
+	// 降级期间不再等待
+	_, err = w.Write([]byte("degraded\n"))
+	assert.Equal(t, ErrWriteTimeout, err)
+	assert.Equal(t, "stalled\ndegraded\n", sink.String())
+	assert.Equal(t, Stats{WriteTimeouts: 1, Degraded: true}, w.Stats())
+
+	inner.mu.Lock()
+	inner.stall = false
+	inner.mu.Unlock()
+	close(inner.release)
+	assert.Eventually(t, w.Healthy, time.Second, 10*time.Millisecond)
+	_, err = w.Write([]byte("recovered\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\nstalled\nrecovered\n", inner.String())
+	assert.Equal(t, []string{EventDegraded, EventRecovered}, events)
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, ErrClosed, w.Close())
+	_, err = w.Write([]byte("closed\n"))
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestWriteTimeoutDisabled(t *testing.T) {
+	cfg := NewDefaultConfig()
+	inner := &stallWriter{}
+	assert.Equal(t, RollingWriter(inner), cfg.withDeadline(inner))
+}
+
+func TestWriteTimeoutStringAndReader(t *testing.T) {
+	var sink bytes.Buffer
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	WithWriteTimeout(time.Second, &sink)(&cfg)
+	inner := &stallWriter{}
+	w := cfg.withDeadline(inner)
+
+	n, err := w.(io.StringWriter).WriteString("string\n")
+	assert.Equal(t, 7, n)
+	assert.NoError(t, err)
+	m, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("a\nb\n"))
+	assert.Equal(t, int64(4), m)
+	assert.NoError(t, err)
+	assert.Equal(t, "string\na\nb\n", inner.String())
+	assert.Empty(t, sink.String())
+	assert.NoError(t, w.Close())
+}
+
+func TestWriteTimeoutHandover(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(WithLogPath(dir), WithLock(), WithoutRollingPolicy(), WithWriteTimeout(time.Second, ioutil.Discard))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	_, err = w.Write([]byte("before\n"))
+	assert.NoError(t, err)
+
+	cmd := &exec.Cmd{}
+	if !assert.NoError(t, PrepareHandover(cmd, w)) {
+		return
+	}
+	assert.Len(t, cmd.ExtraFiles, 1)
+	assert.True(t, strings.HasSuffix(cmd.Env[len(cmd.Env)-1], "/log.log=3"))
+	assert.Equal(t, ErrHandedOver, PrepareHandover(&exec.Cmd{}, w))
+}
@@ -0,0 +1,315 @@
+package rollingwriter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 磁盘剩余空间低于危险水位时丢弃日志返回的错误
+var ErrDiskFull = errors.New("error disk free space below critical watermark")
+
+// 磁盘剩余空间的事件类型
+const (
+	EventDiskLow      = "disk_low"      // 剩余空间低于低水位，立即压缩并清理最旧的历史日志文件
+	EventDiskCritical = "disk_critical" // 剩余空间低于危险水位，日志不再写入日志文件
+	EventDiskOK       = "disk_ok"       // 剩余空间恢复到低水位以上
+)
+
+// 磁盘剩余空间的状态
+const (
+	diskOK int32 = iota
+	diskLow
+	diskCritical
+)
+
+var _diskStateNames = [...]string{"ok", "low", "critical"}
+
+// 日志所在磁盘的剩余空间和总空间，不支持的平台返回错误，不启用水位检查
+var diskUsage = statDisk
+
+// 磁盘剩余空间的状态，各写入模式共享
+type diskState struct {
+	free    uint64 // 原子操作的字段放在开头，保证32位平台上8字节对齐
+	dropped uint64
+	state   int32
+	mu      sync.Mutex
+	low     chan struct{} // 进入低水位时关闭，通知等待中的压缩立即执行
+}
+
+func newDiskState() *diskState {
+	return &diskState{low: make(chan struct{})}
+}
+
+func (d *diskState) level() int32 {
+	return atomic.LoadInt32(&d.state)
+}
+
+// 低于低水位时关闭的chan
+func (d *diskState) lowCh() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.low
+}
+
+// 更新状态，返回状态是否变化
+func (d *diskState) set(free uint64, state int32) bool {
+	atomic.StoreUint64(&d.free, free)
+	old := atomic.SwapInt32(&d.state, state)
+	if old == state {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if old == diskOK {
+		close(d.low)
+	} else if state == diskOK {
+		d.low = make(chan struct{})
+	}
+	return true
+}
+
+// 是否配置了磁盘剩余空间水位
+func (c *Config) diskWatermark() bool {
+	return c.DiskLowWatermark != "" || c.DiskCriticalWatermark != ""
+}
+
+// 解析水位，支持大小（格式与RollingVolumeSize相同）和磁盘总空间的百分比（如10%）
+func parseWatermark(s string, total uint64) uint64 {
+	if s == "" {
+		return 0
+	}
+	if strings.HasSuffix(s, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || p <= 0 {
+			return 0
+		}
+		return uint64(float64(total) * p / 100)
+	}
+	return uint64(ParseSize(s))
+}
+
+// 检查水位配置
+func (c *Config) checkWatermark() error {
+	for _, s := range []string{c.DiskLowWatermark, c.DiskCriticalWatermark} {
+		if !strings.HasSuffix(s, "%") {
+			continue
+		}
+		if p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64); err != nil || p <= 0 || p >= 100 {
+			return ErrInvalidArgument
+		}
+	}
+	switch c.DiskCriticalMode {
+	case "", "stderr", "drop":
+	default:
+		return ErrInvalidArgument
+	}
+	return nil
+}
+
+// 每Precision秒检查一次日志所在磁盘的剩余空间，关闭writer时停止
+func (w *Writer) watchDisk() {
+	if _, _, err := diskUsage(w.cf.LogPath); err != nil {
+		w.handleError("check disk free space", err)
+		return
+	}
+	w.checkDisk()
+	go func() {
+		ticker := w.cf.clock().NewTicker(time.Duration(Precision) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.closing:
+				return
+			case <-ticker.C():
+				w.checkDisk()
+			}
+		}
+	}()
+}
+
+// 按剩余空间更新状态，低于低水位时清理最旧的历史日志文件直到恢复到低水位以上
+func (w *Writer) checkDisk() {
+	free, total, err := diskUsage(w.cf.LogPath)
+	if err != nil {
+		w.handleError("check disk free space", err)
+		return
+	}
+	low := parseWatermark(w.cf.DiskLowWatermark, total)
+	if low > 0 && free < low {
+		if w.disk.set(free, w.diskLevel(free, total)) {
+			w.emitDisk()
+		}
+		w.reclaim(low)
+		if free, _, err = diskUsage(w.cf.LogPath); err != nil {
+			return
+		}
+	}
+	if w.disk.set(free, w.diskLevel(free, total)) {
+		w.emitDisk()
+	}
+}
+
+func (w *Writer) diskLevel(free, total uint64) int32 {
+	if critical := parseWatermark(w.cf.DiskCriticalWatermark, total); critical > 0 && free < critical {
+		return diskCritical
+	}
+	if low := parseWatermark(w.cf.DiskLowWatermark, total); low > 0 && free < low {
+		return diskLow
+	}
+	return diskOK
+}
+
+func (w *Writer) emitDisk() {
+	switch w.disk.level() {
+	case diskLow:
+		w.emit(EventDiskLow, "", nil)
+	case diskCritical:
+		w.emit(EventDiskCritical, "", ErrDiskFull)
+	default:
+		w.emit(EventDiskOK, "", nil)
+	}
+}
+
+// 从最旧的开始删除历史日志文件，直到剩余空间不低于target，不移动到回收目录，dry-run时只记录
+func (w *Writer) reclaim(target uint64) {
+	w.sweepMu.Lock()
+	defer w.sweepMu.Unlock()
+	archives, err := ListArchives(w.cf)
+	if err != nil {
+		w.handleError("list archives", err)
+		return
+	}
+	now := w.cf.clock().Now()
+	for _, a := range archives {
+		free, _, err := diskUsage(w.cf.LogPath)
+		if err != nil || free >= target {
+			return
+		}
+		r := RetentionRecord{
+			Time:   now,
+			File:   a.Path,
+			Size:   a.Size,
+			Age:    int64(now.Sub(a.ModTime) / time.Second),
+			Reason: "disk_low",
+			DryRun: w.cf.RetentionDryRun,
+		}
+		if !r.DryRun {
+			if err := os.Remove(a.Path); err != nil {
+				w.handleError("remove log file", err)
+				r.Error = err.Error()
+			} else {
+				os.Remove(a.Path + ChecksumSuffix)
+				w.cf.removeEmptyDir(path.Dir(a.Path))
+			}
+		}
+		w.recordRetention(r)
+	}
+}
+
+// 剩余空间低于危险水位时不写入日志文件的writer
+// DiskCriticalMode为stderr时日志输出到标准错误，为drop时丢弃，都返回ErrDiskFull
+type diskGuardWriter struct {
+	RollingWriter
+	disk *diskState
+	sink io.Writer // 为nil时丢弃
+	pool *bufferPool
+}
+
+// 配置了水位时为writer设置剩余空间检查
+func (c *Config) withDiskGuard(w RollingWriter, disk *diskState) RollingWriter {
+	if disk == nil {
+		return w
+	}
+	g := &diskGuardWriter{RollingWriter: w, disk: disk, pool: newBufferPool(c.BufferSize)}
+	if c.DiskCriticalMode != "drop" {
+		g.sink = os.Stderr
+	}
+	return g
+}
+
+func (w *diskGuardWriter) Write(b []byte) (int, error) {
+	if w.disk.level() != diskCritical {
+		return w.RollingWriter.Write(b)
+	}
+	atomic.AddUint64(&w.disk.dropped, 1)
+	if w.sink != nil {
+		w.sink.Write(b)
+	}
+	return len(b), ErrDiskFull
+}
+
+func (w *diskGuardWriter) WriteString(s string) (int, error) {
+	if w.disk.level() != diskCritical {
+		return io.WriteString(w.RollingWriter, s)
+	}
+	atomic.AddUint64(&w.disk.dropped, 1)
+	if w.sink != nil {
+		io.WriteString(w.sink, s)
+	}
+	return len(s), ErrDiskFull
+}
+
+// 开始时剩余空间正常则交给下层writer读取，否则按行分块经过Write降级输出
+func (w *diskGuardWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := w.RollingWriter.(io.ReaderFrom); ok && w.disk.level() != diskCritical {
+		return rf.ReadFrom(r)
+	}
+	return readFrom(w, w.pool, r)
+}
+
+// 是否正常写入日志文件，剩余空间低于危险水位时为false
+func (w *diskGuardWriter) Healthy() bool {
+	if w.disk.level() == diskCritical {
+		return false
+	}
+	if h, ok := w.RollingWriter.(interface{ Healthy() bool }); ok {
+		return h.Healthy()
+	}
+	return true
+}
+
+// 交接下层writer的日志文件
+func (w *diskGuardWriter) Handover() (*os.File, string, error) {
+	if h, ok := w.RollingWriter.(handoverer); ok {
+		return h.Handover()
+	}
+	return nil, "", ErrInvalidArgument
+}
+
+// 下层writer的运行统计
+func (w *diskGuardWriter) Stats() Stats {
+	if st, ok := w.RollingWriter.(interface{ Stats() Stats }); ok {
+		return st.Stats()
+	}
+	return Stats{}
+}
+
+// 立即执行一次日志滚动
+func (w *diskGuardWriter) Rotate() {
+	if r, ok := w.RollingWriter.(interface{ Rotate() }); ok {
+		r.Rotate()
+	}
+}
+
+// writer实际生效的配置，包括默认值
+func (w *diskGuardWriter) Config() Config {
+	if c, ok := w.RollingWriter.(interface{ Config() Config }); ok {
+		return c.Config()
+	}
+	return Config{}
+}
+
+// 磁盘剩余空间低于低水位时关闭的chan，没有配置水位时为nil
+func (w *Writer) diskLow() <-chan struct{} {
+	if w.disk == nil {
+		return nil
+	}
+	return w.disk.lowCh()
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package rollingwriter
+
+import "errors"
+
+func statDisk(dir string) (free, total uint64, err error) {
+	return 0, 0, errors.New("error disk free space not supported")
+}
@@ -0,0 +1,146 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWatermark(t *testing.T) {
+	assert.Equal(t, uint64(0), parseWatermark("", 1000))
+	assert.Equal(t, uint64(50), parseWatermark("5%", 1000))
+	assert.Equal(t, uint64(2*1024*1024), parseWatermark("2mb", 1000))
+
+	cfg := NewDefaultConfig()
+	WithDiskWatermark("120%", "", "")(&cfg)
+	assert.Equal(t, ErrInvalidArgument, cfg.Validate())
+	WithDiskWatermark("10%", "1G", "panic")(&cfg)
+	assert.Equal(t, ErrInvalidArgument, cfg.Validate())
+	WithDiskWatermark("10%", "1G", "drop")(&cfg)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestDiskWatermark(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"unittest.log.202001010000", "unittest.log.202001020000", "unittest.log.202001030000"} {
+		assert.NoError(t, ioutil.WriteFile(path.Join(dir, name), make([]byte, 100), 0644))
+	}
+	// 磁盘总空间10000，历史日志文件之外已使用的空间为reserved
+	var mu sync.Mutex
+	reserved := uint64(9400)
+	defer func(f func(string) (uint64, uint64, error)) { diskUsage = f }(diskUsage)
+	diskUsage = func(string) (uint64, uint64, error) {
+		infos, _ := ioutil.ReadDir(dir)
+		used := uint64(0)
+		for _, fi := range infos {
+			if strings.HasPrefix(fi.Name(), "unittest.log.") && !IsArtifact(fi.Name()) {
+				used += uint64(fi.Size())
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return 10000 - reserved - used, 10000, nil
+	}
+
+	var events []string
+	var reasons []string
+	var emu sync.Mutex
+	cfg := NewDefaultConfig()
+	cfg.LogPath = dir
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	WithDiskWatermark("5%", "2%", "drop")(&cfg)
+	cfg.OnEvent = func(e Event) {
+		emu.Lock()
+		defer emu.Unlock()
+		if strings.HasPrefix(e.Type, "disk_") {
+			events = append(events, e.Type)
+		}
+	}
+	cfg.OnRetention = func(r RetentionRecord) {
+		emu.Lock()
+		defer emu.Unlock()
+		reasons = append(reasons, path.Base(r.File)+" "+r.Reason)
+	}
+	rw, err := NewWriterFromConfig(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rw.Close()
+
+	// 剩余300低于低水位500，删除最旧的两个历史日志文件后恢复
+	emu.Lock()
+	assert.Equal(t, []string{EventDiskLow, EventDiskOK}, events)
+	assert.Equal(t, []string{"unittest.log.202001010000 disk_low", "unittest.log.202001020000 disk_low"}, reasons)
+	emu.Unlock()
+	writer := rw.(*diskGuardWriter).RollingWriter.(*Writer)
+	assert.Equal(t, "ok", writer.Stats().DiskState)
+	_, err = rw.Write([]byte("ok\n"))
+	assert.NoError(t, err)
+	guard := rw.(*diskGuardWriter)
+	assert.True(t, guard.Healthy())
+	_, err = guard.WriteString("string\n")
+	assert.NoError(t, err)
+	_, err = guard.ReadFrom(strings.NewReader("reader\n"))
+	assert.NoError(t, err)
+
+	// 删除全部历史日志文件后剩余空间仍低于危险水位，丢弃日志
+	mu.Lock()
+	reserved = 9850
+	mu.Unlock()
+	writer.checkDisk()
+	n, err := rw.Write([]byte("dropped\n"))
+	assert.Equal(t, 8, n)
+	assert.Equal(t, ErrDiskFull, err)
+	assert.False(t, guard.Healthy())
+	n, err = guard.WriteString("dropped\n")
+	assert.Equal(t, 8, n)
+	assert.Equal(t, ErrDiskFull, err)
+	m, err := guard.ReadFrom(strings.NewReader("dropped\n"))
+	assert.Equal(t, int64(8), m)
+	assert.Equal(t, ErrDiskFull, err)
+	stats := writer.Stats()
+	assert.Equal(t, "critical", stats.DiskState)
+	assert.Equal(t, uint64(150), stats.DiskFree)
+	assert.Equal(t, uint64(3), atomic.LoadUint64(&writer.disk.dropped))
+	buf, _ := ioutil.ReadFile(LogFilePath(&cfg))
+	assert.Equal(t, "ok\nstring\nreader\n", string(buf))
+
+	// 低于低水位时等待中的压缩立即执行
+	select {
+	case <-writer.diskLow():
+	default:
+		t.Error("compression not released")
+	}
+	emu.Lock()
+	assert.Equal(t, []string{EventDiskLow, EventDiskOK, EventDiskCritical}, events)
+	emu.Unlock()
+}
+
+func TestDiskGuardHandover(t *testing.T) {
+	defer func(f func(string) (uint64, uint64, error)) { diskUsage = f }(diskUsage)
+	diskUsage = func(string) (uint64, uint64, error) {
+		return 5000, 10000, nil
+	}
+	dir := t.TempDir()
+	w, err := NewWriter(WithLogPath(dir), WithLock(), WithoutRollingPolicy(), WithDiskWatermark("5%", "2%", "drop"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	_, ok := w.(*diskGuardWriter)
+	assert.True(t, ok)
+
+	cmd := &exec.Cmd{}
+	if !assert.NoError(t, PrepareHandover(cmd, w)) {
+		return
+	}
+	assert.Len(t, cmd.ExtraFiles, 1)
+	assert.True(t, strings.HasSuffix(cmd.Env[len(cmd.Env)-1], "/log.log=3"))
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package rollingwriter
+
+import "syscall"
+
+// 非特权进程可用的剩余空间和总空间
+func statDisk(dir string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil
+}
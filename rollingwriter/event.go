@@ -0,0 +1,44 @@
+package rollingwriter
+
+import (
+	"errors"
+	"time"
+)
+
+// 滚动事件的类型
+const (
+	EventRotationStarted   = "rotation_started"   // 开始滚动当前日志文件
+	EventRotationCompleted = "rotation_completed" // 滚动完成或失败，失败时Err不为空
+	EventCompressed        = "compressed"         // 历史日志文件压缩完成，Archive为压缩后的文件
+	EventRetention         = "retention"          // 按保留策略删除或移动了历史日志文件，dry-run时也会产生
+)
+
+// 日志滚动过程中的事件，用于应用或日志采集程序在滚动后做出响应
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	File    string    `json:"file"`             // 当前日志文件
+	Archive string    `json:"archive"`          // 相关的历史日志文件
+	Reason  string    `json:"reason,omitempty"` // 清理原因，同RetentionRecord.Reason
+	Err     error     `json:"-"`
+}
+
+// 交给配置的OnEvent处理
+func (w *Writer) emit(typ, archive string, err error) {
+	if w.cf.OnEvent == nil {
+		return
+	}
+	w.cf.OnEvent(Event{Type: typ, Time: w.cf.clock().Now(), File: w.path(), Archive: archive, Err: err})
+}
+
+// 清理记录对应的事件
+func (w *Writer) emitRetention(r RetentionRecord) {
+	if w.cf.OnEvent == nil {
+		return
+	}
+	var err error
+	if r.Error != "" {
+		err = errors.New(r.Error)
+	}
+	w.cf.OnEvent(Event{Type: EventRetention, Time: r.Time, File: w.path(), Archive: r.File, Reason: r.Reason, Err: err})
+}
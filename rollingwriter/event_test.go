@@ -0,0 +1,62 @@
+package rollingwriter
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	var mu sync.Mutex
+	var events []Event
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "lock"
+	cfg.TimeTagFormat = "20060102150405.000000000"
+	cfg.RollingPolicy = WithoutRolling
+	cfg.Compress = true
+	cfg.MaxRemain = 1
+	cfg.OnEvent = func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+	lw := w.(*LockedWriter)
+	for i := 0; i < 2; i++ {
+		lw.Write([]byte("line\n"))
+		lw.Rotate()
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, e := range events {
+			if e.Type == EventRetention {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+		assert.Equal(t, LogFilePath(&cfg), e.File)
+		assert.NoError(t, e.Err)
+	}
+	assert.Equal(t, []string{EventRotationStarted, EventRotationCompleted, EventCompressed,
+		EventRotationStarted, EventRotationCompleted, EventCompressed, EventRetention}, types)
+	assert.True(t, strings.HasSuffix(events[2].Archive, ".gz"))
+	assert.Equal(t, "max_remain", events[6].Reason)
+}
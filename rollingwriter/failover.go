@@ -0,0 +1,109 @@
+package rollingwriter
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// 故障转移后重新尝试之前writer的默认间隔
+const DefaultFailoverRetry = time.Second
+
+// 按顺序使用多个writer的组合writer，如主日志文件、其他磁盘上的日志文件、标准错误
+// 写入失败或Healthy返回false的writer在retry时间内不再使用，日志写入下一个writer；
+// 之前的writer到达重试时间后用下一条日志尝试，写入成功即切回，保证日志总是写入可用的最靠前的writer
+type FailoverWriter struct {
+	failovers uint64 // 原子操作的字段放在开头，保证32位平台上8字节对齐
+	active    int32
+	mu        sync.Mutex
+	writers   []RollingWriter
+	retryAt   []time.Time // 失败的writer下次尝试的时间
+	retry     time.Duration
+	closed    bool
+}
+
+// 生成FailoverWriter，writers按优先级排列，retry为失败的writer重新尝试的间隔，不大于0时使用DefaultFailoverRetry
+func NewFailoverWriter(retry time.Duration, writers ...RollingWriter) (*FailoverWriter, error) {
+	if len(writers) == 0 {
+		return nil, ErrInvalidArgument
+	}
+	if retry <= 0 {
+		retry = DefaultFailoverRetry
+	}
+	return &FailoverWriter{writers: writers, retryAt: make([]time.Time, len(writers)), retry: retry}, nil
+}
+
+// 写入可用的最靠前的writer，所有writer都失败时返回最后一个错误
+func (w *FailoverWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	now := time.Now()
+	active := int(w.active)
+	var err error
+	for i, writer := range w.writers {
+		// 当前writer总是尝试，其他失败的writer等待重试时间
+		if i != active && now.Before(w.retryAt[i]) {
+			continue
+		}
+		if h, ok := writer.(interface{ Healthy() bool }); ok && !h.Healthy() {
+			w.retryAt[i] = now.Add(w.retry)
+			err = fmt.Errorf("sink %d unhealthy", i)
+			continue
+		}
+		n, e := writer.Write(b)
+		if e == nil && n != len(b) {
+			e = io.ErrShortWrite
+		}
+		if e != nil {
+			w.retryAt[i] = now.Add(w.retry)
+			err = e
+			continue
+		}
+		if i != active {
+			w.switchTo(active, i, err)
+		}
+		return len(b), nil
+	}
+	return 0, err
+}
+
+// 切换当前writer，切换到后面的writer时记录导致切换的错误
+func (w *FailoverWriter) switchTo(from, to int, err error) {
+	atomic.StoreInt32(&w.active, int32(to))
+	if to > from {
+		atomic.AddUint64(&w.failovers, 1)
+		DefaultErrorHandler.HandleError("failover", fmt.Errorf("switch from sink %d to %d: %v", from, to, err))
+	}
+}
+
+// 当前使用的writer的序号
+func (w *FailoverWriter) Active() int {
+	return int(atomic.LoadInt32(&w.active))
+}
+
+// 切换到后面的writer的次数
+func (w *FailoverWriter) Failovers() uint64 {
+	return atomic.LoadUint64(&w.failovers)
+}
+
+// 关闭所有writer，返回所有writer的错误
+func (w *FailoverWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	var err error
+	for _, writer := range w.writers {
+		err = multierr.Append(err, writer.Close())
+	}
+	return err
+}
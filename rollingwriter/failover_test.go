@@ -0,0 +1,65 @@
+package rollingwriter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type unhealthyWriter struct {
+	bufferCloser
+	healthy bool
+}
+
+func (w *unhealthyWriter) Healthy() bool {
+	return w.healthy
+}
+
+func TestFailoverWriter(t *testing.T) {
+	errWrite := errors.New("write failed")
+	primary, secondary, last := &bufferCloser{}, &unhealthyWriter{healthy: true}, &bufferCloser{}
+	w, err := NewFailoverWriter(20*time.Millisecond, primary, secondary, last)
+	if !assert.NoError(t, err) {
+		return
+	}
+	w.Write([]byte("a\n"))
+	assert.Equal(t, "a\n", primary.String())
+
+	// 主writer失败，切换到下一个
+	primary.err = errWrite
+	n, err := w.Write([]byte("b\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "b\n", secondary.String())
+	assert.Equal(t, 1, w.Active())
+	assert.Equal(t, uint64(1), w.Failovers())
+
+	// 不健康的writer同样跳过
+	secondary.healthy = false
+	w.Write([]byte("c\n"))
+	assert.Equal(t, "c\n", last.String())
+	assert.Equal(t, 2, w.Active())
+
+	// 主writer恢复，重试时间到达后切回
+	primary.err = nil
+	w.Write([]byte("d\n"))
+	assert.Equal(t, "c\nd\n", last.String())
+	time.Sleep(30 * time.Millisecond)
+	w.Write([]byte("e\n"))
+	assert.Equal(t, "a\ne\n", primary.String())
+	assert.Equal(t, 0, w.Active())
+
+	// 全部失败时返回错误
+	primary.err, last.err = errWrite, errWrite
+	_, err = w.Write([]byte("f\n"))
+	assert.Equal(t, errWrite, err)
+
+	assert.NoError(t, w.Close())
+	assert.True(t, primary.closed && secondary.closed && last.closed)
+	assert.Equal(t, ErrClosed, w.Close())
+
+	_, err = NewFailoverWriter(0)
+	assert.Equal(t, ErrInvalidArgument, err)
+}
@@ -0,0 +1,63 @@
+package rollingwriter
+
+import (
+	"os"
+)
+
+// 日志文件权限，未配置时使用DefualtFileMode
+func (c *Config) fileMode() os.FileMode {
+	if c.FileMode != 0 {
+		return c.FileMode
+	}
+	return DefualtFileMode
+}
+
+// 日志目录权限，未配置时使用0700
+func (c *Config) dirMode() os.FileMode {
+	if c.DirMode != 0 {
+		return c.DirMode
+	}
+	return 0700
+}
+
+// 按配置修改文件属主，Uid和Gid都为nil时不修改
+func (c *Config) chown(name string) error {
+	if c.Uid == nil && c.Gid == nil {
+		return nil
+	}
+	uid, gid := -1, -1
+	if c.Uid != nil {
+		uid = *c.Uid
+	}
+	if c.Gid != nil {
+		gid = *c.Gid
+	}
+	return os.Chown(name, uid, gid)
+}
+
+// 按配置的权限和属主创建日志目录
+func (c *Config) mkdirAll(dir string) error {
+	if err := os.MkdirAll(dir, c.dirMode()); err != nil {
+		return err
+	}
+	return c.chown(dir)
+}
+
+// 按配置的权限和属主打开日志文件，显式配置了FileMode时会修正已存在文件的权限
+func (c *Config) openFile(name string, flag int) (*os.File, error) {
+	file, err := os.OpenFile(name, flag, c.fileMode())
+	if err != nil {
+		return nil, err
+	}
+	if c.FileMode != 0 {
+		if err := file.Chmod(c.FileMode); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if err := c.chown(name); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,53 @@
+//go:build linux
+// +build linux
+
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileOwner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing owner requires root")
+	}
+	dir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.LogPath = dir
+	cfg.FileName = "unittest"
+	name := LogFilePath(&cfg)
+	assert.Nil(t, ioutil.WriteFile(name, nil, 0644))
+	assert.Nil(t, os.Chown(name, 1, 1))
+
+	// 未配置时不修改属主
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w.Close()
+	assert.Equal(t, [2]int{1, 1}, owner(t, name))
+
+	// 可以修改为root
+	WithOwner(0, -1)(&cfg)
+	w, err = NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w.Close()
+	assert.Equal(t, [2]int{0, 1}, owner(t, name))
+}
+
+// 文件的属主和属组
+func owner(t *testing.T, name string) [2]int {
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := info.Sys().(*syscall.Stat_t)
+	return [2]int{int(st.Uid), int(st.Gid)}
+}
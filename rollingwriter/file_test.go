@@ -0,0 +1,46 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file mode is not supported on windows")
+	}
+	dir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.LogPath = path.Join(dir, "log")
+	cfg.FileName = "unittest"
+	cfg.FileMode = 0600
+	cfg.DirMode = 0750
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+
+	info, err := os.Stat(LogFilePath(&cfg))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	info, err = os.Stat(cfg.LogPath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0750)&^umask(), info.Mode().Perm())
+}
+
+// 读取当前进程的umask
+func umask() os.FileMode {
+	dir, _ := ioutil.TempDir("", "umask")
+	defer os.RemoveAll(dir)
+	name := path.Join(dir, "d")
+	os.Mkdir(name, 0777)
+	info, _ := os.Stat(name)
+	return 0777 &^ info.Mode().Perm()
+}
@@ -0,0 +1,121 @@
+package rollingwriter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 日志过滤规则，Pattern和Contains同时配置时都需要匹配
+type FilterRule struct {
+	Field    string `json:"field" yaml:"field"`       // json日志的字段名，为空时匹配整行日志
+	Pattern  string `json:"pattern" yaml:"pattern"`   // 正则表达式
+	Contains string `json:"contains" yaml:"contains"` // 子字符串
+}
+
+// 日志过滤配置
+// Include不为空时，只保留至少匹配一条Include规则的日志
+// 匹配任意一条Exclude规则的日志会被丢弃
+type FilterConfig struct {
+	Include []FilterRule `json:"include" yaml:"include"`
+	Exclude []FilterRule `json:"exclude" yaml:"exclude"`
+}
+
+// 编译后的过滤规则
+type filterRule struct {
+	field    string
+	re       *regexp.Regexp
+	contains string
+}
+
+// 按规则过滤日志的writer，被过滤的日志不会写入下层writer
+type FilterWriter struct {
+	RollingWriter
+	include []filterRule
+	exclude []filterRule
+	fields  bool // 是否存在按字段匹配的规则
+}
+
+// 生成FilterWriter，规则中的正则表达式不合法时返回错误
+func NewFilterWriter(w RollingWriter, c FilterConfig) (*FilterWriter, error) {
+	fw := &FilterWriter{RollingWriter: w}
+	var err error
+	if fw.include, err = fw.compile(c.Include); err != nil {
+		return nil, err
+	}
+	if fw.exclude, err = fw.compile(c.Exclude); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// 编译过滤规则
+func (w *FilterWriter) compile(rules []FilterRule) ([]filterRule, error) {
+	compiled := make([]filterRule, 0, len(rules))
+	for _, r := range rules {
+		fr := filterRule{field: r.Field, contains: r.Contains}
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			fr.re = re
+		}
+		if r.Field != "" {
+			w.fields = true
+		}
+		compiled = append(compiled, fr)
+	}
+	return compiled, nil
+}
+
+// 写入未被过滤的日志，被过滤的日志同样返回len(b)
+func (w *FilterWriter) Write(b []byte) (int, error) {
+	if !w.Allow(b) {
+		return len(b), nil
+	}
+	return w.RollingWriter.Write(b)
+}
+
+// 判断日志是否需要写入
+func (w *FilterWriter) Allow(b []byte) bool {
+	var fields map[string]interface{}
+	if w.fields {
+		// 非json格式的日志没有字段，字段规则均不匹配
+		_ = json.Unmarshal(b, &fields)
+	}
+	if len(w.include) > 0 && !matchAny(w.include, b, fields) {
+		return false
+	}
+	return !matchAny(w.exclude, b, fields)
+}
+
+// 判断日志是否匹配任意一条规则
+func matchAny(rules []filterRule, b []byte, fields map[string]interface{}) bool {
+	for _, r := range rules {
+		if r.match(b, fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断日志是否匹配规则
+func (r filterRule) match(b []byte, fields map[string]interface{}) bool {
+	target := b
+	if r.field != "" {
+		v, ok := fields[r.field]
+		if !ok {
+			return false
+		}
+		target = []byte(fmt.Sprint(v))
+	}
+	if r.re != nil && !r.re.Match(target) {
+		return false
+	}
+	if r.contains != "" && !strings.Contains(string(target), r.contains) {
+		return false
+	}
+	return true
+}
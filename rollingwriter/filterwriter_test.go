@@ -0,0 +1,33 @@
+package rollingwriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterWriter(t *testing.T) {
+	buf := &bufferCloser{}
+	writer, err := NewFilterWriter(buf, FilterConfig{
+		Include: []FilterRule{{Field: "level", Pattern: "^(WARN|ERROR)$"}},
+		Exclude: []FilterRule{{Contains: "healthcheck"}, {Field: "msg", Pattern: "^known"}},
+	})
+	assert.Nil(t, err)
+
+	lines := []string{
+		`{"level":"INFO","msg":"hello"}` + "\n",
+		`{"level":"ERROR","msg":"boom"}` + "\n",
+		`{"level":"WARN","msg":"healthcheck slow"}` + "\n",
+		`{"level":"WARN","msg":"known benign"}` + "\n",
+		"plain text line\n",
+	}
+	for _, l := range lines {
+		n, err := writer.Write([]byte(l))
+		assert.Nil(t, err)
+		assert.Equal(t, len(l), n)
+	}
+	assert.Equal(t, lines[1], buf.String())
+
+	_, err = NewFilterWriter(buf, FilterConfig{Exclude: []FilterRule{{Pattern: "("}}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,113 @@
+package rollingwriter
+
+import (
+	"compress/gzip"
+	"os"
+	"sync"
+	"time"
+)
+
+// gzip模式默认的刷新间隔
+const _defaultGzipFlushInterval = time.Second
+
+// 当WriterMode为gzip时使用的结构，并发安全
+// 当前日志文件直接以gzip流写入，每个刷新间隔写入一个刷新点，刷新点之前的日志可以被zcat读取，
+// 每次打开或滚动开始一个新的gzip成员，不能与Compress、Header和Footer同时使用
+type GzipWriter struct {
+	Writer
+	sync.Mutex
+	gz  *gzip.Writer
+	out *os.File // gz当前写入的文件
+}
+
+func init() {
+	RegisterWriterMode("gzip", func(c Config, w Writer) (RollingWriter, error) {
+		// 历史文件已经是gzip格式，头尾信息会破坏gzip流
+		if c.Compress || c.Header != "" || c.Footer != "" {
+			return nil, ErrInvalidArgument
+		}
+		gw := &GzipWriter{Writer: w, out: w.current()}
+		gw.gz = gzip.NewWriter(gw.out)
+		interval := time.Duration(c.FlushInterval) * time.Millisecond
+		if interval <= 0 {
+			interval = _defaultGzipFlushInterval
+		}
+		go gw.flusher(interval)
+		return gw, nil
+	})
+}
+
+// 压缩后写入当前日志文件
+func (w *GzipWriter) Write(b []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if err := w.reset(); err != nil {
+		return 0, err
+	}
+	n, err := w.gz.Write(b)
+	return w.written(b, n, err)
+}
+
+// 处理日志文件重建，重建后在新文件中开始新的gzip流，需要持有锁
+func (w *GzipWriter) reset() error {
+	if err := w.rolling(); err != nil {
+		return err
+	}
+	if file := w.current(); file != w.out {
+		w.out = file
+		w.gz.Reset(file)
+	}
+	return nil
+}
+
+// 定时写入刷新点，关闭writer时退出
+func (w *GzipWriter) flusher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.Flush()
+		case <-w.closing:
+			return
+		}
+	}
+}
+
+// 将已写入的日志压缩后写入文件
+func (w *GzipWriter) Flush() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.gz.Flush()
+}
+
+// 结束旧文件的gzip流后执行滚动，在新文件中开始新的gzip流
+func (w *GzipWriter) rotate(filename string) {
+	w.Lock()
+	defer w.Unlock()
+	if w.isClosed() {
+		return
+	}
+	if err := w.gz.Close(); err != nil {
+		w.handleError("gzip close", err)
+	}
+	w.Writer.rotate(filename)
+	w.out = w.current()
+	w.gz.Reset(w.out)
+}
+
+// 结束gzip流后停止manager，再关闭文件
+func (w *GzipWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.isClosed() {
+		return ErrClosed
+	}
+	err := w.gz.Close()
+	w.shutdown()
+	if err != nil {
+		w.current().Close()
+		return err
+	}
+	return w.current().Close()
+}
@@ -0,0 +1,54 @@
+package rollingwriter
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readGzip(t *testing.T, name string) string {
+	file, err := os.Open(name)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	// 未结束的gzip流可以读取到最后一个刷新点
+	buf, err := ioutil.ReadAll(r)
+	if err != io.ErrUnexpectedEOF {
+		assert.NoError(t, err)
+	}
+	return string(buf)
+}
+
+func TestGzipWriter(t *testing.T) {
+	dir := t.TempDir()
+	gzipMode := func(c *Config) { c.WriterMode = "gzip" }
+	_, err := NewWriter(WithLogPath(dir), WithoutRollingPolicy(), WithCompress(), gzipMode)
+	assert.Equal(t, ErrInvalidArgument, err)
+
+	w, err := NewWriter(WithLogPath(dir), WithoutRollingPolicy(), gzipMode)
+	if !assert.NoError(t, err) {
+		return
+	}
+	gw := w.(*GzipWriter)
+	_, err = gw.WriteString("first\n")
+	assert.NoError(t, err)
+	assert.NoError(t, gw.Flush())
+	assert.Equal(t, "first\n", readGzip(t, dir+"/log.log"))
+
+	gw.rotate(dir + "/log.log.1")
+	_, err = gw.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "first\n", readGzip(t, dir+"/log.log.1"))
+	assert.Equal(t, "second\n", readGzip(t, dir+"/log.log"))
+}
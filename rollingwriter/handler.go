@@ -0,0 +1,34 @@
+package rollingwriter
+
+import (
+	"log"
+)
+
+// 内部错误的处理接口，接收删除、压缩、滚动等后台操作产生的错误，op为出错的操作
+type ErrorHandler interface {
+	HandleError(op string, err error)
+}
+
+// 函数形式的ErrorHandler
+type ErrorHandlerFunc func(op string, err error)
+
+func (f ErrorHandlerFunc) HandleError(op string, err error) {
+	f(op, err)
+}
+
+// 未配置ErrorHandler时使用的默认处理，通过log包输出
+var DefaultErrorHandler ErrorHandler = ErrorHandlerFunc(func(op string, err error) {
+	log.Println("error in", op, err)
+})
+
+// 将内部错误交给配置的ErrorHandler处理
+func (c *Config) handleError(op string, err error) {
+	if err == nil {
+		return
+	}
+	if c.ErrorHandler != nil {
+		c.ErrorHandler.HandleError(op, err)
+		return
+	}
+	DefaultErrorHandler.HandleError(op, err)
+}
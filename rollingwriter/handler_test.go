@@ -0,0 +1,28 @@
+package rollingwriter
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorHandler(t *testing.T) {
+	var ops []string
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.ErrorHandler = ErrorHandlerFunc(func(op string, err error) {
+		ops = append(ops, op)
+	})
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+
+	// 滚动到不存在的目录
+	w.(*Writer).rotate(path.Join(cfg.LogPath, "missing", "unittest.log.1"))
+	assert.Equal(t, []string{"rotate log file"}, ops)
+}
@@ -0,0 +1,153 @@
+package rollingwriter
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 热重启时交接日志文件的环境变量，值为 日志文件绝对路径=文件描述符，多个文件以;分隔
+const HandoverEnv = "LOGX_HANDOVER_FDS"
+
+var ErrHandedOver = errors.New("error log file handed over")
+
+// 日志文件的交接状态，各写入模式共享
+type handoverState struct {
+	sync.Mutex // 保护日志滚动与交接
+	done       bool
+}
+
+// 支持交接日志文件的RollingWriter
+type handoverer interface {
+	Handover() (*os.File, string, error)
+}
+
+// 将日志文件交接给子进程，返回当前写入的日志文件及其路径
+// 交接后当前进程不再滚动和监测日志文件，继续以追加方式写入直到退出，滚动和历史日志的清理由子进程负责，
+// 两个进程的日志都写入同一个文件，切换期间不会丢失日志
+func (w *Writer) Handover() (*os.File, string, error) {
+	w.handover.Lock()
+	defer w.handover.Unlock()
+	if w.handover.done {
+		return nil, "", ErrHandedOver
+	}
+	w.handover.done = true
+	return w.current(), w.path(), nil
+}
+
+// 日志文件是否已交接给子进程
+func (w *Writer) handedOver() bool {
+	w.handover.Lock()
+	defer w.handover.Unlock()
+	return w.handover.done
+}
+
+// 没有写入过时没有需要交接的日志文件，子进程自行创建
+func (w *LazyWriter) Handover() (*os.File, string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, "", ErrClosed
+	}
+	if w.writer == nil {
+		return nil, "", nil
+	}
+	h, ok := w.writer.(handoverer)
+	if !ok {
+		return nil, "", ErrInvalidArgument
+	}
+	return h.Handover()
+}
+
+// 将writers的日志文件交接给即将启动的子进程，文件加入cmd.ExtraFiles并通过HandoverEnv告知子进程
+// 子进程使用相同的配置创建writer时直接使用交接的文件，在cmd.Start之前调用
+func PrepareHandover(cmd *exec.Cmd, writers ...RollingWriter) error {
+	var entries []string
+	for _, w := range writers {
+		h, ok := w.(handoverer)
+		if !ok {
+			return ErrInvalidArgument
+		}
+		file, name, err := h.Handover()
+		if err != nil {
+			return err
+		}
+		if file == nil {
+			continue
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return err
+		}
+		// ExtraFiles中的第i个文件在子进程中的描述符为3+i
+		entries = append(entries, abs+"="+strconv.Itoa(3+len(cmd.ExtraFiles)))
+		cmd.ExtraFiles = append(cmd.ExtraFiles, file)
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	env := cmd.Env[:0]
+	for _, e := range cmd.Env {
+		if !strings.HasPrefix(e, HandoverEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	cmd.Env = append(env, HandoverEnv+"="+strings.Join(entries, ";"))
+	return nil
+}
+
+var (
+	inheritedOnce sync.Once
+	inheritedMu   sync.Mutex
+	inherited     map[string]*os.File // 父进程交接的日志文件，key为绝对路径
+)
+
+// 读取父进程交接的日志文件，读取后清除环境变量，避免再传给孙进程
+func loadInherited() {
+	inherited = map[string]*os.File{}
+	value := os.Getenv(HandoverEnv)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(HandoverEnv)
+	for _, e := range strings.Split(value, ";") {
+		i := strings.LastIndex(e, "=")
+		if i <= 0 {
+			continue
+		}
+		fd, err := strconv.Atoi(e[i+1:])
+		if err != nil || fd < 3 {
+			continue
+		}
+		inherited[e[:i]] = os.NewFile(uintptr(fd), e[:i])
+	}
+}
+
+// 取出父进程交接的日志文件，每个文件只能取出一次，描述符与路径上的文件不一致时忽略
+func inheritedFile(name string) *os.File {
+	inheritedOnce.Do(loadInherited)
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil
+	}
+	inheritedMu.Lock()
+	file, ok := inherited[abs]
+	delete(inherited, abs)
+	inheritedMu.Unlock()
+	if !ok {
+		return nil
+	}
+	current, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(name)
+	if err != nil || !os.SameFile(current, info) {
+		return nil
+	}
+	return file
+}
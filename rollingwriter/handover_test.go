@@ -0,0 +1,54 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandover(t *testing.T) {
+	dir := t.TempDir()
+	parent, err := NewWriter(WithLogPath(dir), WithLock(), WithoutRollingPolicy())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer parent.Close()
+	_, err = parent.Write([]byte("parent before\n"))
+	assert.NoError(t, err)
+
+	cmd := &exec.Cmd{Env: []string{HandoverEnv + "=stale", "FOO=bar"}}
+	assert.NoError(t, PrepareHandover(cmd, parent))
+	assert.Len(t, cmd.ExtraFiles, 1)
+	assert.Equal(t, "FOO=bar", cmd.Env[0])
+	assert.True(t, strings.HasSuffix(cmd.Env[1], "/log.log=3"))
+	// 只能交接一次
+	assert.Equal(t, ErrHandedOver, PrepareHandover(&exec.Cmd{}, parent))
+
+	// 在同一进程中模拟子进程，使用交接文件的描述符
+	fd := cmd.ExtraFiles[0].Fd()
+	os.Setenv(HandoverEnv, strings.TrimSuffix(cmd.Env[1][len(HandoverEnv)+1:], "=3")+"="+strconv.Itoa(int(fd)))
+	inheritedOnce = sync.Once{}
+	child, err := NewWriter(WithLogPath(dir), WithLock(), WithoutRollingPolicy())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "", os.Getenv(HandoverEnv))
+	assert.Equal(t, fd, child.(*LockedWriter).current().Fd())
+
+	_, err = child.Write([]byte("child\n"))
+	assert.NoError(t, err)
+	_, err = parent.Write([]byte("parent after\n"))
+	assert.NoError(t, err)
+	buf, _ := ioutil.ReadFile(dir + "/log.log")
+	assert.Equal(t, "parent before\nchild\nparent after\n", string(buf))
+
+	// 交接后父进程不再监测日志文件
+	os.Rename(dir+"/log.log", dir+"/moved.log")
+	assert.False(t, parent.(*LockedWriter).fileMissing())
+}
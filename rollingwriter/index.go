@@ -0,0 +1,74 @@
+package rollingwriter
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"time"
+)
+
+// 日志滚动索引中的一条记录，每次滚动追加一行json
+type RotationRecord struct {
+	Time       time.Time `json:"time"`       // 滚动时间
+	Strategy   string    `json:"strategy"`   // 滚动方式，rename或copytruncate
+	File       string    `json:"file"`       // 当前日志文件路径
+	Archive    string    `json:"archive"`    // 滚动生成的历史日志文件路径
+	Size       int64     `json:"size"`       // 滚动时日志文件的大小，即历史日志文件的结束偏移
+	Compressed bool      `json:"compressed"` // 历史日志文件是否会被压缩，压缩后为Archive加压缩扩展名，如.gz
+}
+
+// 日志滚动索引文件的路径，按日期分目录时位于LogPath中
+func IndexFilePath(c *Config) string {
+	return path.Join(c.LogPath, c.FileName) + ".log.index"
+}
+
+// 在索引文件中记录一次滚动
+func (w *Writer) recordRotation(archive string, size int64) {
+	if !w.cf.RotationIndex {
+		return
+	}
+	strategy := w.cf.RotationStrategy
+	if strategy == "" {
+		strategy = "rename"
+	}
+	buf, err := json.Marshal(RotationRecord{
+		Time:       w.cf.clock().Now(),
+		Strategy:   strategy,
+		File:       w.path(),
+		Archive:    archive,
+		Size:       size,
+		Compressed: w.cf.Compress,
+	})
+	if err != nil {
+		w.handleError("record rotation", err)
+		return
+	}
+	file, err := w.cf.openFile(IndexFilePath(w.cf), DefualtFileFlag)
+	if err != nil {
+		w.handleError("record rotation", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(buf, '\n')); err != nil {
+		w.handleError("record rotation", err)
+	}
+}
+
+// 读取日志滚动索引中的所有记录
+func ReadRotationIndex(c *Config) ([]RotationRecord, error) {
+	file, err := os.Open(IndexFilePath(c))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []RotationRecord
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var r RotationRecord
+		if err := dec.Decode(&r); err != nil {
+			return records, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
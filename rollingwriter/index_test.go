@@ -0,0 +1,37 @@
+package rollingwriter
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotationIndex(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.RotationIndex = true
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	assert.Nil(t, w.(*Writer).Reopen(path.Join(cfg.LogPath, "unittest.log.1")))
+	w.(*Writer).cf.RotationStrategy = "copytruncate"
+	w.Write([]byte("second line\n"))
+	assert.Nil(t, w.(*Writer).Reopen(path.Join(cfg.LogPath, "unittest.log.2")))
+
+	records, err := ReadRotationIndex(&cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "rename", records[0].Strategy)
+	assert.Equal(t, path.Join(cfg.LogPath, "unittest.log.1"), records[0].Archive)
+	assert.Equal(t, int64(6), records[0].Size)
+	assert.Equal(t, "copytruncate", records[1].Strategy)
+	assert.Equal(t, int64(12), records[1].Size)
+	assert.Equal(t, LogFilePath(&cfg), records[1].File)
+}
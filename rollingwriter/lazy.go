@@ -0,0 +1,98 @@
+package rollingwriter
+
+import (
+	"io"
+	"sync"
+)
+
+// 配置了LazyOpen时使用的结构，并发安全
+// 第一次写入时才按配置生成实际的writer，打开日志文件并扫描历史日志，之后的写入直接交给实际的writer
+type LazyWriter struct {
+	cf     Config
+	once   sync.Once
+	mu     sync.Mutex // 保护writer与Close
+	writer RollingWriter
+	err    error
+	closed bool
+
+	closing chan struct{} // Close时关闭
+}
+
+func newLazyWriter(c *Config) *LazyWriter {
+	cf := *c
+	cf.LazyOpen = false
+	return &LazyWriter{cf: cf, closing: make(chan struct{})}
+}
+
+// 生成实际的writer，只执行一次，关闭后不再生成
+func (w *LazyWriter) open() (RollingWriter, error) {
+	w.once.Do(func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.closed {
+			w.err = ErrClosed
+			return
+		}
+		w.writer, w.err = NewWriterFromConfig(&w.cf)
+	})
+	return w.writer, w.err
+}
+
+func (w *LazyWriter) Write(b []byte) (int, error) {
+	writer, err := w.open()
+	if err != nil {
+		return 0, err
+	}
+	return writer.Write(b)
+}
+
+func (w *LazyWriter) WriteString(s string) (int, error) {
+	writer, err := w.open()
+	if err != nil {
+		return 0, err
+	}
+	return io.WriteString(writer, s)
+}
+
+// 已打开时立即执行一次日志滚动
+func (w *LazyWriter) Rotate() {
+	w.mu.Lock()
+	writer := w.writer
+	w.mu.Unlock()
+	if r, ok := writer.(interface{ Rotate() }); ok {
+		r.Rotate()
+	}
+}
+
+// writer实际生效的配置，包括默认值
+func (w *LazyWriter) Config() Config {
+	c := w.cf.resolved()
+	c.LazyOpen = true
+	return c
+}
+
+// 是否已经打开日志文件
+func (w *LazyWriter) Opened() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer != nil
+}
+
+// 关闭实际的writer，没有写入过时不创建日志文件
+func (w *LazyWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	close(w.closing)
+	if w.writer == nil {
+		return nil
+	}
+	return w.writer.Close()
+}
+
+func (w *LazyWriter) closingCh() <-chan struct{} {
+	return w.closing
+}
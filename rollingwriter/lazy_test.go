@@ -0,0 +1,37 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazyWriter(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewWriter(WithLogPath(dir), WithLazyOpen(), WithRollingTimePattern("not a cron"))
+	assert.NotNil(t, err)
+
+	w, err := NewWriter(WithLogPath(dir), WithLazyOpen(), WithoutRollingPolicy())
+	if !assert.NoError(t, err) {
+		return
+	}
+	lw := w.(*LazyWriter)
+	assert.False(t, lw.Opened())
+	assertNoFile(t, dir+"/log.log")
+
+	_, err = lw.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	assert.True(t, lw.Opened())
+	assert.NoError(t, lw.Close())
+	assert.Equal(t, ErrClosed, lw.Close())
+	buf, _ := ioutil.ReadFile(dir + "/log.log")
+	assert.Equal(t, "first\n", string(buf))
+
+	// 没有写入过时关闭不创建日志文件
+	w, _ = NewWriter(WithLogPath(dir), WithFileName("idle"), WithLazyOpen())
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("late\n"))
+	assert.Equal(t, ErrClosed, err)
+	assertNoFile(t, dir+"/idle.log")
+}
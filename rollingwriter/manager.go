@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -15,19 +16,32 @@ type manager struct {
 	thresholdSize int64
 	startAt       time.Time
 	fire          chan string
-	cr            *cron.Cron
 	context       chan int
 	wg            sync.WaitGroup
 	lock          sync.Mutex
+	cf            *Config
+	triggerMu     sync.Mutex // 保证同一时间只有一次滚动在触发
+	generation    uint64     // 已交给writer执行的滚动次数
+	closeOnce     sync.Once
+	dataSize      atomic.Value // func() int64，文件大小与日志数据大小不同的写入模式提供的数据大小
 }
 
 func NewManager(c *Config) (Manager, error) {
 	m := &manager{
-		startAt: time.Now(),
+		startAt: c.clock().Now(),
 		fire:    make(chan string),
-		cr:      cron.New(),
 		context: make(chan int),
 		wg:      sync.WaitGroup{},
+		cf:      c,
+	}
+
+	// 进程重启后沿用当前日志文件的开始时间，新的日志文件记录开始时间
+	if c.RollingPolicy == TimeRolling || c.RollingPolicy == VolumeRolling {
+		if t, ok := c.loadStartAt(); ok {
+			m.startAt = t
+		} else {
+			c.saveStartAt(m.startAt)
+		}
 	}
 
 	// 判断日志滚动模式
@@ -37,21 +51,28 @@ func NewManager(c *Config) (Manager, error) {
 	case WithoutRolling:
 		return m, nil
 	case TimeRolling:
-		if _, err := m.cr.AddFunc(c.RollingTimePattern, func() {
-			m.fire <- m.GenLogFileName(c)
-		}); err != nil {
+		sched, err := cron.ParseStandard(c.RollingTimePattern)
+		if err != nil {
 			return nil, err
 		}
-		m.cr.Start()
+		// 停止期间错过了滚动时间，当前日志文件属于之前的周期，启动后立即滚动
+		if !sched.Next(m.startAt).After(c.clock().Now()) {
+			go m.trigger()
+		}
+		go schedule(c.clock(), sched, func() {
+			m.trigger()
+		}, m.context)
 	case VolumeRolling:
 		m.ParseVolume(c)
 		m.wg.Add(1)
 		go func() {
 			// 每秒一次的计时器
-			timer := time.Tick(time.Duration(Precision) * time.Second)
-			filepath := LogFilePath(c)
+			ticker := c.clock().NewTicker(time.Duration(Precision) * time.Second)
+			defer ticker.Stop()
 			var file *os.File
 			var err error
+			// 最近一次触发滚动时的日志文件，writer完成滚动前不重复触发
+			var triggered os.FileInfo
 			m.wg.Done()
 
 			// 触发滚动或关闭，关闭时退出循环
@@ -61,13 +82,22 @@ func NewManager(c *Config) (Manager, error) {
 				case <-m.context:
 					return
 				//	每秒一次检查当前日志文件大小
-				case <-timer:
-					if file, err = os.Open(filepath); err != nil {
+				case <-ticker.C():
+					// 按日期分目录时日期变化后滚动到新的日期目录
+					if m.dayChanged() {
+						m.trigger()
+						continue
+					}
+					if file, err = os.Open(LogFilePath(c)); err != nil {
 						continue
 					}
 					// 判断是否触发滚动
-					if info, err := file.Stat(); err == nil && info.Size() > m.thresholdSize {
-						m.fire <- m.GenLogFileName(c)
+					info, err := file.Stat()
+					if err == nil && m.size(info) > m.thresholdSize &&
+						(triggered == nil || !os.SameFile(triggered, info)) {
+						if m.trigger() {
+							triggered = info
+						}
 					}
 					_ = file.Close()
 				}
@@ -78,40 +108,91 @@ func NewManager(c *Config) (Manager, error) {
 	return m, nil
 }
 
+// 当前日志文件中日志数据的大小，mmap模式的文件包含预先扩展的映射区域，使用writer提供的数据大小
+func (m *manager) size(info os.FileInfo) int64 {
+	if f, ok := m.dataSize.Load().(func() int64); ok {
+		return f()
+	}
+	return info.Size()
+}
+
+// 生成历史日志文件名称并通知writer执行日志滚动，manager关闭后不再等待
+// 多个来源同时触发时串行执行，每次触发都会且只会交给writer一次
+func (m *manager) trigger() bool {
+	m.triggerMu.Lock()
+	defer m.triggerMu.Unlock()
+	select {
+	case <-m.context:
+		return false
+	default:
+	}
+	select {
+	case m.fire <- m.GenLogFileName(m.cf):
+		atomic.AddUint64(&m.generation, 1)
+		return true
+	case <-m.context:
+		return false
+	}
+}
+
+// 立即触发一次日志滚动，与按时间或大小触发的滚动串行执行
+func (m *manager) Rotate() {
+	m.trigger()
+}
+
+// 已触发的滚动次数
+func (m *manager) Generation() uint64 {
+	return atomic.LoadUint64(&m.generation)
+}
+
 func (m *manager) Fire() chan string {
 	return m.fire
 }
 
+// 停止滚动检查，可以重复调用
 func (m *manager) Close() {
-	close(m.context)
-	m.cr.Stop()
+	m.closeOnce.Do(func() {
+		close(m.context)
+	})
 }
 
-// 生成新的历史日志文件名称，更新startAt为当前时间
+// 生成新的历史日志文件名称，更新startAt为当前时间，压缩后的历史日志文件在该名称后加压缩扩展名，如.gz
 func (m *manager) GenLogFileName(c *Config) (filename string) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	if c.Compress {
-		filename = path.Join(c.LogPath, c.FileName+".log.gz."+m.startAt.Format(c.TimeTagFormat))
-	} else {
-		filename = path.Join(c.LogPath, c.FileName+".log."+m.startAt.Format(c.TimeTagFormat))
+	filename = path.Join(c.logDir(m.startAt), c.FileName+".log."+m.startAt.Format(c.TimeTagFormat))
+	m.startAt = c.clock().Now()
+	if c.RollingPolicy != WithoutRolling {
+		c.saveStartAt(m.startAt)
 	}
-	m.startAt = time.Now()
 	return filename
 }
 
+// 按日期分目录时判断当前日志文件的日期目录是否已经过期
+func (m *manager) dayChanged() bool {
+	if m.cf.DirLayout == "" {
+		return false
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.cf.logDir(m.startAt) != m.cf.logDir(m.cf.clock().Now())
+}
+
 // 根据配置更新m.thresholdSize
 func (m *manager) ParseVolume(c *Config) {
-	// 读取大小滚动策略时的截断大小
-	s := []byte(strings.ToUpper(c.RollingVolumeSize))
-	// 如果不包含单位，则thresholdSize为1G
+	m.thresholdSize = ParseSize(c.RollingVolumeSize)
+}
+
+// 解析带单位的大小，如100MB、1G，不包含单位时为1G
+func ParseSize(size string) int64 {
+	s := []byte(strings.ToUpper(size))
+	// 如果不包含单位，则为1G
 	if !(strings.Contains(string(s), "K") || strings.Contains(string(s), "KB") ||
 		strings.Contains(string(s), "M") || strings.Contains(string(s), "MB") ||
 		strings.Contains(string(s), "G") || strings.Contains(string(s), "GB") ||
 		strings.Contains(string(s), "T") || strings.Contains(string(s), "TB")) {
 
-		m.thresholdSize = 1024 * 1024 * 1024
-		return
+		return 1024 * 1024 * 1024
 	}
 
 	var unit int64 = 1
@@ -138,5 +219,5 @@ func (m *manager) ParseVolume(c *Config) {
 	case "K", "KB":
 		unit *= 1024
 	}
-	m.thresholdSize = int64(p) * unit
+	return int64(p) * unit
 }
@@ -1,8 +1,8 @@
 package rollingwriter
 
 import (
-	"fmt"
 	"path"
+	"sync"
 	"testing"
 	"time"
 
@@ -81,11 +81,48 @@ func TestGenLogFileName(t *testing.T) {
 
 	dest := m.GenLogFileName(c)
 	timetag := m.startAt.Format(c.TimeTagFormat)
-	assert.Equal(t, path.Join("./", "file"+".log"+timetag), dest)
+	assert.Equal(t, path.Join("./", "file"+".log."+timetag), dest)
 
+	// 压缩时在历史日志文件名称后加.gz，生成的名称不变
 	c.Compress = true
 	dest = m.GenLogFileName(c)
 	timetag = m.startAt.Format(c.TimeTagFormat)
-	fmt.Println(dest)
-	assert.Equal(t, path.Join("./", "file"+".log.gz"+timetag), dest)
+	assert.Equal(t, path.Join("./", "file"+".log."+timetag), dest)
+}
+
+func TestRotateSingleFlight(t *testing.T) {
+	c := NewDefaultConfig()
+	c.RollingPolicy = WithoutRolling
+	mng, err := NewManager(&c)
+	assert.Nil(t, err)
+
+	var received int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range mng.Fire() {
+			received++
+			if received == 50 {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mng.Rotate()
+		}()
+	}
+	wg.Wait()
+	<-done
+	assert.Equal(t, 50, received)
+	assert.Equal(t, uint64(50), mng.Generation())
+
+	// 关闭后不再触发
+	mng.Close()
+	mng.Rotate()
+	assert.Equal(t, uint64(50), mng.Generation())
 }
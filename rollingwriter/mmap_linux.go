@@ -0,0 +1,243 @@
+//go:build linux
+// +build linux
+
+package rollingwriter
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// mmap模式每次映射的文件区域大小
+var MmapRegionSize int64 = 16 << 20
+
+// 当WriterMode为mmap时使用的结构，并发安全
+// 日志写入预分配并映射到内存的文件区域，每Precision秒执行一次msync，
+// 进程崩溃时已写入映射区域的数据由内核保留，重启时会截掉区域中未写入的部分
+type MmapWriter struct {
+	Writer
+	sync.Mutex
+	region []byte // 当前映射的文件区域
+	base   int64  // 映射区域在文件中的起始偏移
+	size   int64  // 日志文件中实际数据的大小
+	stop   chan struct{}
+	closed bool
+}
+
+func init() {
+	RegisterWriterMode("mmap", func(c Config, w Writer) (RollingWriter, error) {
+		// copytruncate会截断仍在映射中的文件
+		if c.RotationStrategy == "copytruncate" {
+			return nil, ErrInvalidArgument
+		}
+		mw := &MmapWriter{Writer: w, stop: make(chan struct{})}
+		if err := mw.open(); err != nil {
+			return nil, err
+		}
+		// 文件包含映射区域中未写入的部分，按大小滚动时使用实际数据的大小
+		if m, ok := w.m.(*manager); ok {
+			m.dataSize.Store(mw.dataSize)
+		}
+		go mw.syncer()
+		return mw, nil
+	})
+}
+
+// 恢复上次未正常关闭时残留的映射区域，并映射新的区域
+func (w *MmapWriter) open() error {
+	size, err := recoverMmapTail(w.current())
+	if err != nil {
+		return err
+	}
+	w.size = size
+	return w.remap(0)
+}
+
+// 从size处重新映射至少能容纳n字节的文件区域
+func (w *MmapWriter) remap(n int64) error {
+	if err := w.unmap(); err != nil {
+		return err
+	}
+	page := int64(os.Getpagesize())
+	w.base = w.size &^ (page - 1)
+	length := MmapRegionSize
+	if need := w.size - w.base + n; need > length {
+		length = (need + page - 1) &^ (page - 1)
+	}
+	file := w.current()
+	if err := file.Truncate(w.base + length); err != nil {
+		return err
+	}
+	region, err := syscall.Mmap(int(file.Fd()), w.base, int(length), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	w.region = region
+	return nil
+}
+
+// 同步并解除映射，将文件截断为实际数据的大小
+func (w *MmapWriter) unmap() error {
+	if w.region == nil {
+		return nil
+	}
+	msync(w.region)
+	if err := syscall.Munmap(w.region); err != nil {
+		return err
+	}
+	w.region = nil
+	return w.current().Truncate(w.size)
+}
+
+// mmap模式的Write接口实现
+func (w *MmapWriter) Write(b []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	select {
+	// 日志文件被外部删除或移动，重建前需要解除对旧文件的映射
+	case <-w.recreate:
+		if err := w.unmap(); err != nil {
+			return 0, err
+		}
+		if err := w.recreateFile(); err != nil {
+			return 0, err
+		}
+		w.size = fileSize(w.current())
+	default:
+	}
+
+	pos := w.size - w.base
+	if w.region == nil || pos+int64(len(b)) > int64(len(w.region)) {
+		if err := w.remap(int64(len(b))); err != nil {
+			return 0, err
+		}
+		pos = w.size - w.base
+	}
+	n := copy(w.region[pos:], b)
+	w.size += int64(n)
+	return w.written(b, n, nil)
+}
+
+// 日志文件中实际数据的大小
+func (w *MmapWriter) dataSize() int64 {
+	w.Lock()
+	defer w.Unlock()
+	return w.size
+}
+
+// mmap模式在锁内执行滚动，滚动前需要解除对旧文件的映射
+func (w *MmapWriter) rotate(filename string) {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return
+	}
+	if err := w.unmap(); err != nil {
+		w.handleError("unmap log file", err)
+		return
+	}
+	w.Writer.rotate(filename)
+	w.size = fileSize(w.current())
+}
+
+// 定时将映射区域同步到磁盘
+func (w *MmapWriter) syncer() {
+	ticker := time.NewTicker(time.Duration(Precision) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.Lock()
+			if w.region != nil {
+				msync(w.region)
+			}
+			w.Unlock()
+		}
+	}
+}
+
+// mmap模式的Close接口实现
+func (w *MmapWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	close(w.stop)
+	if err := w.unmap(); err != nil {
+		return err
+	}
+	return w.Writer.Close()
+}
+
+// mmap模式写入预分配的映射区域，其他进程无法追加写入，不支持交接日志文件
+func (w *MmapWriter) Handover() (*os.File, string, error) {
+	return nil, "", ErrInvalidArgument
+}
+
+// 同步映射区域到磁盘
+func msync(region []byte) {
+	syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&region[0])), uintptr(len(region)), syscall.MS_SYNC)
+}
+
+// 查找文件末尾残留的未写入区域（全部为0字节），截断后返回文件实际数据的大小
+func recoverMmapTail(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := info.Size()
+	buf := make([]byte, 64*1024)
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		i := len(chunk) - 1
+		for i >= 0 && chunk[i] == 0 {
+			i--
+		}
+		if i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+	if end != info.Size() {
+		if err := file.Truncate(end); err != nil {
+			return 0, err
+		}
+	}
+	return end, nil
+}
+
+// 文件的当前大小，新文件可能已写入头信息
+func fileSize(file *os.File) int64 {
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func (w *MmapWriter) WriteString(s string) (int, error) {
+	return writeString(w, w.pool, s)
+}
+
+func (w *MmapWriter) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(w, w.pool, r)
+}
@@ -0,0 +1,73 @@
+//go:build linux
+// +build linux
+
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMmapWriter(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "mmap"
+	filepath := LogFilePath(&cfg)
+
+	// 模拟上次崩溃时残留的映射区域
+	assert.Nil(t, ioutil.WriteFile(filepath, append([]byte("before crash\n"), make([]byte, 4096)...), 0644))
+
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	var c int = 100
+	for i := 0; i < c; i++ {
+		w.Write([]byte("mmap line\n"))
+	}
+	// 超过映射区域大小的写入
+	large := strings.Repeat("x", int(MmapRegionSize)) + "\n"
+	w.Write([]byte(large))
+	assert.Nil(t, w.Close())
+
+	buf, err := ioutil.ReadFile(filepath)
+	assert.Nil(t, err)
+	assert.Equal(t, "before crash\n"+strings.Repeat("mmap line\n", c)+large, string(buf))
+
+	cfg.RotationStrategy = "copytruncate"
+	_, err = NewWriterFromConfig(&cfg)
+	assert.Equal(t, ErrInvalidArgument, err)
+	os.Remove(filepath)
+}
+
+func TestMmapVolumeRolling(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "mmap"
+	cfg.RollingPolicy = VolumeRolling
+	cfg.RollingVolumeSize = "1mb"
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+	mw := w.(*MmapWriter)
+
+	// 映射区域超过滚动大小，数据没有超过时不滚动
+	w.Write([]byte("mmap line\n"))
+	assert.Greater(t, fileSize(mw.current()), int64(1<<20))
+	time.Sleep(time.Duration(Precision)*time.Second + 500*time.Millisecond)
+	assert.Equal(t, uint64(0), mw.m.Generation())
+
+	w.Write([]byte(strings.Repeat("x", 1<<20) + "\n"))
+	assert.Eventually(t, func() bool {
+		return mw.m.Generation() == 1
+	}, 3*time.Duration(Precision)*time.Second, 50*time.Millisecond)
+}
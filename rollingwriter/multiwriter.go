@@ -0,0 +1,48 @@
+package rollingwriter
+
+import (
+	"io"
+
+	"go.uber.org/multierr"
+)
+
+// 将一次写入分发到多个RollingWriter的组合writer，各个writer之间的错误互不影响
+type MultiWriter struct {
+	writers []RollingWriter
+}
+
+// 生成MultiWriter，写入时依次写入所有writer
+func NewMultiWriter(writers ...RollingWriter) RollingWriter {
+	ws := make([]RollingWriter, 0, len(writers))
+	for _, w := range writers {
+		// 展开嵌套的MultiWriter
+		if mw, ok := w.(*MultiWriter); ok {
+			ws = append(ws, mw.writers...)
+			continue
+		}
+		ws = append(ws, w)
+	}
+	return &MultiWriter{writers: ws}
+}
+
+// 写入所有writer，某个writer出错时仍会继续写入其他writer，返回所有writer的错误
+func (w *MultiWriter) Write(b []byte) (int, error) {
+	var err error
+	for _, writer := range w.writers {
+		n, e := writer.Write(b)
+		if e == nil && n != len(b) {
+			e = io.ErrShortWrite
+		}
+		err = multierr.Append(err, e)
+	}
+	return len(b), err
+}
+
+// 关闭所有writer，返回所有writer的错误
+func (w *MultiWriter) Close() error {
+	var err error
+	for _, writer := range w.writers {
+		err = multierr.Append(err, writer.Close())
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package rollingwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+	err    error
+	closed bool
+}
+
+func (b *bufferCloser) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.Buffer.Write(p)
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestMultiWriter(t *testing.T) {
+	errWrite := errors.New("write failed")
+	w1, w2, w3 := &bufferCloser{}, &bufferCloser{err: errWrite}, &bufferCloser{}
+	writer := NewMultiWriter(w1, NewMultiWriter(w2, w3))
+
+	n, err := writer.Write([]byte("foo"))
+	assert.Equal(t, 3, n)
+	assert.Equal(t, errWrite, err)
+	assert.Equal(t, "foo", w1.String())
+	assert.Equal(t, "foo", w3.String())
+
+	assert.Nil(t, writer.Close())
+	assert.True(t, w1.closed && w2.closed && w3.closed)
+}
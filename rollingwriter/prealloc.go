@@ -0,0 +1,21 @@
+package rollingwriter
+
+import "os"
+
+// 大小滚动策略下为日志文件预分配的大小，即滚动阈值，其他策略不预分配
+func (c *Config) preallocSize() int64 {
+	if !c.Preallocate || c.RollingPolicy != VolumeRolling {
+		return 0
+	}
+	return ParseSize(c.RollingVolumeSize)
+}
+
+// 为当前日志文件预分配到滚动阈值大小的磁盘空间，文件大小保持不变
+// 磁盘空间不足时在打开或滚动日志文件时返回错误，而不是在写入过程中
+func (c *Config) preallocate(file *os.File) error {
+	size := c.preallocSize()
+	if size <= 0 {
+		return nil
+	}
+	return fallocate(file, size)
+}
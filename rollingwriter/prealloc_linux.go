@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package rollingwriter
+
+import (
+	"os"
+	"syscall"
+)
+
+// 通过fallocate预分配磁盘空间，文件系统不支持时忽略
+func fallocate(file *os.File, size int64) error {
+	err := syscall.Fallocate(int(file.Fd()), _fallocKeepSize, 0, size)
+	if err == syscall.EOPNOTSUPP || err == syscall.ENOSYS {
+		return nil
+	}
+	if err != nil {
+		return &os.PathError{Op: "fallocate", Path: file.Name(), Err: err}
+	}
+	return nil
+}
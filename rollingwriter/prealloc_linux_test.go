@@ -0,0 +1,52 @@
+//go:build linux
+// +build linux
+
+package rollingwriter
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 文件实际占用的磁盘空间
+func allocated(t *testing.T, name string) int64 {
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Sys().(*syscall.Stat_t).Blocks * 512
+}
+
+func TestPreallocate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "lock"
+	cfg.RollingPolicy = VolumeRolling
+	cfg.RollingVolumeSize = "1mb"
+	cfg.Preallocate = true
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+	w.Write([]byte("prealloc line\n"))
+
+	name := LogFilePath(&cfg)
+	info, _ := os.Stat(name)
+	assert.Equal(t, int64(len("prealloc line\n")), info.Size())
+	if allocated(t, name) < 1<<20 {
+		t.Skip("filesystem does not support fallocate")
+	}
+
+	// 滚动后的新日志文件同样预分配
+	assert.NoError(t, w.(*LockedWriter).Reopen(name+".1"))
+	assert.GreaterOrEqual(t, allocated(t, name), int64(1<<20))
+
+	// 其他滚动策略不预分配
+	cfg.RollingPolicy = TimeRolling
+	assert.Equal(t, int64(0), cfg.preallocSize())
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package rollingwriter
+
+import "os"
+
+// 其他平台不预分配磁盘空间
+func fallocate(file *os.File, size int64) error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package rollingwriter
+
+import "runtime"
+
+// 调优方案，按CPU数量和可用内存推导队列长度、刷新间隔、缓存阈值和分片数
+const (
+	ProfileLowLatency = "low-latency" // 不合并写入，刷新间隔短，适合日志量不大但要求及时落盘的服务
+	ProfileThroughput = "throughput"  // 队列长、批量大，适合日志量大的服务
+	ProfileLowMemory  = "low-memory"  // 队列短、缓存小，适合内存受限的容器
+)
+
+// 无法获取可用内存时假定的值
+const _defaultMemory = 1 << 30
+
+// 日志所在机器或容器的可用内存，无法获取时返回0
+var availableMemory = systemMemory
+
+// 调优参数
+type tuning struct {
+	queueSize  int
+	batchSize  int
+	batchDelay int // 毫秒
+	flush      int // 毫秒
+	threshold  int
+	bufferSize int
+	shards     int
+}
+
+// 按调优方案、CPU数量和可用内存计算调优参数，async队列最多占用可用内存的1%
+func profileTuning(profile string, cpus int, memory uint64) tuning {
+	if memory == 0 {
+		memory = _defaultMemory
+	}
+	var t tuning
+	switch profile {
+	case ProfileLowLatency:
+		t = tuning{queueSize: 1024 * cpus, batchSize: 1, flush: 10, threshold: 4 << 10, shards: 2 * cpus}
+	case ProfileThroughput:
+		t = tuning{queueSize: 4096 * cpus, batchSize: 256, batchDelay: 5, flush: 200, threshold: 256 << 10, shards: cpus}
+	case ProfileLowMemory:
+		t = tuning{queueSize: 256, batchSize: 16, flush: 100, threshold: 4 << 10, bufferSize: 16 << 10, shards: minInt(cpus, 2)}
+	}
+	// 按平均单条日志512字节估算队列占用的内存
+	if max := int(memory / 100 / 512); t.queueSize > max {
+		t.queueSize = max
+	}
+	if t.queueSize < 64 {
+		t.queueSize = 64
+	}
+	return t
+}
+
+// 按Profile填充调优参数后的配置，未设置或保持默认值的参数由Profile决定，其他参数不变
+func (c *Config) withProfile() Config {
+	r := *c
+	if c.Profile == "" {
+		return r
+	}
+	def := NewDefaultConfig()
+	t := profileTuning(c.Profile, runtime.GOMAXPROCS(0), availableMemory())
+	if r.QueueSize == 0 {
+		r.QueueSize = t.queueSize
+	}
+	if r.BatchSize == 0 || r.BatchSize == def.BatchSize {
+		r.BatchSize = t.batchSize
+	}
+	if r.BatchMaxLatency == 0 {
+		r.BatchMaxLatency = t.batchDelay
+	}
+	if r.FlushInterval == 0 {
+		r.FlushInterval = t.flush
+	}
+	if r.BufferWriterThreshold == 0 || r.BufferWriterThreshold == def.BufferWriterThreshold {
+		r.BufferWriterThreshold = t.threshold
+	}
+	if r.BufferSize == 0 {
+		r.BufferSize = t.bufferSize
+	}
+	if r.Shards == 0 {
+		r.Shards = t.shards
+	}
+	return r
+}
+
+// sharded模式的分片数
+func (c *Config) shards() int {
+	if c.Shards > 0 {
+		return c.Shards
+	}
+	return runtime.GOMAXPROCS(0)
+}
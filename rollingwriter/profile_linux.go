@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package rollingwriter
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 可用内存，容器中为cgroup的内存上限，否则为/proc/meminfo中的MemAvailable
+func systemMemory() uint64 {
+	var avail uint64
+	if f, err := os.Open("/proc/meminfo"); err == nil {
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			fields := strings.Fields(s.Text())
+			if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+				kb, _ := strconv.ParseUint(fields[1], 10, 64)
+				avail = kb << 10
+				break
+			}
+		}
+		f.Close()
+	}
+	// cgroup v2和v1的内存上限，没有限制时为max或一个极大的值
+	for _, name := range []string{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes"} {
+		buf, err := ioutil.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(string(buf)), 10, 64)
+		if err == nil && limit > 0 && (avail == 0 || limit < avail) {
+			return limit
+		}
+		break
+	}
+	return avail
+}
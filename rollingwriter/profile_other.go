@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package rollingwriter
+
+func systemMemory() uint64 {
+	return 0
+}
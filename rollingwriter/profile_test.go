@@ -0,0 +1,59 @@
+package rollingwriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileTuning(t *testing.T) {
+	t.Run("throughput", func(t *testing.T) {
+		tn := profileTuning(ProfileThroughput, 8, 16<<30)
+		assert.Equal(t, 4096*8, tn.queueSize)
+		assert.Equal(t, 256, tn.batchSize)
+		assert.Equal(t, 8, tn.shards)
+	})
+	t.Run("low-latency", func(t *testing.T) {
+		tn := profileTuning(ProfileLowLatency, 4, 16<<30)
+		assert.Equal(t, 1, tn.batchSize)
+		assert.Equal(t, 10, tn.flush)
+		assert.Equal(t, 8, tn.shards)
+	})
+	t.Run("memory capped", func(t *testing.T) {
+		// 队列最多占用可用内存的1%
+		tn := profileTuning(ProfileThroughput, 64, 256<<20)
+		assert.Equal(t, 256<<20/100/512, tn.queueSize)
+		assert.Equal(t, 64, profileTuning(ProfileLowMemory, 1, 1<<20).queueSize)
+	})
+}
+
+func TestWithProfile(t *testing.T) {
+	defer func(fn func() uint64) { availableMemory = fn }(availableMemory)
+	availableMemory = func() uint64 { return 1 << 30 }
+
+	cfg := NewDefaultConfig()
+	cfg.Profile = ProfileLowMemory
+	cfg.FlushInterval = 50
+	r := cfg.withProfile()
+	assert.Equal(t, 16, r.BatchSize)
+	assert.Equal(t, 4<<10, r.BufferWriterThreshold)
+	assert.Equal(t, 16<<10, r.BufferSize)
+	assert.Equal(t, 256, r.QueueSize)
+	// 已设置的参数不变
+	assert.Equal(t, 50, r.FlushInterval)
+	// 不修改原配置
+	assert.Equal(t, 0, cfg.QueueSize)
+
+	cfg.LogPath = t.TempDir()
+	cfg.WriterMode = "sharded"
+	w, err := NewWriterFromConfig(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	assert.Equal(t, 256, w.(interface{ Config() Config }).Config().QueueSize)
+	assert.LessOrEqual(t, len(w.(*ShardedWriter).shards), 2)
+
+	cfg.Profile = "fast"
+	assert.Equal(t, ErrInvalidArgument, cfg.Validate())
+}
@@ -0,0 +1,34 @@
+package rollingwriter
+
+import (
+	"sync"
+)
+
+// 自定义写入模式的构造函数，c为writer的配置，w为已打开日志文件的基础writer
+// Writer没有WriteString和ReadFrom方法，嵌入Writer的写入模式不实现时，io.Copy等通过Write写入
+type WriterFactory func(c Config, w Writer) (RollingWriter, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]WriterFactory)
+)
+
+// 注册自定义写入模式，注册后可以通过配置中的WriterMode选择
+// 内置的none, lock, async, buffer模式不能被覆盖
+func RegisterWriterMode(name string, factory WriterFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		delete(factories, name)
+		return
+	}
+	factories[name] = factory
+}
+
+// 查找自定义写入模式的构造函数
+func lookupWriterMode(name string) (WriterFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	factory, ok := factories[name]
+	return factory, ok
+}
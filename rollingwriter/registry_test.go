@@ -0,0 +1,74 @@
+package rollingwriter
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingWriter struct {
+	*Writer
+	count int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.count++
+	return w.Writer.Write(b)
+}
+
+func TestRegisterWriterMode(t *testing.T) {
+	RegisterWriterMode("counting", func(c Config, w Writer) (RollingWriter, error) {
+		return &countingWriter{Writer: &w}, nil
+	})
+	defer RegisterWriterMode("counting", nil)
+
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "counting"
+	w, err := NewWriterFromConfig(&cfg)
+	assert.Nil(t, err)
+	w.Write([]byte("foo\n"))
+	w.Write([]byte("bar\n"))
+	assert.Equal(t, 2, w.(*countingWriter).count)
+	// 嵌入Writer不会继承绕过Write的WriteString和ReadFrom
+	_, ok := w.(io.ReaderFrom)
+	assert.False(t, ok)
+	_, ok = w.(io.StringWriter)
+	assert.False(t, ok)
+	io.Copy(w, strings.NewReader("baz\n"))
+	io.WriteString(w, "qux\n")
+	assert.Equal(t, 4, w.(*countingWriter).count)
+	assert.Nil(t, w.Close())
+
+	cfg.WriterMode = "unknown"
+	_, err = NewWriterFromConfig(&cfg)
+	assert.Equal(t, ErrInvalidArgument, err)
+}
+
+func TestRegisterWriterModeError(t *testing.T) {
+	var failed Writer
+	RegisterWriterMode("failing", func(c Config, w Writer) (RollingWriter, error) {
+		failed = w
+		return nil, ErrInvalidArgument
+	})
+	defer RegisterWriterMode("failing", nil)
+
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "failing"
+	WithRollingVolumeSize("1M")(&cfg)
+	_, err := NewWriterFromConfig(&cfg)
+	assert.Equal(t, ErrInvalidArgument, err)
+	// 构造失败时关闭日志文件和滚动管理
+	_, err = failed.current().Write([]byte("foo\n"))
+	assert.Error(t, err)
+	select {
+	case <-failed.m.(*manager).context:
+	default:
+		t.Error("manager not closed")
+	}
+}
@@ -0,0 +1,220 @@
+package rollingwriter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 属于writer的一个历史日志文件
+type Archive struct {
+	Path    string    // 文件路径
+	Time    time.Time // 文件名中的时间，无法解析时为修改时间
+	ModTime time.Time // 修改时间，即滚动或压缩完成的时间
+	Size    int64     // 文件大小
+}
+
+// 不属于历史日志的附属文件后缀：滚动索引、清理记录、不完整日志、临时文件和校验文件
+var _artifactSuffixes = []string{".index", ".state", ".retention", ".partial", ".tmp", SpillSuffix, ChecksumSuffix}
+
+// 列出属于writer的所有历史日志文件，按时间从旧到新排序
+// 包括LogPath及按日期分的子目录中以FileName.log.开头的文件（压缩或未压缩，新旧命名方式）和匹配ArchivePatterns的文件
+func ListArchives(c *Config) ([]Archive, error) {
+	dirs := []string{c.LogPath}
+	if c.DirLayout != "" {
+		infos, err := ioutil.ReadDir(c.LogPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range infos {
+			if fi.IsDir() && c.isDailyDir(fi.Name()) {
+				dirs = append(dirs, path.Join(c.LogPath, fi.Name()))
+			}
+		}
+	}
+	prefix := c.FileName + ".log."
+	seen := make(map[string]bool)
+	var archives []Archive
+	add := func(name string, fi os.FileInfo) {
+		if seen[name] || fi.IsDir() || IsArtifact(name) {
+			return
+		}
+		seen[name] = true
+		a := Archive{Path: name, ModTime: fi.ModTime(), Size: fi.Size()}
+		var ok bool
+		if a.Time, ok = c.archiveTime(name); !ok {
+			a.Time = a.ModTime
+		}
+		archives = append(archives, a)
+	}
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range infos {
+			if strings.HasPrefix(fi.Name(), prefix) {
+				add(path.Join(dir, fi.Name()), fi)
+			}
+		}
+		for _, pattern := range c.ArchivePatterns {
+			matches, err := filepath.Glob(path.Join(dir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range matches {
+				if fi, err := os.Stat(name); err == nil && name != path.Join(dir, c.FileName+".log") {
+					add(name, fi)
+				}
+			}
+		}
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		// 同一时间的多个历史日志文件按滚动的先后排序
+		if archives[i].Time.Equal(archives[j].Time) {
+			return archives[i].ModTime.Before(archives[j].ModTime)
+		}
+		return archives[i].Time.Before(archives[j].Time)
+	})
+	return archives, nil
+}
+
+// 判断文件是否为历史日志的附属文件，如滚动索引、校验文件和溢出文件，查找历史日志时需要跳过
+func IsArtifact(name string) bool {
+	for _, suffix := range _artifactSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否配置了历史日志的保留策略
+func (c *Config) retention() bool {
+	return c.MaxRemain > 0 || c.MaxAge > 0 || c.MaxTotalSize != ""
+}
+
+// 保留策略清理的一条记录，实际删除的文件和dry-run时将要删除的文件都会记录
+type RetentionRecord struct {
+	Time   time.Time `json:"time"`            // 清理时间
+	File   string    `json:"file"`            // 历史日志文件路径
+	Size   int64     `json:"size"`            // 文件大小
+	Age    int64     `json:"age"`             // 距离修改时间的秒数
+	Reason string    `json:"reason"`          // 删除原因，max_remain、max_age、max_total_size或回收目录中过期的trash_ttl
+	DryRun bool      `json:"dry_run"`         // 是否为dry-run，为true时文件没有被删除
+	Trash  string    `json:"trash,omitempty"` // 移动到回收目录中的路径
+	Error  string    `json:"error,omitempty"`
+}
+
+// 清理记录文件的路径，每条记录为一行json
+func RetentionLogPath(c *Config) string {
+	return path.Join(c.LogPath, c.FileName) + ".log.retention"
+}
+
+// 按保留策略选出需要删除的历史日志文件，archives按时间从旧到新排序
+// 超过MaxRemain个数、早于MaxAge或使总大小超过MaxTotalSize的最旧文件会被删除
+func (c *Config) expired(archives []Archive) []RetentionRecord {
+	var limit int64
+	if c.MaxTotalSize != "" {
+		limit = ParseSize(c.MaxTotalSize)
+	}
+	var total int64
+	for _, a := range archives {
+		total += a.Size
+	}
+	now := c.clock().Now()
+	var expired []RetentionRecord
+	for i, a := range archives {
+		age := now.Sub(a.ModTime)
+		var reason string
+		switch {
+		case c.MaxRemain > 0 && len(archives)-i > c.MaxRemain:
+			reason = "max_remain"
+		case c.MaxAge > 0 && age > time.Duration(c.MaxAge)*time.Second:
+			reason = "max_age"
+		case limit > 0 && total > limit:
+			reason = "max_total_size"
+		default:
+			continue
+		}
+		total -= a.Size
+		expired = append(expired, RetentionRecord{
+			Time:   now,
+			File:   a.Path,
+			Size:   a.Size,
+			Age:    int64(age / time.Second),
+			Reason: reason,
+			DryRun: c.RetentionDryRun,
+		})
+	}
+	return expired
+}
+
+// 删除过期的历史日志文件及其校验文件或移动到回收目录，清理空的日期目录和回收目录中过期的文件，dry-run时只记录不删除
+func (w *Writer) sweep() {
+	w.sweepMu.Lock()
+	defer w.sweepMu.Unlock()
+	archives, err := ListArchives(w.cf)
+	if err != nil {
+		w.handleError("list archives", err)
+		return
+	}
+	for _, r := range w.cf.expired(archives) {
+		if !r.DryRun {
+			if r.Trash, err = w.discard(r.File); err != nil {
+				w.handleError("remove log file", err)
+				r.Error = err.Error()
+			} else {
+				w.cf.removeEmptyDir(path.Dir(r.File))
+			}
+		}
+		w.recordRetention(r)
+	}
+	w.purgeTrash()
+}
+
+// 在清理记录文件中追加一条记录，并交给配置的OnRetention处理
+func (w *Writer) recordRetention(r RetentionRecord) {
+	if w.cf.OnRetention != nil {
+		w.cf.OnRetention(r)
+	}
+	w.emitRetention(r)
+	buf, err := json.Marshal(r)
+	if err != nil {
+		w.handleError("record retention", err)
+		return
+	}
+	file, err := w.cf.openFile(RetentionLogPath(w.cf), DefualtFileFlag)
+	if err != nil {
+		w.handleError("record retention", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(buf, '\n')); err != nil {
+		w.handleError("record retention", err)
+	}
+}
+
+// 读取清理记录文件中的所有记录
+func ReadRetentionLog(c *Config) ([]RetentionRecord, error) {
+	file, err := os.Open(RetentionLogPath(c))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []RetentionRecord
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var r RetentionRecord
+		if err := dec.Decode(&r); err != nil {
+			return records, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
@@ -0,0 +1,122 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app.log.202401010000",
+		"app.log.gz.202401020000",
+		"app.log.202401030000.gz",
+		"app.log.202401030000.gz.sha256",
+		"app.log.index",
+		"app-old.log.gz",
+		"other.log.202401010000",
+	} {
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, name), []byte(name), 0644))
+	}
+	old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local)
+	assert.Nil(t, os.Chtimes(path.Join(dir, "app-old.log.gz"), old, old))
+
+	cfg := NewDefaultConfig()
+	cfg.LogPath = dir
+	cfg.FileName = "app"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	cfg.ArchivePatterns = []string{"app-*.log.gz"}
+	archives, err := ListArchives(&cfg)
+	assert.Nil(t, err)
+	var names []string
+	for _, a := range archives {
+		names = append(names, path.Base(a.Path))
+	}
+	assert.Equal(t, []string{"app-old.log.gz", "app.log.202401010000", "app.log.gz.202401020000", "app.log.202401030000.gz"}, names)
+
+	cfg.MaxRemain = 2
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+	for _, name := range []string{"app-old.log.gz", "app.log.202401010000"} {
+		assertNoFile(t, path.Join(dir, name))
+	}
+	for _, name := range []string{"app.log.gz.202401020000", "app.log.202401030000.gz", "app.log.202401030000.gz.sha256", "other.log.202401010000"} {
+		_, err := os.Stat(path.Join(dir, name))
+		assert.Nil(t, err, name)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	archives := []Archive{
+		{Path: "a", ModTime: now.Add(-72 * time.Hour), Size: 1024},
+		{Path: "b", ModTime: now.Add(-48 * time.Hour), Size: 1024},
+		{Path: "c", ModTime: now.Add(-time.Hour), Size: 1024},
+	}
+	paths := func(rs []RetentionRecord) (ps []string) {
+		for _, r := range rs {
+			ps = append(ps, r.File+":"+r.Reason)
+		}
+		return ps
+	}
+	c := &Config{MaxAge: 24 * 3600}
+	assert.Equal(t, []string{"a:max_age", "b:max_age"}, paths(c.expired(archives)))
+	c = &Config{MaxTotalSize: "2K"}
+	assert.Equal(t, []string{"a:max_total_size"}, paths(c.expired(archives)))
+	c = &Config{MaxRemain: 1, MaxTotalSize: "2K"}
+	assert.Equal(t, []string{"a:max_remain", "b:max_remain"}, paths(c.expired(archives)))
+	c = &Config{MaxRemain: 5}
+	assert.Nil(t, c.expired(archives))
+}
+
+func TestRetentionDryRun(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app.log.202401010000", "app.log.202401020000"} {
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, name), []byte(name), 0644))
+	}
+	cfg := NewDefaultConfig()
+	cfg.LogPath = dir
+	cfg.FileName = "app"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	cfg.MaxRemain = 1
+	var reported []RetentionRecord
+	WithRetentionDryRun(func(r RetentionRecord) { reported = append(reported, r) })(&cfg)
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w.Close()
+	_, err = os.Stat(path.Join(dir, "app.log.202401010000"))
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(reported)) {
+		assert.Equal(t, path.Join(dir, "app.log.202401010000"), reported[0].File)
+		assert.Equal(t, "max_remain", reported[0].Reason)
+		assert.True(t, reported[0].DryRun)
+	}
+
+	cfg.RetentionDryRun = false
+	cfg.OnRetention = nil
+	w, err = NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w.Close()
+	assertNoFile(t, path.Join(dir, "app.log.202401010000"))
+	records, err := ReadRetentionLog(&cfg)
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(records)) {
+		assert.True(t, records[0].DryRun)
+		assert.False(t, records[1].DryRun)
+		assert.Equal(t, int64(len("app.log.202401010000")), records[1].Size)
+	}
+}
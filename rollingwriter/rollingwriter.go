@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"time"
 )
 
 // 三种滚动模式
@@ -14,7 +15,7 @@ const (
 	VolumeRolling
 )
 
-// 一些默认的全局变量
+// 一些默认的全局变量，BufferSize和QueueSize为Config中未设置时的默认值
 var (
 	BufferSize      = 0x100000
 	QueueSize       = 1024
@@ -26,13 +27,22 @@ var (
 	ErrInternal        = errors.New("error internal")
 	ErrClosed          = errors.New("error write on close")
 	ErrInvalidArgument = errors.New("error argument invalid")
+
+	// 修复不完整日志时补写的标记
+	TailMarker = " [truncated]"
 )
 
 type Manager interface {
 	Fire() chan string
+	Rotate()            // 立即触发一次日志滚动
+	Generation() uint64 // 已触发的滚动次数
 	Close()
 }
 
+// 日志写入的writer，各写入模式的Close遵循相同的语义：
+// 先写入所有缓存和队列中的日志，再停止manager和后台协程，最后关闭日志文件；
+// 重复调用Close和Close之后的Write返回ErrClosed，与Close同时进行的Write要么在关闭前写入，要么返回ErrClosed
+// none模式不提供并发安全保障，不能与Write同时调用Close
 type RollingWriter interface {
 	io.Writer
 	Close() error
@@ -44,6 +54,24 @@ type Config struct {
 	FileName      string `json:"file_name" yaml:"fileName"`            // 日志文件名称
 	MaxRemain     int    `json:"max_remain" yaml:"maxRemain"`          // 日志文件的最大存留数
 
+	// 历史日志的保留策略，与MaxRemain同时生效，启动时和每次滚动后按时间从旧到新删除
+	MaxAge          int      `json:"max_age" yaml:"maxAge"`                    // 历史日志文件的最长保留时间，单位秒，为0时不限制
+	MaxTotalSize    string   `json:"max_total_size" yaml:"maxTotalSize"`       // 历史日志文件的总大小上限，格式与RollingVolumeSize相同，为空时不限制
+	ArchivePatterns []string `json:"archive_patterns" yaml:"archivePatterns"`  // 其他属于该日志的历史文件的glob模式，相对日志目录，如app-*.log.gz
+	RetentionDryRun bool     `json:"retention_dry_run" yaml:"retentionDryRun"` // 只记录将要删除的历史日志文件，不实际删除
+	TrashDir        string   `json:"trash_dir" yaml:"trashDir"`                // 回收目录，不为空时过期的历史日志文件移动到该目录而不是删除
+	TrashTTL        int      `json:"trash_ttl" yaml:"trashTTL"`                // 回收目录中文件的保留时间，单位秒，为0时不删除
+
+	// 每条清理记录的回调，清理记录同时追加到.retention文件
+	OnRetention func(RetentionRecord) `json:"-" yaml:"-"`
+
+	// 滚动、压缩和清理事件的回调，在执行滚动的协程中同步调用，不能阻塞
+	OnEvent func(Event) `json:"-" yaml:"-"`
+
+	// 按日期分目录的时间格式，如2006-01-02，不为空时当前日志文件和历史日志文件位于LogPath下按日期命名的子目录中，
+	// 历史日志文件按其开始时间放入对应的目录，按大小滚动时日期变化也会触发滚动，不能与copytruncate同时使用
+	DirLayout string `json:"dir_layout" yaml:"dirLayout"`
+
 	// 日志滚动策略，三个选项
 	// 0：WithoutRolling:，不滚动
 	// 1：TimeRolling，时间滚动策略，
@@ -52,9 +80,81 @@ type Config struct {
 	RollingTimePattern string `json:"rolling_time_pattern" yaml:"rollingTimePattern"` // 时间滚动策略时的cron表达式
 	RollingVolumeSize  string `json:"rolling_volume_size" yaml:"rollingVolumeSize"`   // 大小滚动策略时的截断大小
 
-	WriterMode            string `json:"writer_mode" yaml:"writerMode"`                 // none, lock, async, buffer
+	WriterMode            string `json:"writer_mode" yaml:"writerMode"`                 // none, lock, async, buffer, sharded, gzip, audit, mmap和uring（仅Linux），或通过RegisterWriterMode注册的模式
 	BufferWriterThreshold int    `json:"buffer_threshold" yaml:"bufferWriterThreshold"` // 一部并发是缓存池的大小
 	Compress              bool   `json:"compress" yaml:"compress"`                      // 是否压缩历史日志
+	Compressor            string `json:"compressor" yaml:"compressor"`                  // 压缩方式，为空时为gzip，其他方式需要通过RegisterCompressor注册
+	Checksum              bool   `json:"checksum" yaml:"checksum"`                      // 是否为历史日志写入.sha256校验文件
+
+	// 历史日志压缩的时间安排，避免大文件压缩与业务高峰争抢CPU，writer在等待期间关闭时不再压缩
+	CompressAfter  int    `json:"compress_after" yaml:"compressAfter"`   // 滚动后延迟压缩的时间，单位秒
+	CompressWindow string `json:"compress_window" yaml:"compressWindow"` // 允许压缩的时间段，如02:00-05:00，为空时不限制
+
+	// 日志滚动方式，两个选项
+	// rename：重命名当前日志文件后重新打开，默认方式
+	// copytruncate：复制当前日志文件后清空，文件描述符保持不变，适用于与其他程序共享文件的场景
+	RotationStrategy string `json:"rotation_strategy" yaml:"rotationStrategy"`
+
+	// 滚动的历史日志文件已存在时追加到该文件，而不是使用带序号的新文件，适用于copytruncate和多进程写入同名历史日志的场景
+	// 只有压缩后的同名文件存在时仍使用序号
+	AppendOnConflict bool `json:"append_on_conflict" yaml:"appendOnConflict"`
+
+	FileMode os.FileMode `json:"file_mode" yaml:"fileMode"` // 日志文件权限，为0时使用DefualtFileMode
+	DirMode  os.FileMode `json:"dir_mode" yaml:"dirMode"`   // 日志目录权限，为0时使用0700
+	Uid      *int        `json:"uid" yaml:"uid"`            // 日志文件及目录的属主，为nil时不修改
+	Gid      *int        `json:"gid" yaml:"gid"`            // 日志文件及目录的属组，为nil时不修改
+
+	WatchFile bool `json:"watch_file" yaml:"watchFile"` // 是否监测日志文件被外部删除或移动，发生时重新创建
+	LazyOpen  bool `json:"lazy_open" yaml:"lazyOpen"`   // 是否延迟到第一次写入时打开日志文件，没有写入的日志不创建文件
+
+	// async模式下批量写入的配置，将队列中的多条日志合并后一次写入文件
+	BatchSize       int `json:"batch_size" yaml:"batchSize"`              // 单次合并写入的最大日志条数，小于等于1时不合并
+	BatchMaxLatency int `json:"batch_max_latency" yaml:"batchMaxLatency"` // 等待凑满一批日志的最长时间，单位毫秒，为0时不等待
+
+	// 写入使用的缓存池和队列，async模式最多占用约QueueSize*BufferSize的内存，可以通过Stats中的缓存池命中率和队列峰值调整
+	BufferSize int `json:"buffer_size" yaml:"bufferSize"` // 使用缓存池的最大单条日志大小，单位字节，超过时直接分配，为0时使用全局的BufferSize
+	QueueSize  int `json:"queue_size" yaml:"queueSize"`   // async模式的队列长度，为0时使用全局的QueueSize
+	// async模式队列中等待写入数据的内存预算，格式与RollingVolumeSize相同，超过预算或队列已满时日志按顺序写入日志文件旁的.spill溢出文件，
+	// 写入协程处理完队列后再追加到日志文件，为空时不限制，队列已满时Write阻塞
+	MaxPendingBytes string `json:"max_pending_bytes" yaml:"maxPendingBytes"`
+
+	FlushInterval int `json:"flush_interval" yaml:"flushInterval"` // sharded模式下缓存的刷新间隔，单位毫秒，为0时为100毫秒
+	Shards        int `json:"shards" yaml:"shards"`                // sharded模式的分片数，为0时为GOMAXPROCS
+
+	// 调优方案，low-latency、throughput或low-memory，按CPU数量和可用内存推导队列长度、批量大小、刷新间隔、缓存阈值和分片数，
+	// 只填充未设置或保持默认值的参数，为空时不使用
+	Profile string `json:"profile" yaml:"profile"`
+
+	// 启动时对日志文件末尾不完整日志（没有换行符或不是合法的json）的处理，三个选项
+	// 空：不处理
+	// repair：缺少换行符时补写TailMarker和换行符
+	// partial：将不完整的日志移动到.partial文件
+	RecoverTail string `json:"recover_tail" yaml:"recoverTail"`
+
+	ErrorHandler ErrorHandler `json:"-" yaml:"-"` // 内部错误的处理，为空时使用DefaultErrorHandler
+
+	// 日志文件的头尾信息，text/template模板，可以使用BannerInfo中的变量
+	Header string `json:"header" yaml:"header"` // 新日志文件开头写入的内容
+	Footer string `json:"footer" yaml:"footer"` // 日志滚动前在旧文件末尾写入的内容
+
+	RotationIndex bool `json:"rotation_index" yaml:"rotationIndex"` // 是否在.index文件中记录每次滚动，便于外部采集程序跨滚动续读
+
+	Clock Clock `json:"-" yaml:"-"` // 日志滚动使用的时钟，为空时使用RealClock
+
+	// 写入超时的配置，避免日志所在的挂载点无响应时阻塞应用
+	WriteTimeout   int       `json:"write_timeout" yaml:"writeTimeout"` // 单次写入的超时时间，单位毫秒，为0时不限制
+	DegradedWriter io.Writer `json:"-" yaml:"-"`                        // 写入超时后日志的降级输出，为空时使用标准错误
+
+	// 日志所在磁盘的剩余空间水位，格式与RollingVolumeSize相同或为磁盘总空间的百分比，如10%，为空时不检查
+	// 低于低水位时立即压缩等待中的历史日志文件，并从最旧的开始删除历史日志文件直到恢复到低水位以上
+	// 低于危险水位时日志不再写入日志文件，按DiskCriticalMode处理
+	DiskLowWatermark      string `json:"disk_low_watermark" yaml:"diskLowWatermark"`
+	DiskCriticalWatermark string `json:"disk_critical_watermark" yaml:"diskCriticalWatermark"`
+	DiskCriticalMode      string `json:"disk_critical_mode" yaml:"diskCriticalMode"` // stderr：输出到标准错误，drop：丢弃，为空时为stderr
+
+	// 大小滚动策略下是否为日志文件预分配到滚动阈值大小的磁盘空间（Linux上使用fallocate，不改变文件大小）
+	// 减少文件碎片，磁盘空间不足时在打开或滚动日志文件时报错，而不是在写入过程中
+	Preallocate bool `json:"preallocate" yaml:"preallocate"`
 }
 
 // 默认配置
@@ -70,18 +170,42 @@ func NewDefaultConfig() Config {
 		WriterMode:            "lock",
 		BufferWriterThreshold: 64,
 		Compress:              false,
+		RotationStrategy:      "rename",
+		BatchSize:             64,
 	}
 }
 
-// 生成日志文件完整路径
+// 填充默认值后实际生效的配置
+func (c *Config) resolved() Config {
+	r := c.withProfile()
+	if r.RotationStrategy == "" {
+		r.RotationStrategy = "rename"
+	}
+	r.FileMode = c.fileMode()
+	r.DirMode = c.dirMode()
+	r.Clock = c.clock()
+	if r.ErrorHandler == nil {
+		r.ErrorHandler = DefaultErrorHandler
+	}
+	return r
+}
+
+// 生成日志文件完整路径，按日期分目录时位于当前日期的目录中
 func LogFilePath(c *Config) (filepath string) {
-	filepath = path.Join(c.LogPath, c.FileName) + ".log"
+	filepath = path.Join(c.logDir(c.clock().Now()), c.FileName) + ".log"
 	return filepath
 }
 
 // 配置构造函数，用于更新配置
 type Option func(*Config)
 
+// 使用完整的配置替换当前配置，之后的Option在其基础上继续修改
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
 // 更新时间格式
 func WithTimeTagFormat(format string) Option {
 	return func(c *Config) {
@@ -103,6 +227,27 @@ func WithFileName(name string) Option {
 	}
 }
 
+// 开启延迟打开日志文件
+func WithLazyOpen() Option {
+	return func(c *Config) {
+		c.LazyOpen = true
+	}
+}
+
+// 设置按日期分目录的时间格式
+func WithDirLayout(layout string) Option {
+	return func(c *Config) {
+		c.DirLayout = layout
+	}
+}
+
+// 设置写入模式，none、lock、async、buffer或注册的其他模式
+func WithWriterMode(mode string) Option {
+	return func(c *Config) {
+		c.WriterMode = mode
+	}
+}
+
 // 改为async模式
 func WithAsynchronous() Option {
 	return func(c *Config) {
@@ -138,6 +283,29 @@ func WithCompress() Option {
 	}
 }
 
+// 开启压缩历史日志文件并使用通过RegisterCompressor注册的压缩方式
+func WithCompressor(name string) Option {
+	return func(c *Config) {
+		c.Compress = true
+		c.Compressor = name
+	}
+}
+
+// 设置滚动后延迟压缩的时间和允许压缩的时间段
+func WithCompressSchedule(after time.Duration, window string) Option {
+	return func(c *Config) {
+		c.CompressAfter = int(after / time.Second)
+		c.CompressWindow = window
+	}
+}
+
+// 开启历史日志文件的校验文件
+func WithChecksum() Option {
+	return func(c *Config) {
+		c.Checksum = true
+	}
+}
+
 // 更新历史文件保存数
 func WithMaxRemain(max int) Option {
 	return func(c *Config) {
@@ -145,6 +313,64 @@ func WithMaxRemain(max int) Option {
 	}
 }
 
+// 设置历史日志文件的最长保留时间
+func WithMaxAge(age time.Duration) Option {
+	return func(c *Config) {
+		c.MaxAge = int(age / time.Second)
+	}
+}
+
+// 设置历史日志文件的总大小上限
+func WithMaxTotalSize(size string) Option {
+	return func(c *Config) {
+		c.MaxTotalSize = size
+	}
+}
+
+// 设置其他属于该日志的历史文件的glob模式
+func WithArchivePatterns(patterns ...string) Option {
+	return func(c *Config) {
+		c.ArchivePatterns = append([]string(nil), patterns...)
+	}
+}
+
+// 设置清理记录的回调
+func WithOnRetention(fn func(RetentionRecord)) Option {
+	return func(c *Config) {
+		c.OnRetention = fn
+	}
+}
+
+// 设置滚动、压缩和清理事件的回调
+func WithOnEvent(fn func(Event)) Option {
+	return func(c *Config) {
+		c.OnEvent = fn
+	}
+}
+
+// 开启保留策略的dry-run，回调接收将要删除的历史日志文件
+func WithRetentionDryRun(fn func(RetentionRecord)) Option {
+	return func(c *Config) {
+		c.RetentionDryRun = true
+		c.OnRetention = fn
+	}
+}
+
+// 设置回收目录及其中文件的保留时间，过期的历史日志文件移动到回收目录
+func WithTrash(dir string, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.TrashDir = dir
+		c.TrashTTL = int(ttl / time.Second)
+	}
+}
+
+// 设置日志滚动策略，WithoutRolling、TimeRolling或VolumeRolling
+func WithRollingPolicy(policy int) Option {
+	return func(c *Config) {
+		c.RollingPolicy = policy
+	}
+}
+
 // 设置为不滚动模式
 func WithoutRollingPolicy() Option {
 	return func(c *Config) {
@@ -167,3 +393,157 @@ func WithRollingVolumeSize(size string) Option {
 		c.RollingVolumeSize = size
 	}
 }
+
+// 设置日志滚动方式，rename或copytruncate
+func WithRotationStrategy(strategy string) Option {
+	return func(c *Config) {
+		c.RotationStrategy = strategy
+	}
+}
+
+// 改为copytruncate滚动方式
+func WithCopyTruncate() Option {
+	return func(c *Config) {
+		c.RotationStrategy = "copytruncate"
+	}
+}
+
+// 开启历史日志文件重名时追加
+func WithAppendOnConflict() Option {
+	return func(c *Config) {
+		c.AppendOnConflict = true
+	}
+}
+
+// 设置日志文件权限
+func WithFileMode(mode os.FileMode) Option {
+	return func(c *Config) {
+		c.FileMode = mode
+	}
+}
+
+// 设置日志目录权限
+func WithDirMode(mode os.FileMode) Option {
+	return func(c *Config) {
+		c.DirMode = mode
+	}
+}
+
+// 设置日志文件及目录的属主和属组，与os.Chown相同，为-1时不修改
+func WithOwner(uid, gid int) Option {
+	return func(c *Config) {
+		c.Uid = &uid
+		c.Gid = &gid
+	}
+}
+
+// 开启日志文件监测，文件被外部删除或移动后重新创建
+func WithWatchFile() Option {
+	return func(c *Config) {
+		c.WatchFile = true
+	}
+}
+
+// 设置async模式下批量写入的最大日志条数和最长等待时间
+func WithBatch(size int, maxLatency time.Duration) Option {
+	return func(c *Config) {
+		c.BatchSize = size
+		c.BatchMaxLatency = int(maxLatency / time.Millisecond)
+	}
+}
+
+// 设置缓存的刷新间隔
+func WithFlushInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.FlushInterval = int(interval / time.Millisecond)
+	}
+}
+
+// 设置启动时对不完整日志的处理方式，repair或partial
+func WithRecoverTail(mode string) Option {
+	return func(c *Config) {
+		c.RecoverTail = mode
+	}
+}
+
+// 设置内部错误的处理
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(c *Config) {
+		c.ErrorHandler = h
+	}
+}
+
+// 设置日志文件的头尾信息模板
+func WithBanner(header, footer string) Option {
+	return func(c *Config) {
+		c.Header = header
+		c.Footer = footer
+	}
+}
+
+// 开启日志滚动索引
+func WithRotationIndex() Option {
+	return func(c *Config) {
+		c.RotationIndex = true
+	}
+}
+
+// 设置日志滚动使用的时钟
+func WithClock(clock Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// 设置日志所在磁盘的剩余空间水位及低于危险水位时的处理方式
+func WithDiskWatermark(low, critical, mode string) Option {
+	return func(c *Config) {
+		c.DiskLowWatermark = low
+		c.DiskCriticalWatermark = critical
+		c.DiskCriticalMode = mode
+	}
+}
+
+// 开启日志文件预分配
+func WithPreallocate() Option {
+	return func(c *Config) {
+		c.Preallocate = true
+	}
+}
+
+// 设置缓存池的最大单条日志大小和async模式的队列长度
+func WithBufferPool(bufferSize, queueSize int) Option {
+	return func(c *Config) {
+		c.BufferSize = bufferSize
+		c.QueueSize = queueSize
+	}
+}
+
+// 设置调优方案，按CPU数量和可用内存推导未设置的调优参数
+func WithProfile(profile string) Option {
+	return func(c *Config) {
+		c.Profile = profile
+	}
+}
+
+// 设置sharded模式的分片数
+func WithShards(n int) Option {
+	return func(c *Config) {
+		c.Shards = n
+	}
+}
+
+// 设置async模式队列的内存预算，超过时写入溢出文件
+func WithMaxPendingBytes(size string) Option {
+	return func(c *Config) {
+		c.MaxPendingBytes = size
+	}
+}
+
+// 设置写入超时及超时后日志的降级输出，sink为nil时使用标准错误
+func WithWriteTimeout(timeout time.Duration, sink io.Writer) Option {
+	return func(c *Config) {
+		c.WriteTimeout = int(timeout / time.Millisecond)
+		c.DegradedWriter = sink
+	}
+}
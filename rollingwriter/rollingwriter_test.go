@@ -1,7 +1,10 @@
 package rollingwriter
 
 import (
+	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,6 +30,46 @@ func TestOptions(t *testing.T) {
 		WriterMode:            "lock",
 		BufferWriterThreshold: 8,
 		Compress:              true,
+		RotationStrategy:      "rename",
+		BatchSize:             64,
 	}
 	assert.Equal(t, cfg, destcfg)
 }
+
+func TestWithConfig(t *testing.T) {
+	base := NewDefaultConfig()
+	base.FileName = "bar"
+	base.MaxAge = 60
+	cfg := NewDefaultConfig()
+	for _, opt := range []Option{WithLogPath("./tmp"), WithConfig(base), WithRollingPolicy(VolumeRolling), WithWriterMode("async")} {
+		opt(&cfg)
+	}
+	base.RollingPolicy = VolumeRolling
+	base.WriterMode = "async"
+	assert.Equal(t, base, cfg)
+}
+
+// 每个可配置的字段都有对应的Option
+func TestOptionsCoverConfig(t *testing.T) {
+	options := []Option{
+		WithTimeTagFormat("2006"), WithLogPath("./"), WithFileName("foo"), WithMaxRemain(3),
+		WithMaxAge(time.Hour), WithMaxTotalSize("1gb"), WithArchivePatterns("foo-*.log"),
+		WithRetentionDryRun(func(RetentionRecord) {}), WithOnEvent(func(Event) {}), WithTrash("./trash", time.Hour),
+		WithDirLayout("2006-01-02"), WithRollingTimePattern("0 0 * * *"), WithRollingVolumeSize("1mb"), WithWriterMode("async"),
+		WithBufferThreshold(8), WithCompress(), WithCompressor("gzip"), WithChecksum(), WithCompressSchedule(time.Second, "02:00-05:00"),
+		WithRotationStrategy("rename"), WithAppendOnConflict(), WithFileMode(0600), WithDirMode(0700), WithOwner(1, 1),
+		WithWatchFile(), WithLazyOpen(), WithBatch(8, time.Millisecond), WithFlushInterval(time.Millisecond),
+		WithRecoverTail("repair"), WithErrorHandler(DefaultErrorHandler), WithBanner("h", "f"),
+		WithRotationIndex(), WithClock(RealClock), WithWriteTimeout(time.Second, os.Stderr),
+		WithDiskWatermark("1G", "5%", "drop"), WithPreallocate(), WithBufferPool(4096, 16), WithMaxPendingBytes("64mb"),
+		WithProfile(ProfileThroughput), WithShards(4),
+	}
+	var cfg Config
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	v := reflect.ValueOf(cfg)
+	for i := 0; i < v.NumField(); i++ {
+		assert.False(t, v.Field(i).IsZero(), v.Type().Field(i).Name)
+	}
+}
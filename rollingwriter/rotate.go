@@ -0,0 +1,143 @@
+package rollingwriter
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// 支持在后台执行日志滚动的RollingWriter，各写入模式按自身的并发保护方式实现
+type rotator interface {
+	rotate(filename string)
+}
+
+// 接收manager触发的日志滚动并交给rotator执行，关闭writer时退出
+func (w *Writer) rotateLoop(r rotator) {
+	for {
+		select {
+		case filename := <-w.fire:
+			// 日志文件交接给子进程后由子进程滚动
+			w.handover.Lock()
+			if !w.handover.done {
+				r.rotate(filename)
+			}
+			w.handover.Unlock()
+		case <-w.closing:
+			return
+		}
+	}
+}
+
+// 立即执行一次日志滚动，与定时或按大小触发的滚动串行执行，writer关闭后不再执行
+// 不能在持有writer锁的情况下调用
+func (w *Writer) Rotate() {
+	w.m.Rotate()
+}
+
+// 关闭writer的后台协程和manager，可以重复调用，只有第一次调用返回true
+func (w *Writer) shutdown() bool {
+	first := false
+	w.closeOnce.Do(func() {
+		close(w.closing)
+		w.m.Close()
+		first = true
+	})
+	return first
+}
+
+// writer是否已经关闭
+func (w *Writer) isClosed() bool {
+	select {
+	case <-w.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// 原子性的获取当前写入日志文件
+func (w *Writer) current() *os.File {
+	return w.file.Load()
+}
+
+// 无保护的writer直接执行滚动，Reopen原子性的替换日志文件，writer关闭后不再滚动
+func (w *Writer) rotate(filename string) {
+	if w.isClosed() {
+		return
+	}
+	if err := w.Reopen(filename); err != nil {
+		w.handleError("rotate log file", err)
+	}
+}
+
+// lock模式在锁内执行滚动
+func (w *LockedWriter) rotate(filename string) {
+	w.Lock()
+	defer w.Unlock()
+	w.Writer.rotate(filename)
+}
+
+// async模式交给写入协程执行滚动，保证滚动前入队的日志写入旧文件
+func (w *AsynchronousWriter) rotate(filename string) {
+	select {
+	case w.rotations <- filename:
+	case <-w.ctx:
+	}
+}
+
+// 滚动的目标历史日志文件，未压缩的同名文件已存在且开启了AppendOnConflict时返回true，追加到该文件
+func (c *Config) archiveTarget(file string) (string, bool) {
+	if c.AppendOnConflict && exists(file) {
+		return file, true
+	}
+	return c.uniqueArchive(file), false
+}
+
+// 将重命名为renamed的历史日志追加到已存在的file后删除renamed，返回之后处理的历史日志文件
+// 追加失败时保留renamed作为单独的历史日志文件
+func (w *Writer) appendArchive(file, renamed string, oldfile *os.File) (string, *os.File) {
+	w.appendMu.Lock()
+	defer w.appendMu.Unlock()
+	err := func() error {
+		dst, err := w.cf.openFile(file, os.O_WRONLY|os.O_APPEND)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+		if _, err := oldfile.Seek(0, 0); err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, oldfile); err != nil {
+			return err
+		}
+		return dst.Sync()
+	}()
+	if err != nil {
+		w.handleError("append archive", err)
+		return renamed, oldfile
+	}
+	appended, err := os.Open(file)
+	if err != nil {
+		w.handleError("append archive", err)
+		return renamed, oldfile
+	}
+	oldfile.Close()
+	w.handleError("remove appended archive", os.Remove(renamed))
+	return file, appended
+}
+
+// 历史日志文件已存在时（如一个时间格式精度内多次滚动）在名称后加.1、.2等序号，避免覆盖
+// 压缩后的同名文件也视为已存在
+func (c *Config) uniqueArchive(file string) string {
+	ext := c.compressExt()
+	name := file
+	for seq := 1; exists(name) || exists(name+ext); seq++ {
+		name = file + "." + strconv.Itoa(seq)
+	}
+	return name
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
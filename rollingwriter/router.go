@@ -0,0 +1,154 @@
+package rollingwriter
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// 按字段值路由日志文件的配置
+type RouterConfig struct {
+	Field   string `json:"field" yaml:"field"`      // json日志中选择日志文件的字段，如tenant_id
+	MaxOpen int    `json:"max_open" yaml:"maxOpen"` // 同时打开的日志文件数量上限，超过时关闭最久未使用的，为0时为64
+	Default string `json:"default" yaml:"default"`  // 字段不存在时使用的值，为空时为default
+}
+
+// 按字段值将日志写入不同文件的writer，并发安全
+// 每个字段值对应FileName.<值>.log，首次写入时创建，按配置的策略各自滚动
+type RouterWriter struct {
+	sync.Mutex
+	cf     Config
+	rc     RouterConfig
+	lru    *list.List // 按最近使用排序的routes，最近使用的在前
+	routes map[string]*list.Element
+	closed bool
+}
+
+// 字段值对应的writer
+type route struct {
+	key    string
+	writer RollingWriter
+}
+
+// 生成RouterWriter，c为每个字段值的日志文件配置，FileName作为文件名前缀
+func NewRouterWriter(c Config, rc RouterConfig) (*RouterWriter, error) {
+	if rc.Field == "" || c.LogPath == "" || c.FileName == "" {
+		return nil, ErrInvalidArgument
+	}
+	if rc.MaxOpen <= 0 {
+		rc.MaxOpen = 64
+	}
+	if rc.Default == "" {
+		rc.Default = "default"
+	}
+	return &RouterWriter{cf: c, rc: rc, lru: list.New(), routes: make(map[string]*list.Element)}, nil
+}
+
+// 按字段值写入对应的日志文件
+func (w *RouterWriter) Write(b []byte) (int, error) {
+	key := w.key(b)
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	writer, err := w.get(key)
+	if err != nil {
+		return 0, err
+	}
+	return writer.Write(b)
+}
+
+// 立即滚动所有打开的日志文件
+func (w *RouterWriter) Rotate() {
+	w.Lock()
+	defer w.Unlock()
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		if r, ok := e.Value.(*route).writer.(interface{ Rotate() }); ok {
+			r.Rotate()
+		}
+	}
+}
+
+// 每个字段值的日志文件实际生效的配置，FileName为文件名前缀
+func (w *RouterWriter) Config() Config {
+	return w.cf.resolved()
+}
+
+// 当前打开的字段值
+func (w *RouterWriter) Keys() []string {
+	w.Lock()
+	defer w.Unlock()
+	keys := make([]string, 0, w.lru.Len())
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*route).key)
+	}
+	return keys
+}
+
+// 关闭所有打开的日志文件
+func (w *RouterWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	var err error
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		err = multierr.Append(err, e.Value.(*route).writer.Close())
+	}
+	w.lru.Init()
+	w.routes = nil
+	return err
+}
+
+// 取出日志中的字段值，替换不能用于文件名的字符
+func (w *RouterWriter) key(b []byte) string {
+	var fields map[string]interface{}
+	if json.Unmarshal(b, &fields) != nil {
+		return w.rc.Default
+	}
+	v, ok := fields[w.rc.Field]
+	if !ok || v == nil {
+		return w.rc.Default
+	}
+	key := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, fmt.Sprint(v))
+	if key == "" {
+		return w.rc.Default
+	}
+	return key
+}
+
+// 获取字段值对应的writer，不存在时创建，超过MaxOpen时关闭最久未使用的writer，需要持有锁
+func (w *RouterWriter) get(key string) (RollingWriter, error) {
+	if e, ok := w.routes[key]; ok {
+		w.lru.MoveToFront(e)
+		return e.Value.(*route).writer, nil
+	}
+	for w.lru.Len() >= w.rc.MaxOpen {
+		oldest := w.lru.Back()
+		r := w.lru.Remove(oldest).(*route)
+		delete(w.routes, r.key)
+		if err := r.writer.Close(); err != nil {
+			w.cf.handleError("close routed writer", err)
+		}
+	}
+	c := w.cf
+	c.FileName = w.cf.FileName + "." + key
+	writer, err := NewWriterFromConfig(&c)
+	if err != nil {
+		return nil, err
+	}
+	w.routes[key] = w.lru.PushFront(&route{key: key, writer: writer})
+	return writer, nil
+}
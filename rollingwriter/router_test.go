@@ -0,0 +1,46 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterWriter(t *testing.T) {
+	dir := t.TempDir()
+	c := NewDefaultConfig()
+	c.LogPath, c.FileName, c.RollingPolicy = dir, "app", WithoutRolling
+	_, err := NewRouterWriter(c, RouterConfig{})
+	assert.Equal(t, ErrInvalidArgument, err)
+
+	w, err := NewRouterWriter(c, RouterConfig{Field: "tenant", MaxOpen: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, line := range []string{
+		`{"tenant":"acme","msg":"1"}`,
+		`{"tenant":"globex","msg":"2"}`,
+		`{"msg":"3"}`,
+		`{"tenant":"acme","msg":"4"}`,
+		`{"tenant":"../etc","msg":"5"}`,
+		"plain text",
+	} {
+		_, err := w.Write([]byte(line + "\n"))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"default", "___etc"}, w.Keys())
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("{}\n"))
+	assert.Equal(t, ErrClosed, err)
+
+	read := func(name string) string {
+		buf, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(buf)
+	}
+	assert.Equal(t, "{\"tenant\":\"acme\",\"msg\":\"1\"}\n{\"tenant\":\"acme\",\"msg\":\"4\"}\n", read("app.acme.log"))
+	assert.Equal(t, "{\"tenant\":\"globex\",\"msg\":\"2\"}\n", read("app.globex.log"))
+	assert.Equal(t, "{\"msg\":\"3\"}\nplain text\n", read("app.default.log"))
+	assert.Equal(t, "{\"tenant\":\"../etc\",\"msg\":\"5\"}\n", read("app.___etc.log"))
+}
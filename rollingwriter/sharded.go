@@ -0,0 +1,175 @@
+package rollingwriter
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sharded模式默认的刷新间隔
+const _defaultFlushInterval = 100 * time.Millisecond
+
+// 当WriterMode为sharded时使用的结构，并发安全
+// 写入按轮询分散到多个分片缓存，每个分片有独立的锁，由单个刷新协程按写入顺序合并后写入文件，
+// 降低大量协程并发写入时在单个mutex上的竞争
+type ShardedWriter struct {
+	Writer
+	fileMu   sync.Mutex // 保护刷新写入与日志滚动
+	shards   []shard
+	seq      uint64 // 全局写入序号，用于合并时恢复写入顺序
+	next     uint32 // 下一次写入使用的分片
+	interval time.Duration
+	kick     chan struct{} // 分片缓存超过阈值时通知刷新
+	stop     chan struct{}
+	done     chan struct{}
+	closed   int32
+}
+
+// 分片缓存
+type shard struct {
+	sync.Mutex
+	buf     []byte
+	entries []shardEntry
+	_       [40]byte // 避免相邻分片的伪共享
+}
+
+// 分片缓存中的一条日志
+type shardEntry struct {
+	seq uint64
+	end int // 日志在分片缓存中的结束位置
+}
+
+// 合并时使用的日志
+type mergeEntry struct {
+	seq  uint64
+	data []byte
+}
+
+func init() {
+	RegisterWriterMode("sharded", func(c Config, w Writer) (RollingWriter, error) {
+		sw := &ShardedWriter{
+			Writer:   w,
+			shards:   make([]shard, c.shards()),
+			interval: time.Duration(c.FlushInterval) * time.Millisecond,
+			kick:     make(chan struct{}, 1),
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+		if sw.interval <= 0 {
+			sw.interval = _defaultFlushInterval
+		}
+		go sw.flusher()
+		return sw, nil
+	})
+}
+
+// sharded模式的Write接口实现
+func (w *ShardedWriter) Write(b []byte) (int, error) {
+	s := &w.shards[atomic.AddUint32(&w.next, 1)%uint32(len(w.shards))]
+	s.Lock()
+	// 在分片锁内检查，关闭后的最后一次刷新取出所有分片中的日志，之后的写入返回ErrClosed
+	if atomic.LoadInt32(&w.closed) == 1 {
+		s.Unlock()
+		return 0, ErrClosed
+	}
+	// 在分片锁内获取序号，保证刷新时序号不大于截止序号的日志都已写入分片
+	seq := atomic.AddUint64(&w.seq, 1)
+	s.buf = append(s.buf, b...)
+	s.entries = append(s.entries, shardEntry{seq: seq, end: len(s.buf)})
+	full := len(s.buf) > w.cf.BufferWriterThreshold
+	s.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return w.written(b, len(b), nil)
+}
+
+// 定时或分片缓存超过阈值时刷新
+func (w *ShardedWriter) flusher() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	var out []byte
+	var merged []mergeEntry
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.kick:
+		case <-w.stop:
+			w.flush(out, merged, math.MaxUint64)
+			return
+		}
+		out, merged = w.flush(out, merged, atomic.LoadUint64(&w.seq))
+	}
+}
+
+// 取出所有分片中序号不大于截止序号cutoff的日志，按序号合并后一次写入文件
+func (w *ShardedWriter) flush(out []byte, merged []mergeEntry, cutoff uint64) ([]byte, []mergeEntry) {
+	out, merged = out[:0], merged[:0]
+	for i := range w.shards {
+		s := &w.shards[i]
+		s.Lock()
+		n := sort.Search(len(s.entries), func(j int) bool { return s.entries[j].seq > cutoff })
+		if n > 0 {
+			// 复制需要刷新的部分，剩余的日志留在分片中
+			start := len(out)
+			end := s.entries[n-1].end
+			out = append(out, s.buf[:end]...)
+			prev := 0
+			for _, e := range s.entries[:n] {
+				merged = append(merged, mergeEntry{seq: e.seq, data: out[start+prev : start+e.end]})
+				prev = e.end
+			}
+			rest := copy(s.buf, s.buf[end:])
+			s.buf = s.buf[:rest]
+			k := copy(s.entries, s.entries[n:])
+			s.entries = s.entries[:k]
+			for j := range s.entries {
+				s.entries[j].end -= end
+			}
+		}
+		s.Unlock()
+	}
+	if len(merged) == 0 {
+		return out, merged
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].seq < merged[j].seq })
+
+	// 按序号重新排列，merged中的data引用out，因此写入新的缓存
+	ordered := w.pool.Get(len(out))
+	for _, e := range merged {
+		*ordered = append(*ordered, e.data...)
+	}
+	w.fileMu.Lock()
+	if err := w.rolling(); err != nil {
+		w.handleError("recreate log file", err)
+	}
+	if _, err := w.current().Write(*ordered); err != nil {
+		w.handleError("sharded write", err)
+	}
+	w.fileMu.Unlock()
+	w.pool.Put(ordered)
+	return out, merged
+}
+
+// sharded模式与刷新写入互斥的执行滚动
+func (w *ShardedWriter) rotate(filename string) {
+	w.fileMu.Lock()
+	defer w.fileMu.Unlock()
+	w.Writer.rotate(filename)
+}
+
+// sharded模式的Close接口实现，写入所有缓存的日志后关闭文件
+func (w *ShardedWriter) Close() error {
+	if !atomic.CompareAndSwapInt32(&w.closed, 0, 1) {
+		return ErrClosed
+	}
+	close(w.stop)
+	<-w.done
+	return w.Writer.Close()
+}
@@ -0,0 +1,56 @@
+package rollingwriter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedWriter(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "sharded"
+	cfg.FlushInterval = 1
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+
+	// 单个协程的写入顺序在合并后保持不变
+	var c int = 1000
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < c; i++ {
+				w.Write([]byte(fmt.Sprintf("%d %d\n", g, i)))
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Nil(t, w.Close())
+	assert.Equal(t, ErrClosed, w.Close())
+
+	file, err := os.Open(LogFilePath(&cfg))
+	assert.Nil(t, err)
+	defer file.Close()
+	last := make(map[int]int)
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var g, i int
+		fmt.Sscanf(scanner.Text(), "%d %d", &g, &i)
+		if prev, ok := last[g]; ok {
+			assert.Equal(t, prev+1, i)
+		}
+		last[g] = i
+		lines++
+	}
+	assert.Equal(t, 8*c, lines)
+}
@@ -0,0 +1,164 @@
+package rollingwriter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// 溢出文件的后缀，溢出文件名为日志文件名加序号和后缀，如app.log.3.spill
+const SpillSuffix = ".spill"
+
+// async模式的内存预算，队列中等待写入的数据超过MaxPendingBytes或队列已满时，之后的日志写入溢出文件，
+// 写入协程处理完队列后按顺序将溢出文件追加到日志文件，突发的大量日志既不占用过多内存也不阻塞应用
+// 溢出期间所有日志都写入溢出文件，保证队列中的日志总是早于溢出文件中的日志
+type asyncSpill struct {
+	bytes  uint64 // 原子操作的字段放在开头，保证32位平台上8字节对齐，累计写入溢出文件的字节数
+	budget int64
+	prefix string // 溢出文件名前缀，即日志文件路径
+	mu     sync.Mutex
+	file   *os.File // 正在写入的溢出文件，没有溢出时为nil
+	seq    int
+	signal chan struct{} // 有新的溢出文件时通知写入协程
+}
+
+// 配置了MaxPendingBytes时生成溢出处理
+func newAsyncSpill(c *Config, logpath string) *asyncSpill {
+	if c.MaxPendingBytes == "" {
+		return nil
+	}
+	return &asyncSpill{budget: ParseSize(c.MaxPendingBytes), prefix: logpath, signal: make(chan struct{}, 1)}
+}
+
+// 日志文件残留的溢出文件，按序号排序
+func spillFiles(logpath string) []string {
+	files, _ := filepath.Glob(logpath + ".*" + SpillSuffix)
+	seq := func(name string) int {
+		var n int
+		fmt.Sscanf(name[len(logpath)+1:], "%d", &n)
+		return n
+	}
+	sort.Slice(files, func(i, j int) bool { return seq(files[i]) < seq(files[j]) })
+	return files
+}
+
+// 放入队列或写入溢出文件，溢出文件无法创建时等待放入队列
+func (w *AsynchronousWriter) enqueue(buf *[]byte) {
+	n := int64(len(*buf))
+	if s := w.spill; s != nil {
+		s.mu.Lock()
+		if s.file == nil && atomic.LoadInt64(&w.pending)+n <= s.budget {
+			atomic.AddInt64(&w.pending, n)
+			select {
+			case w.queue <- buf:
+				s.mu.Unlock()
+				w.queued(len(w.queue))
+				return
+			default:
+				atomic.AddInt64(&w.pending, -n)
+			}
+		}
+		err := w.spillWrite(*buf)
+		s.mu.Unlock()
+		if err == nil {
+			w.pool.Put(buf)
+			return
+		}
+		w.handleError("spill log", err)
+	}
+	atomic.AddInt64(&w.pending, n)
+	w.queue <- buf
+	w.queued(len(w.queue))
+}
+
+// 在持有锁时写入溢出文件
+func (w *AsynchronousWriter) spillWrite(b []byte) error {
+	s := w.spill
+	if s.file == nil {
+		s.seq++
+		file, err := w.cf.openFile(fmt.Sprintf("%s.%d%s", s.prefix, s.seq, SpillSuffix), os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		s.file = file
+		select {
+		case s.signal <- struct{}{}:
+		default:
+		}
+	}
+	if _, err := s.file.Write(b); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.bytes, uint64(len(b)))
+	return nil
+}
+
+// 写入队列中早于溢出文件的日志，再将溢出文件追加到日志文件，直到不再溢出
+// 取出溢出文件时队列中的日志都早于溢出文件，之后放入队列的日志都晚于溢出文件
+func (w *AsynchronousWriter) drainSpill() {
+	s := w.spill
+	for {
+		s.mu.Lock()
+		file := s.file
+		s.file = nil
+		n := len(w.queue)
+		s.mu.Unlock()
+		if file == nil {
+			return
+		}
+		for ; n > 0; n-- {
+			b := <-w.queue
+			w.writeEntry(*b)
+			w.release(b)
+		}
+		w.appendSpill(file)
+	}
+}
+
+// 将溢出文件追加到日志文件后删除
+func (w *AsynchronousWriter) appendSpill(file *os.File) {
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.Seek(0, 0); err != nil {
+		w.reportError(err)
+		return
+	}
+	if _, err := w.current().ReadFrom(file); err != nil {
+		w.reportError(err)
+	}
+}
+
+// 将上次异常退出时残留的溢出文件追加到日志文件，这些日志早于本次写入的日志
+func (w *AsynchronousWriter) recoverSpill() {
+	for _, name := range spillFiles(w.spill.prefix) {
+		file, err := os.Open(name)
+		if err != nil {
+			w.handleError("recover spill", err)
+			continue
+		}
+		if _, err := w.current().ReadFrom(file); err != nil {
+			w.handleError("recover spill", err)
+			file.Close()
+			continue
+		}
+		file.Close()
+		os.Remove(name)
+	}
+}
+
+// 从队列中取出的缓存写入后归还缓存池
+func (w *AsynchronousWriter) release(b *[]byte) {
+	atomic.AddInt64(&w.pending, -int64(len(*b)))
+	w.pool.Put(b)
+}
+
+// 有新的溢出文件时的通知，没有配置MaxPendingBytes时为nil
+func (w *AsynchronousWriter) spillSignal() <-chan struct{} {
+	if w.spill == nil {
+		return nil
+	}
+	return w.spill.signal
+}
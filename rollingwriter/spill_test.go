@@ -0,0 +1,60 @@
+package rollingwriter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncSpill(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "async"
+	cfg.BatchSize = 0
+	WithMaxPendingBytes("4kb")(&cfg)
+
+	// 上次异常退出残留的溢出文件先追加到日志文件
+	logpath := LogFilePath(&cfg)
+	assert.NoError(t, ioutil.WriteFile(logpath+".2"+SpillSuffix, []byte("old 2\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(logpath+".10"+SpillSuffix, []byte("old 10\n"), 0644))
+	rw, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w := rw.(*AsynchronousWriter)
+	buf, _ := ioutil.ReadFile(logpath)
+	assert.Equal(t, "old 2\nold 10\n", string(buf))
+	assert.Empty(t, spillFiles(logpath))
+
+	// 写入不读取的管道，管道写满后写入协程阻塞，模拟写入缓慢的磁盘
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.file.Swap(pw).Close()
+	done := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(pr)
+		done <- string(b)
+	}()
+	const lines = 5000
+	var expect strings.Builder
+	for i := 0; i < lines; i++ {
+		line := fmt.Sprintf("line %04d %s\n", i, strings.Repeat("x", 100))
+		expect.WriteString(line)
+		_, err := w.Write([]byte(line))
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, w.Stats().PendingBytes, int64(4096))
+	}
+	stats := w.Stats()
+	assert.NoError(t, w.Close())
+	got := <-done
+	assert.True(t, got == expect.String(), "lines lost or out of order: got %d bytes, want %d", len(got), expect.Len())
+	assert.True(t, stats.SpilledBytes > 0)
+	assert.Empty(t, spillFiles(logpath))
+}
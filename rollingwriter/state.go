@@ -0,0 +1,38 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// 记录当前日志文件开始时间的文件路径，进程重启后据此恢复历史日志文件名称中的时间
+func StateFilePath(c *Config) string {
+	return path.Join(c.LogPath, c.FileName) + ".log.state"
+}
+
+// 读取上次运行时当前日志文件的开始时间，日志文件为空时返回false
+// 没有.state文件时（如旧版本创建的日志文件）使用日志文件的修改时间，保证历史日志文件的名称落在正确的周期内
+func (c *Config) loadStartAt() (time.Time, bool) {
+	info, err := os.Stat(LogFilePath(c))
+	if err != nil || info.Size() == 0 {
+		return time.Time{}, false
+	}
+	buf, err := ioutil.ReadFile(StateFilePath(c))
+	if err != nil {
+		return info.ModTime(), true
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(buf)))
+	if err != nil || t.After(info.ModTime()) {
+		return info.ModTime(), true
+	}
+	return t, true
+}
+
+// 记录当前日志文件的开始时间
+func (c *Config) saveStartAt(t time.Time) {
+	err := ioutil.WriteFile(StateFilePath(c), []byte(t.Format(time.RFC3339Nano)+"\n"), c.fileMode())
+	c.handleError("save rotation state", err)
+}
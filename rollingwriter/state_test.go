@@ -0,0 +1,55 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartAtRestored(t *testing.T) {
+	clock := &stubClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)}
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "app"
+	cfg.WriterMode = "none"
+	cfg.RollingTimePattern = "0 0 * * *"
+	cfg.Clock = clock
+
+	w, err := NewWriterFromConfig(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	w.Write([]byte("first\n"))
+	w.Close()
+
+	// 同一周期内重启，沿用上次的开始时间
+	clock.set(time.Date(2024, 5, 1, 15, 0, 0, 0, time.Local))
+	w, err = NewWriterFromConfig(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	m := w.(*Writer).m.(*manager)
+	assert.Equal(t, path.Join(cfg.LogPath, "app.log.202405011000"), m.GenLogFileName(&cfg))
+	buf, _ := ioutil.ReadFile(StateFilePath(&cfg))
+	assert.Equal(t, "2024-05-01T15:00:00", string(buf[:19]))
+	w.Close()
+
+	// 停止期间错过了滚动时间，启动后立即滚动
+	os.Remove(StateFilePath(&cfg))
+	ioutil.WriteFile(StateFilePath(&cfg), []byte(time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local).Format(time.RFC3339Nano)), 0644)
+	clock.set(time.Date(2024, 5, 2, 1, 0, 0, 0, time.Local))
+	w, err = NewWriterFromConfig(&cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	archive := path.Join(cfg.LogPath, "app.log.202405011000")
+	assert.Eventually(t, func() bool {
+		buf, _ := ioutil.ReadFile(archive)
+		return string(buf) == "first\n"
+	}, time.Second, 10*time.Millisecond)
+}
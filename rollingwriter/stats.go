@@ -0,0 +1,141 @@
+package rollingwriter
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// writer的运行统计
+type Stats struct {
+	OpenedAt      time.Time `json:"opened_at"`      // writer打开的时间
+	BytesWritten  uint64    `json:"bytes_written"`  // 打开后接收的字节数
+	Lines         uint64    `json:"lines"`          // 打开后接收的日志行数，ReadFrom直接写入文件的数据只计入字节数
+	FileSize      int64     `json:"file_size"`      // 当前日志文件的大小
+	Rotations     uint64    `json:"rotations"`      // 打开后完成的滚动次数
+	LastRotation  time.Time `json:"last_rotation"`  // 最近一次滚动的时间，没有滚动时为零值
+	Queued        int       `json:"queued"`         // 等待写入文件的日志条数（async）或字节数（buffer）
+	Errors        uint64    `json:"errors"`         // 写入和后台操作的错误次数
+	PoolHits      uint64    `json:"pool_hits"`      // 从缓存池获取到可复用缓存的次数
+	PoolMisses    uint64    `json:"pool_misses"`    // 缓存池中没有可用缓存或超过BufferSize而新分配的次数
+	PeakQueued    int       `json:"peak_queued"`    // async模式下队列中日志条数的峰值
+	PendingBytes  int64     `json:"pending_bytes"`  // async模式下队列中等待写入的字节数
+	SpilledBytes  uint64    `json:"spilled_bytes"`  // async模式下超过MaxPendingBytes后累计写入溢出文件的字节数
+	WriteTimeouts uint64    `json:"write_timeouts"` // 配置了WriteTimeout时写入超时的次数
+	Degraded      bool      `json:"degraded"`       // 是否因写入超时正在降级输出
+	DiskFree      uint64    `json:"disk_free"`      // 配置了水位时日志所在磁盘最近一次检查的剩余空间
+	DiskState     string    `json:"disk_state"`     // 配置了水位时剩余空间的状态，ok、low或critical
+	DiskDropped   uint64    `json:"disk_dropped"`   // 低于危险水位时没有写入日志文件的日志条数
+}
+
+// writer共享的统计计数
+type writerStats struct {
+	bytes     uint64
+	lines     uint64
+	rotations uint64
+	errors    uint64
+	peak      int64 // 队列长度的峰值
+	mu        sync.Mutex
+	openedAt  time.Time
+	rotatedAt time.Time
+}
+
+// 记录一次写入，返回写入的结果
+func (w *Writer) written(b []byte, n int, err error) (int, error) {
+	if n > 0 {
+		w.count(n, bytes.Count(b[:n], _newline))
+	}
+	if err != nil {
+		atomic.AddUint64(&w.stats.errors, 1)
+	}
+	return n, err
+}
+
+// 累加写入的字节数和行数
+func (w *Writer) count(n, lines int) {
+	atomic.AddUint64(&w.stats.bytes, uint64(n))
+	atomic.AddUint64(&w.stats.lines, uint64(lines))
+}
+
+// 记录入队后的队列长度，更新峰值
+func (w *Writer) queued(n int) {
+	for {
+		peak := atomic.LoadInt64(&w.stats.peak)
+		if int64(n) <= peak || atomic.CompareAndSwapInt64(&w.stats.peak, peak, int64(n)) {
+			return
+		}
+	}
+}
+
+// 记录ReadFrom直接写入文件的字节数
+func (w *Writer) readFromWritten(n int64, err error) (int64, error) {
+	if n > 0 {
+		atomic.AddUint64(&w.stats.bytes, uint64(n))
+	}
+	if err != nil {
+		atomic.AddUint64(&w.stats.errors, 1)
+	}
+	return n, err
+}
+
+// 记录一次完成的滚动
+func (w *Writer) rotated() {
+	atomic.AddUint64(&w.stats.rotations, 1)
+	w.stats.mu.Lock()
+	w.stats.rotatedAt = w.cf.clock().Now()
+	w.stats.mu.Unlock()
+}
+
+// 记录后台操作的错误并交给配置的ErrorHandler处理
+func (w *Writer) handleError(op string, err error) {
+	if err == nil {
+		return
+	}
+	atomic.AddUint64(&w.stats.errors, 1)
+	w.cf.handleError(op, err)
+}
+
+// writer的运行统计
+func (w *Writer) Stats() Stats {
+	w.stats.mu.Lock()
+	s := Stats{OpenedAt: w.stats.openedAt, LastRotation: w.stats.rotatedAt}
+	w.stats.mu.Unlock()
+	s.BytesWritten = atomic.LoadUint64(&w.stats.bytes)
+	s.Lines = atomic.LoadUint64(&w.stats.lines)
+	s.Rotations = atomic.LoadUint64(&w.stats.rotations)
+	s.Errors = atomic.LoadUint64(&w.stats.errors)
+	s.PeakQueued = int(atomic.LoadInt64(&w.stats.peak))
+	if w.pool != nil {
+		s.PoolHits, s.PoolMisses = w.pool.stats()
+	}
+	if info, err := w.current().Stat(); err == nil {
+		s.FileSize = info.Size()
+	}
+	if w.disk != nil {
+		s.DiskFree = atomic.LoadUint64(&w.disk.free)
+		s.DiskState = _diskStateNames[w.disk.level()]
+		s.DiskDropped = atomic.LoadUint64(&w.disk.dropped)
+	}
+	return s
+}
+
+// async模式的运行统计，包括队列中等待写入的日志条数
+func (w *AsynchronousWriter) Stats() Stats {
+	s := w.Writer.Stats()
+	s.Queued = len(w.queue)
+	s.PendingBytes = atomic.LoadInt64(&w.pending)
+	if w.spill != nil {
+		s.SpilledBytes = atomic.LoadUint64(&w.spill.bytes)
+	}
+	return s
+}
+
+// buffer模式的运行统计，包括缓存中等待写入的字节数
+func (w *BufferWriter) Stats() Stats {
+	s := w.Writer.Stats()
+	w.mu.Lock()
+	s.Queued = len(w.buf)
+	w.mu.Unlock()
+	return s
+}
@@ -0,0 +1,38 @@
+package rollingwriter
+
+import (
+	"io"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	for _, mode := range []string{"none", "lock", "async", "buffer", "sharded", "audit"} {
+		dir := t.TempDir()
+		w, err := NewWriter(WithLogPath(dir), WithoutRollingPolicy(), func(c *Config) { c.WriterMode = mode })
+		if !assert.NoError(t, err, mode) {
+			continue
+		}
+		sw := w.(interface{ Stats() Stats })
+		w.Write([]byte("first\nsecond\n"))
+		io.WriteString(w, "third\n")
+		s := sw.Stats()
+		assert.Equal(t, uint64(19), s.BytesWritten, mode)
+		assert.Equal(t, uint64(3), s.Lines, mode)
+		assert.False(t, s.OpenedAt.IsZero(), mode)
+
+		r, ok := w.(rotator)
+		if ok {
+			r.rotate(path.Join(dir, "log.log.1"))
+		}
+		w.Close()
+		s = sw.Stats()
+		if ok {
+			assert.Equal(t, uint64(1), s.Rotations, mode)
+			assert.False(t, s.LastRotation.IsZero(), mode)
+		}
+		assert.Equal(t, uint64(0), s.Errors, mode)
+	}
+}
@@ -0,0 +1,149 @@
+package rollingwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 不支持的配置文件类型
+var ErrConfigType = errors.New("error config type")
+
+var (
+	_jsonUnknownField = regexp.MustCompile(`^json: unknown field "(.*)"$`)
+	_yamlUnknownField = regexp.MustCompile(`^(line \d+): field (.*) not found in type \S+$`)
+)
+
+// 按typ（json或yaml）严格解析配置，未知的字段返回错误，错误中提示最接近的字段名
+func UnmarshalConfig(buf []byte, typ string, v interface{}) error {
+	switch typ {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(buf))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(v)
+		if err == nil {
+			return nil
+		}
+		if m := _jsonUnknownField.FindStringSubmatch(err.Error()); m != nil {
+			return unknownField("", m[1], configKeys(reflect.TypeOf(v), "json"))
+		}
+		return err
+	case "yaml":
+		err := yaml.UnmarshalStrict(buf, v)
+		terr, ok := err.(*yaml.TypeError)
+		if !ok {
+			return err
+		}
+		keys := configKeys(reflect.TypeOf(v), "yaml")
+		msgs := make([]string, 0, len(terr.Errors))
+		for _, e := range terr.Errors {
+			if m := _yamlUnknownField.FindStringSubmatch(e); m != nil {
+				e = unknownField(m[1], m[2], keys).Error()
+			}
+			msgs = append(msgs, e)
+		}
+		return errors.New(strings.Join(msgs, "; "))
+	default:
+		return ErrConfigType
+	}
+}
+
+// 未知字段的错误，有接近的字段名时提示
+func unknownField(pos, key string, keys []string) error {
+	if pos != "" {
+		pos += ": "
+	}
+	if s := suggestKey(key, keys); s != "" {
+		return fmt.Errorf("%sunknown field %q, did you mean %q?", pos, key, s)
+	}
+	return fmt.Errorf("%sunknown field %q", pos, key)
+}
+
+// 配置结构及其嵌套结构中的所有字段名
+func configKeys(t reflect.Type, tag string) []string {
+	seen := make(map[reflect.Type]bool)
+	var keys []string
+	var walk func(reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts := f.Tag.Get(tag), ""
+			if j := strings.IndexByte(name, ','); j >= 0 {
+				name, opts = name[:j], name[j:]
+			}
+			if name == "-" || f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			// json展开没有名称的嵌入结构，yaml展开inline的结构
+			if f.Anonymous && name == "" && (tag == "json" || strings.Contains(opts, "inline")) {
+				walk(f.Type)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+				if tag == "yaml" {
+					name = strings.ToLower(name)
+				}
+			}
+			keys = append(keys, name)
+			walk(f.Type)
+		}
+	}
+	walk(t)
+	return keys
+}
+
+// 返回与key编辑距离最小且足够接近的字段名，没有时返回空
+func suggestKey(key string, keys []string) string {
+	best, min := "", len(key)/3+2
+	for _, k := range keys {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(k)); d < min {
+			best, min = k, d
+		}
+	}
+	return best
+}
+
+// 两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(vs ...int) int {
+	m := vs[0]
+	for _, v := range vs[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
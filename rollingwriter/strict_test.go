@@ -0,0 +1,40 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalConfig(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.NoError(t, UnmarshalConfig([]byte(`{"log_path": "./log", "file_name": "app"}`), "json", &cfg))
+	assert.Equal(t, "./log", cfg.LogPath)
+
+	err := UnmarshalConfig([]byte(`{"log_path": "./log", "file_nme": "app"}`), "json", &cfg)
+	if assert.Error(t, err) {
+		assert.Equal(t, `unknown field "file_nme", did you mean "file_name"?`, err.Error())
+	}
+
+	err = UnmarshalConfig([]byte("logPath: ./log\nwriterMod: async\nfoo: 1\n"), "yaml", &cfg)
+	if assert.Error(t, err) {
+		assert.Equal(t, `line 2: unknown field "writerMod", did you mean "writerMode"?; line 3: unknown field "foo"`, err.Error())
+	}
+
+	assert.Equal(t, ErrConfigType, UnmarshalConfig([]byte(`{}`), "toml", &cfg))
+}
+
+func TestNewWriterFromConfigFileStrict(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "writer.yaml")
+	ioutil.WriteFile(name, []byte("logPath: "+dir+"\nfileName: app\nmaxRemian: 3\n"), 0644)
+
+	_, err := NewWriterFromConfigFile(name, "yaml")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `did you mean "maxRemain"?`)
+	}
+	_, err = NewWriterFromConfigFile(name, "ini")
+	assert.Equal(t, ErrConfigType, err)
+}
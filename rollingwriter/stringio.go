@@ -0,0 +1,156 @@
+package rollingwriter
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// ReadFrom每次读取的大小，与缓存池的一个级别一致
+const _readFromSize = 0x10000
+
+// 将字符串复制到缓存池的缓存中再写入，下层writer可能修改写入的数据，不能直接使用字符串的内存
+func writeString(w io.Writer, pool *bufferPool, s string) (int, error) {
+	buf := pool.Get(len(s))
+	*buf = append((*buf)[:0], s...)
+	n, err := w.Write(*buf)
+	pool.Put(buf)
+	return n, err
+}
+
+// 从r按行分块读取，每块以换行结尾（r结束时的最后一块除外），超过缓存大小的长行扩大缓存，
+// 保证每次写入都是完整的行，滚动不会将一行日志拆分到两个文件
+// emit取得块的所有权并返回写入的字节数，缓存从pool中获取
+func readLines(pool *bufferPool, r io.Reader, emit func(buf *[]byte) (int, error)) (n int64, err error) {
+	buf := pool.Get(_readFromSize)
+	*buf = (*buf)[:0]
+	for {
+		if len(*buf) == cap(*buf) {
+			grown := pool.Get(2 * cap(*buf))
+			*grown = append((*grown)[:0], *buf...)
+			pool.Put(buf)
+			buf = grown
+		}
+		m, rerr := r.Read((*buf)[len(*buf):cap(*buf)])
+		*buf = (*buf)[:len(*buf)+m]
+		if rerr != nil {
+			if len(*buf) == 0 {
+				pool.Put(buf)
+			} else {
+				written, werr := emit(buf)
+				n += int64(written)
+				if werr != nil {
+					return n, werr
+				}
+			}
+			if rerr == io.EOF {
+				return n, nil
+			}
+			return n, rerr
+		}
+		i := bytes.LastIndexByte(*buf, '\n')
+		if i < 0 {
+			continue
+		}
+		// 最后一个换行之后不完整的行留到下一块
+		rest := (*buf)[i+1:]
+		next := pool.Get(_readFromSize)
+		*next = append((*next)[:0], rest...)
+		*buf = (*buf)[:i+1]
+		written, werr := emit(buf)
+		n += int64(written)
+		if werr != nil {
+			pool.Put(next)
+			return n, werr
+		}
+		buf = next
+	}
+}
+
+// 按行分块读取r并写入w
+func readFrom(w io.Writer, pool *bufferPool, r io.Reader) (int64, error) {
+	return readLines(pool, r, func(buf *[]byte) (int, error) {
+		n, err := w.Write(*buf)
+		pool.Put(buf)
+		return n, err
+	})
+}
+
+func (w *LockedWriter) WriteString(s string) (int, error) {
+	return writeString(w, w.pool, s)
+}
+
+// 在锁内直接写入日志文件，期间其他写入等待
+func (w *LockedWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.Lock()
+	defer w.Unlock()
+	if err := w.rolling(); err != nil {
+		return 0, err
+	}
+	return w.readFromWritten(w.current().ReadFrom(r))
+}
+
+// 直接将字符串复制到队列使用的缓存中
+func (w *AsynchronousWriter) WriteString(s string) (int, error) {
+	buf := w.pool.Get(len(s))
+	*buf = append((*buf)[:0], s...)
+	if err := w.put(buf); err != nil {
+		return 0, err
+	}
+	w.count(len(s), strings.Count(s, "\n"))
+	return len(s), nil
+}
+
+// 按行直接读取到队列使用的缓存中，避免再次复制，每块按Write的登记协议放入队列，入队后才计入统计
+func (w *AsynchronousWriter) ReadFrom(r io.Reader) (int64, error) {
+	return readLines(w.pool, r, func(buf *[]byte) (int, error) {
+		m, lines := len(*buf), bytes.Count(*buf, _newline)
+		if err := w.put(buf); err != nil {
+			return 0, err
+		}
+		w.count(m, lines)
+		return m, nil
+	})
+}
+
+func (w *BufferWriter) WriteString(s string) (int, error) {
+	return writeString(w, w.pool, s)
+}
+
+func (w *BufferWriter) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(w, w.pool, r)
+}
+
+func (w *AuditWriter) WriteString(s string) (int, error) {
+	return writeString(w, w.pool, s)
+}
+
+// 在锁内直接写入日志文件并同步到磁盘
+func (w *AuditWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.Lock()
+	defer w.Unlock()
+	if err := w.rolling(); err != nil {
+		return 0, err
+	}
+	n, err := w.readFromWritten(w.current().ReadFrom(r))
+	if err != nil {
+		return n, err
+	}
+	return n, w.current().Sync()
+}
+
+func (w *ShardedWriter) WriteString(s string) (int, error) {
+	return writeString(w, w.pool, s)
+}
+
+func (w *ShardedWriter) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(w, w.pool, r)
+}
+
+func (w *GzipWriter) WriteString(s string) (int, error) {
+	return writeString(w, w.pool, s)
+}
+
+func (w *GzipWriter) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(w, w.pool, r)
+}
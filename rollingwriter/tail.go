@@ -0,0 +1,85 @@
+package rollingwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// 不完整日志移动到的文件名称
+func partialFilePath(c *Config) string {
+	return LogFilePath(c) + ".partial"
+}
+
+// 按配置处理日志文件末尾的不完整日志
+func (c *Config) recoverTail(file *os.File) error {
+	if c.RecoverTail == "" {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	start, tail, err := lastLine(file, info.Size())
+	if err != nil || len(tail) == 0 {
+		return err
+	}
+	terminated := tail[len(tail)-1] == '\n'
+	if terminated && (tail[0] != '{' || json.Valid(tail)) {
+		return nil
+	}
+
+	switch c.RecoverTail {
+	case "repair":
+		// 只修复缺少换行符的日志
+		if terminated {
+			return nil
+		}
+		_, err = file.Write([]byte(TailMarker + "\n"))
+		return err
+	case "partial":
+		partial, err := c.openFile(partialFilePath(c), DefualtFileFlag)
+		if err != nil {
+			return err
+		}
+		defer partial.Close()
+		if !terminated {
+			tail = append(tail, '\n')
+		}
+		if _, err := partial.Write(tail); err != nil {
+			return err
+		}
+		return file.Truncate(start)
+	}
+	return nil
+}
+
+// 读取文件的最后一行，返回该行的起始偏移和内容（包含末尾的换行符）
+func lastLine(file *os.File, size int64) (int64, []byte, error) {
+	var tail []byte
+	end := size
+	chunk := make([]byte, 4096)
+	for end > 0 {
+		start := end - int64(len(chunk))
+		if start < 0 {
+			start = 0
+		}
+		buf := chunk[:end-start]
+		if _, err := file.ReadAt(buf, start); err != nil && err != io.EOF {
+			return 0, nil, err
+		}
+		search := buf
+		// 跳过文件末尾的换行符
+		if end == size && buf[len(buf)-1] == '\n' {
+			search = buf[:len(buf)-1]
+		}
+		if i := bytes.LastIndexByte(search, '\n'); i >= 0 {
+			tail = append(append([]byte{}, buf[i+1:]...), tail...)
+			return start + int64(i) + 1, tail, nil
+		}
+		tail = append(append([]byte{}, buf...), tail...)
+		end = start
+	}
+	return 0, tail, nil
+}
@@ -0,0 +1,43 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverTail(t *testing.T) {
+	cases := []struct {
+		mode    string
+		content string
+		active  string
+		partial string
+	}{
+		{"repair", "{\"a\":1}\n{\"a\":", "{\"a\":1}\n{\"a\":" + TailMarker + "\n", ""},
+		{"repair", "{\"a\":1}\n", "{\"a\":1}\n", ""},
+		{"partial", "{\"a\":1}\n{\"a\":", "{\"a\":1}\n", "{\"a\":\n"},
+		{"partial", "{\"a\":1}\n{\"a\":2\n", "{\"a\":1}\n", "{\"a\":2\n"},
+		{"partial", "plain\ntext\n", "plain\ntext\n", ""},
+		{"partial", strings.Repeat("x", 10000), "", strings.Repeat("x", 10000) + "\n"},
+	}
+	for _, c := range cases {
+		cfg := NewDefaultConfig()
+		cfg.LogPath = t.TempDir()
+		cfg.FileName = "unittest"
+		cfg.RecoverTail = c.mode
+		assert.Nil(t, ioutil.WriteFile(LogFilePath(&cfg), []byte(c.content), 0644))
+
+		w, err := NewWriterFromConfig(&cfg)
+		if err != nil {
+			t.Fatal("error in new writer", err)
+		}
+		w.Close()
+
+		buf, _ := ioutil.ReadFile(LogFilePath(&cfg))
+		assert.Equal(t, c.active, string(buf))
+		buf, _ = ioutil.ReadFile(partialFilePath(&cfg))
+		assert.Equal(t, c.partial, string(buf))
+	}
+}
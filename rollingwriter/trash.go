@@ -0,0 +1,116 @@
+package rollingwriter
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 删除历史日志文件及其校验文件，配置了TrashDir时移动到回收目录，返回回收目录中的路径
+func (w *Writer) discard(name string) (string, error) {
+	if w.cf.TrashDir == "" {
+		if err := os.Remove(name); err != nil {
+			return "", err
+		}
+		if err := os.Remove(name + ChecksumSuffix); err != nil && !os.IsNotExist(err) {
+			w.handleError("remove checksum file", err)
+		}
+		return "", nil
+	}
+	if err := w.cf.mkdirAll(w.cf.TrashDir); err != nil {
+		return "", err
+	}
+	trash := path.Join(w.cf.TrashDir, path.Base(name))
+	if err := moveFile(name, trash); err != nil {
+		return "", err
+	}
+	// 回收目录中的保留时间从移入时开始计算
+	now := w.cf.clock().Now()
+	_ = os.Chtimes(trash, now, now)
+	if err := moveFile(name+ChecksumSuffix, trash+ChecksumSuffix); err != nil && !os.IsNotExist(err) {
+		w.handleError("trash checksum file", err)
+	}
+	return trash, nil
+}
+
+// 移动文件，不能重命名时（如跨文件系统）复制后删除原文件
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || os.IsNotExist(err) {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, DefualtFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// 判断文件名称是否属于writer的历史日志文件或其校验文件
+func (c *Config) ownsArchive(name string) bool {
+	name = strings.TrimSuffix(name, ChecksumSuffix)
+	if strings.HasPrefix(name, c.FileName+".log.") {
+		return true
+	}
+	for _, pattern := range c.ArchivePatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 删除回收目录中属于writer且超过TrashTTL的文件
+func (w *Writer) purgeTrash() {
+	if w.cf.TrashDir == "" || w.cf.TrashTTL <= 0 {
+		return
+	}
+	infos, err := ioutil.ReadDir(w.cf.TrashDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			w.handleError("list trash", err)
+		}
+		return
+	}
+	now := w.cf.clock().Now()
+	ttl := time.Duration(w.cf.TrashTTL) * time.Second
+	for _, fi := range infos {
+		age := now.Sub(fi.ModTime())
+		if fi.IsDir() || age <= ttl || !w.cf.ownsArchive(fi.Name()) {
+			continue
+		}
+		r := RetentionRecord{
+			Time:   now,
+			File:   path.Join(w.cf.TrashDir, fi.Name()),
+			Size:   fi.Size(),
+			Age:    int64(age / time.Second),
+			Reason: "trash_ttl",
+			DryRun: w.cf.RetentionDryRun,
+		}
+		if !r.DryRun {
+			if err := os.Remove(r.File); err != nil {
+				w.handleError("purge trash", err)
+				r.Error = err.Error()
+			}
+		}
+		w.recordRetention(r)
+	}
+}
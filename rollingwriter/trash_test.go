@@ -0,0 +1,52 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	dir, trash := t.TempDir(), t.TempDir()
+	for _, name := range []string{"app.log.202401010000", "app.log.202401010000.sha256", "app.log.202401020000"} {
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, name), []byte(name), 0644))
+	}
+	// 回收目录中已经过期的文件和不属于该日志的文件
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"app.log.202312010000", "other.log.202312010000"} {
+		assert.Nil(t, ioutil.WriteFile(path.Join(trash, name), []byte(name), 0644))
+		assert.Nil(t, os.Chtimes(path.Join(trash, name), old, old))
+	}
+
+	cfg := NewDefaultConfig()
+	cfg.LogPath = dir
+	cfg.FileName = "app"
+	cfg.WriterMode = "none"
+	cfg.RollingPolicy = WithoutRolling
+	cfg.MaxRemain = 1
+	WithTrash(trash, 24*time.Hour)(&cfg)
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	w.Close()
+
+	assertNoFile(t, path.Join(dir, "app.log.202401010000"))
+	assertNoFile(t, path.Join(dir, "app.log.202401010000.sha256"))
+	for _, name := range []string{"app.log.202401010000", "app.log.202401010000.sha256", "other.log.202312010000"} {
+		_, err := os.Stat(path.Join(trash, name))
+		assert.Nil(t, err, name)
+	}
+	assertNoFile(t, path.Join(trash, "app.log.202312010000"))
+
+	records, err := ReadRetentionLog(&cfg)
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(records)) {
+		assert.Equal(t, path.Join(trash, "app.log.202401010000"), records[0].Trash)
+		assert.Equal(t, "trash_ttl", records[1].Reason)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build linux
+// +build linux
+
+package rollingwriter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUringWriter(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "uring"
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	var c int = 100
+	for i := 0; i < c; i++ {
+		w.Write([]byte("uring line\n"))
+	}
+	assert.Nil(t, w.Close())
+
+	// 预分配不改变文件大小
+	info, err := os.Stat(LogFilePath(&cfg))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(c*len("uring line\n")), info.Size())
+}
@@ -0,0 +1,77 @@
+package rollingwriter
+
+import (
+	"os"
+	"path"
+	"sync/atomic"
+	"time"
+)
+
+// 支持日志文件监测的RollingWriter
+type watcher interface {
+	watch()
+}
+
+// 开启日志文件监测，每Precision秒检查一次当前日志文件是否仍位于配置的路径，关闭writer时停止
+func (w *Writer) watch() {
+	if !atomic.CompareAndSwapInt32(&w.watching, 0, 1) {
+		return
+	}
+	w.recreate = make(chan struct{}, 1)
+	go func(recreate, stop chan struct{}) {
+		ticker := w.cf.clock().NewTicker(time.Duration(Precision) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C():
+				if w.fileMissing() {
+					select {
+					case recreate <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}(w.recreate, w.closing)
+}
+
+// 判断当前写入的日志文件是否已被删除或移动
+func (w *Writer) fileMissing() bool {
+	// 交接后子进程滚动日志文件，不再重新创建
+	if w.handedOver() {
+		return false
+	}
+	current, err := w.current().Stat()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(w.path())
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return !os.SameFile(current, info)
+}
+
+// 在配置的路径重新创建日志文件，并替换当前写入的日志文件
+func (w *Writer) recreateFile() error {
+	if !w.fileMissing() {
+		return nil
+	}
+	if err := w.cf.mkdirAll(path.Dir(w.path())); err != nil {
+		return err
+	}
+	newfile, err := w.cf.openFile(w.path(), DefualtFileFlag)
+	if err != nil {
+		return err
+	}
+	if info, err := newfile.Stat(); err == nil && info.Size() == 0 {
+		w.writeHeader(newfile)
+	}
+	perr := w.cf.preallocate(newfile)
+	if err := w.file.Swap(newfile).Close(); err != nil {
+		return err
+	}
+	return perr
+}
@@ -0,0 +1,39 @@
+package rollingwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecreateFile(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.WatchFile = true
+	rw, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	writer := rw.(*Writer)
+	defer writer.Close()
+
+	filepath := LogFilePath(&cfg)
+	assert.Nil(t, os.Rename(filepath, path.Join(cfg.LogPath, "moved.log")))
+	assert.True(t, writer.fileMissing())
+
+	// 等待监测触发
+	time.Sleep(time.Duration(Precision)*time.Second + 100*time.Millisecond)
+	_, err = writer.Write([]byte("after move\n"))
+	assert.Nil(t, err)
+
+	buf, err := ioutil.ReadFile(filepath)
+	assert.Nil(t, err)
+	assert.Equal(t, "after move\n", string(buf))
+	assert.False(t, writer.fileMissing())
+}
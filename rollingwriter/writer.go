@@ -2,30 +2,37 @@ package rollingwriter
 
 import (
 	"compress/gzip"
-	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path"
-	"sort"
-	"strings"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
-	"unsafe"
 
-	"gopkg.in/yaml.v2"
+	"github.com/robfig/cron/v3"
 )
 
 // 当WriterMode为none时使用的结构，无保护的writer: 不提供并发安全保障
 type Writer struct {
-	m             Manager
-	file          *os.File // 当前的写入文件
-	absPath       string
-	fire          chan string
-	cf            *Config
-	rollingfilech chan string //
+	m         Manager
+	file      *atomicFile // 当前的写入文件，通过current()读取
+	absPath   *atomicPath // 当前日志文件路径，通过path()读取
+	fire      chan string
+	cf        *Config
+	recreate  chan struct{} // 日志文件被外部删除或移动时的通知chan
+	watching  int32         // 是否正在监测日志文件，默认为：0，监测中为：1
+	closing   chan struct{} // 关闭writer时关闭，通知后台协程退出
+	closeOnce *sync.Once
+	banner    *banner      // 日志文件的头尾信息
+	sweepMu   *sync.Mutex  // 保证同一时间只有一次历史日志清理
+	appendMu  *sync.Mutex  // 保证同一时间只有一次追加到已存在的历史日志文件
+	stats     *writerStats // 运行统计，各写入模式共享
+	handover  *handoverState
+	disk      *diskState // 磁盘剩余空间的状态，没有配置水位时为nil
+	pool      *bufferPool
 }
 
 // 当WriterMode为lock时使用的结构，lock保护的writer: 提供由mutex保护的并发安全保障
@@ -35,108 +42,158 @@ type LockedWriter struct {
 }
 
 // 当WriterMode为async时使用的结构，同步writer，并发安全
+// 每次Write的数据作为一个完整的队列条目写入，日志滚动只发生在条目之间，单条日志不会被拆分到两个文件
 type AsynchronousWriter struct {
+	pending int64 // 队列中等待写入的字节数，原子操作的字段放在开头，保证32位平台上8字节对齐
 	Writer
-	ctx     chan int    // 有数据时退出写入
-	queue   chan []byte // 缓存队列chan
-	errChan chan error  // 数据写入错误chan
-	closed  int32       // 默认为：0，当关闭时为：1
-	wg      sync.WaitGroup
+	ctx       chan int     // 有数据时退出写入
+	done      chan int     // 写入协程退出后关闭
+	rotations chan string  // 待执行的日志滚动，由写入协程执行
+	queue     chan *[]byte // 缓存队列chan，缓存均来自writer的缓存池
+	errChan   chan error   // 数据写入错误chan
+	closed    int32        // 默认为：0，当关闭时为：1
+	writing   int32        // 正在执行Write的协程数，Close等待这些日志入队后再停止写入协程
+	wg        sync.WaitGroup
+	spill     *asyncSpill // 配置了MaxPendingBytes时的溢出处理
 }
 
 // 当WriterMode为buffer时使用的结构，异步write, 并发安全
 type BufferWriter struct {
 	Writer
-	buf     *[]byte // 待写入数据
-	swaping int32   // 缓存池中数据是否处理完的标志，默认为：0，没处理完为：1
+	mu      sync.Mutex // 保护buf和closed
+	buf     []byte     // 待写入数据
+	closed  bool
+	flushMu sync.Mutex // 在锁外写入缓存池中的数据时持有，保证写入顺序
+	swaping int32      // 缓存池中数据是否处理完的标志，默认为：0，没处理完为：1
 }
 
-// 同步并发是使用的临时缓存对象
-var _asyncBufferPool = sync.Pool{
-	New: func() interface{} {
-		return make([]byte, BufferSize)
-	},
+// 补写的换行符
+var _newline = []byte{'\n'}
+
+// 校验配置，不访问文件系统
+func (c *Config) Validate() error {
+	if c.LogPath == "" || c.FileName == "" {
+		return ErrInvalidArgument
+	}
+	switch c.RotationStrategy {
+	case "", "rename", "copytruncate":
+	default:
+		return ErrInvalidArgument
+	}
+	if _, err := parseCompressWindow(c.CompressWindow); err != nil {
+		return err
+	}
+	if _, ok := lookupCompressor(c.Compressor); !ok {
+		return ErrInvalidArgument
+	}
+	if err := c.checkWatermark(); err != nil {
+		return err
+	}
+	switch c.RecoverTail {
+	case "", "repair", "partial":
+	default:
+		return ErrInvalidArgument
+	}
+	switch c.Profile {
+	case "", ProfileLowLatency, ProfileThroughput, ProfileLowMemory:
+	default:
+		return ErrInvalidArgument
+	}
+	if _, ok := lookupWriterMode(c.WriterMode); !ok {
+		switch c.WriterMode {
+		case "none", "lock", "async", "buffer":
+		default:
+			return ErrInvalidArgument
+		}
+	}
+	if c.RollingPolicy == TimeRolling {
+		if _, err := cron.ParseStandard(c.RollingTimePattern); err != nil {
+			return err
+		}
+	}
+	return c.checkDirLayout()
 }
 
 // 根据配置生成RollingWriter，用于接收日志输入
 func NewWriterFromConfig(c *Config) (RollingWriter, error) {
-	// 判断配置
-	if c.LogPath == "" || c.FileName == "" {
-		return nil, ErrInvalidArgument
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	// 按调优方案填充调优参数，不修改调用方的配置
+	if c.Profile != "" {
+		tuned := c.withProfile()
+		c = &tuned
+	}
+	// 延迟到第一次写入时打开日志文件
+	if c.LazyOpen {
+		return newLazyWriter(c), nil
 	}
 
+	filepath := LogFilePath(c)
 	// 创建日志所在目录
-	if err := os.MkdirAll(c.LogPath, 0700); err != nil {
+	if err := c.mkdirAll(path.Dir(filepath)); err != nil {
 		return nil, err
 	}
-
-	filepath := LogFilePath(c)
-	// 打开日志文件
-	file, err := os.OpenFile(filepath, DefualtFileFlag, DefualtFileMode)
+	// 热重启时使用父进程交接的日志文件，父进程仍在写入，不处理不完整日志
+	file := inheritedFile(filepath)
+	if file == nil {
+		var err error
+		// 打开日志文件
+		if file, err = c.openFile(filepath, DefualtFileFlag); err != nil {
+			return nil, err
+		}
+		// 处理上次异常退出时残留的不完整日志
+		if err := c.recoverTail(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	// 删除上次压缩中断时残留的临时文件，未压缩的历史日志文件仍然保留
+	if c.Compress {
+		c.removeCompressTemp()
+	}
+	bn, err := newBanner(c)
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 	mng, err := NewManager(c)
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 	var rollingWriter RollingWriter
 	writer := Writer{
-		m:       mng,
-		file:    file,
-		absPath: filepath,
-		fire:    mng.Fire(), // 最新的历史文件名称
-		cf:      c,
-	}
-
-	if c.MaxRemain > 0 {
-		// 保留历史日志文件名称的chan
-		writer.rollingfilech = make(chan string, c.MaxRemain)
-		// 读取日志目录下的所有文件信息
-		dir, err := ioutil.ReadDir(c.LogPath)
-		if err != nil {
-			return nil, err
-		}
-		files := make([]string, 0, 10)
-		// 查找日志目录中的历史日志文件
-		for _, fi := range dir {
-			if fi.IsDir() {
-				continue
-			}
-
-			fileName := c.FileName + ".log"
-			if strings.Contains(fi.Name(), fileName) {
-				// 文件的后缀名
-				fileSuffix := path.Ext(fi.Name())
-				if len(fileSuffix) > 1 {
-					// 将后缀字符串转化为时间，如果成功的为历史日志文件，添加到files
-					_, err := time.Parse(c.TimeTagFormat, fileSuffix[1:])
-					if err == nil {
-						files = append(files, fi.Name())
-					}
-				}
-			}
-		}
+		m:         mng,
+		file:      newAtomicFile(file),
+		absPath:   newAtomicPath(filepath),
+		fire:      mng.Fire(), // 最新的历史文件名称
+		cf:        c,
+		closing:   make(chan struct{}),
+		closeOnce: &sync.Once{},
+		banner:    bn,
+		sweepMu:   &sync.Mutex{},
+		appendMu:  &sync.Mutex{},
+		stats:     &writerStats{openedAt: c.clock().Now()},
+		handover:  &handoverState{},
+		pool:      newBufferPool(c.BufferSize),
+	}
+	if c.diskWatermark() {
+		writer.disk = newDiskState()
+	}
+	// 新的日志文件写入头信息
+	if info, err := file.Stat(); err == nil && info.Size() == 0 {
+		writer.writeHeader(file)
+	}
+	if err := c.preallocate(file); err != nil {
+		file.Close()
+		mng.Close()
+		return nil, err
+	}
 
-		// 将files按照时间排序
-		sort.Slice(files, func(i, j int) bool {
-			fileSuffix1 := path.Ext(files[i])
-			fileSuffix2 := path.Ext(files[j])
-			t1, _ := time.Parse(c.TimeTagFormat, fileSuffix1[1:])
-			t2, _ := time.Parse(c.TimeTagFormat, fileSuffix2[1:])
-			return t1.Before(t2)
-		})
-
-		// 删除多余的历史日志文件
-		for _, file := range files {
-		retry:
-			select {
-			case writer.rollingfilech <- path.Join(c.LogPath, file):
-			default:
-				writer.DoRemove()
-				goto retry
-			}
-		}
+	// 删除超过保留策略的历史日志文件
+	if c.retention() {
+		writer.sweep()
 	}
 
 	// 判断日志写入模式
@@ -149,28 +206,65 @@ func NewWriterFromConfig(c *Config) (RollingWriter, error) {
 		}
 	case "async":
 		wr := &AsynchronousWriter{
-			Writer:  writer,
-			ctx:     make(chan int),
-			queue:   make(chan []byte, QueueSize),
-			errChan: make(chan error),
-			closed:  0,
-			wg:      sync.WaitGroup{},
+			Writer:    writer,
+			ctx:       make(chan int),
+			done:      make(chan int),
+			rotations: make(chan string),
+			queue:     make(chan *[]byte, c.queueSize()),
+			errChan:   make(chan error),
+			closed:    0,
+			wg:        sync.WaitGroup{},
+			spill:     newAsyncSpill(c, filepath),
+		}
+		if wr.spill != nil {
+			wr.recoverSpill()
 		}
 		wr.wg.Add(1)
 		go wr.writer()
 		wr.wg.Wait()
 		rollingWriter = wr
 	case "buffer":
-		bf := make([]byte, 0, c.BufferWriterThreshold*2)
 		rollingWriter = &BufferWriter{
 			Writer:  writer,
-			buf:     &bf,
+			buf:     make([]byte, 0, c.BufferWriterThreshold*2),
 			swaping: 0,
 		}
 	default:
-		return nil, ErrInvalidArgument
+		// 查找自定义写入模式
+		factory, ok := lookupWriterMode(c.WriterMode)
+		if !ok {
+			file.Close()
+			mng.Close()
+			return nil, ErrInvalidArgument
+		}
+		if rollingWriter, err = factory(*c, writer); err != nil {
+			file.Close()
+			mng.Close()
+			return nil, err
+		}
+	}
+
+	// 在后台执行日志滚动，没有写入时也能按时滚动
+	if r, ok := rollingWriter.(rotator); ok {
+		go writer.rotateLoop(r)
+	}
+
+	// 开启日志文件监测
+	if c.WatchFile {
+		if wt, ok := rollingWriter.(watcher); ok {
+			wt.watch()
+		}
+	}
+	// 开启磁盘剩余空间检查
+	if writer.disk != nil {
+		writer.watchDisk()
 	}
-	return rollingWriter, nil
+	return c.withDeadline(c.withDiskGuard(rollingWriter, writer.disk)), nil
+}
+
+// writer实际生效的配置，包括默认值
+func (w *Writer) Config() Config {
+	return w.cf.resolved()
 }
 
 // 执行各个构造函数更新配置后生产RollingWriter
@@ -182,7 +276,7 @@ func NewWriter(ops ...Option) (RollingWriter, error) {
 	return NewWriterFromConfig(&cfg)
 }
 
-// 从配置文件读取配置,解析后生成RollingWriter,支持json和yaml类型
+// 从配置文件读取配置,解析后生成RollingWriter,支持json和yaml类型,其他类型返回ErrConfigType,未知的字段返回错误
 func NewWriterFromConfigFile(path string, typ string) (RollingWriter, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -195,231 +289,331 @@ func NewWriterFromConfigFile(path string, typ string) (RollingWriter, error) {
 	if err != nil {
 		return nil, err
 	}
-	switch typ {
-	case "json":
-		if err = json.Unmarshal(buf, &cfg); err != nil {
-			return nil, err
-		}
-	case "yaml":
-		if err = yaml.Unmarshal(buf, &cfg); err != nil {
+	if err := UnmarshalConfig(buf, typ, &cfg); err != nil {
+		if err == ErrConfigType {
 			return nil, err
 		}
+		return nil, fmt.Errorf("%s: %v", path, err)
 	}
-
 	return NewWriterFromConfig(&cfg)
 }
 
-// 删除过期的历史日志文件
+// 按保留策略删除过期的历史日志文件
 func (w *Writer) DoRemove() {
-	select {
-	case file := <-w.rollingfilech:
-		if err := os.Remove(file); err != nil {
-			log.Println("error in remove log file", file, err)
-		}
-	}
+	w.sweep()
 }
 
-// 压缩历史文件
-func (w *Writer) CompressFile(oldfile *os.File, cmpname string) error {
-	cmpfile, err := os.OpenFile(cmpname, DefualtFileFlag, DefualtFileMode)
-	defer cmpfile.Close()
+// 压缩历史文件，先写入cmpname.tmp并同步到磁盘，成功后原子性的重命名为cmpname，失败时删除临时文件
+// 不删除oldfile，由调用方在压缩成功后删除
+func (w *Writer) CompressFile(oldfile *os.File, cmpname string) (err error) {
+	tmpname := cmpname + ".tmp"
+	tmpfile, err := w.cf.openFile(tmpname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
 	if err != nil {
 		return err
 	}
-	gw := gzip.NewWriter(cmpfile)
-	defer gw.Close()
+	defer func() {
+		if err != nil {
+			tmpfile.Close()
+			os.Remove(tmpname)
+		}
+	}()
 
 	// 设置下次读取oldfile文件时的偏移量，及从头开始读取oldfile到压缩文件
-	if _, err := oldfile.Seek(0, 0); err != nil {
+	if _, err = oldfile.Seek(0, 0); err != nil {
 		return err
 	}
-
-	if _, err := io.Copy(gw, oldfile); err != nil {
-		// 当压缩失败时删除压缩文件
-		if errR := os.Remove(cmpname); errR != nil {
-			return errR
-		}
+	gw := gzip.NewWriter(tmpfile)
+	if _, err = io.Copy(gw, oldfile); err != nil {
+		return err
+	}
+	if err = gw.Close(); err != nil {
+		return err
+	}
+	if err = tmpfile.Sync(); err != nil {
 		return err
 	}
-	// 删除临时文件
-	return os.Remove(cmpname + ".tmp")
+	if err = tmpfile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpname, cmpname)
 }
 
 // 执行日志滚动， file为生成的历史文件名称
-func (w *Writer) Reopen(file string) error {
-	// 重命名
-	if err := os.Rename(w.absPath, file); err != nil {
+func (w *Writer) Reopen(file string) (err error) {
+	w.emit(EventRotationStarted, file, nil)
+	defer func() {
+		w.emit(EventRotationCompleted, file, err)
+	}()
+	if w.cf.RotationStrategy == "copytruncate" {
+		file, err = w.copyTruncate(file)
 		return err
 	}
+	// 按日期分目录时历史日志文件和新的日志文件可能位于新的日期目录
+	newpath := LogFilePath(w.cf)
+	if w.cf.DirLayout != "" {
+		if err := w.cf.mkdirAll(path.Dir(file)); err != nil {
+			return err
+		}
+		if err := w.cf.mkdirAll(path.Dir(newpath)); err != nil {
+			return err
+		}
+	}
+	// 重命名，追加到已存在的历史日志文件时先重命名为带序号的文件
+	file, conflict := w.cf.archiveTarget(file)
+	renamed := file
+	if conflict {
+		renamed = w.cf.uniqueArchive(file)
+	}
+	if err := os.Rename(w.path(), renamed); err != nil {
+		return err
+	}
+	w.writeFooter(w.current())
+	if info, err := w.current().Stat(); err == nil {
+		w.recordRotation(file, info.Size())
+	}
 	// 打开新的日志文件
-	newfile, err := os.OpenFile(w.absPath, DefualtFileFlag, DefualtFileMode)
+	newfile, err := w.cf.openFile(newpath, DefualtFileFlag)
 	if err != nil {
 		return err
 	}
+	w.setPath(newpath)
+	w.writeHeader(newfile)
+	// 预分配失败时仍然完成滚动，错误作为滚动的结果返回
+	perr := w.cf.preallocate(newfile)
+
+	// 原子性的将新打开的日志文件替换旧日志文件，并返回旧日志文件
+	// oldfile指向最新生成的历史日志文件
+	oldfile := w.file.Swap(newfile)
+
+	w.rotated()
+	if conflict {
+		go func() {
+			w.afterRotate(w.appendArchive(file, renamed, oldfile))
+		}()
+		return perr
+	}
+	go w.afterRotate(file, oldfile)
+	return perr
+}
 
-	// 原子性的将新打开的日志文件替换就日志文件，并返回就日志文件
-	// 使用unsafe.Pointer直接操作了正在写入日志文件的指针
-	// oldfile的指针指向最新生成的历史日志文件
-	oldfile := atomic.SwapPointer((*unsafe.Pointer)(unsafe.Pointer(&w.file)), unsafe.Pointer(newfile))
+// copytruncate方式滚动：将当前日志文件内容复制到历史文件后清空当前文件，
+// 当前文件的描述符保持不变，外部持有该文件的程序不受影响
+func (w *Writer) copyTruncate(file string) (string, error) {
+	w.writeFooter(w.current())
+	src, err := os.Open(w.path())
+	if err != nil {
+		return file, err
+	}
+	defer src.Close()
 
-	go func() {
-		defer (*os.File)(oldfile).Close()
-		// 执行历史日志文件压缩
-		if w.cf.Compress {
-			if err := os.Rename(file, file+".tmp"); err != nil {
-				log.Println("error in compress rename tempfile", err)
-				return
-			}
-			if err := w.CompressFile((*os.File)(oldfile), file); err != nil {
-				log.Println("error in compress log file", err)
-				return
-			}
-		}
+	file, conflict := w.cf.archiveTarget(file)
+	flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if conflict {
+		flag = os.O_RDWR | os.O_APPEND
+	}
+	dst, err := w.cf.openFile(file, flag)
+	if err != nil {
+		return file, err
+	}
+	size, err := io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return file, err
+	}
 
-		// 删除过期历史日志文件
-		if w.cf.MaxRemain > 0 {
-		retry:
-			select {
-			case w.rollingfilech <- file:
-			default:
-				w.DoRemove()
-				goto retry
-			}
+	// 清空当前日志文件，O_APPEND模式下后续写入从文件开头开始
+	if err := w.current().Truncate(0); err != nil {
+		dst.Close()
+		return file, err
+	}
+	w.writeHeader(w.current())
+	w.recordRotation(file, size)
+
+	w.rotated()
+	go w.afterRotate(file, dst)
+	// 清空时释放了预分配的空间，重新预分配
+	return file, w.cf.preallocate(w.current())
+}
+
+// 滚动后对历史日志文件的处理：压缩和删除过期文件，oldfile为历史日志文件的句柄
+func (w *Writer) afterRotate(file string, oldfile *os.File) {
+	defer oldfile.Close()
+	// 执行历史日志文件压缩，writer在等待压缩时间期间关闭时不再压缩
+	if w.cf.Compress {
+		if !w.waitCompress(w.cf.clock().Now()) {
+			return
 		}
-	}()
-	return nil
+		// 压缩失败时保留未压缩的历史日志文件
+		cmpname, err := w.compressArchive(file, oldfile)
+		if err != nil {
+			w.handleError("compress log file", err)
+		}
+		file = cmpname
+		w.emit(EventCompressed, file, err)
+	}
+	if w.cf.Checksum {
+		w.handleError("write checksum", w.writeChecksum(file))
+	}
+
+	// 删除过期历史日志文件
+	if w.cf.retention() {
+		w.sweep()
+	}
 }
 
-// 没有lock的Write接口实现
-func (w *Writer) Write(b []byte) (int, error) {
+// 处理待执行的日志文件重建，writer关闭后返回ErrClosed
+func (w *Writer) rolling() error {
+	if w.isClosed() {
+		return ErrClosed
+	}
 	select {
-	// 触发日志滚动
-	case filename := <-w.fire:
-		if err := w.Reopen(filename); err != nil {
-			return 0, err
-
-		}
+	// 日志文件被外部删除或移动
+	case <-w.recreate:
+		return w.recreateFile()
 	default:
+		return nil
+	}
+}
 
+// 没有lock的Write接口实现
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.rolling(); err != nil {
+		return 0, err
 	}
-	// 原子性的获取当前写入日志文件的指针
-	fp := atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&w.file)))
-	file := (*os.File)(fp)
-	return file.Write(b)
+	n, err := w.current().Write(b)
+	return w.written(b, n, err)
 }
 
 // 使用lock的Write接口实现
 func (w *LockedWriter) Write(b []byte) (n int, err error) {
 	w.Lock()
 	defer w.Unlock()
-	select {
-	// 触发日志滚动
-	case filename := <-w.fire:
-		if err := w.Reopen(filename); err != nil {
-			return 0, err
-		}
-	default:
-
+	if err := w.rolling(); err != nil {
+		return 0, err
 	}
-	n, err = w.file.Write(b)
-	return n, err
+	n, err = w.current().Write(b)
+	return w.written(b, n, err)
 }
 
 // 同步并发的Write接口实现
 func (w *AsynchronousWriter) Write(b []byte) (int, error) {
-	if atomic.LoadInt32(&w.closed) == 0 {
-		select {
-		case err := <-w.errChan:
-			return 0, err
-		// 触发日志滚动
-		case filename := <-w.fire:
-			if err := w.Reopen(filename); err != nil {
-				return 0, err
-			}
-
-			l := len(b)
-			// 将数据写入_asyncBufferPool
-			for len(b) > 0 {
-				buf := _asyncBufferPool.Get().([]byte)
-				n := copy(buf, b)
-				w.queue <- buf[:n]
-				b = b[n:]
-			}
-			return l, nil
-		default:
-			w.queue <- append(_asyncBufferPool.Get().([]byte)[0:0], b...)[:len(b)]
-			return len(b), nil
-		}
+	if err := w.put(w.pool.Copy(b)); err != nil {
+		return 0, err
 	}
-	return 0, ErrClosed
+	return w.written(b, len(b), nil)
 }
 
-// 异步并发的Write接口实现
-func (w *BufferWriter) Write(b []byte) (int, error) {
+// 将缓存放入队列，关闭后或有写入错误时归还缓存并返回错误
+// 先登记再检查closed，Close设置closed后等待已登记的写入完成，保证放入队列的日志都会写入
+func (w *AsynchronousWriter) put(buf *[]byte) error {
+	atomic.AddInt32(&w.writing, 1)
+	defer atomic.AddInt32(&w.writing, -1)
+	if atomic.LoadInt32(&w.closed) != 0 {
+		w.pool.Put(buf)
+		return ErrClosed
+	}
 	select {
-	// 触发日志滚动
-	case filename := <-w.fire:
-		if err := w.Reopen(filename); err != nil {
-			return 0, err
-		}
+	case err := <-w.errChan:
+		w.pool.Put(buf)
+		return err
 	default:
+		w.enqueue(buf)
+		return nil
+	}
+}
 
+// 异步并发的Write接口实现
+func (w *BufferWriter) Write(b []byte) (int, error) {
+	if err := w.rolling(); err != nil {
+		return 0, err
 	}
-	// 读取所有待写入的数据
-	buf := append(*w.buf, b...)
-	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.buf)), (unsafe.Pointer)(&buf))
+	// 追加到待写入的数据
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrClosed
+	}
+	w.buf = append(w.buf, b...)
+	var ob []byte
 	// 判断待写入数据大于缓存池，并且w.swaping==0，并且设置w.swaping=1
-	if len(*w.buf) > w.cf.BufferWriterThreshold && atomic.CompareAndSwapInt32(&w.swaping, 0, 1) {
-		// 创建新缓存池
-		nb := make([]byte, 0, w.cf.BufferWriterThreshold*2)
-		// 新缓存池代替旧缓存池，并返回就缓存池的指针
-		ob := atomic.SwapPointer((*unsafe.Pointer)(unsafe.Pointer(&w.buf)), (unsafe.Pointer(&nb)))
-		// 写入就缓存池中的数据
-		w.file.Write(*(*[]byte)(ob))
+	if len(w.buf) > w.cf.BufferWriterThreshold && atomic.CompareAndSwapInt32(&w.swaping, 0, 1) {
+		// 新缓存池代替旧缓存池，在锁外写入旧缓存池中的数据
+		ob = w.buf
+		w.buf = make([]byte, 0, w.cf.BufferWriterThreshold*2)
+		w.flushMu.Lock()
+	}
+	w.mu.Unlock()
+	if ob != nil {
+		if _, err := w.current().Write(ob); err != nil {
+			w.handleError("buffer write", err)
+		}
 		// 设置w.swaping=0
 		atomic.StoreInt32(&w.swaping, 0)
+		w.flushMu.Unlock()
 	}
-	return len(b), nil
+	return w.written(b, len(b), nil)
 }
 
-// 没有lock的Close接口实现，借助atomic实现原子性操作
+// 没有lock的Close接口实现，停止manager和后台协程后关闭日志文件，重复关闭返回ErrClosed
 func (w *Writer) Close() error {
-	return (*os.File)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&w.file)))).Close()
+	if !w.shutdown() {
+		return ErrClosed
+	}
+	return w.current().Close()
 }
 
-// 使用lock的Close接口实现
+// 使用lock的Close接口实现，等待进行中的写入和滚动完成后关闭
 func (w *LockedWriter) Close() error {
 	w.Lock()
 	defer w.Unlock()
-	return w.file.Close()
+	return w.Writer.Close()
 }
 
-// 同步并发的Close接口实现
+// 同步并发的Close接口实现，写入队列和溢出文件中的日志后停止manager，再关闭日志文件
 func (w *AsynchronousWriter) Close() error {
 	// w.closed==0，并设置w.closed=1
 	if atomic.CompareAndSwapInt32(&w.closed, 0, 1) {
+		w.waitWriting()
 		close(w.ctx)
+		// 等待写入协程退出，避免关闭文件后继续写入
+		<-w.done
 		w.onClose()
-		return w.file.Close()
+		if w.spill != nil {
+			w.drainSpill()
+		}
+		w.shutdown()
+		return w.current().Close()
 	}
 	return ErrClosed
 }
 
+// 等待进行中的Write将日志放入队列，写入协程此时仍在运行
+// 写入协程可能在等待交出写入错误，取走错误避免与放入队列的Write互相等待
+func (w *AsynchronousWriter) waitWriting() {
+	for atomic.LoadInt32(&w.writing) > 0 {
+		select {
+		case <-w.errChan:
+		default:
+			runtime.Gosched()
+		}
+	}
+}
+
 // 将缓存队列中的数据处理完
 func (w *AsynchronousWriter) onClose() {
 	var err error
 	for {
 		select {
 		case b := <-w.queue:
-			if _, err = w.file.Write(b); err != nil {
+			if _, err = w.current().Write(*b); err != nil {
 				select {
 				case w.errChan <- err:
 				default:
-					_asyncBufferPool.Put(b)
+					w.release(b)
 					return
 				}
 			}
-			_asyncBufferPool.Put(b)
+			w.release(b)
 		default:
 			return
 		}
@@ -428,26 +622,146 @@ func (w *AsynchronousWriter) onClose() {
 
 // 同步并发是的数据写入
 func (w *AsynchronousWriter) writer() {
-	var err error
+	defer close(w.done)
 	w.wg.Done()
+	if w.cf.BatchSize > 1 {
+		w.batchWriter()
+		return
+	}
+	for {
+		select {
+		case b := <-w.queue:
+			w.writeEntry(*b)
+			w.release(b)
+		case filename := <-w.rotations:
+			w.drain()
+			w.reportError(w.Reopen(filename))
+		case <-w.recreate:
+			w.reportError(w.recreateFile())
+		case <-w.spillSignal():
+			w.drainSpill()
+		case <-w.ctx:
+			return
+		}
+	}
+}
+
+// 批量写入，从队列中取出最多BatchSize条日志合并后一次写入文件
+func (w *AsynchronousWriter) batchWriter() {
+	latency := time.Duration(w.cf.BatchMaxLatency) * time.Millisecond
+	// 等待凑满一批日志的计时器，初始为停止状态
+	timer := time.NewTimer(latency)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	batch := make([]byte, 0, w.pool.limit())
 	for {
 		select {
 		case b := <-w.queue:
-			if _, err = w.file.Write(b); err != nil {
-				w.errChan <- err
+			batch = append(batch[:0], *b...)
+			w.release(b)
+			fired := latency <= 0
+			if !fired {
+				timer.Reset(latency)
 			}
-			_asyncBufferPool.Put(b)
+		collect:
+			for n := 1; n < w.cf.BatchSize; n++ {
+				select {
+				case b = <-w.queue:
+				default:
+					// 队列为空时，在最长等待时间内等待后续日志
+					if fired {
+						break collect
+					}
+					select {
+					case b = <-w.queue:
+					case <-timer.C:
+						fired = true
+						break collect
+					case <-w.ctx:
+						break collect
+					}
+				}
+				// 合并后超过缓存容量时先写入已合并的日志，避免大块数据（如ReadFrom）反复扩大缓存
+				if len(batch)+len(*b) > cap(batch) {
+					w.writeEntry(batch)
+					batch = batch[:0]
+				}
+				batch = append(batch, *b...)
+				w.release(b)
+			}
+			if !fired && !timer.Stop() {
+				<-timer.C
+			}
+			w.writeEntry(batch)
+			// 避免超大日志长期占用内存
+			if cap(batch) > w.pool.limit() {
+				batch = make([]byte, 0, w.pool.limit())
+			}
+		case filename := <-w.rotations:
+			w.drain()
+			w.reportError(w.Reopen(filename))
+		case <-w.recreate:
+			w.reportError(w.recreateFile())
+		case <-w.spillSignal():
+			w.drainSpill()
 		case <-w.ctx:
 			return
 		}
 	}
 }
 
-// 异步并发的Close接口实现
-func (w BufferWriter) Close() error {
-	_, err := w.file.Write(*w.buf)
+// 写入滚动前已入队的日志
+func (w *AsynchronousWriter) drain() {
+	for n := len(w.queue); n > 0; n-- {
+		b := <-w.queue
+		w.writeEntry(*b)
+		w.release(b)
+	}
+}
+
+// 写入一个或多个完整的日志条目
+// 写入出错且只写入了部分数据时补写换行符，避免不完整的日志与后续日志拼接在同一行
+func (w *AsynchronousWriter) writeEntry(b []byte) {
+	n, err := w.current().Write(b)
+	if err != nil {
+		if n > 0 && b[n-1] != '\n' {
+			w.current().Write(_newline)
+		}
+		w.reportError(err)
+	}
+}
+
+// 将写入错误交给下一次Write返回，关闭时不再等待
+func (w *AsynchronousWriter) reportError(err error) {
+	if err == nil {
+		return
+	}
+	atomic.AddUint64(&w.stats.errors, 1)
+	select {
+	case w.errChan <- err:
+	case <-w.ctx:
+	}
+}
+
+// 异步并发的Close接口实现，等待进行中的缓存写入完成，写入剩余的缓存后停止manager，再关闭日志文件
+func (w *BufferWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	ob := w.buf
+	w.buf = nil
+	w.flushMu.Lock()
+	w.mu.Unlock()
+	_, err := w.current().Write(ob)
+	w.flushMu.Unlock()
+	w.shutdown()
 	if err != nil {
+		w.current().Close()
 		return err
 	}
-	return w.file.Close()
+	return w.current().Close()
 }
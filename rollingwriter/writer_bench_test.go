@@ -1,8 +1,11 @@
 package rollingwriter
 
 import (
+	"bytes"
 	"crypto/rand"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 )
 
@@ -133,3 +136,110 @@ func BenchmarkParallelBufferWrite(b *testing.B) {
 	w.Close()
 	clean()
 }
+
+func BenchmarkAsynWriteSizes(b *testing.B) {
+	for _, l := range []int{64, 512, 4000, 64 * 1024} {
+		b.Run(fmt.Sprintf("%dB", l), func(b *testing.B) {
+			bf := make([]byte, l)
+			rand.Read(bf)
+
+			w := newAsynWriter()
+			b.ReportAllocs()
+			b.SetBytes(int64(l))
+			for i := 0; i < b.N; i++ {
+				w.Write(bf)
+			}
+			w.Close()
+			clean()
+		})
+	}
+}
+
+func newShardedWriter() *ShardedWriter {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = "./test"
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "sharded"
+	w, _ := NewWriterFromConfig(&cfg)
+	return w.(*ShardedWriter)
+}
+
+// 64倍GOMAXPROCS个协程并发写入时lock模式与sharded模式的对比
+func BenchmarkContendedWrite(b *testing.B) {
+	for _, mode := range []string{"lock", "sharded"} {
+		b.Run(mode, func(b *testing.B) {
+			var w io.WriteCloser
+			bf := make([]byte, 256)
+			rand.Read(bf)
+
+			if mode == "lock" {
+				w = newLockedWriter()
+			} else {
+				w = newShardedWriter()
+			}
+			b.ReportAllocs()
+			b.SetParallelism(64)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w.Write(bf)
+				}
+			})
+			w.Close()
+			clean()
+		})
+	}
+}
+
+// WriteString与先转换为[]byte再Write的对比
+func BenchmarkWriteString(b *testing.B) {
+	s := strings.Repeat("x", 1023) + "\n"
+	for _, mode := range []string{"lock", "async"} {
+		b.Run(mode+"/Write", func(b *testing.B) {
+			w, _ := NewWriter(WithLogPath(b.TempDir()), WithoutRollingPolicy(), func(c *Config) { c.WriterMode = mode })
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w.Write([]byte(s))
+			}
+			w.Close()
+		})
+		b.Run(mode+"/WriteString", func(b *testing.B) {
+			w, _ := NewWriter(WithLogPath(b.TempDir()), WithoutRollingPolicy(), func(c *Config) { c.WriterMode = mode })
+			sw := w.(io.StringWriter)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sw.WriteString(s)
+			}
+			w.Close()
+		})
+	}
+}
+
+// 只实现io.Writer，使io.Copy无法使用ReadFrom
+type writerOnly struct {
+	io.Writer
+}
+
+// ReadFrom与io.Copy经过Write的对比，lock模式直接由文件读取，async模式两者都使用缓存池，分配的内存相近
+func BenchmarkReadFrom(b *testing.B) {
+	body := []byte(strings.Repeat("from reader\n", 1<<14))
+	for _, mode := range []string{"lock", "async"} {
+		b.Run(mode+"/Copy", func(b *testing.B) {
+			w, _ := NewWriter(WithLogPath(b.TempDir()), WithoutRollingPolicy(), func(c *Config) { c.WriterMode = mode })
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				io.Copy(writerOnly{w}, bytes.NewReader(body))
+			}
+			w.Close()
+		})
+		b.Run(mode+"/ReadFrom", func(b *testing.B) {
+			w, _ := NewWriter(WithLogPath(b.TempDir()), WithoutRollingPolicy(), func(c *Config) { c.WriterMode = mode })
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				io.Copy(w, bytes.NewReader(body))
+			}
+			w.Close()
+		})
+	}
+}
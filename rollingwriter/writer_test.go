@@ -3,14 +3,23 @@ package rollingwriter
 import (
 	"crypto/rand"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func clean() {
 	os.Remove("./test/unittest.log")
 	os.Remove("./test/unittest.reopen")
 	os.Remove("./test/unittest.gz")
+	os.Remove("./test/unittest.log.state")
 	os.Remove("./test")
 }
 
@@ -70,6 +79,7 @@ func TestNewWriter(t *testing.T) {
 		t.Fatal("error in test new writer", err)
 	}
 	os.Remove("./foo.log")
+	os.Remove("./foo.log.state")
 }
 
 func TestWriter(t *testing.T) {
@@ -254,7 +264,270 @@ func TestCompress(t *testing.T) {
 		rand.Read(bf)
 		writer.Write(bf)
 	}
-	writer.CompressFile(writer.file, "./test/unittest.gz")
+	writer.CompressFile(writer.current(), "./test/unittest.gz")
+	writer.Close()
+	clean()
+}
+
+func TestCopyTruncate(t *testing.T) {
+	var c int = 100
+	var l int = 1024
+
+	writer := newWriter()
+	writer.cf.RotationStrategy = "copytruncate"
+	for i := 0; i < c; i++ {
+		bf := make([]byte, l)
+		rand.Read(bf)
+		writer.Write(bf)
+	}
+	file := writer.current()
+	if err := writer.Reopen("./test/unittest.reopen"); err != nil {
+		t.Fatal("error in copytruncate", err)
+	}
+	if writer.current() != file {
+		t.Fatal("file handle changed after copytruncate")
+	}
+	if info, err := os.Stat("./test/unittest.log"); err != nil || info.Size() != 0 {
+		t.Fatal("active log file not truncated", err)
+	}
+	if info, err := os.Stat("./test/unittest.reopen"); err != nil || info.Size() != int64(c*l) {
+		t.Fatal("archive size mismatch", err)
+	}
 	writer.Close()
 	clean()
 }
+
+func TestAsyncBatchWrite(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "async"
+	cfg.BatchSize = 16
+	cfg.BatchMaxLatency = 5
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	var c int = 1000
+	for i := 0; i < c; i++ {
+		if _, err := w.Write([]byte("batched line\n")); err != nil {
+			t.Fatal("error in write", err)
+		}
+	}
+	w.Close()
+	info, err := os.Stat(LogFilePath(&cfg))
+	if err != nil || info.Size() != int64(c*len("batched line\n")) {
+		t.Fatal("batched write size mismatch", err)
+	}
+}
+
+func TestIdleRotation(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.RollingPolicy = VolumeRolling
+	cfg.RollingVolumeSize = "1kb"
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+	bf := make([]byte, 2048)
+	rand.Read(bf)
+	w.Write(bf)
+
+	// 不再写入，等待manager检查文件大小后在后台滚动
+	time.Sleep(time.Duration(Precision)*time.Second + 500*time.Millisecond)
+	info, err := os.Stat(LogFilePath(&cfg))
+	if err != nil || info.Size() != 0 {
+		t.Fatal("log file not rotated without write", err)
+	}
+	// 当前日志文件、.state文件和历史日志文件
+	files, _ := ioutil.ReadDir(cfg.LogPath)
+	if len(files) != 3 {
+		t.Fatal("archive not created", len(files))
+	}
+}
+
+func TestAsyncLineBoundary(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "async"
+	cfg.TimeTagFormat = "20060102150405.000000000"
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+
+	// 写入大小不同的日志，其中包含超过BufferSize的日志，同时不断触发日志滚动
+	sizes := []int{10, 1000, 5000, BufferSize + 10, 100}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			w.(*AsynchronousWriter).Rotate()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		size := sizes[i%len(sizes)]
+		line := append([]byte(strings.Repeat(string(rune('a'+i%26)), size)), '\n')
+		w.Write(line)
+	}
+	<-done
+	w.Close()
+
+	// 所有文件中的每一行都是完整的日志
+	files, _ := ioutil.ReadDir(cfg.LogPath)
+	lines := 0
+	for _, fi := range files {
+		if fi.Name() == path.Base(StateFilePath(&cfg)) {
+			continue
+		}
+		buf, err := ioutil.ReadFile(path.Join(cfg.LogPath, fi.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			if strings.Trim(line, line[:1]) != "" {
+				t.Fatal("torn line found in", fi.Name())
+			}
+			lines++
+		}
+	}
+	if lines != 50 || len(files) < 3 {
+		t.Fatal("line or file count mismatch", lines, len(files))
+	}
+}
+
+func TestWriteStringReadFrom(t *testing.T) {
+	for _, mode := range []string{"lock", "async", "buffer", "sharded", "audit", "uring", "mmap"} {
+		t.Run(mode, func(t *testing.T) {
+			if _, ok := lookupWriterMode(mode); !ok && (mode == "uring" || mode == "mmap") {
+				t.Skip("unsupported on this platform")
+			}
+			dir := t.TempDir()
+			w, err := NewWriter(WithLogPath(dir), WithoutRollingPolicy(), WithBufferThreshold(1), func(c *Config) { c.WriterMode = mode })
+			if !assert.NoError(t, err) {
+				return
+			}
+			sw, ok := w.(io.StringWriter)
+			assert.True(t, ok)
+			rf, ok := w.(io.ReaderFrom)
+			assert.True(t, ok)
+
+			n, err := sw.WriteString("string\n")
+			assert.NoError(t, err)
+			assert.Equal(t, 7, n)
+			body := strings.Repeat("from reader\n", _readFromSize/8)
+			m, err := rf.ReadFrom(strings.NewReader(body))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(body)), m)
+			assert.NoError(t, w.Close())
+
+			buf, _ := ioutil.ReadFile(filepath.Join(dir, "log.log"))
+			assert.Equal(t, "string\n"+body, string(buf))
+		})
+	}
+}
+
+// ReadFrom每次写入完整的行，长行扩大缓存而不拆分
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("x", 3*_readFromSize) + "\n"
+	body := "first\nsecond\n" + long + "third\nno newline"
+	pool := newBufferPool(0)
+	var chunks []string
+	n, err := readLines(pool, iotest.HalfReader(strings.NewReader(body)), func(buf *[]byte) (int, error) {
+		chunks = append(chunks, string(*buf))
+		m := len(*buf)
+		pool.Put(buf)
+		return m, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(body)), n)
+	assert.Equal(t, body, strings.Join(chunks, ""))
+	for _, c := range chunks[:len(chunks)-1] {
+		assert.True(t, strings.HasSuffix(c, "\n"))
+	}
+	assert.Equal(t, "no newline", chunks[len(chunks)-1])
+
+	// 写入失败时返回已写入的字节数
+	n, err = readLines(pool, strings.NewReader("a\nb\n"), func(buf *[]byte) (int, error) {
+		return 1, io.ErrShortWrite
+	})
+	assert.Equal(t, io.ErrShortWrite, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestRotationNameCollision(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.LogPath = t.TempDir()
+	cfg.FileName = "unittest"
+	cfg.WriterMode = "none"
+	cfg.TimeTagFormat = "2006"
+	cfg.RollingPolicy = WithoutRolling
+	w, err := NewWriterFromConfig(&cfg)
+	if err != nil {
+		t.Fatal("error in new writer", err)
+	}
+	defer w.Close()
+	wr := w.(*Writer)
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		wr.Write([]byte(line))
+		if err := wr.Reopen(wr.m.(*manager).GenLogFileName(&cfg)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	archives, err := ListArchives(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := path.Join(cfg.LogPath, "unittest.log."+time.Now().Format("2006"))
+	var names []string
+	for _, a := range archives {
+		names = append(names, a.Path)
+		assert.Equal(t, time.Now().Year(), a.Time.Year())
+	}
+	assert.Equal(t, []string{base, base + ".1", base + ".2"}, names)
+	buf, _ := ioutil.ReadFile(base + ".2")
+	assert.Equal(t, "c\n", string(buf))
+}
+
+func TestAppendOnConflict(t *testing.T) {
+	for _, strategy := range []string{"rename", "copytruncate"} {
+		cfg := NewDefaultConfig()
+		cfg.LogPath = t.TempDir()
+		cfg.FileName = "unittest"
+		cfg.WriterMode = "none"
+		cfg.TimeTagFormat = "2006"
+		cfg.RollingPolicy = WithoutRolling
+		cfg.RotationStrategy = strategy
+		cfg.AppendOnConflict = true
+		w, err := NewWriterFromConfig(&cfg)
+		if err != nil {
+			t.Fatal("error in new writer", err)
+		}
+		wr := w.(*Writer)
+		var archive string
+		for _, line := range []string{"a\n", "b\n"} {
+			wr.Write([]byte(line))
+			archive = wr.m.(*manager).GenLogFileName(&cfg)
+			if err := wr.Reopen(archive); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assert.Eventually(t, func() bool {
+			buf, _ := ioutil.ReadFile(archive)
+			return string(buf) == "a\nb\n"
+		}, time.Second, 10*time.Millisecond, strategy)
+		assert.Eventually(t, func() bool {
+			return !exists(archive + ".1")
+		}, time.Second, 10*time.Millisecond, strategy)
+		w.Close()
+	}
+}
@@ -0,0 +1,54 @@
+package logx
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 按级别采样，只有Levels中的级别经过采样，其他级别的日志总是记录
+type levelSampler struct {
+	zapcore.Core
+	sampled zapcore.Core
+	levels  map[zapcore.Level]bool
+}
+
+// 按采样配置包装core，未配置Levels时所有级别都经过采样
+func newSampler(core zapcore.Core, sc *SamplingConfig) zapcore.Core {
+	sampled := zapcore.NewSampler(core, time.Second, sc.Initial, sc.Thereafter)
+	if len(sc.Levels) == 0 {
+		return sampled
+	}
+	levels := make(map[zapcore.Level]bool, len(sc.Levels))
+	for _, l := range sc.Levels {
+		levels[logLevel(l)] = true
+	}
+	return &levelSampler{Core: core, sampled: sampled, levels: levels}
+}
+
+func (s *levelSampler) With(fields []zapcore.Field) zapcore.Core {
+	// zap的sampler在With后共用计数
+	return &levelSampler{Core: s.Core.With(fields), sampled: s.sampled.With(fields), levels: s.levels}
+}
+
+func (s *levelSampler) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s.levels[ent.Level] {
+		return s.sampled.Check(ent, ce)
+	}
+	return s.Core.Check(ent, ce)
+}
+
+// 检查采样配置
+func checkSampling(sc *SamplingConfig) []string {
+	var problems []string
+	if sc.Initial < 0 || sc.Thereafter < 0 {
+		problems = append(problems, fmt.Sprintf("sampling: invalid initial %d or thereafter %d", sc.Initial, sc.Thereafter))
+	}
+	for _, l := range sc.Levels {
+		if !validLevel(l) {
+			problems = append(problems, fmt.Sprintf("sampling: invalid level %q", l))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,40 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevelSampling(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newSampler(core, &SamplingConfig{Initial: 1, Thereafter: 100, Levels: []string{"debug"}})).With(zap.String("svc", "api"))
+	for i := 0; i < 200; i++ {
+		l.Debug("poll")
+		l.Warn("slow")
+	}
+	// debug记录第1条和第101条，warn全部记录
+	assert.Equal(t, 2, logs.FilterMessage("poll").Len())
+	assert.Equal(t, 200, logs.FilterMessage("slow").Len())
+	assert.Equal(t, "api", logs.All()[0].ContextMap()["svc"])
+
+	// 未配置Levels时所有级别都采样
+	core, logs = observer.New(zapcore.DebugLevel)
+	l = zap.New(newSampler(core, &SamplingConfig{Initial: 1, Thereafter: 100}))
+	for i := 0; i < 10; i++ {
+		l.Warn("slow")
+	}
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestCheckSampling(t *testing.T) {
+	r := CheckConfig(&Config{
+		Sampling:  &SamplingConfig{Initial: -1},
+		Appenders: []Appender{{Type: "stdout", Sampling: &SamplingConfig{Levels: []string{"verbose"}}}},
+	})
+	assert.Equal(t, []string{"sampling: invalid initial -1 or thereafter 0"}, r.Problems)
+	assert.Equal(t, []string{`sampling: invalid level "verbose"`}, r.Appenders[0].Problems)
+}
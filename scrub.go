@@ -0,0 +1,139 @@
+package logx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 脱敏方案中替换内容的默认值
+const DefaultScrubReplace = "***"
+
+// 脱敏规则，同一条日志可以在内部调试文件中完整输出，在导出的审计日志中删除内网IP、用户ID等内容
+// 通过With添加的字段已完成编码，不会被脱敏
+type ScrubRule struct {
+	// 生效的日志级别，如[debug, info]，为空时所有级别生效
+	Levels []string `json:"levels" yaml:"levels"`
+	// 删除的字段
+	Drop []string `json:"drop" yaml:"drop"`
+	// 值替换为Replace的字段
+	Mask []string `json:"mask" yaml:"mask"`
+	// 正则表达式，消息、字符串字段和error字段中匹配的内容替换为Replace，如内网IP：10(\.\d{1,3}){3}
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// 替换的内容，为空时为***
+	Replace string `json:"replace" yaml:"replace"`
+}
+
+// 按名称查找并编译脱敏方案
+func (cfg *Config) scrubProcessor(name string) (Processor, error) {
+	rules, ok := cfg.ScrubProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scrub profile %q", name)
+	}
+	p, err := newScrubProcessor(rules)
+	if err != nil {
+		return nil, fmt.Errorf("scrub profile %s: %v", name, err)
+	}
+	return p, nil
+}
+
+// 编译后的脱敏规则
+type scrubRule struct {
+	levels  map[zapcore.Level]bool // 为nil时所有级别生效
+	drop    map[string]bool
+	mask    map[string]bool
+	pattern *regexp.Regexp
+	replace string
+}
+
+// 将脱敏方案编译为处理器
+func newScrubProcessor(rules []ScrubRule) (Processor, error) {
+	compiled := make([]scrubRule, 0, len(rules))
+	for i, r := range rules {
+		c := scrubRule{drop: stringSet(r.Drop), mask: stringSet(r.Mask), replace: r.Replace}
+		if c.replace == "" {
+			c.replace = DefaultScrubReplace
+		}
+		for _, l := range r.Levels {
+			if !validLevel(l) {
+				return nil, fmt.Errorf("rules[%d]: invalid level %q", i, l)
+			}
+			if c.levels == nil {
+				c.levels = make(map[zapcore.Level]bool)
+			}
+			c.levels[logLevel(l)] = true
+		}
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rules[%d]: %v", i, err)
+			}
+			c.pattern = re
+		}
+		compiled = append(compiled, c)
+	}
+	return func(e *Entry) {
+		for _, r := range compiled {
+			if r.levels == nil || r.levels[e.Level] {
+				r.apply(e)
+			}
+		}
+	}, nil
+}
+
+func (r *scrubRule) apply(e *Entry) {
+	fields := e.Fields[:0]
+	for _, f := range stringifyFields(e.Fields) {
+		switch {
+		case r.drop[f.Key]:
+			continue
+		case r.mask[f.Key]:
+			f = zap.String(f.Key, r.replace)
+		case r.pattern != nil:
+			f = r.scrubField(f)
+		}
+		fields = append(fields, f)
+	}
+	e.Fields = fields
+	if r.pattern != nil {
+		e.Message = r.pattern.ReplaceAllString(e.Message, r.replace)
+	}
+}
+
+// 替换字段值中匹配的内容，不匹配时保持原字段
+func (r *scrubRule) scrubField(f zapcore.Field) zapcore.Field {
+	var s string
+	switch f.Type {
+	case zapcore.StringType:
+		s = f.String
+	case zapcore.ByteStringType:
+		b, _ := f.Interface.([]byte)
+		s = string(b)
+	case zapcore.ErrorType:
+		err, _ := f.Interface.(error)
+		if err == nil {
+			return f
+		}
+		s = err.Error()
+	default:
+		return f
+	}
+	if !r.pattern.MatchString(s) {
+		return f
+	}
+	return zap.String(f.Key, r.pattern.ReplaceAllString(s, r.replace))
+}
+
+func stringSet(keys []string) map[string]bool {
+	if len(keys) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		m[strings.TrimSpace(k)] = true
+	}
+	return m
+}
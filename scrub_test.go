@@ -0,0 +1,59 @@
+package logx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestScrubProfile(t *testing.T) {
+	cfg := &Config{ScrubProfiles: map[string][]ScrubRule{
+		"external": {
+			{Drop: []string{"user_id"}, Pattern: `10(\.\d{1,3}){3}`, Replace: "<ip>"},
+			{Mask: []string{"token"}},
+			{Levels: []string{"debug", "info"}, Drop: []string{"request"}},
+		},
+	}}
+	scrub, err := cfg.scrubProcessor("external")
+	if !assert.NoError(t, err) {
+		return
+	}
+	internal, internalLogs := observer.New(zapcore.DebugLevel)
+	external, externalLogs := observer.New(zapcore.DebugLevel)
+	l := zap.New(zapcore.NewTee(
+		&processorCore{Core: internal},
+		&processorCore{Core: external, processors: []Processor{scrub}},
+	))
+	fields := []zap.Field{zap.String("user_id", "u42"), zap.String("token", "t0k"), zap.String("peer", "10.0.3.7:443"),
+		zap.Error(errors.New("dial 10.1.2.3 refused")), zap.String("request", "GET /"), zap.Int("status", 502)}
+	l.Info("upstream 10.0.0.1 failed", fields...)
+	l.Error("upstream 10.0.0.1 failed", fields...)
+
+	assert.Equal(t, "upstream 10.0.0.1 failed", internalLogs.All()[0].Message)
+	assert.Equal(t, "u42", internalLogs.All()[0].ContextMap()["user_id"])
+
+	logs := externalLogs.All()
+	assert.Equal(t, "upstream <ip> failed", logs[0].Message)
+	assert.Equal(t, map[string]interface{}{"token": "***", "peer": "<ip>:443", "error": "dial <ip> refused", "status": int64(502)}, logs[0].ContextMap())
+	// request只在debug和info级别删除
+	assert.Equal(t, "GET /", logs[1].ContextMap()["request"])
+
+	_, err = cfg.scrubProcessor("missing")
+	assert.EqualError(t, err, `unknown scrub profile "missing"`)
+	cfg.ScrubProfiles["bad"] = []ScrubRule{{Pattern: "("}}
+	_, err = cfg.scrubProcessor("bad")
+	assert.Error(t, err)
+}
+
+func TestCheckScrubProfile(t *testing.T) {
+	r := CheckConfig(&Config{
+		ScrubProfiles: map[string][]ScrubRule{"bad": {{Levels: []string{"loud"}}}},
+		Appenders:     []Appender{{Type: "stdout", ScrubProfile: "external"}},
+	})
+	assert.Equal(t, []string{`scrub profile bad: rules[0]: invalid level "loud"`}, r.Problems)
+	assert.Equal(t, []string{`unknown scrub profile "external"`}, r.Appenders[0].Problems)
+}
@@ -0,0 +1,69 @@
+package logx
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 开启Sequence时日志携带的字段
+const (
+	SequenceKey = "seq"    // 进程内单调递增的序号，从1开始
+	RunIDKey    = "run_id" // 进程启动时生成的UUID，区分同一程序的多次运行
+)
+
+var (
+	sequence uint64
+	runID    = newUUID()
+)
+
+// 本进程的run_id
+func RunID() string {
+	return runID
+}
+
+// 随机生成的UUID v4
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// 为每条日志分配序号，包在采样之外，被采样丢弃的日志不占用序号，所有appender中同一条日志的序号相同
+// 消费方按run_id分组后，序号不连续说明有日志丢失，时间相同时按序号排序
+type sequenceCore struct {
+	zapcore.Core
+}
+
+func (c *sequenceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sequenceCore{c.Core.With(fields)}
+}
+
+func (c *sequenceCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	downstream := c.Core.Check(ent, nil)
+	if downstream == nil {
+		return ce
+	}
+	e := &sequencedEntry{Core: c.Core, downstream: downstream, seq: atomic.AddUint64(&sequence, 1)}
+	e.outer = ce.AddCore(ent, e)
+	return e.outer
+}
+
+// 一条已分配序号的日志，写入时将序号字段加入下游的core
+type sequencedEntry struct {
+	zapcore.Core
+	outer      *zapcore.CheckedEntry
+	downstream *zapcore.CheckedEntry
+	seq        uint64
+}
+
+func (e *sequencedEntry) Write(_ zapcore.Entry, fields []zapcore.Field) error {
+	e.downstream.ErrorOutput = e.outer.ErrorOutput
+	e.downstream.Write(append(fields, zap.Uint64(SequenceKey, e.seq), zap.String(RunIDKey, runID))...)
+	return nil
+}
@@ -0,0 +1,47 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSequence(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	var info, errs bytes.Buffer
+	Init(&Config{
+		Type:     "json",
+		Sequence: true,
+		Appenders: []Appender{
+			{Writer: NopCloser(&info), Level: "info"},
+			{Writer: NopCloser(&errs), Level: "error"},
+		},
+	})
+	Debug("skipped")
+	Info("first")
+	GetLogger().With(zap.String("k", "v")).Error("second")
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(info.String()), "\n") {
+		var r map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	if !assert.Len(t, records, 2) {
+		return
+	}
+	// 没有写入的日志不占用序号
+	assert.Equal(t, records[0][SequenceKey].(float64)+1, records[1][SequenceKey])
+	assert.Equal(t, RunID(), records[0][RunIDKey])
+	assert.Len(t, RunID(), 36)
+
+	// 同一条日志在各appender中的序号相同
+	var r map[string]interface{}
+	assert.NoError(t, json.Unmarshal(errs.Bytes(), &r))
+	assert.Equal(t, records[1][SequenceKey], r[SequenceKey])
+	assert.Equal(t, "v", r["k"])
+}
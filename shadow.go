@@ -0,0 +1,121 @@
+package logx
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// 影子appender的队列长度，队列满时丢弃日志，不阻塞应用
+const _shadowQueueSize = 1024
+
+// 影子appender关闭时等待队列中日志写入的最长时间
+const _shadowCloseTimeout = 5 * time.Second
+
+// 影子appender的统计，通过控制socket的stats命令查看，用于切换前评估新的输出目标
+type ShadowStats struct {
+	Written    uint64        `json:"written"`     // 写入成功的条数
+	Failed     uint64        `json:"failed"`      // 写入失败的条数
+	Dropped    uint64        `json:"dropped"`     // 队列满或writer创建失败时丢弃的条数
+	MaxLatency time.Duration `json:"max_latency"` // 单条日志写入的最长耗时
+	LastError  string        `json:"last_error"`  // 最近一次的错误
+}
+
+// 在后台协程中写入的writer，Write总是成功且不阻塞
+type shadowWriter struct {
+	written uint64 // 原子操作的字段放在开头，保证32位平台上8字节对齐
+	failed  uint64
+	dropped uint64
+	latency int64
+	w       rollingwriter.RollingWriter // 创建失败时为nil
+	queue   chan []byte
+	done    chan struct{}
+	mu      sync.RWMutex // 保护closed、关闭queue和lastErr
+	closed  bool
+	lastErr string
+}
+
+func newShadowWriter(w rollingwriter.RollingWriter, err error) *shadowWriter {
+	s := &shadowWriter{queue: make(chan []byte, _shadowQueueSize), done: make(chan struct{})}
+	if err != nil {
+		s.setError(err)
+		close(s.done)
+		return s
+	}
+	s.w = w
+	go s.loop()
+	return s
+}
+
+func (s *shadowWriter) Write(b []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.w == nil || s.closed {
+		atomic.AddUint64(&s.dropped, 1)
+		return len(b), nil
+	}
+	select {
+	case s.queue <- append([]byte(nil), b...):
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+	return len(b), nil
+}
+
+// 等待队列中的日志写入后关闭writer，最多等待_shadowCloseTimeout，超时后在后台继续关闭
+func (s *shadowWriter) Close() error {
+	s.mu.Lock()
+	if s.w == nil || s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+	case <-time.After(_shadowCloseTimeout):
+	}
+	return nil
+}
+
+func (s *shadowWriter) loop() {
+	defer close(s.done)
+	for b := range s.queue {
+		start := time.Now()
+		_, err := s.w.Write(b)
+		if d := int64(time.Since(start)); d > atomic.LoadInt64(&s.latency) {
+			atomic.StoreInt64(&s.latency, d)
+		}
+		if err != nil {
+			atomic.AddUint64(&s.failed, 1)
+			s.setError(err)
+			continue
+		}
+		atomic.AddUint64(&s.written, 1)
+	}
+	if err := s.w.Close(); err != nil {
+		s.setError(err)
+	}
+}
+
+func (s *shadowWriter) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err.Error()
+}
+
+func (s *shadowWriter) Stats() ShadowStats {
+	s.mu.RLock()
+	lastErr := s.lastErr
+	s.mu.RUnlock()
+	return ShadowStats{
+		Written:    atomic.LoadUint64(&s.written),
+		Failed:     atomic.LoadUint64(&s.failed),
+		Dropped:    atomic.LoadUint64(&s.dropped),
+		MaxLatency: time.Duration(atomic.LoadInt64(&s.latency)),
+		LastError:  lastErr,
+	}
+}
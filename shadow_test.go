@@ -0,0 +1,56 @@
+package logx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 每次写入都阻塞一段时间后失败的writer
+type slowFailWriter struct {
+	delay  time.Duration
+	closed bool
+}
+
+func (w *slowFailWriter) Write(b []byte) (int, error) {
+	time.Sleep(w.delay)
+	return 0, errors.New("sink unavailable")
+}
+
+func (w *slowFailWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestShadowAppender(t *testing.T) {
+	sink := &slowFailWriter{delay: 50 * time.Millisecond}
+	writers := newAppenderWriters([]Appender{
+		{Writer: sink, Shadow: true},
+		{Type: "missing", Shadow: true},
+	})
+
+	// 写入不等待影子appender，错误不返回给调用方
+	start := time.Now()
+	n, err := writers[0].Write([]byte("entry\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	assert.NoError(t, writers[0].Close())
+	assert.True(t, sink.closed)
+	stats := writers[0].(*shadowWriter).Stats()
+	assert.Equal(t, uint64(1), stats.Failed)
+	assert.Equal(t, "sink unavailable", stats.LastError)
+	assert.GreaterOrEqual(t, int64(stats.MaxLatency), int64(50*time.Millisecond))
+
+	// 创建失败时丢弃日志，不输出到标准输出
+	shadow, ok := writers[1].(*shadowWriter)
+	if assert.True(t, ok) {
+		n, err = shadow.Write([]byte("entry\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 6, n)
+		assert.Equal(t, ShadowStats{Dropped: 1, LastError: ErrUnknownAppender.Error()}, shadow.Stats())
+		assert.NoError(t, shadow.Close())
+	}
+}
@@ -0,0 +1,166 @@
+package logx
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// smtp类型appender的参数，通过appender的options配置，通常与level: warn或error一起使用
+type SMTPConfig struct {
+	Host       string   `json:"host"`        // SMTP服务器地址
+	Port       int      `json:"port"`        // SMTP服务器端口，为0时为25
+	Username   string   `json:"username"`    // 用户名，不为空时使用PLAIN认证
+	Password   string   `json:"password"`    // 密码
+	From       string   `json:"from"`        // 发件人
+	To         []string `json:"to"`          // 收件人
+	Subject    string   `json:"subject"`     // 邮件主题，为空时为logx digest
+	Interval   int      `json:"interval"`    // 汇总发送的间隔，单位秒，为0时为300
+	MaxEntries int      `json:"max_entries"` // 累计的日志条数达到该值时立即发送，为0时为100
+}
+
+// 发送邮件的函数，测试时替换
+var sendMail = smtp.SendMail
+
+func init() {
+	RegisterAppender("smtp", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		var cfg SMTPConfig
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		return NewSMTPWriter(cfg)
+	})
+}
+
+// 将日志汇总后通过邮件发送的writer，每Interval秒或累计MaxEntries条日志发送一封邮件
+// 发送慢于日志产生时最多缓存10*MaxEntries条日志，超过时丢弃最旧的日志并在邮件中注明丢弃的条数
+type SMTPWriter struct {
+	mu      sync.Mutex
+	cfg     SMTPConfig
+	entries [][]byte
+	dropped int
+	full    chan struct{} // 累计条数达到MaxEntries时通知发送
+	stop    chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+func NewSMTPWriter(cfg SMTPConfig) (*SMTPWriter, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 25
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = "logx digest"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 300
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 100
+	}
+	w := &SMTPWriter{
+		cfg:  cfg,
+		full: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+func (w *SMTPWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	if len(w.entries) >= 10*w.cfg.MaxEntries {
+		w.entries = w.entries[1:]
+		w.dropped++
+	}
+	w.entries = append(w.entries, append([]byte(nil), b...))
+	if len(w.entries) >= w.cfg.MaxEntries {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// 停止定时发送，发送剩余的日志
+func (w *SMTPWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+func (w *SMTPWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(time.Duration(w.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.full:
+		case <-w.stop:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// 发送累计的日志，发送失败时输出到标准错误，日志不再重发
+func (w *SMTPWriter) flush() {
+	w.mu.Lock()
+	entries, dropped := w.entries, w.dropped
+	w.entries, w.dropped = nil, 0
+	w.mu.Unlock()
+	if len(entries) == 0 {
+		return
+	}
+	if err := w.send(entries, dropped); err != nil {
+		fmt.Fprintf(os.Stderr, "send log digest: %v\n", err)
+	}
+}
+
+func (w *SMTPWriter) send(entries [][]byte, dropped int) error {
+	c := w.cfg
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s (%d entries)\r\n", c.Subject, len(entries)+dropped)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	if dropped > 0 {
+		fmt.Fprintf(&msg, "%d entries dropped\r\n\r\n", dropped)
+	}
+	for _, e := range entries {
+		msg.Write(bytes.TrimRight(e, "\n"))
+		msg.WriteString("\r\n")
+	}
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	return sendMail(net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), auth, c.From, c.To, msg.Bytes())
+}
@@ -0,0 +1,50 @@
+package logx
+
+import (
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMTPWriter(t *testing.T) {
+	var mu sync.Mutex
+	var mails []string
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "mail.local:2525", addr)
+		assert.Equal(t, []string{"ops@example.com"}, to)
+		mails = append(mails, string(msg))
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	w, err := newAppenderWriter(Appender{Type: "smtp", Options: map[string]interface{}{
+		"host": "mail.local", "port": 2525, "from": "app@example.com",
+		"to": []interface{}{"ops@example.com"}, "max_entries": 2,
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	w.Write([]byte("first\n"))
+	w.Write([]byte("second\n"))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(mails) == 1
+	}, time.Second, 10*time.Millisecond)
+	w.Write([]byte("third\n"))
+	assert.NoError(t, w.Close())
+
+	assert.Len(t, mails, 2)
+	assert.Contains(t, mails[0], "Subject: logx digest (2 entries)")
+	assert.True(t, strings.HasSuffix(mails[0], "first\r\nsecond\r\n"))
+	assert.True(t, strings.HasSuffix(mails[1], "\r\n\r\nthird\r\n"))
+
+	_, err = NewSMTPWriter(SMTPConfig{Host: "mail.local"})
+	assert.Error(t, err)
+}
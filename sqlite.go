@@ -0,0 +1,272 @@
+package logx
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Muskchen/logx/rollingwriter"
+)
+
+// sqlite类型appender的参数，通过appender的options配置，日志需要使用json格式
+// logx不依赖SQLite驱动，使用前需要导入database/sql的驱动，如github.com/mattn/go-sqlite3或modernc.org/sqlite
+type SQLiteConfig struct {
+	Driver        string   `json:"driver"`         // database/sql的驱动名称，为空时为sqlite3，modernc.org/sqlite为sqlite
+	DSN           string   `json:"dsn"`            // 数据库文件路径或驱动支持的DSN
+	Table         string   `json:"table"`          // 表名，为空时为logs
+	IndexFields   []string `json:"index_fields"`   // 需要建立索引的日志字段，嵌套的字段为user.id这样的路径，查询条件为json_extract(fields, '$.字段')时使用索引
+	LevelKey      string   `json:"level_key"`      // 日志中级别的字段名，为空时为level
+	MessageKey    string   `json:"message_key"`    // 日志中消息的字段名，为空时为msg
+	BatchSize     int      `json:"batch_size"`     // 每个事务写入的最大行数，为0时为500
+	FlushInterval int      `json:"flush_interval"` // 行数不足BatchSize时的写入间隔，单位秒，为0时为1
+	MaxAge        int      `json:"max_age"`        // 日志保留的时间，单位秒，每分钟删除更早的行，为0时不删除
+}
+
+// 表名和索引名只允许字母、数字和下划线
+var (
+	sqlIdent     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	sqlIdentChar = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+func init() {
+	RegisterAppender("sqlite", func(options map[string]interface{}) (rollingwriter.RollingWriter, error) {
+		var cfg SQLiteConfig
+		if err := decodeOptions(options, &cfg); err != nil {
+			return nil, err
+		}
+		return NewSQLiteWriter(cfg)
+	})
+}
+
+// 将日志写入本地SQLite数据库的writer，数据库使用WAL模式，由后台协程每BatchSize行或每FlushInterval秒在一个事务中写入
+// 表结构为id、ts（写入时的Unix纳秒时间）、level、message和fields（完整的json日志），ts、level和IndexFields中的字段有索引
+// 写入失败的行保留到下一次写入，最多缓存10*BatchSize行，超过时丢弃最旧的行
+type SQLiteWriter struct {
+	mu      sync.Mutex
+	cfg     SQLiteConfig
+	db      *sql.DB
+	rows    []sqliteRow
+	dropped int
+	signal  chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+type sqliteRow struct {
+	ts      int64
+	level   string
+	message string
+	fields  string
+}
+
+func NewSQLiteWriter(cfg SQLiteConfig) (*SQLiteWriter, error) {
+	if cfg.DSN == "" {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = "sqlite3"
+	}
+	if cfg.Table == "" {
+		cfg.Table = "logs"
+	}
+	if !sqlIdent.MatchString(cfg.Table) {
+		return nil, rollingwriter.ErrInvalidArgument
+	}
+	if cfg.LevelKey == "" {
+		cfg.LevelKey = "level"
+	}
+	if cfg.MessageKey == "" {
+		cfg.MessageKey = "msg"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 1
+	}
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite同时只有一个写入者
+	db.SetMaxOpenConns(1)
+	if err := createSQLiteSchema(db, cfg); err != nil {
+		db.Close()
+		return nil, err
+	}
+	w := &SQLiteWriter{
+		cfg:    cfg,
+		db:     db,
+		signal: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+// 开启WAL，创建表和索引
+func createSQLiteSchema(db *sql.DB, cfg SQLiteConfig) error {
+	t := cfg.Table
+	stmts := []string{
+		"PRAGMA journal_mode=WAL",
+		"PRAGMA synchronous=NORMAL",
+		"CREATE TABLE IF NOT EXISTS " + t + " (id INTEGER PRIMARY KEY AUTOINCREMENT, ts INTEGER NOT NULL, level TEXT, message TEXT, fields TEXT)",
+		"CREATE INDEX IF NOT EXISTS " + t + "_ts ON " + t + " (ts)",
+		"CREATE INDEX IF NOT EXISTS " + t + "_level ON " + t + " (level, ts)",
+	}
+	for _, field := range cfg.IndexFields {
+		name := t + "_f_" + sqlIdentChar.ReplaceAllString(field, "_")
+		path := "'$." + strings.Replace(field, "'", "''", -1) + "'"
+		stmts = append(stmts, "CREATE INDEX IF NOT EXISTS "+name+" ON "+t+" (json_extract(fields, "+path+"))")
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %v", stmt, err)
+		}
+	}
+	return nil
+}
+
+func (w *SQLiteWriter) Write(b []byte) (int, error) {
+	row := w.row(b)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, rollingwriter.ErrClosed
+	}
+	if len(w.rows) >= 10*w.cfg.BatchSize {
+		w.rows = w.rows[1:]
+		w.dropped++
+	}
+	w.rows = append(w.rows, row)
+	if len(w.rows) >= w.cfg.BatchSize {
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// 写入缓存中的日志，关闭数据库
+func (w *SQLiteWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return rollingwriter.ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return w.db.Close()
+}
+
+// 取出级别和消息，不是json对象的日志只有消息
+func (w *SQLiteWriter) row(b []byte) sqliteRow {
+	b = bytes.TrimSpace(b)
+	row := sqliteRow{ts: time.Now().UnixNano()}
+	var fields map[string]interface{}
+	if len(b) == 0 || b[0] != '{' || json.Unmarshal(b, &fields) != nil {
+		row.message = string(b)
+		return row
+	}
+	row.fields = string(b)
+	row.level, _ = fields[w.cfg.LevelKey].(string)
+	row.message, _ = fields[w.cfg.MessageKey].(string)
+	return row
+}
+
+func (w *SQLiteWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(time.Duration(w.cfg.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	var retention <-chan time.Time
+	if w.cfg.MaxAge > 0 {
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		retention = t.C
+		w.deleteExpired()
+	}
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.signal:
+		case <-retention:
+			w.deleteExpired()
+			continue
+		case <-w.stop:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// 按BatchSize写入缓存中的日志，失败时放回缓存
+func (w *SQLiteWriter) flush() {
+	for {
+		w.mu.Lock()
+		n := len(w.rows)
+		if n > w.cfg.BatchSize {
+			n = w.cfg.BatchSize
+		}
+		batch, dropped := w.rows[:n:n], w.dropped
+		w.rows, w.dropped = w.rows[n:], 0
+		w.mu.Unlock()
+		if dropped > 0 {
+			fmt.Fprintf(os.Stderr, "sqlite: %d entries dropped\n", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.insert(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "sqlite: insert: %v\n", err)
+			w.mu.Lock()
+			w.rows = append(batch, w.rows...)
+			w.mu.Unlock()
+			return
+		}
+	}
+}
+
+// 在一个事务中写入
+func (w *SQLiteWriter) insert(batch []sqliteRow) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO " + w.cfg.Table + " (ts, level, message, fields) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range batch {
+		var fields interface{}
+		if row.fields != "" {
+			fields = row.fields
+		}
+		if _, err := stmt.Exec(row.ts, row.level, row.message, fields); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// 删除超过MaxAge的日志
+func (w *SQLiteWriter) deleteExpired() {
+	before := time.Now().Add(-time.Duration(w.cfg.MaxAge) * time.Second).UnixNano()
+	if _, err := w.db.Exec("DELETE FROM "+w.cfg.Table+" WHERE ts < ?", before); err != nil {
+		fmt.Fprintf(os.Stderr, "sqlite: retention: %v\n", err)
+	}
+}
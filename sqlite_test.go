@@ -0,0 +1,95 @@
+package logx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 记录执行的语句的database/sql驱动
+type recordDriver struct {
+	mu    sync.Mutex
+	stmts []string
+	rows  [][]driver.Value
+}
+
+func (d *recordDriver) Open(string) (driver.Conn, error) { return recordConn{d}, nil }
+
+func (d *recordDriver) exec(query string, args []driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if strings.HasPrefix(query, "INSERT") {
+		d.rows = append(d.rows, args)
+		return
+	}
+	if len(args) > 0 {
+		query += fmt.Sprint(len(args))
+	}
+	d.stmts = append(d.stmts, query)
+}
+
+type recordConn struct{ d *recordDriver }
+
+func (c recordConn) Prepare(query string) (driver.Stmt, error) { return recordStmt{c.d, query}, nil }
+func (c recordConn) Close() error                              { return nil }
+func (c recordConn) Begin() (driver.Tx, error)                 { return recordTx{}, nil }
+
+type recordTx struct{}
+
+func (recordTx) Commit() error   { return nil }
+func (recordTx) Rollback() error { return nil }
+
+type recordStmt struct {
+	d     *recordDriver
+	query string
+}
+
+func (s recordStmt) Close() error  { return nil }
+func (s recordStmt) NumInput() int { return -1 }
+func (s recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.exec(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+func (s recordStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestSQLiteWriter(t *testing.T) {
+	d := &recordDriver{}
+	sql.Register("logx-record", d)
+
+	w, err := newAppenderWriter(Appender{Type: "sqlite", Options: map[string]interface{}{
+		"driver": "logx-record", "dsn": "logs.db", "index_fields": []string{"user.id"}, "max_age": 3600,
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	w.Write([]byte(`{"level":"ERROR","msg":"failed","user":{"id":1}}` + "\n"))
+	w.Write([]byte("plain\n"))
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []string{
+		"PRAGMA journal_mode=WAL",
+		"PRAGMA synchronous=NORMAL",
+		"CREATE TABLE IF NOT EXISTS logs (id INTEGER PRIMARY KEY AUTOINCREMENT, ts INTEGER NOT NULL, level TEXT, message TEXT, fields TEXT)",
+		"CREATE INDEX IF NOT EXISTS logs_ts ON logs (ts)",
+		"CREATE INDEX IF NOT EXISTS logs_level ON logs (level, ts)",
+		`CREATE INDEX IF NOT EXISTS logs_f_user_id ON logs (json_extract(fields, '$.user.id'))`,
+		"DELETE FROM logs WHERE ts < ?1",
+	}, d.stmts)
+	if assert.Len(t, d.rows, 2) {
+		assert.InDelta(t, time.Now().UnixNano(), d.rows[0][0], float64(time.Minute))
+		assert.Equal(t, []driver.Value{"ERROR", "failed", `{"level":"ERROR","msg":"failed","user":{"id":1}}`}, d.rows[0][1:])
+		assert.Equal(t, []driver.Value{"", "plain", nil}, d.rows[1][1:])
+	}
+
+	_, err = NewSQLiteWriter(SQLiteConfig{DSN: "logs.db", Table: "logs; DROP TABLE x"})
+	assert.Error(t, err)
+}
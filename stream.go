@@ -0,0 +1,470 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 实时日志流配置，配置后StreamHandler可以通过SSE或WebSocket推送最近的和新的日志
+type StreamConfig struct {
+	// 缓存的最近日志条数，新的连接先收到这些日志，默认为1000
+	Size int `json:"size" yaml:"size"`
+	// 推送的最低日志级别，默认为debug
+	Level string `json:"level" yaml:"level"`
+	// 允许建立WebSocket连接的网页来源，如https://admin.example.com，*为允许所有来源
+	// 默认只允许与请求的Host相同的来源，避免管理员访问的其他网页跨站读取日志，不带Origin的非浏览器客户端不受限制
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowedOrigins"`
+}
+
+// 每个连接待发送日志的队列长度，连接发送慢时丢弃新的日志
+const _streamQueueSize = 256
+
+// WebSocket握手使用的GUID
+const _websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	streamMu sync.RWMutex
+	stream   *streamHub
+)
+
+// 替换当前的日志流，为nil时StreamHandler返回404
+func setStream(h *streamHub) {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	stream = h
+}
+
+// 重新Init时沿用缓存大小相同的日志流，已有的连接继续接收日志
+func newStream(c *StreamConfig) *streamHub {
+	size := c.Size
+	if size <= 0 {
+		size = 1000
+	}
+	h := currentStream()
+	if h == nil || len(h.recent) != size {
+		h = newStreamHub(size)
+	}
+	h.mu.Lock()
+	h.origins = c.AllowedOrigins
+	h.mu.Unlock()
+	return h
+}
+
+func currentStream() *streamHub {
+	streamMu.RLock()
+	defer streamMu.RUnlock()
+	return stream
+}
+
+type streamEntry struct {
+	level zapcore.Level
+	line  []byte
+}
+
+// 缓存最近的日志并分发给订阅的连接
+type streamHub struct {
+	mu      sync.Mutex
+	recent  []streamEntry
+	next    int
+	full    bool
+	subs    map[*streamSub]struct{}
+	origins []string // StreamConfig.AllowedOrigins
+}
+
+// 一个连接的订阅
+type streamSub struct {
+	level   zapcore.Level
+	pattern *regexp.Regexp
+	ch      chan []byte
+	dropped int
+}
+
+func (s *streamSub) match(e streamEntry) bool {
+	return e.level >= s.level && (s.pattern == nil || s.pattern.Match(e.line))
+}
+
+func newStreamHub(size int) *streamHub {
+	return &streamHub{recent: make([]streamEntry, size), subs: make(map[*streamSub]struct{})}
+}
+
+// 是否允许来自origin的WebSocket连接，origin为空或与host相同时允许
+func (h *streamHub) allowOrigin(origin, host string) bool {
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, host) {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, o := range h.origins {
+		if o == "*" || strings.EqualFold(strings.TrimRight(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *streamHub) publish(level zapcore.Level, line []byte) {
+	e := streamEntry{level: level, line: line}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recent[h.next] = e
+	h.next++
+	if h.next == len(h.recent) {
+		h.next, h.full = 0, true
+	}
+	for sub := range h.subs {
+		if !sub.match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- line:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// 订阅新的日志，返回满足条件的最近n条日志
+func (h *streamHub) subscribe(sub *streamSub, n int) [][]byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var entries []streamEntry
+	if h.full {
+		entries = append(entries, h.recent[h.next:]...)
+	}
+	entries = append(entries, h.recent[:h.next]...)
+	var lines [][]byte
+	for _, e := range entries {
+		if sub.match(e) {
+			lines = append(lines, e.line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	h.subs[sub] = struct{}{}
+	return lines
+}
+
+func (h *streamHub) unsubscribe(sub *streamSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, sub)
+}
+
+// 取出并清零丢弃的条数
+func (h *streamHub) dropped(sub *streamSub) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := sub.dropped
+	sub.dropped = 0
+	return n
+}
+
+// 将日志按json编码后发布到日志流
+type streamCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	hub *streamHub
+}
+
+func newStreamCore(config zapcore.EncoderConfig, c *StreamConfig, hub *streamHub) *streamCore {
+	level := zapcore.DebugLevel
+	if c.Level != "" {
+		level = logLevel(c.Level)
+	}
+	config.LineEnding = "\n"
+	return &streamCore{LevelEnabler: level, enc: zapcore.NewJSONEncoder(config), hub: hub}
+}
+
+func (c *streamCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *streamCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *streamCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	line := append([]byte(nil), bytes.TrimRight(buf.Bytes(), "\n")...)
+	buf.Free()
+	c.hub.publish(ent.Level, line)
+	return nil
+}
+
+func (c *streamCore) Sync() error {
+	return nil
+}
+
+// 推送实时日志的http.Handler，请求带WebSocket升级头时使用WebSocket，浏览器直接打开时返回日志查看页面，否则使用SSE，每条日志为一条json
+// 查询参数level为最低级别，q为匹配整条json日志的正则表达式，recent为先推送的最近日志条数，默认为100
+// 连接发送慢时丢弃日志，SSE以dropped事件、WebSocket以{"dropped":n}消息通知丢弃的条数
+// WebSocket连接校验Origin、Connection和Sec-WebSocket-Version，跨站来源需在StreamConfig.AllowedOrigins中允许
+func StreamHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub := currentStream()
+		if hub == nil {
+			http.Error(w, "log stream not enabled", http.StatusNotFound)
+			return
+		}
+		sub := &streamSub{ch: make(chan []byte, _streamQueueSize)}
+		query := r.URL.Query()
+		if level := query.Get("level"); level != "" {
+			if !validLevel(level) {
+				http.Error(w, fmt.Sprintf("invalid level %q", level), http.StatusBadRequest)
+				return
+			}
+			sub.level = logLevel(level)
+		} else {
+			sub.level = zapcore.DebugLevel
+		}
+		if q := query.Get("q"); q != "" {
+			pattern, err := regexp.Compile(q)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			sub.pattern = pattern
+		}
+		n := 100
+		if s := query.Get("recent"); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil || v < 0 {
+				http.Error(w, fmt.Sprintf("invalid recent %q", s), http.StatusBadRequest)
+				return
+			}
+			n = v
+		}
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			serveWebSocket(w, r, hub, sub, n)
+			return
+		}
+		if wantsViewer(r) {
+			serveViewer(w)
+			return
+		}
+		serveSSE(w, r, hub, sub, n)
+	})
+}
+
+// 逗号分隔的头中是否包含token，不区分大小写
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func serveSSE(w http.ResponseWriter, r *http.Request, hub *streamHub, sub *streamSub, n int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	recent := hub.subscribe(sub, n)
+	defer hub.unsubscribe(sub)
+	for _, line := range recent {
+		fmt.Fprintf(w, "data: %s\n\n", line)
+	}
+	flusher.Flush()
+
+	// 定时发送注释保持连接
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line := <-sub.ch:
+			if dropped := hub.dropped(sub); dropped > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", dropped)
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", line); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// 实现RFC 6455中服务端推送文本消息所需的部分，客户端发送的消息除close和ping外忽略
+func serveWebSocket(w http.ResponseWriter, r *http.Request, hub *streamHub, sub *streamSub, n int) {
+	if !hub.allowOrigin(r.Header.Get("Origin"), r.Host) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	hijacker, ok := w.(http.Hijacker)
+	if key == "" || !headerHasToken(r.Header, "Connection", "upgrade") || !ok {
+		http.Error(w, "bad websocket handshake", http.StatusBadRequest)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	sum := sha1.Sum([]byte(key + _websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	var wmu sync.Mutex
+	send := func(opcode byte, payload []byte) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		writeWebSocketFrame(rw.Writer, opcode, payload)
+		return rw.Flush()
+	}
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := readWebSocketFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case 0x8:
+				send(0x8, payload)
+				return
+			case 0x9:
+				send(0xa, payload)
+			}
+		}
+	}()
+
+	recent := hub.subscribe(sub, n)
+	defer hub.unsubscribe(sub)
+	for _, line := range recent {
+		if send(0x1, line) != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case <-closed:
+			return
+		case line := <-sub.ch:
+			if dropped := hub.dropped(sub); dropped > 0 {
+				if send(0x1, []byte(fmt.Sprintf(`{"dropped":%d}`, dropped))) != nil {
+					return
+				}
+			}
+			if send(0x1, line) != nil {
+				return
+			}
+		}
+	}
+}
+
+// 写入一个不分片、不加掩码的帧
+func writeWebSocketFrame(w *bufio.Writer, opcode byte, payload []byte) {
+	w.WriteByte(0x80 | opcode)
+	switch n := len(payload); {
+	case n < 126:
+		w.WriteByte(byte(n))
+	case n <= 0xffff:
+		w.WriteByte(126)
+		var b [2]byte
+		binary.BigEndian.PutUint16(b[:], uint16(n))
+		w.Write(b[:])
+	default:
+		w.WriteByte(127)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(n))
+		w.Write(b[:])
+	}
+	w.Write(payload)
+}
+
+// 读取一个帧，返回去掉掩码后的内容
+func readWebSocketFrame(r *bufio.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	// 客户端只发送控制帧和少量消息
+	if n > 1<<20 {
+		return 0, nil, fmt.Errorf("websocket frame too large: %d", n)
+	}
+	var mask [4]byte
+	masked := head[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return head[0] & 0x0f, payload, nil
+}
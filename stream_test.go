@@ -0,0 +1,150 @@
+package logx
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestStreamHandler(t *testing.T) {
+	defer setStream(nil)
+	hub := newStream(&StreamConfig{Size: 10})
+	setStream(hub)
+	l := zap.New(newStreamCore(newEncoderConfig(""), &StreamConfig{}, hub))
+	l.Debug("old debug")
+	l.Warn("old warn")
+
+	srv := httptest.NewServer(StreamHandler())
+	defer srv.Close()
+
+	// SSE
+	resp, err := http.Get(srv.URL + "?level=warn&q=payment")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+	l.Error("payment failed")
+	l.Error("other failed")
+	l.Info("payment ok")
+	l.Warn("payment slow")
+	for _, msg := range []string{"payment failed", "payment slow"} {
+		line, err := r.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Contains(t, line, `"msg":"`+msg+`"`)
+		r.ReadString('\n')
+	}
+
+	// WebSocket，先收到最近的日志
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /?recent=1 HTTP/1.1\r\nHost: logx\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	wr := bufio.NewReader(conn)
+	status, _ := wr.ReadString('\n')
+	assert.Contains(t, status, "101")
+	for {
+		line, _ := wr.ReadString('\n')
+		if strings.HasPrefix(line, "Sec-WebSocket-Accept") {
+			assert.Equal(t, "Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n", line)
+		}
+		if line == "\r\n" || line == "" {
+			break
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	opcode, payload, err := readWebSocketFrame(wr)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(1), opcode)
+	assert.Contains(t, string(payload), `"msg":"payment slow"`)
+	// 订阅后才写入的日志
+	assert.Eventually(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.subs) == 2
+	}, time.Second, 10*time.Millisecond)
+	l.Info("live")
+	_, payload, err = readWebSocketFrame(wr)
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), `"msg":"live"`)
+
+	setStream(nil)
+	resp, err = http.Get(srv.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestStreamViewer(t *testing.T) {
+	defer setStream(nil)
+	setStream(newStream(&StreamConfig{}))
+	srv := httptest.NewServer(StreamHandler())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "new EventSource(")
+}
+
+func TestStreamWebSocketHandshake(t *testing.T) {
+	defer setStream(nil)
+	setStream(newStream(&StreamConfig{AllowedOrigins: []string{"https://admin.example.com"}}))
+	srv := httptest.NewServer(StreamHandler())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+	}{
+		{"same origin", map[string]string{"Origin": "http://" + host}, http.StatusSwitchingProtocols},
+		{"allowed origin", map[string]string{"Origin": "https://admin.example.com"}, http.StatusSwitchingProtocols},
+		{"no origin", nil, http.StatusSwitchingProtocols},
+		{"cross origin", map[string]string{"Origin": "https://evil.example.com"}, http.StatusForbidden},
+		{"bad version", map[string]string{"Sec-WebSocket-Version": "8"}, http.StatusUpgradeRequired},
+		{"no connection upgrade", map[string]string{"Connection": "keep-alive"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			if tt.status == http.StatusUpgradeRequired {
+				assert.Equal(t, "13", resp.Header.Get("Sec-WebSocket-Version"))
+			}
+		})
+	}
+}
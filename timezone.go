@@ -0,0 +1,46 @@
+package logx
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 日志时间的来源
+type Clock interface {
+	Now() time.Time
+}
+
+// 使用Clock替换日志的时间，包在所有core的最外层，保证采样和各appender使用同一时间
+type clockCore struct {
+	zapcore.Core
+	clock Clock
+}
+
+func (c *clockCore) With(fields []zapcore.Field) zapcore.Core {
+	return &clockCore{Core: c.Core.With(fields), clock: c.clock}
+}
+
+func (c *clockCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ent.Time = c.clock.Now()
+	return c.Core.Check(ent, ce)
+}
+
+// appender配置了TimeZone时返回按该时区输出时间的encoder配置和encoder，时区无效时使用本机时区
+func appenderEncoder(cfg *Config, app Appender, config zapcore.EncoderConfig, enc zapcore.Encoder) (zapcore.EncoderConfig, zapcore.Encoder) {
+	if app.TimeZone == "" || config.EncodeTime == nil {
+		return config, enc
+	}
+	loc, err := time.LoadLocation(app.TimeZone)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load time zone %s: %v\n", app.TimeZone, err)
+		return config, enc
+	}
+	encodeTime := config.EncodeTime
+	config.EncodeTime = func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
+		encodeTime(t.In(loc), pae)
+	}
+	return config, encoder(cfg.Type, config)
+}
@@ -0,0 +1,40 @@
+package logx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestTimeZoneAndClock(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	var utc, shanghai, local bytes.Buffer
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	Init(&Config{
+		Type:   "json",
+		Format: "2006-01-02T15:04:05Z07:00",
+		Clock:  fixedClock(at),
+		Appenders: []Appender{
+			{Writer: NopCloser(&utc), TimeZone: "UTC"},
+			{Writer: NopCloser(&shanghai), TimeZone: "Asia/Shanghai"},
+			{Writer: NopCloser(&local), TimeZone: "Mars/Olympus"},
+		},
+	})
+	Info("hello")
+	assert.Contains(t, utc.String(), `"ts":"2024-01-02T03:04:05Z"`)
+	if _, err := time.LoadLocation("Asia/Shanghai"); err == nil {
+		assert.Contains(t, shanghai.String(), `"ts":"2024-01-02T11:04:05+08:00"`)
+	}
+	// 无效的时区使用本机时区
+	assert.Contains(t, local.String(), `"ts":"`+at.Local().Format("2006-01-02T15:04:05Z07:00")+`"`)
+
+	r := CheckConfig(&Config{Appenders: []Appender{{Type: "stdout", TimeZone: "Mars/Olympus"}}})
+	assert.Equal(t, []string{`invalid time zone "Mars/Olympus"`}, r.Appenders[0].Problems)
+}
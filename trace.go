@@ -0,0 +1,115 @@
+package logx
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	traceEvents int32        // 是否将error及以上级别的日志记录为span事件
+	traceECS    int32        // 是否使用ECS的trace字段名
+	traceGCP    atomic.Value // Schema为gcp时为项目ID，否则为nil
+)
+
+func setTrace(cfg *Config) {
+	var events, ecs int32
+	if cfg.TraceEvents {
+		events = 1
+	}
+	if cfg.ecs() {
+		ecs = 1
+	}
+	atomic.StoreInt32(&traceEvents, events)
+	atomic.StoreInt32(&traceECS, ecs)
+	var project *string
+	if cfg.gcp() {
+		id := ""
+		if cfg.GCP != nil {
+			id = cfg.GCP.ProjectID
+		}
+		project = &id
+	}
+	traceGCP.Store(project)
+}
+
+// 返回携带ctx中OpenTelemetry span信息的logger，添加trace_id、span_id、trace_flags字段
+// ctx中没有有效的span时返回当前logger
+func Ctx(ctx context.Context) *zap.Logger {
+	span := trace.SpanFromContext(ctx)
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return logger
+	}
+	keys := [3]string{"trace_id", "span_id", "trace_flags"}
+	if atomic.LoadInt32(&traceECS) == 1 {
+		keys = [3]string{"trace.id", "span.id", "trace.flags"}
+	}
+	fields := []zap.Field{
+		zap.String(keys[0], sc.TraceID().String()),
+		zap.String(keys[1], sc.SpanID().String()),
+		zap.String(keys[2], sc.TraceFlags().String()),
+	}
+	// Cloud Logging的trace字段，设置了项目ID时关联到Cloud Trace
+	if project, _ := traceGCP.Load().(*string); project != nil {
+		traceID := sc.TraceID().String()
+		if *project != "" {
+			traceID = "projects/" + *project + "/traces/" + traceID
+		}
+		fields = []zap.Field{
+			zap.String(gcpTraceKey, traceID),
+			zap.String(gcpSpanKey, sc.SpanID().String()),
+			zap.Bool(gcpSampledKey, sc.IsSampled()),
+		}
+	}
+	l := logger.With(fields...)
+	if atomic.LoadInt32(&traceEvents) == 1 && span.IsRecording() {
+		l = l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, &spanCore{span: span})
+		}))
+	}
+	return l
+}
+
+// 将error及以上级别的日志记录为span事件
+type spanCore struct {
+	span   trace.Span
+	fields []zapcore.Field
+}
+
+func (c *spanCore) Enabled(l zapcore.Level) bool {
+	return l >= zapcore.ErrorLevel
+}
+
+func (c *spanCore) With(fields []zapcore.Field) zapcore.Core {
+	return &spanCore{span: c.span, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *spanCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *spanCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	attrs := []attribute.KeyValue{attribute.String("log.severity", ent.Level.CapitalString()), attribute.String("log.message", ent.Message)}
+	for _, f := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		if f.Type == zapcore.ErrorType {
+			if err, ok := f.Interface.(error); ok {
+				c.span.RecordError(err, trace.WithAttributes(attrs...))
+				return nil
+			}
+		}
+	}
+	c.span.AddEvent("log", trace.WithAttributes(attrs...))
+	return nil
+}
+
+func (c *spanCore) Sync() error {
+	return nil
+}
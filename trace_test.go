@@ -0,0 +1,61 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// 记录事件的span
+type recordingSpan struct {
+	trace.Span
+	sc     trace.SpanContext
+	events []string
+	errs   []error
+}
+
+func (s *recordingSpan) IsRecording() bool              { return true }
+func (s *recordingSpan) SpanContext() trace.SpanContext { return s.sc }
+func (s *recordingSpan) AddEvent(name string, _ ...trace.EventOption) {
+	s.events = append(s.events, name)
+}
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+
+func TestCtx(t *testing.T) {
+	defer setLogger(zap.NewNop())
+	defer setTrace(&Config{})
+	obs, logs := observer.New(zapcore.InfoLevel)
+	setLogger(zap.New(obs))
+	setTrace(&Config{TraceEvents: true})
+
+	assert.Equal(t, logger, Ctx(context.Background()))
+
+	span := &recordingSpan{
+		Span: trace.SpanFromContext(context.Background()),
+		sc: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{2},
+			TraceFlags: trace.FlagsSampled,
+		}),
+	}
+	ctx := trace.ContextWithSpan(context.Background(), span)
+	Ctx(ctx).Info("info")
+	Ctx(ctx).Error("failed", zap.Error(errors.New("boom")))
+	Ctx(ctx).Error("plain")
+
+	assert.Equal(t, map[string]interface{}{
+		"trace_id":    "01000000000000000000000000000000",
+		"span_id":     "0200000000000000",
+		"trace_flags": "01",
+	}, logs.All()[0].ContextMap())
+	assert.Equal(t, []error{errors.New("boom")}, span.errs)
+	assert.Equal(t, []string{"log"}, span.events)
+}
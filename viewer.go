@@ -0,0 +1,126 @@
+package logx
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 浏览器直接打开StreamHandler的地址时返回的日志查看页面，通过EventSource连接同一地址
+// 级别按钮和搜索框修改level和q参数后重新连接，暂停时新的日志先缓存，继续时一起显示
+const viewerHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>logx</title>
+<style>
+body { margin: 0; font: 12px/1.5 Menlo, Consolas, monospace; background: #1e1e1e; color: #d4d4d4; }
+header { position: sticky; top: 0; display: flex; gap: 6px; align-items: center; padding: 6px 8px; background: #252526; border-bottom: 1px solid #3c3c3c; }
+button { font: inherit; color: inherit; background: #3c3c3c; border: 1px solid #555; border-radius: 3px; padding: 2px 8px; cursor: pointer; }
+button.active { background: #0e639c; border-color: #1177bb; }
+input { font: inherit; color: inherit; background: #3c3c3c; border: 1px solid #555; border-radius: 3px; padding: 2px 6px; width: 320px; }
+#status { margin-left: auto; color: #888; }
+#logs { padding: 4px 8px; white-space: pre-wrap; word-break: break-all; }
+.DEBUG { color: #888; } .WARN { color: #dcdcaa; } .ERROR, .DPANIC { color: #f48771; } .PANIC, .FATAL { color: #fff; background: #a1260d; }
+.dropped { color: #c586c0; }
+</style>
+</head>
+<body>
+<header>
+<button data-level="debug">DEBUG</button>
+<button data-level="info">INFO</button>
+<button data-level="warn">WARN</button>
+<button data-level="error">ERROR</button>
+<input id="search" placeholder="regexp, press Enter">
+<button id="pause">Pause</button>
+<button id="clear">Clear</button>
+<span id="status"></span>
+</header>
+<div id="logs"></div>
+<script>
+(function () {
+  var maxRows = 2000, level = "debug", query = "", paused = false, pending = [], source = null;
+  var logs = document.getElementById("logs"), status = document.getElementById("status");
+  var buttons = document.querySelectorAll("button[data-level]");
+
+  function levelOf(line) {
+    try {
+      var e = JSON.parse(line);
+      return String(e.level || e["log.level"] || e.severity || "").toUpperCase();
+    } catch (err) {
+      return "";
+    }
+  }
+  function append(text, cls) {
+    var atBottom = window.innerHeight + window.scrollY >= document.body.scrollHeight - 4;
+    var row = document.createElement("div");
+    row.className = cls;
+    row.textContent = text;
+    logs.appendChild(row);
+    while (logs.childNodes.length > maxRows) logs.removeChild(logs.firstChild);
+    if (atBottom) window.scrollTo(0, document.body.scrollHeight);
+  }
+  function show(line) {
+    if (paused) {
+      pending.push(line);
+      if (pending.length > maxRows) pending.shift();
+      status.textContent = "paused, " + pending.length + " new";
+      return;
+    }
+    append(line, levelOf(line));
+  }
+  function connect() {
+    if (source) source.close();
+    var params = "?level=" + encodeURIComponent(level) + (query ? "&q=" + encodeURIComponent(query) : "");
+    source = new EventSource(location.pathname + params);
+    source.onopen = function () { status.textContent = paused ? "paused" : "live"; };
+    source.onerror = function () { status.textContent = "reconnecting"; };
+    source.onmessage = function (e) { show(e.data); };
+    source.addEventListener("dropped", function (e) { append(e.data + " entries dropped", "dropped"); });
+    for (var i = 0; i < buttons.length; i++) {
+      buttons[i].className = buttons[i].getAttribute("data-level") === level ? "active" : "";
+    }
+  }
+  for (var i = 0; i < buttons.length; i++) {
+    buttons[i].onclick = function () {
+      level = this.getAttribute("data-level");
+      logs.textContent = "";
+      connect();
+    };
+  }
+  document.getElementById("search").onkeydown = function (e) {
+    if (e.key !== "Enter") return;
+    query = this.value;
+    logs.textContent = "";
+    connect();
+  };
+  document.getElementById("pause").onclick = function () {
+    paused = !paused;
+    this.textContent = paused ? "Resume" : "Pause";
+    this.className = paused ? "active" : "";
+    if (!paused) {
+      var lines = pending;
+      pending = [];
+      for (var i = 0; i < lines.length; i++) append(lines[i], levelOf(lines[i]));
+    }
+    status.textContent = paused ? "paused" : "live";
+  };
+  document.getElementById("clear").onclick = function () { logs.textContent = ""; };
+  connect();
+})();
+</script>
+</body>
+</html>
+`
+
+// 浏览器打开页面时Accept包含text/html，EventSource请求的Accept为text/event-stream
+func wantsViewer(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/html") && !strings.Contains(accept, "text/event-stream")
+}
+
+func serveViewer(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	io.WriteString(w, viewerHTML)
+}